@@ -0,0 +1,80 @@
+// Command reconcile checks the seat counts in Postgres and Redis against the
+// bookings they should be derived from and prints every count that drifted.
+// With -repair it also repairs them. It exits with status 1 if any drift is
+// left in place.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+	"airline-booking-system/internal/services"
+	"airline-booking-system/pkg/database"
+	"airline-booking-system/pkg/redis"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "repair the seat counts that drifted")
+	settle := flag.Duration("settle", 5*time.Second, "how long a drifted seat inventory must hold its value before it is repaired")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	report, err := run(context.Background(), cfg, *repair, *settle)
+	if err != nil {
+		log.Fatalf("Failed to reconcile seats: %v", err)
+	}
+
+	for _, drift := range report.Drifts {
+		fmt.Println(drift)
+	}
+
+	unrepaired := report.Unrepaired()
+	fmt.Printf("Checked %d flights and %d fare classes: %d seat counts drifted, %d left in place\n",
+		report.FlightsChecked, report.FareClassesChecked, len(report.Drifts), len(unrepaired))
+
+	if len(unrepaired) > 0 {
+		os.Exit(1)
+	}
+}
+
+// run reconciles seat counts once, or twice settle apart when repairing so
+// seat inventories can be checked for a stable drift first
+func run(ctx context.Context, cfg *config.Config, repair bool, settle time.Duration) (*models.SeatReconciliationReport, error) {
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&cfg.Redis)
+	defer redisClient.Close()
+
+	if err := redisClient.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	flightRepo := repositories.NewFlightRepository(db)
+	fareClassRepo := repositories.NewFareClassRepository(db)
+	cacheService := cache.NewFlightCacheService(redisClient, &cfg.App)
+	reconciler := services.NewSeatReconciler(flightRepo, fareClassRepo, cacheService, &cfg.App)
+
+	report, err := reconciler.ReconcileSeats(ctx, false)
+	if err != nil || !repair || len(report.Drifts) == 0 {
+		return report, err
+	}
+
+	time.Sleep(settle)
+	return reconciler.ReconcileSeats(ctx, true)
+}
@@ -30,7 +30,10 @@ import (
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
 	// Initialize distributed tracing (no-op if disabled)
 	tracerCtx, cancelTracer := context.WithTimeout(context.Background(), 10*time.Second)
@@ -64,27 +67,83 @@ func main() {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
-	// Initialize Kafka producer
-	kafkaProducer := kafka.NewProducer(&cfg.Kafka)
+	// Initialize Kafka dead-letter queue and producer
+	deadLetterQueue := kafka.NewDeadLetterQueue(&cfg.Kafka)
+	defer deadLetterQueue.Close()
+
+	kafkaProducer := kafka.NewProducer(&cfg.Kafka, deadLetterQueue)
 	defer kafkaProducer.Close()
 
 	// Initialize repositories
 	flightRepo := repositories.NewFlightRepository(db)
 	bookingRepo := repositories.NewBookingRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	seatRepo := repositories.NewSeatRepository(db)
+	fareClassRepo := repositories.NewFareClassRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	routePopularityRepo := repositories.NewRoutePopularityRepository(db)
 
 	// Initialize cache service
 	cacheService := cache.NewFlightCacheService(redisClient, &cfg.App)
 
+	// Search results and quotes carry signed tokens holding their price
+	pricingService := services.NewPricingService(&cfg.App)
+
+	// Initialize payment gateway
+	paymentGateway, err := services.NewPaymentGateway(&cfg.App)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment gateway: %v", err)
+	}
+
+	// Events are recorded in the outbox and relayed to Kafka
+	outboxProducer := services.NewOutboxProducer(outboxRepo)
+
 	// Initialize services
-	flightService := services.NewFlightService(flightRepo, cacheService, &cfg.App)
-	bookingService := services.NewBookingService(bookingRepo, flightRepo, cacheService, kafkaProducer, &cfg.App)
+	flightService := services.NewFlightService(flightRepo, seatRepo, fareClassRepo, cacheService, pricingService, db, &cfg.App)
+	deadLetterService := services.NewDeadLetterService(deadLetterQueue)
+	seatInventoryService := services.NewSeatInventoryService(flightRepo, fareClassRepo, cacheService, &cfg.App)
+	bookingService := services.NewBookingService(bookingRepo, orderRepo, flightRepo, seatRepo, fareClassRepo, cacheService, pricingService, outboxProducer, paymentGateway, db, &cfg.App)
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	bookingReaper := services.NewBookingReaper(bookingRepo, orderRepo, seatRepo, cacheService, outboxProducer, paymentGateway, db, &cfg.App)
+	go bookingReaper.Run(workerCtx)
+
+	outboxRelay := services.NewOutboxRelay(outboxRepo, kafkaProducer, db, &cfg.App)
+	go outboxRelay.Run(workerCtx)
+
+	// Pre-populate the search cache for popular routes
+	cacheWarmer := services.NewCacheWarmer(flightService, routePopularityRepo, cacheService, &cfg.App)
+	go cacheWarmer.Run(workerCtx)
+
+	// Deduct seats booked from seat inventories and correct inventory drift
+	go seatInventoryService.Run(workerCtx)
+
+	// Check seat counts in Postgres and Redis against bookings
+	seatReconciler := services.NewSeatReconciler(flightRepo, fareClassRepo, cacheService, &cfg.App)
+	go seatReconciler.Run(workerCtx)
+
+	// Keep the Redis seat cache in sync with seat update events
+	seatCacheSync := services.NewSeatCacheSync(flightRepo, cacheService)
+	seatUpdateConsumer := kafka.NewConsumer(&cfg.Kafka, cfg.Kafka.TopicBookings, deadLetterQueue)
+	defer seatUpdateConsumer.Close()
+
+	go func() {
+		if err := seatUpdateConsumer.Run(workerCtx, seatCacheSync.HandleSeatUpdate); err != nil {
+			log.Printf("Seat update consumer stopped: %v", err)
+		}
+	}()
 
 	// Initialize handlers
 	flightHandler := handlers.NewFlightHandler(flightService)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
+	paymentHandler := handlers.NewPaymentHandler(bookingService, cfg.App.PaymentWebhookSecret)
+	adminHandler := handlers.NewAdminHandler(deadLetterService, seatInventoryService)
 
 	// Setup routes
-	router := setupRoutes(flightHandler, bookingHandler)
+	router := setupRoutes(flightHandler, bookingHandler, paymentHandler, adminHandler)
 
 	// Setup server
 	server := &http.Server{
@@ -109,6 +168,9 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
+	// Stop background workers
+	stopWorkers()
+
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -120,7 +182,7 @@ func main() {
 	log.Println("Server exited")
 }
 
-func setupRoutes(fh *handlers.FlightHandler, bh *handlers.BookingHandler) *mux.Router {
+func setupRoutes(fh *handlers.FlightHandler, bh *handlers.BookingHandler, ph *handlers.PaymentHandler, ah *handlers.AdminHandler) *mux.Router {
 	router := mux.NewRouter()
 
 	// Expose Prometheus metrics at /metrics
@@ -131,14 +193,33 @@ func setupRoutes(fh *handlers.FlightHandler, bh *handlers.BookingHandler) *mux.R
 
 	// Flight routes
 	api.HandleFunc("/flights/search", fh.SearchFlights).Methods("GET")
+	api.HandleFunc("/itineraries/search", fh.SearchItineraries).Methods("GET")
+	api.HandleFunc("/flights/calendar", fh.GetFareCalendar).Methods("GET")
 	api.HandleFunc("/flights/{id}", fh.GetFlight).Methods("GET")
+	api.HandleFunc("/flights/{id}/seatmap", fh.GetSeatMap).Methods("GET")
 	api.HandleFunc("/flights", fh.CreateFlight).Methods("POST")
 	api.HandleFunc("/flights/{id}", fh.UpdateFlight).Methods("PUT")
 
 	// Booking routes
 	api.HandleFunc("/bookings", bh.CreateBooking).Methods("POST")
+	api.HandleFunc("/flights/{id}/booking-queue", bh.GetBookingQueue).Methods("GET")
+	api.HandleFunc("/itineraries/bookings", bh.CreateItineraryBooking).Methods("POST")
 	api.HandleFunc("/bookings/{id}", bh.GetBooking).Methods("GET")
+	api.HandleFunc("/bookings/{id}/cancel", bh.CancelBooking).Methods("POST")
+	api.HandleFunc("/orders", bh.CreateOrder).Methods("POST")
+	api.HandleFunc("/orders/{id}", bh.GetOrder).Methods("GET")
 	api.HandleFunc("/users/{userId}/bookings", bh.GetUserBookings).Methods("GET")
+	api.HandleFunc("/quotes", bh.CreateQuote).Methods("POST")
+
+	// Payment routes
+	api.HandleFunc("/payments/webhook", ph.PaymentWebhook).Methods("POST")
+
+	// Admin routes
+	api.HandleFunc("/admin/dead-letters", ah.ListDeadLetters).Methods("GET")
+	api.HandleFunc("/admin/dead-letters/{offset}/replay", ah.ReplayDeadLetter).Methods("POST")
+	api.HandleFunc("/admin/flights/{id}/seat-inventory", ah.GetSeatInventory).Methods("GET")
+	api.HandleFunc("/admin/flights/{id}/seat-inventory", ah.EnableSeatInventory).Methods("PUT")
+	api.HandleFunc("/admin/flights/{id}/seat-inventory", ah.DisableSeatInventory).Methods("DELETE")
 
 	// Health check
 	api.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
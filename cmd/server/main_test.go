@@ -17,6 +17,14 @@ func (d *dummyFlightService) SearchFlights(ctx context.Context, req *models.Flig
 	return nil, nil
 }
 
+func (d *dummyFlightService) SearchItineraries(ctx context.Context, req *models.ItinerarySearchRequest) (*models.ItinerarySearchResponse, error) {
+	return nil, nil
+}
+
+func (d *dummyFlightService) GetFareCalendar(ctx context.Context, req *models.FareCalendarRequest) (*models.FareCalendarResponse, error) {
+	return nil, nil
+}
+
 func (d *dummyFlightService) GetFlightByID(ctx context.Context, id int64) (*models.Flight, error) {
 	return nil, nil
 }
@@ -29,12 +37,20 @@ func (d *dummyFlightService) UpdateFlight(ctx context.Context, flight *models.Fl
 	return nil
 }
 
+func (d *dummyFlightService) GetSeatMap(ctx context.Context, flightID int64) (*models.SeatMap, error) {
+	return nil, nil
+}
+
 type dummyBookingService struct{}
 
 func (d *dummyBookingService) CreateBooking(ctx context.Context, req *models.BookingRequest) (*models.BookingResponse, error) {
 	return nil, nil
 }
 
+func (d *dummyBookingService) CreateItineraryBooking(ctx context.Context, req *models.ItineraryBookingRequest) (*models.ItineraryBookingResponse, error) {
+	return nil, nil
+}
+
 func (d *dummyBookingService) GetBookingByID(ctx context.Context, id int64) (*models.Booking, error) {
 	return nil, nil
 }
@@ -43,11 +59,61 @@ func (d *dummyBookingService) GetBookingsByUserID(ctx context.Context, userID in
 	return nil, nil
 }
 
+func (d *dummyBookingService) CancelBooking(ctx context.Context, id int64) (*models.BookingResponse, error) {
+	return nil, nil
+}
+
+func (d *dummyBookingService) CreateQuote(ctx context.Context, req *models.QuoteRequest) (*models.QuoteResponse, error) {
+	return nil, nil
+}
+
+func (d *dummyBookingService) CreateOrder(ctx context.Context, req *models.OrderRequest) (*models.OrderResponse, error) {
+	return nil, nil
+}
+
+func (d *dummyBookingService) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	return nil, nil
+}
+
+func (d *dummyBookingService) GetBookingQueue(ctx context.Context, flightID int64) (*models.BookingQueueStatus, error) {
+	return nil, nil
+}
+
+func (d *dummyBookingService) HandlePaymentWebhook(ctx context.Context, event *models.PaymentWebhookEvent) (*models.BookingResponse, error) {
+	return nil, nil
+}
+
+type dummyDeadLetterService struct{}
+
+func (d *dummyDeadLetterService) ListDeadLetters(ctx context.Context, offset int64, limit int) (*models.DeadLetterListResponse, error) {
+	return nil, nil
+}
+
+func (d *dummyDeadLetterService) ReplayDeadLetter(ctx context.Context, offset int64) (*models.DeadLetter, error) {
+	return nil, nil
+}
+
+type dummySeatInventoryService struct{}
+
+func (d *dummySeatInventoryService) EnableSeatInventory(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	return nil, nil
+}
+
+func (d *dummySeatInventoryService) DisableSeatInventory(ctx context.Context, flightID int64) error {
+	return nil
+}
+
+func (d *dummySeatInventoryService) GetSeatInventoryStatus(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	return nil, nil
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	flightHandler := handlers.NewFlightHandler(&dummyFlightService{})
 	bookingHandler := handlers.NewBookingHandler(&dummyBookingService{})
+	paymentHandler := handlers.NewPaymentHandler(&dummyBookingService{}, "secret")
+	adminHandler := handlers.NewAdminHandler(&dummyDeadLetterService{}, &dummySeatInventoryService{})
 
-	router := setupRoutes(flightHandler, bookingHandler)
+	router := setupRoutes(flightHandler, bookingHandler, paymentHandler, adminHandler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
 	rr := httptest.NewRecorder()
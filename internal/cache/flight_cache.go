@@ -3,13 +3,51 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"airline-booking-system/internal/config"
 	"airline-booking-system/internal/models"
 	"airline-booking-system/pkg/redis"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// webhookEventTTL bounds how long processed payment webhook event IDs are remembered
+const webhookEventTTL = 72 * time.Hour
+
+// searchEntriesKey is the sorted set of cached search keys, scored by how
+// many times each search was served from the cache
+const searchEntriesKey = "search_cache:entries"
+
+// routePopularityKey is the sorted set of searched routes, scored by how many
+// times each route was searched
+const routePopularityKey = "search_cache:routes"
+
+// Search cache lookups by outcome, and cold searches evicted to respect
+// MaxCacheEntries.
+var (
+	searchCacheHitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "search_cache_hits_total",
+		Help: "Total number of flight searches served from the cache.",
+	})
+	searchCacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "search_cache_misses_total",
+		Help: "Total number of flight searches not found in the cache.",
+	})
+	searchCacheEvictionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "search_cache_evictions_total",
+		Help: "Total number of cached flight searches evicted to stay within the entry cap.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(searchCacheHitsTotal, searchCacheMissesTotal, searchCacheEvictionsTotal)
+}
+
 // FlightCacheService handles flight caching operations
 type FlightCacheService struct {
 	redisClient *redis.Client
@@ -24,30 +62,216 @@ func NewFlightCacheService(redisClient *redis.Client, config *config.AppConfig)
 	}
 }
 
-// GetCachedFlights gets flights from cache
+// GetCachedFlights gets flights from cache. Every hit counts towards the
+// popularity of the search.
 func (s *FlightCacheService) GetCachedFlights(ctx context.Context, cacheKey string) ([]models.Flight, error) {
 	cachedData, err := s.redisClient.Get(ctx, cacheKey)
 	if err != nil {
+		searchCacheMissesTotal.Inc()
 		return nil, err
 	}
 
 	var flights []models.Flight
 	err = json.Unmarshal([]byte(cachedData), &flights)
 	if err != nil {
+		searchCacheMissesTotal.Inc()
 		return nil, fmt.Errorf("failed to unmarshal cached flights: %w", err)
 	}
 
+	// A failure to count the hit must not fail the search
+	s.redisClient.IncrementRank(ctx, searchEntriesKey, cacheKey, 1)
+
+	searchCacheHitsTotal.Inc()
 	return flights, nil
 }
 
-// SetCachedFlights sets flights in cache
-func (s *FlightCacheService) SetCachedFlights(ctx context.Context, cacheKey string, flights []models.Flight) error {
+// SetCachedFlights caches the results of a search under its cache key and
+// records the key in the reverse indexes used to invalidate it: one per
+// flight in the results and one per route and day the search covers.
+// Searches among the top TopSearchesPercent by hits are kept for
+// PopularCacheTTL instead of CacheTTL. Searches that expired or were
+// invalidated stop being tracked, and once more than MaxCacheEntries
+// searches are tracked, the least hit ones are evicted.
+func (s *FlightCacheService) SetCachedFlights(ctx context.Context, req *models.FlightSearchRequest, flights []models.Flight) error {
 	flightData, err := json.Marshal(flights)
 	if err != nil {
 		return fmt.Errorf("failed to marshal flights for cache: %w", err)
 	}
 
-	return s.redisClient.SetJSON(ctx, cacheKey, string(flightData), s.config.CacheTTL)
+	cacheKey := req.GetCacheKey()
+	indexKeys := make([]string, 0, len(flights)+2*models.MaxFlexDays+1)
+	for _, flight := range flights {
+		indexKeys = append(indexKeys, flightSearchesKey(flight.ID))
+	}
+	from, to := req.DateRange()
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		indexKeys = append(indexKeys, routeSearchesKey(req.Source, req.Destination, day))
+	}
+
+	ttl := s.config.CacheTTL
+	if s.isPopularSearch(ctx, cacheKey) {
+		ttl = s.config.PopularCacheTTL
+	}
+
+	if err := s.redisClient.SetIndexed(ctx, cacheKey, string(flightData), ttl, s.searchIndexTTL(), indexKeys...); err != nil {
+		return err
+	}
+
+	evicted, err := s.redisClient.EvictLowest(ctx, searchEntriesKey, s.config.MaxCacheEntries, cacheKey)
+	if err != nil {
+		return fmt.Errorf("failed to evict cold searches: %w", err)
+	}
+	searchCacheEvictionsTotal.Add(float64(len(evicted)))
+
+	return nil
+}
+
+// isPopularSearch reports whether a search ranks in the top
+// TopSearchesPercent of tracked searches by lookups
+func (s *FlightCacheService) isPopularSearch(ctx context.Context, cacheKey string) bool {
+	rank, size, err := s.redisClient.IncrementRank(ctx, searchEntriesKey, cacheKey, 0)
+	if err != nil {
+		return false
+	}
+	return isTopRanked(rank, size, s.config.TopSearchesPercent)
+}
+
+// isTopRanked reports whether a 0-based rank falls within the top percent,
+// as a fraction, of size ranked items. At least one item is in the top of a
+// non-empty set unless percent is zero.
+func isTopRanked(rank, size int64, percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	return rank < int64(math.Ceil(float64(size)*percent))
+}
+
+// RecordRouteSearch counts a search of a route towards its popularity
+func (s *FlightCacheService) RecordRouteSearch(ctx context.Context, source, destination string) error {
+	return s.redisClient.ZIncrBy(ctx, routePopularityKey, 1, source+"#"+destination).Err()
+}
+
+// TopSearchedRoutes gets the most searched routes, most searched first
+func (s *FlightCacheService) TopSearchedRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+	members, err := s.redisClient.ZRevRangeWithScores(ctx, routePopularityKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]models.RoutePopularity, 0, len(members))
+	for _, member := range members {
+		route, ok := member.Member.(string)
+		if !ok {
+			continue
+		}
+		source, destination, found := strings.Cut(route, "#")
+		if !found {
+			continue
+		}
+		routes = append(routes, models.RoutePopularity{Source: source, Destination: destination, Searches: int64(member.Score)})
+	}
+
+	return routes, nil
+}
+
+// SeedRouteSearches restores route search counts, such as a stored snapshot
+// after Redis was flushed. Routes searched since keep the higher count.
+func (s *FlightCacheService) SeedRouteSearches(ctx context.Context, routes []models.RoutePopularity) error {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	scores := make(map[string]float64, len(routes))
+	for _, route := range routes {
+		scores[route.Source+"#"+route.Destination] = float64(route.Searches)
+	}
+	return s.redisClient.RaiseScores(ctx, routePopularityKey, scores)
+}
+
+// DeleteCachedFlights removes a cached search result
+func (s *FlightCacheService) DeleteCachedFlights(ctx context.Context, cacheKey string) error {
+	return s.redisClient.Delete(ctx, cacheKey)
+}
+
+// InvalidateFlightSearches removes every cached search and fare calendar whose
+// results include the flight, for changes to a flight that can only alter
+// searches it is in
+func (s *FlightCacheService) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	return s.invalidateSearches(ctx, flightSearchesKey(flightID))
+}
+
+// InvalidateRouteSearches removes every cached search and fare calendar
+// covering a route on the day of departure, including those a changed flight
+// was left out of
+func (s *FlightCacheService) InvalidateRouteSearches(ctx context.Context, source, destination string, departure time.Time) error {
+	return s.invalidateSearches(ctx, routeSearchesKey(source, destination, departure))
+}
+
+// invalidateSearches deletes the searches recorded in a reverse index along
+// with the index. Keys of searches that already expired are deleted harmlessly.
+func (s *FlightCacheService) invalidateSearches(ctx context.Context, indexKey string) error {
+	_, err := s.redisClient.DeleteIndexed(ctx, indexKey)
+	return err
+}
+
+// flightSearchesKey returns the Redis key of the set of cached searches that
+// include a flight
+func flightSearchesKey(flightID int64) string {
+	return fmt.Sprintf("search_index:flight:%d", flightID)
+}
+
+// routeSearchesKey returns the Redis key of the set of cached searches that
+// cover a route on a day
+func routeSearchesKey(source, destination string, day time.Time) string {
+	return fmt.Sprintf("search_index:route:%s#%s#%s", source, destination, day.Format("2006-01-02"))
+}
+
+// GetCachedFareCalendar gets a route's fare calendar for a month from cache
+func (s *FlightCacheService) GetCachedFareCalendar(ctx context.Context, cacheKey string) (*models.FareCalendarResponse, error) {
+	cachedData, err := s.redisClient.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendar models.FareCalendarResponse
+	err = json.Unmarshal([]byte(cachedData), &calendar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached fare calendar: %w", err)
+	}
+
+	return &calendar, nil
+}
+
+// SetCachedFareCalendar caches a route's fare calendar for a month under the
+// request's cache key. Quoted prices move with every booking, so the calendar
+// is recorded in the same reverse indexes as searches: one per flight it was
+// priced from and one per route and day of the month. Invalidating the
+// searches of a flight or route day removes the calendar with them.
+func (s *FlightCacheService) SetCachedFareCalendar(ctx context.Context, req *models.FareCalendarRequest, calendar *models.FareCalendarResponse, flightIDs []int64) error {
+	calendarData, err := json.Marshal(calendar)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fare calendar for cache: %w", err)
+	}
+
+	from, to := req.MonthRange()
+	indexKeys := make([]string, 0, len(flightIDs))
+	for _, flightID := range flightIDs {
+		indexKeys = append(indexKeys, flightSearchesKey(flightID))
+	}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		indexKeys = append(indexKeys, routeSearchesKey(req.Source, req.Destination, day))
+	}
+
+	return s.redisClient.SetIndexed(ctx, req.GetCacheKey(), string(calendarData), s.config.CacheTTL, s.searchIndexTTL(), indexKeys...)
+}
+
+// searchIndexTTL is how long reverse indexes are kept: they must outlive
+// every search they list
+func (s *FlightCacheService) searchIndexTTL() time.Duration {
+	if s.config.PopularCacheTTL > s.config.CacheTTL {
+		return s.config.PopularCacheTTL
+	}
+	return s.config.CacheTTL
 }
 
 // IsCached checks if a search is cached
@@ -55,14 +279,65 @@ func (s *FlightCacheService) IsCached(ctx context.Context, cacheKey string) (boo
 	return s.redisClient.Exists(ctx, cacheKey)
 }
 
-// AcquireFlightLock acquires a distributed lock for a flight
-func (s *FlightCacheService) AcquireFlightLock(ctx context.Context, lockKey string) (bool, error) {
-	return s.redisClient.AcquireLock(ctx, lockKey, s.config.LockTTL)
+// AcquireFlightLock acquires the distributed lock of a flight for LockTTL,
+// holding every key LockKeyMode requires. It returns nil if the lock is held
+// by someone else.
+func (s *FlightCacheService) AcquireFlightLock(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+	keys := flightLockKeys(flightID, s.config.LockKeyMode)
+	token, fence, err := s.redisClient.AcquireLock(ctx, keys, s.config.LockTTL)
+	if err != nil || token == "" {
+		return nil, err
+	}
+
+	return &models.FlightLock{FlightID: flightID, Keys: keys, Token: token, Fence: fence, TTL: s.config.LockTTL}, nil
+}
+
+// ExtendFlightLock renews a flight lock for another LockTTL. It returns false
+// if the lock has already expired or passed to another holder.
+func (s *FlightCacheService) ExtendFlightLock(ctx context.Context, lock *models.FlightLock) (bool, error) {
+	return s.redisClient.ExtendLock(ctx, lock.Keys, lock.Token, lock.TTL)
+}
+
+// ReleaseFlightLock releases a flight lock unless it has passed to another holder
+func (s *FlightCacheService) ReleaseFlightLock(ctx context.Context, lock *models.FlightLock) error {
+	return s.redisClient.ReleaseLock(ctx, lock.Keys, lock.Token)
 }
 
-// ReleaseFlightLock releases a distributed lock for a flight
-func (s *FlightCacheService) ReleaseFlightLock(ctx context.Context, lockKey string) error {
-	return s.redisClient.ReleaseLock(ctx, lockKey)
+// JoinBookingQueue puts a booking request at the back of a flight's booking
+// queue and returns its ticket and the number of requests ahead of it, or no
+// ticket if BookingQueueMaxLength requests are already waiting. The ticket
+// keeps its place for BookingQueueTicketTTL after each position check.
+func (s *FlightCacheService) JoinBookingQueue(ctx context.Context, flightID int64) (string, int64, error) {
+	return s.redisClient.JoinQueue(ctx, BookingQueueKey(flightID), s.config.BookingQueueTicketTTL, s.config.BookingQueueMaxLength)
+}
+
+// BookingQueuePosition returns the number of requests ahead of a ticket in a
+// flight's booking queue, or -1 if the ticket has lost its place
+func (s *FlightCacheService) BookingQueuePosition(ctx context.Context, flightID int64, ticket string) (int64, error) {
+	return s.redisClient.QueuePosition(ctx, BookingQueueKey(flightID), ticket, s.config.BookingQueueTicketTTL)
+}
+
+// LeaveBookingQueue removes a ticket from a flight's booking queue
+func (s *FlightCacheService) LeaveBookingQueue(ctx context.Context, flightID int64, ticket string) error {
+	return s.redisClient.LeaveQueue(ctx, BookingQueueKey(flightID), ticket)
+}
+
+// BookingQueueLength returns the number of requests waiting in a flight's
+// booking queue
+func (s *FlightCacheService) BookingQueueLength(ctx context.Context, flightID int64) (int64, error) {
+	return s.redisClient.QueueLength(ctx, BookingQueueKey(flightID))
+}
+
+// AcquireLeadership acquires or renews leadership of a background job for this instance
+func (s *FlightCacheService) AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("leader:%s", job)
+	return s.redisClient.AcquireLease(ctx, key, instanceID, ttl)
+}
+
+// ResignLeadership gives up leadership of a background job if this instance holds it
+func (s *FlightCacheService) ResignLeadership(ctx context.Context, job string, instanceID string) error {
+	key := fmt.Sprintf("leader:%s", job)
+	return s.redisClient.ReleaseLease(ctx, key, instanceID)
 }
 
 // GetAvailableSeats gets available seats for a flight from cache
@@ -81,11 +356,30 @@ func (s *FlightCacheService) SetAvailableSeats(ctx context.Context, flightID int
 	return s.redisClient.SetJSON(ctx, key, seats, s.config.CacheTTL)
 }
 
-// DecrementAvailableSeats decrements available seats for a flight
+// DecrementAvailableSeats decrements the available seats cached for a flight.
+// It does nothing if no seats are cached, and drops the cached count if it
+// holds fewer seats than the decrement, so it is reloaded from the database.
 func (s *FlightCacheService) DecrementAvailableSeats(ctx context.Context, flightID int64, decrement int) error {
 	key := fmt.Sprintf("flight_seats:%d", flightID)
-	_, err := s.redisClient.IncrBy(ctx, key, -int64(decrement))
-	return err
+	taken, err := s.redisClient.TakeFromCounter(ctx, key, int64(decrement))
+	if errors.Is(err, redis.ErrCounterNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !taken {
+		return s.redisClient.Delete(ctx, key)
+	}
+	return nil
+}
+
+// GetCachedSeats gets the available seats cached for a flight and whether
+// any are cached
+func (s *FlightCacheService) GetCachedSeats(ctx context.Context, flightID int64) (int, bool, error) {
+	key := fmt.Sprintf("flight_seats:%d", flightID)
+	seats, ok, err := s.redisClient.GetCounter(ctx, key)
+	return int(seats), ok, err
 }
 
 // DeleteCachedSeats removes cached seat information
@@ -93,3 +387,91 @@ func (s *FlightCacheService) DeleteCachedSeats(ctx context.Context, flightID int
 	key := fmt.Sprintf("flight_seats:%d", flightID)
 	return s.redisClient.Delete(ctx, key)
 }
+
+// ErrSeatInventoryNotTracked is returned when reserving seats of a flight
+// whose seats are not sold from a Redis seat inventory
+var ErrSeatInventoryNotTracked = errors.New("seat inventory not tracked")
+
+// ReserveInventorySeats atomically takes seats from a flight's seat
+// inventory. It returns false if too few seats are left and
+// ErrSeatInventoryNotTracked if the flight has no seat inventory.
+func (s *FlightCacheService) ReserveInventorySeats(ctx context.Context, flightID int64, seats int) (bool, error) {
+	reserved, err := s.redisClient.TakeFromCounter(ctx, seatInventoryKey(flightID), int64(seats))
+	if errors.Is(err, redis.ErrCounterNotFound) {
+		return false, ErrSeatInventoryNotTracked
+	}
+	return reserved, err
+}
+
+// ReturnInventorySeats gives seats back to a flight's seat inventory. It does
+// nothing for a flight without one.
+func (s *FlightCacheService) ReturnInventorySeats(ctx context.Context, flightID int64, seats int) error {
+	return s.redisClient.ReturnToCounter(ctx, seatInventoryKey(flightID), int64(seats))
+}
+
+// IsInventoryTracked reports whether a flight's seats are sold from a seat inventory
+func (s *FlightCacheService) IsInventoryTracked(ctx context.Context, flightID int64) (bool, error) {
+	return s.redisClient.Exists(ctx, seatInventoryKey(flightID))
+}
+
+// GetInventorySeats returns the seats left in a flight's seat inventory and
+// whether it has one
+func (s *FlightCacheService) GetInventorySeats(ctx context.Context, flightID int64) (int64, bool, error) {
+	return s.redisClient.GetCounter(ctx, seatInventoryKey(flightID))
+}
+
+// SeedInventorySeats creates a flight's seat inventory with seats unless it
+// already has one. The inventory does not expire.
+func (s *FlightCacheService) SeedInventorySeats(ctx context.Context, flightID int64, seats int64) (bool, error) {
+	return s.redisClient.SetNX(ctx, seatInventoryKey(flightID), seats, 0).Result()
+}
+
+// CorrectInventorySeats sets a flight's seat inventory to seats only if it
+// still holds expected, and reports whether it did
+func (s *FlightCacheService) CorrectInventorySeats(ctx context.Context, flightID int64, expected, seats int64) (bool, error) {
+	return s.redisClient.CompareAndSetCounter(ctx, seatInventoryKey(flightID), expected, seats)
+}
+
+// DeleteInventorySeats removes a flight's seat inventory
+func (s *FlightCacheService) DeleteInventorySeats(ctx context.Context, flightID int64) error {
+	return s.redisClient.Delete(ctx, seatInventoryKey(flightID))
+}
+
+// seatInventoryKey returns the Redis key of a flight's seat inventory
+func seatInventoryKey(flightID int64) string {
+	return fmt.Sprintf("seat_inventory:%d", flightID)
+}
+
+// ClaimQuote marks a price quote as used until it expires. It returns false
+// if a booking already claimed it.
+func (s *FlightCacheService) ClaimQuote(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return s.redisClient.SetNX(ctx, quoteClaimKey(quoteID), time.Now().Unix(), ttl).Result()
+}
+
+// ReleaseQuote returns a claimed price quote when its booking failed, so it
+// can be used again until it expires
+func (s *FlightCacheService) ReleaseQuote(ctx context.Context, quoteID string) error {
+	return s.redisClient.Delete(ctx, quoteClaimKey(quoteID))
+}
+
+// quoteClaimKey returns the key marking a price quote as used
+func quoteClaimKey(quoteID string) string {
+	return fmt.Sprintf("quote_claim:%s", quoteID)
+}
+
+// MarkWebhookEventProcessed records a payment webhook event ID. It returns
+// false if the event was already recorded by an earlier delivery.
+func (s *FlightCacheService) MarkWebhookEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	key := fmt.Sprintf("payment_webhook:%s", eventID)
+	return s.redisClient.SetNX(ctx, key, time.Now().Unix(), webhookEventTTL).Result()
+}
+
+// ClearWebhookEvent forgets a payment webhook event ID so it can be reprocessed
+func (s *FlightCacheService) ClearWebhookEvent(ctx context.Context, eventID string) error {
+	key := fmt.Sprintf("payment_webhook:%s", eventID)
+	return s.redisClient.Delete(ctx, key)
+}
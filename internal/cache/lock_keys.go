@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"fmt"
+
+	"airline-booking-system/internal/config"
+)
+
+// lockKeyPrefix namespaces every booking lock key. Bump the version when
+// the key layout changes and roll out through config.LockKeyModeDual again.
+const lockKeyPrefix = "lock:v2"
+
+// FlightLockKey returns the lock key of a flight
+func FlightLockKey(flightID int64) string {
+	return fmt.Sprintf("%s:flight:%d", lockKeyPrefix, flightID)
+}
+
+// BookingQueueKey returns the key of the queue of booking requests waiting
+// for a flight's lock
+func BookingQueueKey(flightID int64) string {
+	return fmt.Sprintf("%s:queue", FlightLockKey(flightID))
+}
+
+// legacyFlightLockKey returns the flight lock key of releases before
+// lockKeyPrefix. It maps flight IDs to Unicode code points, so IDs that are
+// not valid code points share one key and IDs past 32 bits wrap around.
+func legacyFlightLockKey(flightID int64) string {
+	return "flight_lock:" + string(rune(flightID))
+}
+
+// flightLockKeys returns the keys that must all be held to lock a flight in
+// mode. config.Load rejects unknown modes, so only an unset mode, as in an
+// AppConfig built without Load, falls through to config.LockKeyModeDual.
+func flightLockKeys(flightID int64, mode string) []string {
+	if mode == config.LockKeyModeV2 {
+		return []string{FlightLockKey(flightID)}
+	}
+	return []string{legacyFlightLockKey(flightID), FlightLockKey(flightID)}
+}
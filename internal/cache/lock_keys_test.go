@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+
+	"airline-booking-system/internal/config"
+)
+
+func TestFlightLockKey_DistinctForLegacyCollisions(t *testing.T) {
+	// Surrogates and IDs past the last code point share one legacy key, and
+	// IDs past 32 bits are truncated before conversion
+	flightIDs := []int64{0xD800, 0xDFFF, 0x110000, 1 << 40, 1 << 41}
+
+	if legacyFlightLockKey(0xD800) != legacyFlightLockKey(0x110000) || legacyFlightLockKey(1<<40) != legacyFlightLockKey(1<<41) {
+		t.Fatalf("expected the legacy keys of invalid code points to collide")
+	}
+
+	seen := make(map[string]int64)
+	for _, flightID := range flightIDs {
+		key := FlightLockKey(flightID)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("flights %d and %d share lock key %s", other, flightID, key)
+		}
+		seen[key] = flightID
+	}
+
+	if key := FlightLockKey(1 << 40); key != "lock:v2:flight:1099511627776" {
+		t.Fatalf("expected a decimal flight lock key, got %s", key)
+	}
+}
+
+func TestFlightLockKeys_Modes(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected []string
+	}{
+		{mode: config.LockKeyModeDual, expected: []string{"flight_lock:\u0007", "lock:v2:flight:7"}},
+		{mode: "", expected: []string{"flight_lock:\u0007", "lock:v2:flight:7"}},
+		{mode: config.LockKeyModeV2, expected: []string{"lock:v2:flight:7"}},
+	}
+
+	for _, tt := range tests {
+		keys := flightLockKeys(7, tt.mode)
+		if len(keys) != len(tt.expected) {
+			t.Fatalf("mode %q: expected keys %q, got %q", tt.mode, tt.expected, keys)
+		}
+		for i := range keys {
+			if keys[i] != tt.expected[i] {
+				t.Fatalf("mode %q: expected keys %q, got %q", tt.mode, tt.expected, keys)
+			}
+		}
+	}
+}
@@ -1,11 +1,22 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Lock key modes. LockKeyModeDual holds the legacy flight lock key as well
+// as the current one, so instances still running a release that only knows
+// the legacy key stay excluded; LockKeyModeV2 holds only the current key.
+const (
+	LockKeyModeDual = "dual"
+	LockKeyModeV2   = "v2"
+)
+
 // Config holds all configuration for the application
 type Config struct {
 	Server   ServerConfig
@@ -43,18 +54,62 @@ type RedisConfig struct {
 
 // KafkaConfig holds Kafka configuration
 type KafkaConfig struct {
-	Brokers       []string
-	TopicBookings string
-	TopicPayments string
-	GroupID       string
+	Brokers         []string
+	TopicBookings   string
+	TopicPayments   string
+	GroupID         string
+	DeadLetterTopic string
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
 }
 
 // AppConfig holds application-specific configuration
 type AppConfig struct {
 	CacheTTL          time.Duration
 	LockTTL           time.Duration
+	LockKeyMode       string
+	BookingQueuePollInterval time.Duration
+	BookingQueueTicketTTL    time.Duration
+	BookingQueueMaxLength    int
+	SeatInventorySyncInterval time.Duration
+	SeatReconcileInterval     time.Duration
+	SeatReconcileRepair       bool
 	MaxCacheEntries   int
 	TopSearchesPercent float64
+	PopularCacheTTL    time.Duration
+	CacheWarmupInterval    time.Duration
+	CacheWarmupRoutes      int
+	CacheWarmupDays        int
+	CacheWarmupConcurrency int
+	PaymentProvider    string
+	FakePaymentOutcome string
+	FakePaymentLatency time.Duration
+	PaymentWebhookSecret string
+	BookingHoldWindow    time.Duration
+	ReaperInterval       time.Duration
+	ReaperBatchSize      int
+	OutboxPollInterval   time.Duration
+	OutboxBatchSize      int
+	OutboxClaimTTL       time.Duration
+	OutboxMaxBackoff     time.Duration
+	LoadFactorPricing    []PricingRule
+	DeparturePricing     []PricingRule
+	MinPriceMultiplier   float64
+	MaxPriceMultiplier   float64
+	QuoteSigningSecret   string
+	QuoteTTL             time.Duration
+	MinLayover           time.Duration
+	MaxLayover           time.Duration
+	MaxItineraryDuration time.Duration
+}
+
+// PricingRule applies a price multiplier once a threshold is reached. For
+// load factor rules the threshold is the fraction of seats sold; for
+// departure rules it is the number of days left until departure.
+type PricingRule struct {
+	Threshold  float64
+	Multiplier float64
 }
 
 // TracingConfig holds distributed tracing configuration
@@ -66,9 +121,15 @@ type TracingConfig struct {
 	SamplerRatio float64
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	return &Config{
+// Load loads configuration from environment variables. Settings that would
+// silently change how instances coordinate, such as an unknown
+// LOCK_KEY_MODE, are rejected instead of falling back to a default, and so
+// is a missing QUOTE_SIGNING_SECRET, without which no price can be held.
+func Load() (*Config, error) {
+	// A queued booking keeps its place for several missed polls
+	bookingQueuePollInterval := getDurationEnv("BOOKING_QUEUE_POLL_INTERVAL", 500*time.Millisecond)
+
+	cfg := &Config{
 		Server: ServerConfig{
 			Port:         getEnv("SERVER_PORT", "8080"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
@@ -89,16 +150,52 @@ func Load() *Config {
 			DB:       getIntEnv("REDIS_DB", 0),
 		},
 		Kafka: KafkaConfig{
-			Brokers:       []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
-			TopicBookings: getEnv("KAFKA_TOPIC_BOOKINGS", "flight-bookings"),
-			TopicPayments: getEnv("KAFKA_TOPIC_PAYMENTS", "payment-events"),
-			GroupID:       getEnv("KAFKA_GROUP_ID", "booking-service"),
+			Brokers:         []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
+			TopicBookings:   getEnv("KAFKA_TOPIC_BOOKINGS", "flight-bookings"),
+			TopicPayments:   getEnv("KAFKA_TOPIC_PAYMENTS", "payment-events"),
+			GroupID:         getEnv("KAFKA_GROUP_ID", "booking-service"),
+			DeadLetterTopic: getEnv("KAFKA_DEAD_LETTER_TOPIC", "dead-letters"),
+			MaxAttempts:     getIntEnv("KAFKA_MAX_ATTEMPTS", 5),
+			RetryBackoff:    getDurationEnv("KAFKA_RETRY_BACKOFF", 200*time.Millisecond),
+			MaxRetryBackoff: getDurationEnv("KAFKA_MAX_RETRY_BACKOFF", 10*time.Second),
 		},
 		App: AppConfig{
 			CacheTTL:          getDurationEnv("CACHE_TTL", time.Hour),
 			LockTTL:           getDurationEnv("LOCK_TTL", 5*time.Minute),
+			LockKeyMode:       getEnv("LOCK_KEY_MODE", LockKeyModeDual),
+			BookingQueuePollInterval: bookingQueuePollInterval,
+			BookingQueueTicketTTL:    getDurationEnv("BOOKING_QUEUE_TICKET_TTL", 10*bookingQueuePollInterval),
+			BookingQueueMaxLength:    getIntEnv("BOOKING_QUEUE_MAX_LENGTH", 1000),
+			SeatInventorySyncInterval: getDurationEnv("SEAT_INVENTORY_SYNC_INTERVAL", time.Second),
+			SeatReconcileInterval:     getDurationEnv("SEAT_RECONCILE_INTERVAL", 15*time.Minute),
+			SeatReconcileRepair:       getEnv("SEAT_RECONCILE_REPAIR", "false") == "true",
 			MaxCacheEntries:   getIntEnv("MAX_CACHE_ENTRIES", 1000),
 			TopSearchesPercent: getFloatEnv("TOP_SEARCHES_PERCENT", 0.4),
+			PopularCacheTTL:    getDurationEnv("POPULAR_CACHE_TTL", 6*time.Hour),
+			CacheWarmupInterval:    getDurationEnv("CACHE_WARMUP_INTERVAL", 30*time.Minute),
+			CacheWarmupRoutes:      getIntEnv("CACHE_WARMUP_ROUTES", 20),
+			CacheWarmupDays:        getIntEnv("CACHE_WARMUP_DAYS", 7),
+			CacheWarmupConcurrency: getIntEnv("CACHE_WARMUP_CONCURRENCY", 4),
+			PaymentProvider:    getEnv("PAYMENT_PROVIDER", ""),
+			FakePaymentOutcome: getEnv("FAKE_PAYMENT_OUTCOME", "approve"),
+			FakePaymentLatency: getDurationEnv("FAKE_PAYMENT_LATENCY", 2*time.Second),
+			PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+			BookingHoldWindow:    getDurationEnv("BOOKING_HOLD_WINDOW", 15*time.Minute),
+			ReaperInterval:       getDurationEnv("BOOKING_REAPER_INTERVAL", time.Minute),
+			ReaperBatchSize:      getIntEnv("BOOKING_REAPER_BATCH_SIZE", 100),
+			OutboxPollInterval:   getDurationEnv("OUTBOX_POLL_INTERVAL", time.Second),
+			OutboxBatchSize:      getIntEnv("OUTBOX_BATCH_SIZE", 100),
+			OutboxClaimTTL:       getDurationEnv("OUTBOX_CLAIM_TTL", time.Minute),
+			OutboxMaxBackoff:     getDurationEnv("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+			LoadFactorPricing:    getPricingRulesEnv("PRICING_LOAD_FACTOR_RULES", "0.5:1.1,0.75:1.25,0.9:1.5"),
+			DeparturePricing:     getPricingRulesEnv("PRICING_DEPARTURE_RULES", "3:1.3,7:1.15,14:1.05"),
+			MinPriceMultiplier:   getFloatEnv("PRICING_MIN_MULTIPLIER", 0.8),
+			MaxPriceMultiplier:   getFloatEnv("PRICING_MAX_MULTIPLIER", 2.0),
+			QuoteSigningSecret:   getEnv("QUOTE_SIGNING_SECRET", ""),
+			QuoteTTL:             getDurationEnv("QUOTE_TTL", 10*time.Minute),
+			MinLayover:           getDurationEnv("ITINERARY_MIN_LAYOVER", 45*time.Minute),
+			MaxLayover:           getDurationEnv("ITINERARY_MAX_LAYOVER", 6*time.Hour),
+			MaxItineraryDuration: getDurationEnv("ITINERARY_MAX_DURATION", 24*time.Hour),
 		},
 		Tracing: TracingConfig{
 			Enabled:      getEnv("TRACING_ENABLED", "false") == "true",
@@ -108,6 +205,26 @@ func Load() *Config {
 			SamplerRatio: getFloatEnv("TRACING_SAMPLER_RATIO", 1.0),
 		},
 	}
+
+	switch cfg.App.LockKeyMode {
+	case LockKeyModeDual, LockKeyModeV2:
+	default:
+		return nil, fmt.Errorf("unknown LOCK_KEY_MODE %q: must be %q or %q", cfg.App.LockKeyMode, LockKeyModeDual, LockKeyModeV2)
+	}
+
+	if cfg.App.BookingQueueTicketTTL <= cfg.App.BookingQueuePollInterval {
+		return nil, fmt.Errorf("BOOKING_QUEUE_TICKET_TTL %s must be longer than BOOKING_QUEUE_POLL_INTERVAL %s", cfg.App.BookingQueueTicketTTL, cfg.App.BookingQueuePollInterval)
+	}
+
+	if cfg.App.PaymentProvider == "" {
+		return nil, fmt.Errorf("PAYMENT_PROVIDER must be set")
+	}
+
+	if cfg.App.QuoteSigningSecret == "" {
+		return nil, fmt.Errorf("QUOTE_SIGNING_SECRET must be set")
+	}
+
+	return cfg, nil
 }
 
 // getEnv gets an environment variable with a default value
@@ -147,3 +264,39 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getPricingRulesEnv gets pricing rules written as comma-separated
+// threshold:multiplier pairs, sorted by threshold. The default is used if
+// any pair is malformed.
+func getPricingRulesEnv(key, defaultValue string) []PricingRule {
+	if rules, ok := parsePricingRules(getEnv(key, defaultValue)); ok {
+		return rules
+	}
+	rules, _ := parsePricingRules(defaultValue)
+	return rules
+}
+
+// parsePricingRules parses threshold:multiplier pairs
+func parsePricingRules(value string) ([]PricingRule, bool) {
+	var rules []PricingRule
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, false
+		}
+		threshold, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, false
+		}
+		multiplier, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || multiplier <= 0 {
+			return nil, false
+		}
+		rules = append(rules, PricingRule{Threshold: threshold, Multiplier: multiplier})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Threshold < rules[j].Threshold
+	})
+	return rules, true
+}
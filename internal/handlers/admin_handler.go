@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"airline-booking-system/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// DeadLetterService defines the interface for inspecting and replaying dead-lettered messages.
+// This allows the HTTP handlers to be unit tested with mocks.
+type DeadLetterService interface {
+	ListDeadLetters(rctx context.Context, offset int64, limit int) (*models.DeadLetterListResponse, error)
+	ReplayDeadLetter(rctx context.Context, offset int64) (*models.DeadLetter, error)
+}
+
+// SeatInventoryService defines the interface for turning seat inventories of
+// high-demand flights on and off.
+type SeatInventoryService interface {
+	EnableSeatInventory(rctx context.Context, flightID int64) (*models.SeatInventoryStatus, error)
+	DisableSeatInventory(rctx context.Context, flightID int64) error
+	GetSeatInventoryStatus(rctx context.Context, flightID int64) (*models.SeatInventoryStatus, error)
+}
+
+// AdminHandler handles operational HTTP requests
+type AdminHandler struct {
+	deadLetterService    DeadLetterService
+	seatInventoryService SeatInventoryService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(deadLetterService DeadLetterService, seatInventoryService SeatInventoryService) *AdminHandler {
+	return &AdminHandler{
+		deadLetterService:    deadLetterService,
+		seatInventoryService: seatInventoryService,
+	}
+}
+
+// ListDeadLetters handles listing dead-lettered messages
+func (h *AdminHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var offset int64
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		parsed, err := strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	var limit int
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := h.deadLetterService.ListDeadLetters(r.Context(), offset, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReplayDeadLetter handles republishing a dead-lettered message to its original topic
+func (h *AdminHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	offsetStr := vars["offset"]
+
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid dead letter offset", http.StatusBadRequest)
+		return
+	}
+
+	deadLetter, err := h.deadLetterService.ReplayDeadLetter(r.Context(), offset)
+	if err != nil {
+		if errors.Is(err, models.ErrDeadLetterNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deadLetter)
+}
+
+// GetSeatInventory handles requests for a flight's seat inventory
+func (h *AdminHandler) GetSeatInventory(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFlightID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.seatInventoryService.GetSeatInventoryStatus(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnableSeatInventory handles putting a flight on sale from a seat inventory
+func (h *AdminHandler) EnableSeatInventory(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFlightID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.seatInventoryService.EnableSeatInventory(r.Context(), id)
+	if err != nil {
+		writeSeatInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// DisableSeatInventory handles taking a flight off its seat inventory
+func (h *AdminHandler) DisableSeatInventory(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFlightID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.seatInventoryService.DisableSeatInventory(r.Context(), id); err != nil {
+		writeSeatInventoryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseFlightID reads the flight ID of a request, writing a bad request
+// response if it is invalid
+func parseFlightID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeSeatInventoryError writes the response for a failed seat inventory change
+func writeSeatInventoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrSeatInventoryUnsupported) || errors.Is(err, models.ErrSeatInventoryBusy) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
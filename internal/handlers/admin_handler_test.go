@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"airline-booking-system/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// mockDeadLetterService is a test double for DeadLetterService.
+type mockDeadLetterService struct {
+	listResp   *models.DeadLetterListResponse
+	replayResp *models.DeadLetter
+	err        error
+	offset     int64
+	limit      int
+}
+
+func (m *mockDeadLetterService) ListDeadLetters(ctx context.Context, offset int64, limit int) (*models.DeadLetterListResponse, error) {
+	m.offset = offset
+	m.limit = limit
+	return m.listResp, m.err
+}
+
+func (m *mockDeadLetterService) ReplayDeadLetter(ctx context.Context, offset int64) (*models.DeadLetter, error) {
+	m.offset = offset
+	return m.replayResp, m.err
+}
+
+// mockSeatInventoryService is a test double for SeatInventoryService.
+type mockSeatInventoryService struct {
+	status   *models.SeatInventoryStatus
+	err      error
+	flightID int64
+}
+
+func (m *mockSeatInventoryService) EnableSeatInventory(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	m.flightID = flightID
+	return m.status, m.err
+}
+
+func (m *mockSeatInventoryService) DisableSeatInventory(ctx context.Context, flightID int64) error {
+	m.flightID = flightID
+	return m.err
+}
+
+func (m *mockSeatInventoryService) GetSeatInventoryStatus(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	m.flightID = flightID
+	return m.status, m.err
+}
+
+func TestListDeadLetters_Success(t *testing.T) {
+	service := &mockDeadLetterService{
+		listResp: &models.DeadLetterListResponse{
+			DeadLetters: []models.DeadLetter{{Offset: 3, OriginalTopic: "flight-bookings"}},
+			Count:       1,
+			NextOffset:  4,
+		},
+	}
+	handler := NewAdminHandler(service, &mockSeatInventoryService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dead-letters?offset=3&limit=10", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ListDeadLetters(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.offset != 3 || service.limit != 10 {
+		t.Fatalf("expected offset 3 and limit 10, got %d and %d", service.offset, service.limit)
+	}
+
+	var resp models.DeadLetterListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Count != 1 || resp.NextOffset != 4 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestListDeadLetters_InvalidLimit(t *testing.T) {
+	handler := NewAdminHandler(&mockDeadLetterService{}, &mockSeatInventoryService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dead-letters?limit=abc", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ListDeadLetters(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestReplayDeadLetter_Success(t *testing.T) {
+	service := &mockDeadLetterService{
+		replayResp: &models.DeadLetter{Offset: 7, OriginalTopic: "flight-bookings"},
+	}
+	handler := NewAdminHandler(service, &mockSeatInventoryService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/dead-letters/7/replay", nil)
+	req = mux.SetURLVars(req, map[string]string{"offset": "7"})
+	rr := httptest.NewRecorder()
+
+	handler.ReplayDeadLetter(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.offset != 7 {
+		t.Fatalf("expected offset 7 to be replayed, got %d", service.offset)
+	}
+}
+
+func TestReplayDeadLetter_NotFound(t *testing.T) {
+	service := &mockDeadLetterService{
+		err: fmt.Errorf("failed to replay dead letter: %w", models.ErrDeadLetterNotFound),
+	}
+	handler := NewAdminHandler(service, &mockSeatInventoryService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/dead-letters/99/replay", nil)
+	req = mux.SetURLVars(req, map[string]string{"offset": "99"})
+	rr := httptest.NewRecorder()
+
+	handler.ReplayDeadLetter(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestReplayDeadLetter_InvalidOffset(t *testing.T) {
+	handler := NewAdminHandler(&mockDeadLetterService{}, &mockSeatInventoryService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/dead-letters/abc/replay", nil)
+	req = mux.SetURLVars(req, map[string]string{"offset": "abc"})
+	rr := httptest.NewRecorder()
+
+	handler.ReplayDeadLetter(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestEnableSeatInventory_Success(t *testing.T) {
+	service := &mockSeatInventoryService{
+		status: &models.SeatInventoryStatus{FlightID: 12, Enabled: true, AvailableSeats: 150},
+	}
+	handler := NewAdminHandler(&mockDeadLetterService{}, service)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/flights/12/seat-inventory", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "12"})
+	rr := httptest.NewRecorder()
+
+	handler.EnableSeatInventory(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.flightID != 12 {
+		t.Fatalf("expected flight 12, got %d", service.flightID)
+	}
+
+	var resp models.SeatInventoryStatus
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if !resp.Enabled || resp.AvailableSeats != 150 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestEnableSeatInventory_FareClassFlight(t *testing.T) {
+	service := &mockSeatInventoryService{err: models.ErrSeatInventoryUnsupported}
+	handler := NewAdminHandler(&mockDeadLetterService{}, service)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/flights/12/seat-inventory", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "12"})
+	rr := httptest.NewRecorder()
+
+	handler.EnableSeatInventory(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, status)
+	}
+}
+
+func TestDisableSeatInventory_Success(t *testing.T) {
+	service := &mockSeatInventoryService{}
+	handler := NewAdminHandler(&mockDeadLetterService{}, service)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/flights/12/seat-inventory", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "12"})
+	rr := httptest.NewRecorder()
+
+	handler.DisableSeatInventory(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, status)
+	}
+
+	if service.flightID != 12 {
+		t.Fatalf("expected flight 12, got %d", service.flightID)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -15,8 +16,14 @@ import (
 // This allows the HTTP handlers to be unit tested with mocks.
 type BookingService interface {
 	CreateBooking(rctx context.Context, req *models.BookingRequest) (*models.BookingResponse, error)
+	CreateItineraryBooking(rctx context.Context, req *models.ItineraryBookingRequest) (*models.ItineraryBookingResponse, error)
 	GetBookingByID(rctx context.Context, id int64) (*models.Booking, error)
 	GetBookingsByUserID(rctx context.Context, userID int64) ([]models.Booking, error)
+	CancelBooking(rctx context.Context, id int64) (*models.BookingResponse, error)
+	CreateQuote(rctx context.Context, req *models.QuoteRequest) (*models.QuoteResponse, error)
+	CreateOrder(rctx context.Context, req *models.OrderRequest) (*models.OrderResponse, error)
+	GetOrderByID(rctx context.Context, id int64) (*models.Order, error)
+	GetBookingQueue(rctx context.Context, flightID int64) (*models.BookingQueueStatus, error)
 }
 
 // BookingHandler handles booking-related HTTP requests
@@ -50,6 +57,89 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// CreateItineraryBooking handles requests to book every leg of an itinerary
+func (h *BookingHandler) CreateItineraryBooking(w http.ResponseWriter, r *http.Request) {
+	var req models.ItineraryBookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.bookingService.CreateItineraryBooking(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateOrder handles requests to book a round-trip or multi-city trip as one order
+func (h *BookingHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var req models.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.bookingService.CreateOrder(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetOrder handles getting an order and all of its segments by ID
+func (h *BookingHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.bookingService.GetOrderByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// CreateQuote handles requests for a signed, expiring price quote
+func (h *BookingHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	var req models.QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.bookingService.CreateQuote(r.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotQuotable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetBooking handles getting a booking by ID
 func (h *BookingHandler) GetBooking(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -71,6 +161,48 @@ func (h *BookingHandler) GetBooking(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(booking)
 }
 
+// GetBookingQueue handles requests for the number of bookings waiting for a flight
+func (h *BookingHandler) GetBookingQueue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.bookingService.GetBookingQueue(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// CancelBooking handles booking cancellation requests
+func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.bookingService.CancelBooking(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetUserBookings handles getting bookings for a user
 func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
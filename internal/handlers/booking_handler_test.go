@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,17 +20,39 @@ type mockBookingService struct {
 	createResp *models.BookingResponse
 	createErr  error
 
+	itineraryResp *models.ItineraryBookingResponse
+	itineraryErr  error
+
 	getBookingResp *models.Booking
 	getBookingErr  error
 
 	getByUserResp []models.Booking
 	getByUserErr  error
+
+	cancelResp *models.BookingResponse
+	cancelErr  error
+
+	quoteResp *models.QuoteResponse
+	quoteErr  error
+
+	orderResp *models.OrderResponse
+	orderErr  error
+
+	getOrderResp *models.Order
+	getOrderErr  error
+
+	queueResp *models.BookingQueueStatus
+	queueErr  error
 }
 
 func (m *mockBookingService) CreateBooking(ctx context.Context, req *models.BookingRequest) (*models.BookingResponse, error) {
 	return m.createResp, m.createErr
 }
 
+func (m *mockBookingService) CreateItineraryBooking(ctx context.Context, req *models.ItineraryBookingRequest) (*models.ItineraryBookingResponse, error) {
+	return m.itineraryResp, m.itineraryErr
+}
+
 func (m *mockBookingService) GetBookingByID(ctx context.Context, id int64) (*models.Booking, error) {
 	return m.getBookingResp, m.getBookingErr
 }
@@ -37,6 +61,26 @@ func (m *mockBookingService) GetBookingsByUserID(ctx context.Context, userID int
 	return m.getByUserResp, m.getByUserErr
 }
 
+func (m *mockBookingService) CancelBooking(ctx context.Context, id int64) (*models.BookingResponse, error) {
+	return m.cancelResp, m.cancelErr
+}
+
+func (m *mockBookingService) CreateQuote(ctx context.Context, req *models.QuoteRequest) (*models.QuoteResponse, error) {
+	return m.quoteResp, m.quoteErr
+}
+
+func (m *mockBookingService) CreateOrder(ctx context.Context, req *models.OrderRequest) (*models.OrderResponse, error) {
+	return m.orderResp, m.orderErr
+}
+
+func (m *mockBookingService) GetBookingQueue(ctx context.Context, flightID int64) (*models.BookingQueueStatus, error) {
+	return m.queueResp, m.queueErr
+}
+
+func (m *mockBookingService) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	return m.getOrderResp, m.getOrderErr
+}
+
 func TestCreateBooking_InvalidJSON(t *testing.T) {
 	service := &mockBookingService{}
 	handler := NewBookingHandler(service)
@@ -163,4 +207,248 @@ func TestGetUserBookings_Success(t *testing.T) {
 	}
 }
 
+func TestCancelBooking_InvalidID(t *testing.T) {
+	service := &mockBookingService{}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/bookings/abc/cancel", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+	rr := httptest.NewRecorder()
+
+	handler.CancelBooking(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestCancelBooking_Success(t *testing.T) {
+	service := &mockBookingService{
+		cancelResp: &models.BookingResponse{
+			BookingID: 1,
+			Status:    models.BookingStatusCancelled,
+			Message:   "Booking cancelled",
+		},
+	}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/bookings/1/cancel", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+
+	handler.CancelBooking(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	var resp models.BookingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCancelled {
+		t.Fatalf("expected cancelled status, got %s", resp.Status)
+	}
+}
+
+func TestGetBookingQueue(t *testing.T) {
+	service := &mockBookingService{
+		queueResp: &models.BookingQueueStatus{FlightID: 1, Waiting: 12},
+	}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/1/booking-queue", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+
+	handler.GetBookingQueue(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	var resp models.BookingQueueStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.FlightID != 1 || resp.Waiting != 12 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/flights/abc/booking-queue", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+	rr = httptest.NewRecorder()
+
+	handler.GetBookingQueue(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestCreateItineraryBooking_Success(t *testing.T) {
+	service := &mockBookingService{
+		itineraryResp: &models.ItineraryBookingResponse{
+			BookingIDs: []int64{1, 2},
+			Status:     models.BookingStatusPending,
+			TotalPrice: 5200,
+			Message:    "Itinerary booked, processing payment",
+		},
+	}
+	handler := NewBookingHandler(service)
+
+	body := `{"user_id": 123, "seats_booked": 1, "legs": [{"flight_id": 1}, {"flight_id": 2}], "passenger_details": [{"name": "John Doe"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/itineraries/bookings", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler.CreateItineraryBooking(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	var resp models.ItineraryBookingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(resp.BookingIDs) != 2 || resp.Status != models.BookingStatusPending {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCreateItineraryBooking_InvalidJSON(t *testing.T) {
+	handler := NewBookingHandler(&mockBookingService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/itineraries/bookings", bytes.NewBufferString(`invalid-json`))
+	rr := httptest.NewRecorder()
+
+	handler.CreateItineraryBooking(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestCreateQuote_Success(t *testing.T) {
+	service := &mockBookingService{
+		quoteResp: &models.QuoteResponse{
+			PriceQuote: models.PriceQuote{FlightID: 1, Seats: 2, PricePerSeat: 100, TotalPrice: 200},
+			QuoteToken: "token",
+		},
+	}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewBufferString(`{"flight_id": 1, "seats": 2}`))
+	rr := httptest.NewRecorder()
+
+	handler.CreateQuote(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	var resp models.QuoteResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.QuoteToken != "token" || resp.TotalPrice != 200 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCreateQuote_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "not quotable", err: fmt.Errorf("%w: insufficient seats available", models.ErrNotQuotable), status: http.StatusConflict},
+		{name: "internal error", err: errors.New("db down"), status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewBookingHandler(&mockBookingService{quoteErr: tt.err})
+
+			req := httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewBufferString(`{"user_id": 123, "flight_id": 1, "seats": 2}`))
+			rr := httptest.NewRecorder()
+
+			handler.CreateQuote(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestCreateOrder_Success(t *testing.T) {
+	service := &mockBookingService{
+		orderResp: &models.OrderResponse{OrderID: 7, Status: models.BookingStatusPending, TotalPrice: 9000},
+	}
+	handler := NewBookingHandler(service)
+
+	body := `{"user_id":123,"seats_booked":1,"segments":[{"flight_id":1},{"flight_id":2}],"passenger_details":[{"name":"John Doe"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler.CreateOrder(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	var resp models.OrderResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
 
+	if resp.OrderID != 7 || resp.TotalPrice != 9000 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetOrder_ReturnsSegments(t *testing.T) {
+	service := &mockBookingService{
+		getOrderResp: &models.Order{ID: 7, Segments: []models.Booking{{ID: 1, OrderID: 7}, {ID: 2, OrderID: 7}}},
+	}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	rr := httptest.NewRecorder()
+
+	handler.GetOrder(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(rr.Body.Bytes(), &order); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if order.ID != 7 || len(order.Segments) != 2 {
+		t.Fatalf("expected order 7 with 2 segments, got %+v", order)
+	}
+}
+
+func TestGetOrder_NotFound(t *testing.T) {
+	service := &mockBookingService{getOrderErr: errors.New("order not found")}
+	handler := NewBookingHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	rr := httptest.NewRecorder()
+
+	handler.GetOrder(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
@@ -16,9 +16,12 @@ import (
 // This allows the HTTP handlers to be unit tested with mocks.
 type FlightService interface {
 	SearchFlights(rctx context.Context, req *models.FlightSearchRequest) (*models.FlightSearchResponse, error)
+	SearchItineraries(rctx context.Context, req *models.ItinerarySearchRequest) (*models.ItinerarySearchResponse, error)
+	GetFareCalendar(rctx context.Context, req *models.FareCalendarRequest) (*models.FareCalendarResponse, error)
 	GetFlightByID(rctx context.Context, id int64) (*models.Flight, error)
 	CreateFlight(rctx context.Context, flight *models.Flight) (*models.Flight, error)
 	UpdateFlight(rctx context.Context, flight *models.Flight) error
+	GetSeatMap(rctx context.Context, flightID int64) (*models.SeatMap, error)
 }
 
 // FlightHandler handles flight-related HTTP requests.
@@ -52,13 +55,165 @@ func (h *FlightHandler) SearchFlights(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := r.URL.Query()
 	req := &models.FlightSearchRequest{
+		Source:          source,
+		Destination:     destination,
+		Date:            date,
+		DepartureAfter:  query.Get("departure_after"),
+		DepartureBefore: query.Get("departure_before"),
+		Status:          models.FlightStatus(query.Get("status")),
+		SortBy:          models.FlightSortField(query.Get("sort")),
+		Cursor:          query.Get("cursor"),
+	}
+
+	if flexDaysStr := query.Get("flex_days"); flexDaysStr != "" {
+		if req.FlexDays, err = strconv.Atoi(flexDaysStr); err != nil {
+			http.Error(w, "Invalid flex_days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if minSeatsStr := query.Get("min_seats"); minSeatsStr != "" {
+		if req.MinSeats, err = strconv.Atoi(minSeatsStr); err != nil {
+			http.Error(w, "Invalid min_seats", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if req.Limit, err = strconv.Atoi(limitStr); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if minPriceStr := query.Get("min_price"); minPriceStr != "" {
+		if req.MinPrice, err = strconv.ParseFloat(minPriceStr, 64); err != nil {
+			http.Error(w, "Invalid min_price", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if maxPriceStr := query.Get("max_price"); maxPriceStr != "" {
+		if req.MaxPrice, err = strconv.ParseFloat(maxPriceStr, 64); err != nil {
+			http.Error(w, "Invalid max_price", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !req.IsValid() {
+		http.Error(w, "Invalid search parameters", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.flightService.SearchFlights(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SearchItineraries handles searches for direct and connecting journeys
+func (h *FlightHandler) SearchItineraries(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	query := r.URL.Query()
+	source := query.Get("source")
+	destination := query.Get("destination")
+	dateStr := query.Get("date")
+
+	if source == "" || destination == "" || dateStr == "" {
+		http.Error(w, "Missing required parameters: source, destination, date", http.StatusBadRequest)
+		return
+	}
+
+	// Parse date
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	req := &models.ItinerarySearchRequest{
 		Source:      source,
 		Destination: destination,
 		Date:        date,
+		Seats:       1,
+		MaxStops:    models.MaxItineraryStops,
 	}
 
-	response, err := h.flightService.SearchFlights(r.Context(), req)
+	if seatsStr := query.Get("seats"); seatsStr != "" {
+		if req.Seats, err = strconv.Atoi(seatsStr); err != nil {
+			http.Error(w, "Invalid seats", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if maxStopsStr := query.Get("max_stops"); maxStopsStr != "" {
+		if req.MaxStops, err = strconv.Atoi(maxStopsStr); err != nil {
+			http.Error(w, "Invalid max_stops", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !req.IsValid() {
+		http.Error(w, "Invalid search parameters", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.flightService.SearchItineraries(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFareCalendar handles requests for the lowest fare per day on a route over a month
+func (h *FlightHandler) GetFareCalendar(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	query := r.URL.Query()
+	source := query.Get("source")
+	destination := query.Get("destination")
+	monthStr := query.Get("month")
+
+	if source == "" || destination == "" || monthStr == "" {
+		http.Error(w, "Missing required parameters: source, destination, month", http.StatusBadRequest)
+		return
+	}
+
+	// Parse month
+	month, err := time.Parse("2006-01", monthStr)
+	if err != nil {
+		http.Error(w, "Invalid month format. Use YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	req := &models.FareCalendarRequest{
+		Source:      source,
+		Destination: destination,
+		Month:       month,
+		Seats:       1,
+	}
+
+	if seatsStr := query.Get("seats"); seatsStr != "" {
+		if req.Seats, err = strconv.Atoi(seatsStr); err != nil {
+			http.Error(w, "Invalid seats", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !req.IsValid() {
+		http.Error(w, "Invalid fare calendar parameters", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.flightService.GetFareCalendar(r.Context(), req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -89,6 +244,27 @@ func (h *FlightHandler) GetFlight(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(flight)
 }
 
+// GetSeatMap handles getting the seat map of a flight
+func (h *FlightHandler) GetSeatMap(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		return
+	}
+
+	seatMap, err := h.flightService.GetSeatMap(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seatMap)
+}
+
 // CreateFlight handles flight creation
 func (h *FlightHandler) CreateFlight(w http.ResponseWriter, r *http.Request) {
 	var flight models.Flight
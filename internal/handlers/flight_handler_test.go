@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -16,9 +17,18 @@ import (
 
 // mockFlightService is a test double for FlightService.
 type mockFlightService struct {
+	searchReq  *models.FlightSearchRequest
 	searchResp *models.FlightSearchResponse
 	searchErr  error
 
+	calendarReq  *models.FareCalendarRequest
+	calendarResp *models.FareCalendarResponse
+	calendarErr  error
+
+	itinerariesReq  *models.ItinerarySearchRequest
+	itinerariesResp *models.ItinerarySearchResponse
+	itinerariesErr  error
+
 	getFlightResp *models.Flight
 	getFlightErr  error
 
@@ -26,12 +36,26 @@ type mockFlightService struct {
 	createErr  error
 
 	updateErr error
+
+	seatMapResp *models.SeatMap
+	seatMapErr  error
 }
 
 func (m *mockFlightService) SearchFlights(ctx context.Context, req *models.FlightSearchRequest) (*models.FlightSearchResponse, error) {
+	m.searchReq = req
 	return m.searchResp, m.searchErr
 }
 
+func (m *mockFlightService) GetFareCalendar(ctx context.Context, req *models.FareCalendarRequest) (*models.FareCalendarResponse, error) {
+	m.calendarReq = req
+	return m.calendarResp, m.calendarErr
+}
+
+func (m *mockFlightService) SearchItineraries(ctx context.Context, req *models.ItinerarySearchRequest) (*models.ItinerarySearchResponse, error) {
+	m.itinerariesReq = req
+	return m.itinerariesResp, m.itinerariesErr
+}
+
 func (m *mockFlightService) GetFlightByID(ctx context.Context, id int64) (*models.Flight, error) {
 	return m.getFlightResp, m.getFlightErr
 }
@@ -44,6 +68,10 @@ func (m *mockFlightService) UpdateFlight(ctx context.Context, flight *models.Fli
 	return m.updateErr
 }
 
+func (m *mockFlightService) GetSeatMap(ctx context.Context, flightID int64) (*models.SeatMap, error) {
+	return m.seatMapResp, m.seatMapErr
+}
+
 func TestSearchFlights_Success(t *testing.T) {
 	service := &mockFlightService{
 		searchResp: &models.FlightSearchResponse{
@@ -111,6 +139,194 @@ func TestSearchFlights_InvalidDate(t *testing.T) {
 	}
 }
 
+func TestSearchFlights_FlexDays(t *testing.T) {
+	service := &mockFlightService{searchResp: &models.FlightSearchResponse{}}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/search?source=Delhi&destination=Mumbai&date=2025-01-20&flex_days=2", nil)
+	rr := httptest.NewRecorder()
+
+	handler.SearchFlights(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.searchReq.FlexDays != 2 {
+		t.Fatalf("expected a 2-day flexible search, got %+v", service.searchReq)
+	}
+
+	for _, flexDays := range []string{"two", "-1", "4"} {
+		req := httptest.NewRequest(http.MethodGet, "/flights/search?source=Delhi&destination=Mumbai&date=2025-01-20&flex_days="+flexDays, nil)
+		rr := httptest.NewRecorder()
+
+		handler.SearchFlights(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Fatalf("expected status %d for flex_days=%s, got %d", http.StatusBadRequest, flexDays, status)
+		}
+	}
+}
+
+func TestSearchFlights_FiltersSortAndPaging(t *testing.T) {
+	service := &mockFlightService{searchResp: &models.FlightSearchResponse{NextCursor: "next"}}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/search?source=Delhi&destination=Mumbai&date=2025-01-20"+
+		"&min_price=2000&max_price=5000&departure_after=06:00&departure_before=12:00&min_seats=2&status=on_time&sort=price&limit=10", nil)
+	rr := httptest.NewRecorder()
+
+	handler.SearchFlights(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	got := service.searchReq
+	if got.MinPrice != 2000 || got.MaxPrice != 5000 || got.DepartureAfter != "06:00" || got.DepartureBefore != "12:00" ||
+		got.MinSeats != 2 || got.Status != models.FlightStatusOnTime || got.SortBy != models.FlightSortPrice || got.Limit != 10 {
+		t.Fatalf("unexpected search request: %+v", got)
+	}
+
+	var resp models.FlightSearchResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.NextCursor != "next" {
+		t.Fatalf("expected next cursor in response, got %+v", resp)
+	}
+
+	for _, query := range []string{"max_price=cheap", "max_price=100&min_price=200", "departure_after=6am", "sort=name", "status=cancelled", "limit=500", "cursor=bogus"} {
+		req := httptest.NewRequest(http.MethodGet, "/flights/search?source=Delhi&destination=Mumbai&date=2025-01-20&"+query, nil)
+		rr := httptest.NewRecorder()
+
+		handler.SearchFlights(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s, got %d", http.StatusBadRequest, query, status)
+		}
+	}
+}
+
+func TestGetFareCalendar_Success(t *testing.T) {
+	service := &mockFlightService{
+		calendarResp: &models.FareCalendarResponse{
+			Month: "2025-01",
+			Days:  []models.FareCalendarDay{{Date: "2025-01-15", LowestPrice: 2200, Flights: 3}},
+		},
+	}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/calendar?source=Delhi&destination=Mumbai&month=2025-01", nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetFareCalendar(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.calendarReq.Seats != 1 || service.calendarReq.Month.Month() != time.January {
+		t.Fatalf("expected 1 seat in January by default, got %+v", service.calendarReq)
+	}
+
+	var resp models.FareCalendarResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(resp.Days) != 1 || resp.Days[0].LowestPrice != 2200 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetFareCalendar_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "missing month", query: "source=Delhi&destination=Mumbai"},
+		{name: "invalid month", query: "source=Delhi&destination=Mumbai&month=2025-01-15"},
+		{name: "invalid seats", query: "source=Delhi&destination=Mumbai&month=2025-01&seats=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewFlightHandler(&mockFlightService{})
+
+			req := httptest.NewRequest(http.MethodGet, "/flights/calendar?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.GetFareCalendar(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+			}
+		})
+	}
+}
+
+func TestSearchItineraries_Defaults(t *testing.T) {
+	service := &mockFlightService{
+		itinerariesResp: &models.ItinerarySearchResponse{
+			Itineraries: []models.Itinerary{{Stops: 1, DurationMinutes: 300, TotalPrice: 5200}},
+			Count:       1,
+		},
+	}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/itineraries/search?source=Delhi&destination=Chennai&date=2025-01-20", nil)
+	rr := httptest.NewRecorder()
+
+	handler.SearchItineraries(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if service.itinerariesReq.Seats != 1 || service.itinerariesReq.MaxStops != models.MaxItineraryStops {
+		t.Fatalf("expected 1 seat and up to %d stops by default, got %+v", models.MaxItineraryStops, service.itinerariesReq)
+	}
+
+	var resp models.ItinerarySearchResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Count != 1 || resp.Itineraries[0].Stops != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSearchItineraries_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "missing date", query: "source=Delhi&destination=Chennai"},
+		{name: "invalid date", query: "source=Delhi&destination=Chennai&date=invalid"},
+		{name: "invalid seats", query: "source=Delhi&destination=Chennai&date=2025-01-20&seats=two"},
+		{name: "too many stops", query: "source=Delhi&destination=Chennai&date=2025-01-20&max_stops=3"},
+		{name: "same source and destination", query: "source=Delhi&destination=Delhi&date=2025-01-20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewFlightHandler(&mockFlightService{})
+
+			req := httptest.NewRequest(http.MethodGet, "/itineraries/search?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.SearchItineraries(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+			}
+		})
+	}
+}
+
 func TestGetFlight_Success(t *testing.T) {
 	service := &mockFlightService{
 		getFlightResp: &models.Flight{ID: 1},
@@ -143,6 +359,53 @@ func TestGetFlight_InvalidID(t *testing.T) {
 	}
 }
 
+func TestGetSeatMap_Success(t *testing.T) {
+	service := &mockFlightService{
+		seatMapResp: &models.SeatMap{
+			FlightID:       1,
+			Seats:          []models.Seat{{SeatNumber: "1A", Available: true}},
+			AvailableCount: 1,
+		},
+	}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/1/seatmap", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+
+	handler.GetSeatMap(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	var resp models.SeatMap
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.AvailableCount != 1 || len(resp.Seats) != 1 {
+		t.Fatalf("unexpected seat map: %+v", resp)
+	}
+}
+
+func TestGetSeatMap_NotFound(t *testing.T) {
+	service := &mockFlightService{
+		seatMapErr: errors.New("flight not found"),
+	}
+	handler := NewFlightHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/flights/99/seatmap", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "99"})
+	rr := httptest.NewRecorder()
+
+	handler.GetSeatMap(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
 func TestCreateFlight_InvalidJSON(t *testing.T) {
 	service := &mockFlightService{}
 	handler := NewFlightHandler(service)
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"airline-booking-system/internal/models"
+)
+
+// PaymentSignatureHeader carries the hex-encoded HMAC-SHA256 of the webhook body
+const PaymentSignatureHeader = "X-Payment-Signature"
+
+// maxWebhookBodyBytes limits the size of webhook payloads read into memory
+const maxWebhookBodyBytes = 1 << 20
+
+// PaymentWebhookService defines the interface for applying payment gateway callbacks.
+// This allows the HTTP handlers to be unit tested with mocks.
+type PaymentWebhookService interface {
+	HandlePaymentWebhook(rctx context.Context, event *models.PaymentWebhookEvent) (*models.BookingResponse, error)
+}
+
+// PaymentHandler handles payment-related HTTP requests
+type PaymentHandler struct {
+	paymentService PaymentWebhookService
+	webhookSecret  []byte
+}
+
+// NewPaymentHandler creates a new payment handler
+func NewPaymentHandler(paymentService PaymentWebhookService, webhookSecret string) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		webhookSecret:  []byte(webhookSecret),
+	}
+}
+
+// PaymentWebhook handles asynchronous payment gateway callbacks
+func (h *PaymentHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(body, r.Header.Get(PaymentSignatureHeader)) {
+		http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event models.PaymentWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if !event.IsValid() {
+		http.Error(w, "Invalid webhook event", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.paymentService.HandlePaymentWebhook(r.Context(), &event)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPaymentReferenceNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrPaymentAmountMismatch):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validSignature checks the payload against its HMAC-SHA256 signature.
+// Without a configured secret every signature is rejected.
+func (h *PaymentHandler) validSignature(body []byte, signature string) bool {
+	if len(h.webhookSecret) == 0 || signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
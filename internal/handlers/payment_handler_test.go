@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"airline-booking-system/internal/models"
+)
+
+// mockPaymentWebhookService is a test double for PaymentWebhookService.
+type mockPaymentWebhookService struct {
+	resp   *models.BookingResponse
+	err    error
+	called bool
+}
+
+func (m *mockPaymentWebhookService) HandlePaymentWebhook(ctx context.Context, event *models.PaymentWebhookEvent) (*models.BookingResponse, error) {
+	m.called = true
+	return m.resp, m.err
+}
+
+func signWebhook(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+const webhookBody = `{
+	"event_id": "evt-1",
+	"type": "payment.succeeded",
+	"payment_reference_id": "PAY-1",
+	"amount": 5000
+}`
+
+func TestPaymentWebhook_InvalidSignature(t *testing.T) {
+	service := &mockPaymentWebhookService{}
+	handler := NewPaymentHandler(service, "secret")
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(webhookBody))
+	req.Header.Set(PaymentSignatureHeader, signWebhook("wrong-secret", webhookBody))
+	rr := httptest.NewRecorder()
+
+	handler.PaymentWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	if service.called {
+		t.Fatalf("did not expect service to be called")
+	}
+}
+
+func TestPaymentWebhook_NoSecretConfigured(t *testing.T) {
+	service := &mockPaymentWebhookService{}
+	handler := NewPaymentHandler(service, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(webhookBody))
+	req.Header.Set(PaymentSignatureHeader, signWebhook("", webhookBody))
+	rr := httptest.NewRecorder()
+
+	handler.PaymentWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestPaymentWebhook_InvalidEvent(t *testing.T) {
+	service := &mockPaymentWebhookService{}
+	handler := NewPaymentHandler(service, "secret")
+
+	body := `{"event_id": "evt-1", "type": "payment.unknown", "payment_reference_id": "PAY-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(body))
+	req.Header.Set(PaymentSignatureHeader, signWebhook("secret", body))
+	rr := httptest.NewRecorder()
+
+	handler.PaymentWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestPaymentWebhook_Success(t *testing.T) {
+	service := &mockPaymentWebhookService{
+		resp: &models.BookingResponse{
+			BookingID: 1,
+			Status:    models.BookingStatusCompleted,
+			Message:   "Payment event processed",
+		},
+	}
+	handler := NewPaymentHandler(service, "secret")
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(webhookBody))
+	req.Header.Set(PaymentSignatureHeader, signWebhook("secret", webhookBody))
+	rr := httptest.NewRecorder()
+
+	handler.PaymentWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	var resp models.BookingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCompleted {
+		t.Fatalf("expected completed status, got %s", resp.Status)
+	}
+}
+
+func TestPaymentWebhook_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "unknown payment reference", err: fmt.Errorf("%w: PAY-1", models.ErrPaymentReferenceNotFound), wantStatus: http.StatusNotFound},
+		{name: "amount mismatch", err: fmt.Errorf("%w: event reports 1.00", models.ErrPaymentAmountMismatch), wantStatus: http.StatusUnprocessableEntity},
+		{name: "settlement failed", err: errors.New("db unavailable"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPaymentHandler(&mockPaymentWebhookService{err: tt.err}, "secret")
+
+			req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(webhookBody))
+			req.Header.Set(PaymentSignatureHeader, signWebhook("secret", webhookBody))
+			rr := httptest.NewRecorder()
+
+			handler.PaymentWebhook(rr, req)
+
+			if status := rr.Code; status != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, status)
+			}
+		})
+	}
+}
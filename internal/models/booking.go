@@ -12,6 +12,7 @@ const (
 	BookingStatusCompleted BookingStatus = "completed"
 	BookingStatusFailed    BookingStatus = "failed"
 	BookingStatusCancelled BookingStatus = "cancelled"
+	BookingStatusExpired   BookingStatus = "expired"
 )
 
 // PassengerDetails represents passenger information
@@ -21,6 +22,7 @@ type PassengerDetails struct {
 	Phone   string `json:"phone"`
 	Age     int    `json:"age"`
 	Gender  string `json:"gender"`
+	SeatNumber string `json:"seat_number,omitempty"`
 }
 
 // Booking represents a booking entity
@@ -32,6 +34,8 @@ type Booking struct {
 	PaymentReferenceID string           `json:"payment_reference_id" db:"payment_reference_id"`
 	BookingPrice      float64           `json:"booking_price" db:"booking_price"`
 	SeatsBooked       int               `json:"seats_booked" db:"seats_booked"`
+	FareClassCode     string            `json:"fare_class,omitempty" db:"fare_class_code"`
+	OrderID           int64             `json:"order_id,omitempty" db:"order_id"`
 	BookingMetadata   []PassengerDetails `json:"booking_metadata" db:"booking_metadata"`
 	CreatedAt         time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at" db:"updated_at"`
@@ -42,6 +46,9 @@ type BookingRequest struct {
 	FlightID        int64             `json:"flight_id"`
 	UserID          int64             `json:"user_id"`
 	SeatsBooked     int               `json:"seats_booked"`
+	FareClassCode   string            `json:"fare_class,omitempty"`
+	QuoteToken      string            `json:"quote_token,omitempty"`
+	QueueTicket     string            `json:"queue_ticket,omitempty"`
 	PassengerDetails []PassengerDetails `json:"passenger_details"`
 }
 
@@ -51,6 +58,16 @@ type BookingResponse struct {
 	Status           BookingStatus `json:"status"`
 	PaymentReferenceID string       `json:"payment_reference_id,omitempty"`
 	Message          string        `json:"message"`
+	CurrentPrice     float64       `json:"current_price,omitempty"`
+	QueuePosition    int64         `json:"queue_position,omitempty"`
+	QueueTicket      string        `json:"queue_ticket,omitempty"`
+	RetryAfterMs     int64         `json:"retry_after_ms,omitempty"`
+}
+
+// BookingQueueStatus reports how many booking requests are waiting for a flight
+type BookingQueueStatus struct {
+	FlightID int64 `json:"flight_id"`
+	Waiting  int64 `json:"waiting"`
 }
 
 // SeatUpdateEvent represents an event for seat updates
@@ -61,9 +78,28 @@ type SeatUpdateEvent struct {
 	BookingID    int64     `json:"booking_id"`
 }
 
+// BookingCancellationEvent represents an event for a cancelled booking
+type BookingCancellationEvent struct {
+	BookingID     int64     `json:"booking_id"`
+	FlightID      int64     `json:"flight_id"`
+	UserID        int64     `json:"user_id"`
+	SeatsReleased int       `json:"seats_released"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// BookingExpiredEvent represents an event for a pending booking whose seat hold expired
+type BookingExpiredEvent struct {
+	BookingID     int64     `json:"booking_id"`
+	FlightID      int64     `json:"flight_id"`
+	UserID        int64     `json:"user_id"`
+	SeatsReleased int       `json:"seats_released"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
 // PaymentEvent represents a payment processing event
 type PaymentEvent struct {
 	BookingID         int64     `json:"booking_id"`
+	OrderID           int64     `json:"order_id,omitempty"`
 	PaymentReferenceID string   `json:"payment_reference_id"`
 	Amount           float64    `json:"amount"`
 	Status           string     `json:"status"`
@@ -72,10 +108,52 @@ type PaymentEvent struct {
 
 // IsValid checks if the booking request is valid
 func (br *BookingRequest) IsValid() bool {
-	return br.FlightID > 0 && br.UserID > 0 && br.SeatsBooked > 0 && len(br.PassengerDetails) > 0
+	if br.FlightID <= 0 || br.UserID <= 0 || br.SeatsBooked <= 0 || len(br.PassengerDetails) == 0 {
+		return false
+	}
+
+	if br.FareClassCode != "" && !IsValidFareClassCode(NormalizeFareClassCode(br.FareClassCode)) {
+		return false
+	}
+
+	// Selected seats must be well formed, distinct and no more than the seats booked
+	selected := br.SelectedSeats()
+	seen := make(map[string]bool, len(selected))
+	for _, seatNumber := range selected {
+		if !IsValidSeatNumber(seatNumber) || seen[seatNumber] {
+			return false
+		}
+		seen[seatNumber] = true
+	}
+
+	return len(selected) <= br.SeatsBooked
+}
+
+// SelectedSeats returns the normalized seat numbers requested by passengers
+func (br *BookingRequest) SelectedSeats() []string {
+	var seats []string
+	for _, passenger := range br.PassengerDetails {
+		if passenger.SeatNumber != "" {
+			seats = append(seats, NormalizeSeatNumber(passenger.SeatNumber))
+		}
+	}
+	return seats
 }
 
-// GetLockKey returns the Redis lock key for this flight
-func (br *BookingRequest) GetLockKey() string {
-	return "flight_lock:" + string(rune(br.FlightID))
+// FlightLock is a held flight lock, made of the Redis keys in Keys. Token
+// identifies the holder, so only it can extend or release the lock, and Fence
+// orders holders: every acquisition of a lock gets a larger fencing token
+// than the one before.
+type FlightLock struct {
+	FlightID int64
+	Keys     []string
+	Token    string
+	Fence    int64
+	TTL      time.Duration
 }
+
+// IsCancellable checks if the booking can still be cancelled
+func (b *Booking) IsCancellable() bool {
+	return b.Status == BookingStatusPending || b.Status == BookingStatusCompleted
+}
+
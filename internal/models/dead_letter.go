@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDeadLetterNotFound is returned when no dead letter exists at an offset
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterSource identifies which side of Kafka gave up on a message
+type DeadLetterSource string
+
+const (
+	DeadLetterSourceProducer DeadLetterSource = "producer"
+	DeadLetterSourceConsumer DeadLetterSource = "consumer"
+)
+
+// DeadLetter represents a message moved to the dead-letter topic after
+// repeated failures
+type DeadLetter struct {
+	Offset         int64            `json:"offset"`
+	Source         DeadLetterSource `json:"source"`
+	OriginalTopic  string           `json:"original_topic"`
+	OriginalOffset int64            `json:"original_offset"`
+	Key            string           `json:"key"`
+	Payload        string           `json:"payload"`
+	Error          string           `json:"error"`
+	Attempts       int              `json:"attempts"`
+	FailedAt       time.Time        `json:"failed_at"`
+}
+
+// DeadLetterListResponse represents a page of dead letters
+type DeadLetterListResponse struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	Count       int          `json:"count"`
+	NextOffset  int64        `json:"next_offset"`
+}
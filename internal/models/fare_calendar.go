@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// FareCalendarRequest asks for the lowest quoted price per day on a route over one
+// calendar month
+type FareCalendarRequest struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Month       time.Time `json:"month"`
+	Seats       int       `json:"seats"`
+}
+
+// FareCalendarDay is the lowest quoted price on one day among flights with
+// enough seats left
+type FareCalendarDay struct {
+	Date        string  `json:"date"`
+	LowestPrice float64 `json:"lowest_price"`
+	Flights     int     `json:"flights"`
+}
+
+// FareCalendarResponse represents the fare calendar of a route for a month.
+// Days without a bookable flight are left out.
+type FareCalendarResponse struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Month       string            `json:"month"`
+	Days        []FareCalendarDay `json:"days"`
+}
+
+// IsValid checks if the fare calendar request is valid
+func (fcr *FareCalendarRequest) IsValid() bool {
+	return fcr.Source != "" && fcr.Destination != "" && fcr.Source != fcr.Destination &&
+		!fcr.Month.IsZero() && fcr.Seats > 0
+}
+
+// MonthRange returns the start of the requested month and of the next one
+func (fcr *FareCalendarRequest) MonthRange() (time.Time, time.Time) {
+	start := time.Date(fcr.Month.Year(), fcr.Month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// GetCacheKey returns the Redis cache key for this route and month
+func (fcr *FareCalendarRequest) GetCacheKey() string {
+	return fmt.Sprintf("fare_calendar:%s#%s#%s#%d", fcr.Source, fcr.Destination, fcr.Month.Format("2006-01"), fcr.Seats)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// fareClassCodePattern matches booking codes such as Y, W or J
+var fareClassCodePattern = regexp.MustCompile(`^[A-Z]{1,2}$`)
+
+// FareClass represents a booking code on a flight with its own seat
+// allocation, price and refund rules
+type FareClass struct {
+	ID             int64     `json:"id" db:"id"`
+	FlightID       int64     `json:"flight_id" db:"flight_id"`
+	Code           string    `json:"code" db:"code"`
+	Cabin          SeatCabin `json:"cabin" db:"cabin"`
+	Price          float64   `json:"price" db:"price"`
+	QuotedPrice    float64   `json:"quoted_price,omitempty"`
+	QuoteToken     string    `json:"quote_token,omitempty"`
+	TotalSeats     int       `json:"total_seats" db:"total_seats"`
+	AvailableSeats int       `json:"available_seats" db:"available_seats"`
+	Refundable     bool      `json:"refundable" db:"refundable"`
+	Version        int       `json:"version" db:"version"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NormalizeFareClassCode upper-cases and trims a fare class code
+func NormalizeFareClassCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsValidFareClassCode checks if a fare class code has the booking code form
+func IsValidFareClassCode(code string) bool {
+	return fareClassCodePattern.MatchString(code)
+}
+
+// IsValid checks if the fare class can be sold
+func (fc *FareClass) IsValid() bool {
+	return IsValidFareClassCode(fc.Code) && fc.Cabin.IsValid() && fc.Price > 0 && fc.TotalSeats > 0
+}
@@ -1,9 +1,17 @@
 package models
 
 import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// MaxFlexDays is the widest flexible-date window a search can ask for, in
+// days either side of the requested date
+const MaxFlexDays = 3
+
 // FlightStatus represents the status of a flight
 type FlightStatus string
 
@@ -17,38 +25,277 @@ const (
 
 // Flight represents a flight entity
 type Flight struct {
-	ID             int64         `json:"id" db:"id"`
-	Source         string        `json:"source" db:"source"`
-	Destination    string        `json:"destination" db:"destination"`
-	Timestamp      time.Time     `json:"timestamp" db:"timestamp"`
-	AvailableSeats int           `json:"available_seats" db:"available_seats"`
-	TotalSeats     int           `json:"total_seats" db:"total_seats"`
-	FlightStatus   FlightStatus  `json:"flight_status" db:"flight_status"`
-	Price          float64       `json:"price" db:"price"`
-	Version        int           `json:"version" db:"version"`
-	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
-}
-
-// FlightSearchRequest represents search parameters for flights
+	ID               int64        `json:"id" db:"id"`
+	Source           string       `json:"source" db:"source"`
+	Destination      string       `json:"destination" db:"destination"`
+	Timestamp        time.Time    `json:"timestamp" db:"timestamp"`
+	ArrivalTimestamp time.Time    `json:"arrival_timestamp" db:"arrival_timestamp"`
+	AvailableSeats   int          `json:"available_seats" db:"available_seats"`
+	TotalSeats       int          `json:"total_seats" db:"total_seats"`
+	FlightStatus     FlightStatus `json:"flight_status" db:"flight_status"`
+	Price            float64      `json:"price" db:"price"`
+	QuotedPrice      float64      `json:"quoted_price,omitempty"`
+	QuoteToken       string       `json:"quote_token,omitempty"`
+	Version          int          `json:"version" db:"version"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+	FareClasses      []FareClass  `json:"fare_classes,omitempty"`
+}
+
+// FlightSortField is the order flight search results are returned in.
+// FlightSortPrice orders by the lowest quoted price, the price customers see.
+type FlightSortField string
+
+const (
+	FlightSortDeparture FlightSortField = "departure"
+	FlightSortPrice     FlightSortField = "price"
+	FlightSortDuration  FlightSortField = "duration"
+)
+
+// IsValid checks if the sort field is known
+func (f FlightSortField) IsValid() bool {
+	return f == FlightSortDeparture || f == FlightSortPrice || f == FlightSortDuration
+}
+
+const (
+	// DefaultSearchPageSize is the number of flights a search page holds when
+	// the request does not ask for a limit
+	DefaultSearchPageSize = 20
+	// MaxSearchPageSize is the largest page a search can ask for
+	MaxSearchPageSize = 100
+)
+
+// FlightSearchRequest represents search parameters for flights. A non-zero
+// FlexDays also matches flights departing up to that many days before or
+// after Date. The optional filters narrow the results by lowest quoted price,
+// time of day of departure ("15:04"), seats left and status. Results are sorted by
+// SortBy, then flight ID, and paged Limit at a time; Cursor continues from
+// the page that returned it.
 type FlightSearchRequest struct {
-	Source      string    `json:"source"`
-	Destination string    `json:"destination"`
-	Date        time.Time `json:"date"`
+	Source          string          `json:"source"`
+	Destination     string          `json:"destination"`
+	Date            time.Time       `json:"date"`
+	FlexDays        int             `json:"flex_days,omitempty"`
+	MinPrice        float64         `json:"min_price,omitempty"`
+	MaxPrice        float64         `json:"max_price,omitempty"`
+	DepartureAfter  string          `json:"departure_after,omitempty"`
+	DepartureBefore string          `json:"departure_before,omitempty"`
+	MinSeats        int             `json:"min_seats,omitempty"`
+	Status          FlightStatus    `json:"status,omitempty"`
+	SortBy          FlightSortField `json:"sort_by,omitempty"`
+	Limit           int             `json:"limit,omitempty"`
+	Cursor          string          `json:"cursor,omitempty"`
 }
 
-// FlightSearchResponse represents the response for flight search
+// FlightSearchResponse represents the response for flight search.
+// NextCursor is set when more results follow this page.
 type FlightSearchResponse struct {
-	Flights []Flight `json:"flights"`
-	Count   int      `json:"count"`
+	Flights    []Flight `json:"flights"`
+	Count      int      `json:"count"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// FlightSearchCursor is the position after the last flight of a search page:
+// the sort key and ID of that flight
+type FlightSearchCursor struct {
+	SortBy    FlightSortField `json:"s"`
+	Departure time.Time       `json:"d,omitempty"`
+	Price     float64         `json:"p,omitempty"`
+	Duration  int64           `json:"t,omitempty"`
+	ID        int64           `json:"id"`
 }
 
 // IsValid checks if the flight search request is valid
 func (fsr *FlightSearchRequest) IsValid() bool {
-	return fsr.Source != "" && fsr.Destination != "" && !fsr.Date.IsZero()
+	if fsr.Source == "" || fsr.Destination == "" || fsr.Date.IsZero() {
+		return false
+	}
+
+	if fsr.FlexDays < 0 || fsr.FlexDays > MaxFlexDays {
+		return false
+	}
+
+	if fsr.MinPrice < 0 || fsr.MaxPrice < 0 || (fsr.MaxPrice > 0 && fsr.MaxPrice < fsr.MinPrice) {
+		return false
+	}
+
+	for _, clock := range []string{fsr.DepartureAfter, fsr.DepartureBefore} {
+		if clock != "" {
+			if _, err := time.Parse("15:04", clock); err != nil {
+				return false
+			}
+		}
+	}
+
+	if fsr.MinSeats < 0 || fsr.Limit < 0 || fsr.Limit > MaxSearchPageSize {
+		return false
+	}
+
+	if fsr.Status != "" && fsr.Status != FlightStatusScheduled && fsr.Status != FlightStatusOnTime {
+		return false
+	}
+
+	if fsr.SortBy != "" && !fsr.SortBy.IsValid() {
+		return false
+	}
+
+	if fsr.Cursor != "" {
+		if _, err := fsr.DecodeCursor(); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DateRange returns the first and last departure dates the search matches
+func (fsr *FlightSearchRequest) DateRange() (time.Time, time.Time) {
+	return fsr.Date.AddDate(0, 0, -fsr.FlexDays), fsr.Date.AddDate(0, 0, fsr.FlexDays)
+}
+
+// SortField returns the field results are sorted by, departure by default
+func (fsr *FlightSearchRequest) SortField() FlightSortField {
+	if fsr.SortBy == "" {
+		return FlightSortDeparture
+	}
+	return fsr.SortBy
+}
+
+// PageSize returns the number of flights per page
+func (fsr *FlightSearchRequest) PageSize() int {
+	if fsr.Limit == 0 {
+		return DefaultSearchPageSize
+	}
+	return fsr.Limit
+}
+
+// SeatsNeeded returns the fewest seats a flight must have left to match
+func (fsr *FlightSearchRequest) SeatsNeeded() int {
+	if fsr.MinSeats == 0 {
+		return 1
+	}
+	return fsr.MinSeats
+}
+
+// FiltersByPrice reports whether the search filters or sorts by quoted
+// price. Quoted prices are not stored, so such a search loads every flight
+// matching its other criteria and is filtered, sorted and paged once priced.
+func (fsr *FlightSearchRequest) FiltersByPrice() bool {
+	return fsr.MinPrice > 0 || fsr.MaxPrice > 0 || fsr.SortField() == FlightSortPrice
+}
+
+// MatchesPrice checks if a priced flight can be sold for the seats needed at
+// a lowest quoted price within the requested range
+func (fsr *FlightSearchRequest) MatchesPrice(flight Flight) bool {
+	price, ok := flight.LowestQuotedPrice(fsr.SeatsNeeded())
+	return ok && price >= fsr.MinPrice && (fsr.MaxPrice == 0 || price <= fsr.MaxPrice)
+}
+
+// Position returns where a flight falls in the search's sort order, in the
+// form of the cursor that continues after it. Flights sorted by price must
+// be priced first.
+func (fsr *FlightSearchRequest) Position(flight Flight) FlightSearchCursor {
+	cursor := FlightSearchCursor{SortBy: fsr.SortField(), ID: flight.ID}
+	switch cursor.SortBy {
+	case FlightSortPrice:
+		cursor.Price, _ = flight.LowestQuotedPrice(fsr.SeatsNeeded())
+	case FlightSortDuration:
+		cursor.Duration = int64(flight.ArrivalTimestamp.Sub(flight.Timestamp) / time.Second)
+	default:
+		cursor.Departure = flight.Timestamp
+	}
+	return cursor
+}
+
+// CursorAfter returns the cursor of the page that follows the given flight
+func (fsr *FlightSearchRequest) CursorAfter(flight Flight) string {
+	payload, _ := json.Marshal(fsr.Position(flight))
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// Compare orders two positions of the same sort: by sort key, then flight
+// ID. It returns a negative number if c comes first, zero if they are the
+// same flight and a positive number otherwise.
+func (c FlightSearchCursor) Compare(other FlightSearchCursor) int {
+	switch {
+	case c.SortBy == FlightSortPrice && c.Price != other.Price:
+		return cmp.Compare(c.Price, other.Price)
+	case c.SortBy == FlightSortDuration && c.Duration != other.Duration:
+		return cmp.Compare(c.Duration, other.Duration)
+	case c.SortBy != FlightSortPrice && c.SortBy != FlightSortDuration && !c.Departure.Equal(other.Departure):
+		return c.Departure.Compare(other.Departure)
+	}
+	return cmp.Compare(c.ID, other.ID)
+}
+
+// LowestQuotedPrice returns the lowest quoted price a priced flight sells
+// seats seats at: its cheapest fare class with enough seats left, or the
+// flight itself when it is not sold by fare class. It reports false if no
+// fare class has enough seats.
+func (f Flight) LowestQuotedPrice(seats int) (float64, bool) {
+	if len(f.FareClasses) == 0 {
+		return f.QuotedPrice, true
+	}
+
+	lowest, found := 0.0, false
+	for _, fareClass := range f.FareClasses {
+		if fareClass.AvailableSeats >= seats && (!found || fareClass.QuotedPrice < lowest) {
+			lowest, found = fareClass.QuotedPrice, true
+		}
+	}
+	return lowest, found
+}
+
+// DecodeCursor decodes the request's cursor. A cursor only continues a
+// search sorted the same way as the one that issued it.
+func (fsr *FlightSearchRequest) DecodeCursor() (*FlightSearchCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(fsr.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var cursor FlightSearchCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	if cursor.SortBy != fsr.SortField() {
+		return nil, fmt.Errorf("cursor was issued for a search sorted by %s", cursor.SortBy)
+	}
+
+	return &cursor, nil
 }
 
-// GetCacheKey returns the Redis cache key for this search
+// GetCacheKey returns the Redis cache key for this search. Filters, sorting
+// and paging are only part of the key when they differ from the defaults, so
+// a plain search keeps the route and day key. Searches by price cache every
+// flight matching their other filters under one key, whatever their price
+// range, sort and page.
 func (fsr *FlightSearchRequest) GetCacheKey() string {
-	return fsr.Source + "#" + fsr.Destination + "#" + fsr.Date.Format("2006-01-02")
+	key := fsr.Source + "#" + fsr.Destination + "#" + fsr.Date.Format("2006-01-02")
+	if fsr.FlexDays > 0 {
+		key += fmt.Sprintf("#flex%d", fsr.FlexDays)
+	}
+	if fsr.DepartureAfter != "" || fsr.DepartureBefore != "" {
+		key += "#dep" + fsr.DepartureAfter + "-" + fsr.DepartureBefore
+	}
+	if fsr.SeatsNeeded() > 1 {
+		key += fmt.Sprintf("#seats%d", fsr.SeatsNeeded())
+	}
+	if fsr.Status != "" {
+		key += "#status" + string(fsr.Status)
+	}
+	if fsr.FiltersByPrice() {
+		return key + "#priced"
+	}
+	if fsr.SortField() != FlightSortDeparture {
+		key += "#sort" + string(fsr.SortField())
+	}
+	if fsr.PageSize() != DefaultSearchPageSize {
+		key += fmt.Sprintf("#limit%d", fsr.PageSize())
+	}
+	if fsr.Cursor != "" {
+		key += "#after" + fsr.Cursor
+	}
+	return key
 }
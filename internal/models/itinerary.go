@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxItineraryStops is the most connections an itinerary can make
+const MaxItineraryStops = 2
+
+// Itinerary is a journey from a source to a destination over one or more
+// connecting flights
+type Itinerary struct {
+	Legs            []Flight  `json:"legs"`
+	Stops           int       `json:"stops"`
+	DepartureTime   time.Time `json:"departure_timestamp"`
+	ArrivalTime     time.Time `json:"arrival_timestamp"`
+	DurationMinutes int       `json:"duration_minutes"`
+	TotalPrice      float64   `json:"total_price"`
+}
+
+// ItinerarySearchRequest represents search parameters for itineraries
+// departing on a date
+type ItinerarySearchRequest struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Date        time.Time `json:"date"`
+	Seats       int       `json:"seats"`
+	MaxStops    int       `json:"max_stops"`
+}
+
+// ItinerarySearchResponse represents the response for itinerary search
+type ItinerarySearchResponse struct {
+	Itineraries []Itinerary `json:"itineraries"`
+	Count       int         `json:"count"`
+}
+
+// ItineraryLeg is one flight of an itinerary booking and the fare class it
+// is booked in
+type ItineraryLeg struct {
+	FlightID      int64  `json:"flight_id"`
+	FareClassCode string `json:"fare_class,omitempty"`
+}
+
+// ItineraryBookingRequest represents a request to book the same passengers on
+// every leg of an itinerary
+type ItineraryBookingRequest struct {
+	UserID           int64              `json:"user_id"`
+	SeatsBooked      int                `json:"seats_booked"`
+	Legs             []ItineraryLeg     `json:"legs"`
+	PassengerDetails []PassengerDetails `json:"passenger_details"`
+}
+
+// ItineraryBookingResponse represents the response for an itinerary booking,
+// booked as an order; BookingIDs holds one booking per leg in travel order
+type ItineraryBookingResponse struct {
+	OrderID            int64         `json:"order_id,omitempty"`
+	BookingIDs         []int64       `json:"booking_ids,omitempty"`
+	Status             BookingStatus `json:"status"`
+	PaymentReferenceID string        `json:"payment_reference_id,omitempty"`
+	TotalPrice         float64       `json:"total_price,omitempty"`
+	Message            string        `json:"message"`
+}
+
+// IsValid checks if the itinerary search request is valid
+func (isr *ItinerarySearchRequest) IsValid() bool {
+	return isr.Source != "" && isr.Destination != "" && isr.Source != isr.Destination &&
+		!isr.Date.IsZero() && isr.Seats > 0 && isr.MaxStops >= 0 && isr.MaxStops <= MaxItineraryStops
+}
+
+// IsValid checks if the itinerary booking request is valid. Seats cannot be
+// selected on itinerary bookings since a seat number only applies to one leg.
+func (ibr *ItineraryBookingRequest) IsValid() bool {
+	if ibr.UserID <= 0 || ibr.SeatsBooked <= 0 || len(ibr.PassengerDetails) == 0 {
+		return false
+	}
+
+	if len(ibr.Legs) == 0 || len(ibr.Legs) > MaxItineraryStops+1 {
+		return false
+	}
+
+	seen := make(map[int64]bool, len(ibr.Legs))
+	for _, leg := range ibr.Legs {
+		if leg.FlightID <= 0 || seen[leg.FlightID] {
+			return false
+		}
+		seen[leg.FlightID] = true
+
+		if leg.FareClassCode != "" && !IsValidFareClassCode(NormalizeFareClassCode(leg.FareClassCode)) {
+			return false
+		}
+	}
+
+	for _, passenger := range ibr.PassengerDetails {
+		if passenger.SeatNumber != "" {
+			return false
+		}
+	}
+
+	return true
+}
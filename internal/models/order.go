@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxOrderSegments is the most flights one order can book
+const MaxOrderSegments = 6
+
+// Order groups the bookings of one trip, such as the outbound and return
+// flights of a round trip, under a single payment. Each segment is a booking
+// carrying the order's ID and payment reference.
+type Order struct {
+	ID                 int64         `json:"id" db:"id"`
+	UserID             int64         `json:"user_id" db:"user_id"`
+	Status             BookingStatus `json:"status" db:"status"`
+	PaymentReferenceID string        `json:"payment_reference_id" db:"payment_reference_id"`
+	TotalPrice         float64       `json:"total_price" db:"total_price"`
+	SeatsBooked        int           `json:"seats_booked" db:"seats_booked"`
+	Segments           []Booking     `json:"segments"`
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// OrderRequest represents a request to book the same passengers on several
+// flights, listed in travel order, and pay for them at once
+type OrderRequest struct {
+	UserID           int64              `json:"user_id"`
+	SeatsBooked      int                `json:"seats_booked"`
+	Segments         []ItineraryLeg     `json:"segments"`
+	PassengerDetails []PassengerDetails `json:"passenger_details"`
+}
+
+// OrderResponse represents the response for order operations
+type OrderResponse struct {
+	OrderID            int64         `json:"order_id,omitempty"`
+	Status             BookingStatus `json:"status"`
+	PaymentReferenceID string        `json:"payment_reference_id,omitempty"`
+	TotalPrice         float64       `json:"total_price,omitempty"`
+	Message            string        `json:"message"`
+}
+
+// IsValid checks if the order request is valid. As with itinerary bookings,
+// seats cannot be selected since a seat number only applies to one flight.
+func (or *OrderRequest) IsValid() bool {
+	if or.UserID <= 0 || or.SeatsBooked <= 0 || len(or.PassengerDetails) == 0 {
+		return false
+	}
+
+	if len(or.Segments) == 0 || len(or.Segments) > MaxOrderSegments {
+		return false
+	}
+
+	seen := make(map[int64]bool, len(or.Segments))
+	for _, segment := range or.Segments {
+		if segment.FlightID <= 0 || seen[segment.FlightID] {
+			return false
+		}
+		seen[segment.FlightID] = true
+
+		if segment.FareClassCode != "" && !IsValidFareClassCode(NormalizeFareClassCode(segment.FareClassCode)) {
+			return false
+		}
+	}
+
+	for _, passenger := range or.PassengerDetails {
+		if passenger.SeatNumber != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxMessage represents an event waiting in the outbox to be published to Kafka
+type OutboxMessage struct {
+	ID         int64      `json:"id" db:"id"`
+	Topic      string     `json:"topic" db:"topic"`
+	MessageKey string     `json:"message_key" db:"message_key"`
+	Payload    []byte     `json:"payload" db:"payload"`
+	Attempts   int        `json:"attempts" db:"attempts"`
+	LastError  *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrPaymentReferenceNotFound is returned when a payment event names a
+// payment no booking was charged with
+var ErrPaymentReferenceNotFound = errors.New("payment reference not found")
+
+// ErrPaymentAmountMismatch is returned when a payment event reports a
+// different amount than the booking or order was charged
+var ErrPaymentAmountMismatch = errors.New("payment amount does not match")
+
+// PaymentStatus represents the outcome of a payment gateway operation
+type PaymentStatus string
+
+const (
+	PaymentStatusApproved PaymentStatus = "approved"
+	PaymentStatusDeclined PaymentStatus = "declined"
+)
+
+// PaymentRequest represents a payment authorization request for a booking,
+// or for all segments of an order at once
+type PaymentRequest struct {
+	BookingID          int64   `json:"booking_id,omitempty"`
+	OrderID            int64   `json:"order_id,omitempty"`
+	PaymentReferenceID string  `json:"payment_reference_id"`
+	Amount             float64 `json:"amount"`
+}
+
+// PaymentResult represents the response of a payment gateway operation
+type PaymentResult struct {
+	PaymentReferenceID string        `json:"payment_reference_id"`
+	TransactionID      string        `json:"transaction_id"`
+	Status             PaymentStatus `json:"status"`
+	Message            string        `json:"message"`
+}
+
+// IsApproved checks if the gateway approved the operation
+func (pr *PaymentResult) IsApproved() bool {
+	return pr.Status == PaymentStatusApproved
+}
+
+// PaymentWebhookEventType represents the kind of gateway callback
+type PaymentWebhookEventType string
+
+const (
+	PaymentWebhookEventSucceeded PaymentWebhookEventType = "payment.succeeded"
+	PaymentWebhookEventFailed    PaymentWebhookEventType = "payment.failed"
+)
+
+// PaymentWebhookEvent represents an asynchronous callback from the payment gateway
+type PaymentWebhookEvent struct {
+	EventID            string                  `json:"event_id"`
+	Type               PaymentWebhookEventType `json:"type"`
+	PaymentReferenceID string                  `json:"payment_reference_id"`
+	Amount             float64                 `json:"amount"`
+	Timestamp          time.Time               `json:"timestamp"`
+}
+
+// IsValid checks if the webhook event is valid
+func (e *PaymentWebhookEvent) IsValid() bool {
+	return e.EventID != "" && e.PaymentReferenceID != "" &&
+		(e.Type == PaymentWebhookEventSucceeded || e.Type == PaymentWebhookEventFailed)
+}
+
+// MatchesAmount checks if the event reports the amount charged, to the cent
+func (e *PaymentWebhookEvent) MatchesAmount(amount float64) bool {
+	return math.Abs(e.Amount-amount) < 0.005
+}
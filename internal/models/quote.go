@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotQuotable is returned when a flight cannot be sold as requested, so
+// no price can be guaranteed for it
+var ErrNotQuotable = errors.New("flight cannot be quoted")
+
+// QuoteRequest represents a request for a guaranteed price
+type QuoteRequest struct {
+	UserID        int64  `json:"user_id"`
+	FlightID      int64  `json:"flight_id"`
+	FareClassCode string `json:"fare_class,omitempty"`
+	Seats         int    `json:"seats"`
+}
+
+// PriceQuote represents a price offered for a number of seats on a flight,
+// valid until ExpiresAt for one booking. BaseFare is the flight or fare class
+// base fare the price was computed from. Quotes issued with search results
+// are for any user and number of seats, so UserID and Seats are zero.
+type PriceQuote struct {
+	QuoteID       string    `json:"quote_id"`
+	UserID        int64     `json:"user_id,omitempty"`
+	FlightID      int64     `json:"flight_id"`
+	FareClassCode string    `json:"fare_class,omitempty"`
+	Seats         int       `json:"seats"`
+	BaseFare      float64   `json:"base_fare"`
+	PricePerSeat  float64   `json:"price_per_seat"`
+	TotalPrice    float64   `json:"total_price"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// QuoteResponse represents a price quote with the signed token that
+// guarantees it
+type QuoteResponse struct {
+	PriceQuote
+	QuoteToken string `json:"quote_token"`
+}
+
+// IsValid checks if the quote request is valid
+func (qr *QuoteRequest) IsValid() bool {
+	if qr.UserID <= 0 || qr.FlightID <= 0 || qr.Seats <= 0 {
+		return false
+	}
+	return qr.FareClassCode == "" || IsValidFareClassCode(NormalizeFareClassCode(qr.FareClassCode))
+}
+
+// Matches checks if the quote was issued for the given flight, fare class
+// and number of seats
+func (q *PriceQuote) Matches(flightID int64, fareClassCode string, seats int) bool {
+	return q.FlightID == flightID && q.FareClassCode == fareClassCode && (q.Seats == 0 || q.Seats == seats)
+}
+
+// IssuedTo checks if the quote can be used by the given user
+func (q *PriceQuote) IssuedTo(userID int64) bool {
+	return q.UserID == 0 || q.UserID == userID
+}
+
+// HoldsPrice checks if the quoted price can still be charged: it holds while
+// the base fare it was computed from is unchanged, however demand moved since
+func (q *PriceQuote) HoldsPrice(baseFare float64) bool {
+	return q.BaseFare == baseFare
+}
@@ -0,0 +1,8 @@
+package models
+
+// RoutePopularity is the number of times a route has been searched
+type RoutePopularity struct {
+	Source      string `json:"source" db:"source"`
+	Destination string `json:"destination" db:"destination"`
+	Searches    int64  `json:"searches" db:"searches"`
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SeatCabin represents the cabin a seat belongs to
+type SeatCabin string
+
+const (
+	SeatCabinEconomy  SeatCabin = "economy"
+	SeatCabinPremium  SeatCabin = "premium"
+	SeatCabinBusiness SeatCabin = "business"
+)
+
+// cabinOrder lists cabins from the front of the aircraft to the back
+var cabinOrder = []SeatCabin{SeatCabinBusiness, SeatCabinPremium, SeatCabinEconomy}
+
+// IsValid checks if the cabin is one the airline sells
+func (c SeatCabin) IsValid() bool {
+	return c == SeatCabinEconomy || c == SeatCabinPremium || c == SeatCabinBusiness
+}
+
+// seatLetters is the default six-abreast layout; A and F are windows, C and D aisles
+const seatLetters = "ABCDEF"
+
+// seatNumberPattern matches seat numbers such as 12A
+var seatNumberPattern = regexp.MustCompile(`^[1-9][0-9]{0,2}[A-K]$`)
+
+// Seat represents a single seat on a flight
+type Seat struct {
+	ID         int64     `json:"id" db:"id"`
+	FlightID   int64     `json:"flight_id" db:"flight_id"`
+	SeatNumber string    `json:"seat_number" db:"seat_number"`
+	Row        int       `json:"row" db:"seat_row"`
+	Letter     string    `json:"letter" db:"seat_letter"`
+	Cabin      SeatCabin `json:"cabin" db:"cabin"`
+	IsWindow   bool      `json:"is_window" db:"is_window"`
+	IsAisle    bool      `json:"is_aisle" db:"is_aisle"`
+	IsExitRow  bool      `json:"is_exit_row" db:"is_exit_row"`
+	BookingID  *int64    `json:"-" db:"booking_id"`
+	Available  bool      `json:"available"`
+}
+
+// SeatMap represents the seat layout and availability of a flight
+type SeatMap struct {
+	FlightID       int64  `json:"flight_id"`
+	Seats          []Seat `json:"seats"`
+	AvailableCount int    `json:"available_count"`
+}
+
+// NormalizeSeatNumber upper-cases and trims a seat number
+func NormalizeSeatNumber(seatNumber string) string {
+	return strings.ToUpper(strings.TrimSpace(seatNumber))
+}
+
+// IsValidSeatNumber checks if a seat number has the row-and-letter form
+func IsValidSeatNumber(seatNumber string) bool {
+	return seatNumberPattern.MatchString(seatNumber)
+}
+
+// GenerateSeatMap builds the default six-abreast layout for a flight with
+// totalSeats seats. On flights with at least ten rows the first two rows are
+// business class and the middle row is an exit row.
+func GenerateSeatMap(flightID int64, totalSeats int) []Seat {
+	rows := (totalSeats + len(seatLetters) - 1) / len(seatLetters)
+	exitRow := 0
+	businessRows := 0
+	if rows >= 10 {
+		exitRow = rows/2 + 1
+		businessRows = 2
+	}
+
+	seats := make([]Seat, 0, totalSeats)
+	for i := 0; i < totalSeats; i++ {
+		row := i/len(seatLetters) + 1
+		letter := string(seatLetters[i%len(seatLetters)])
+
+		cabin := SeatCabinEconomy
+		if row <= businessRows {
+			cabin = SeatCabinBusiness
+		}
+
+		seats = append(seats, Seat{
+			FlightID:   flightID,
+			SeatNumber: strconv.Itoa(row) + letter,
+			Row:        row,
+			Letter:     letter,
+			Cabin:      cabin,
+			IsWindow:   letter == "A" || letter == "F",
+			IsAisle:    letter == "C" || letter == "D",
+			IsExitRow:  row == exitRow,
+			Available:  true,
+		})
+	}
+
+	return seats
+}
+
+// GenerateFareClassSeatMap builds a six-abreast layout whose cabins follow
+// the seat allocation of the flight's fare classes. Cabins are laid out
+// business first, then premium, then economy, each starting on a new row;
+// seats not allocated to any class are economy. On flights with at least ten
+// rows the middle row is an exit row.
+func GenerateFareClassSeatMap(flightID int64, totalSeats int, fareClasses []FareClass) []Seat {
+	cabinSeats := make(map[SeatCabin]int)
+	allocated := 0
+	for _, fareClass := range fareClasses {
+		cabinSeats[fareClass.Cabin] += fareClass.TotalSeats
+		allocated += fareClass.TotalSeats
+	}
+	if allocated < totalSeats {
+		cabinSeats[SeatCabinEconomy] += totalSeats - allocated
+	}
+
+	seats := make([]Seat, 0, totalSeats)
+	row := 0
+	for _, cabin := range cabinOrder {
+		for i := 0; i < cabinSeats[cabin]; i++ {
+			if i%len(seatLetters) == 0 {
+				row++
+			}
+			letter := string(seatLetters[i%len(seatLetters)])
+
+			seats = append(seats, Seat{
+				FlightID:   flightID,
+				SeatNumber: strconv.Itoa(row) + letter,
+				Row:        row,
+				Letter:     letter,
+				Cabin:      cabin,
+				IsWindow:   letter == "A" || letter == "F",
+				IsAisle:    letter == "C" || letter == "D",
+				Available:  true,
+			})
+		}
+	}
+
+	if row >= 10 {
+		exitRow := row/2 + 1
+		for i := range seats {
+			seats[i].IsExitRow = seats[i].Row == exitRow
+		}
+	}
+
+	return seats
+}
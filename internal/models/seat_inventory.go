@@ -0,0 +1,20 @@
+package models
+
+import "errors"
+
+// ErrSeatInventoryUnsupported is returned when enabling a seat inventory for
+// a flight sold by fare class, whose seats are counted per class
+var ErrSeatInventoryUnsupported = errors.New("seat inventory is not supported for flights sold by fare class")
+
+// ErrSeatInventoryBusy is returned when a flight's seat inventory cannot be
+// changed because the flight is being booked under its lock
+var ErrSeatInventoryBusy = errors.New("flight is being booked, try again")
+
+// SeatInventoryStatus reports a flight's seat inventory in Redis along with
+// the seats booked from it that have not been deducted from the flight yet
+type SeatInventoryStatus struct {
+	FlightID       int64 `json:"flight_id"`
+	Enabled        bool  `json:"enabled"`
+	AvailableSeats int64 `json:"available_seats"`
+	PendingSeats   int   `json:"pending_seats"`
+}
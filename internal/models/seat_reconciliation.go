@@ -0,0 +1,111 @@
+package models
+
+import "fmt"
+
+// SeatStore names a place where a flight's seat count is kept
+type SeatStore string
+
+const (
+	SeatStoreFlights       SeatStore = "flights"
+	SeatStoreFareClasses   SeatStore = "fare_classes"
+	SeatStoreSeatCache     SeatStore = "seat_cache"
+	SeatStoreSeatInventory SeatStore = "seat_inventory"
+)
+
+// SeatStores lists every seat store, in the order they are reconciled
+var SeatStores = []SeatStore{SeatStoreFlights, SeatStoreFareClasses, SeatStoreSeatCache, SeatStoreSeatInventory}
+
+// FlightSeatCount is a flight's recorded available seats alongside the seats
+// held by its bookings, read in one snapshot
+type FlightSeatCount struct {
+	FlightID         int64
+	TotalSeats       int
+	AvailableSeats   int
+	Version          int
+	BookedSeats      int
+	PendingSeats     int
+	InventoryTracked bool
+}
+
+// ExpectedAvailableSeats is the flight's total seats less the seats of its
+// pending and completed bookings. Seats booked from a seat inventory and not
+// yet deducted from the flight still count as available.
+func (c FlightSeatCount) ExpectedAvailableSeats() int {
+	return c.TotalSeats - c.BookedSeats + c.PendingSeats
+}
+
+// ExpectedInventorySeats is what the flight's seat inventory should hold:
+// its total seats less the seats of its pending and completed bookings
+func (c FlightSeatCount) ExpectedInventorySeats() int {
+	return c.TotalSeats - c.BookedSeats
+}
+
+// FareClassSeatCount is a fare class's recorded available seats alongside
+// the seats held by bookings in that class, read in one snapshot
+type FareClassSeatCount struct {
+	FareClassID    int64
+	FlightID       int64
+	Code           string
+	TotalSeats     int
+	AvailableSeats int
+	Version        int
+	BookedSeats    int
+}
+
+// ExpectedAvailableSeats is the fare class's total seats less the seats of
+// its pending and completed bookings
+func (c FareClassSeatCount) ExpectedAvailableSeats() int {
+	return c.TotalSeats - c.BookedSeats
+}
+
+// SeatDrift is a seat count that disagrees with the bookings it should be
+// derived from
+type SeatDrift struct {
+	FlightID      int64     `json:"flight_id"`
+	FareClassCode string    `json:"fare_class,omitempty"`
+	Store         SeatStore `json:"store"`
+	RecordedSeats int       `json:"recorded_seats"`
+	ExpectedSeats int       `json:"expected_seats"`
+	Repaired      bool      `json:"repaired"`
+}
+
+// Seats is how many seats the recorded count is off by, positive if it
+// shows too many seats available
+func (d SeatDrift) Seats() int {
+	return d.RecordedSeats - d.ExpectedSeats
+}
+
+// String describes the drift for logs and reports
+func (d SeatDrift) String() string {
+	target := fmt.Sprintf("flight %d", d.FlightID)
+	if d.FareClassCode != "" {
+		target += fmt.Sprintf(" class %s", d.FareClassCode)
+	}
+
+	outcome := "left in place"
+	if d.Repaired {
+		outcome = "repaired"
+	}
+
+	return fmt.Sprintf("%s: %s has %d seats available, bookings leave %d (%s)",
+		d.Store, target, d.RecordedSeats, d.ExpectedSeats, outcome)
+}
+
+// SeatReconciliationReport lists the seat counts found to drift in one
+// reconciliation pass
+type SeatReconciliationReport struct {
+	FlightsChecked     int         `json:"flights_checked"`
+	FareClassesChecked int         `json:"fare_classes_checked"`
+	Drifts             []SeatDrift `json:"drifts"`
+}
+
+// Unrepaired returns the drifts left in place
+func (r *SeatReconciliationReport) Unrepaired() []SeatDrift {
+	var drifts []SeatDrift
+	for _, drift := range r.Drifts {
+		if !drift.Repaired {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
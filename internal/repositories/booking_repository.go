@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,14 @@ import (
 	"airline-booking-system/pkg/database"
 )
 
+// ErrBookingStatusConflict is returned when a booking status change finds
+// the booking missing or no longer in the status the change expects, as
+// when a payment, cancellation or expiry settled it first
+var ErrBookingStatusConflict = errors.New("booking not found or status changed")
+
+// ErrBookingNotFound is returned when no booking matches a lookup
+var ErrBookingNotFound = errors.New("booking not found")
+
 // BookingRepository handles booking database operations
 type BookingRepository struct {
 	db *database.DB
@@ -30,16 +39,16 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.B
 
 	query := `
 		INSERT INTO bookings (flight_id, user_id, status, payment_reference_id, 
-		                     booking_price, seats_booked, booking_metadata, 
+		                     booking_price, seats_booked, fare_class_code, order_id, booking_metadata, 
 		                     created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, 0), $9, $10, $11)
 		RETURNING id
 	`
 
 	now := time.Now()
-	err = r.db.QueryRowContext(ctx, query,
+	err = r.db.Executor(ctx).QueryRowContext(ctx, query,
 		booking.FlightID, booking.UserID, booking.Status, booking.PaymentReferenceID,
-		booking.BookingPrice, booking.SeatsBooked, string(metadataJSON), now, now,
+		booking.BookingPrice, booking.SeatsBooked, booking.FareClassCode, booking.OrderID, string(metadataJSON), now, now,
 	).Scan(&booking.ID)
 
 	if err != nil {
@@ -56,7 +65,8 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.B
 func (r *BookingRepository) GetBookingByID(ctx context.Context, id int64) (*models.Booking, error) {
 	query := `
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE id = $1
 	`
@@ -64,15 +74,50 @@ func (r *BookingRepository) GetBookingByID(ctx context.Context, id int64) (*mode
 	var booking models.Booking
 	var metadataJSON string
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, id).Scan(
+		&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
+		&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
+		&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	// Unmarshal booking metadata
+	err = json.Unmarshal([]byte(metadataJSON), &booking.BookingMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal booking metadata: %w", err)
+	}
+
+	return &booking, nil
+}
+
+// GetBookingByPaymentReferenceID gets a booking by its payment reference ID
+func (r *BookingRepository) GetBookingByPaymentReferenceID(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+	query := `
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE payment_reference_id = $1
+	`
+
+	var booking models.Booking
+	var metadataJSON string
+
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, paymentRefID).Scan(
 		&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
 		&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
-		&metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+		&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("booking not found")
+			return nil, ErrBookingNotFound
 		}
 		return nil, fmt.Errorf("failed to get booking: %w", err)
 	}
@@ -86,12 +131,14 @@ func (r *BookingRepository) GetBookingByID(ctx context.Context, id int64) (*mode
 	return &booking, nil
 }
 
-// UpdateBookingStatus updates the status of a booking
-func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID int64, status models.BookingStatus, paymentRefID *string) error {
+// UpdateBookingStatus moves a booking from one status to another. A booking
+// no longer in the expected status is left untouched and
+// ErrBookingStatusConflict is returned.
+func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID int64, from, to models.BookingStatus, paymentRefID *string) error {
 	query := `
 		UPDATE bookings 
 		SET status = $1, payment_reference_id = $2, updated_at = $3
-		WHERE id = $4
+		WHERE id = $4 AND status = $5
 	`
 
 	var paymentRef interface{}
@@ -99,7 +146,7 @@ func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID i
 		paymentRef = *paymentRefID
 	}
 
-	result, err := r.db.ExecContext(ctx, query, status, paymentRef, time.Now(), bookingID)
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, to, paymentRef, time.Now(), bookingID, from)
 	if err != nil {
 		return fmt.Errorf("failed to update booking status: %w", err)
 	}
@@ -110,23 +157,137 @@ func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID i
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("booking not found")
+		return ErrBookingStatusConflict
 	}
 
 	return nil
 }
 
+// FailBookingAndReleaseSeats marks a pending booking as failed and returns its
+// seats to the flight in a single statement, so both changes apply atomically
+func (r *BookingRepository) FailBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID *string) error {
+	var paymentRef interface{}
+	if paymentRefID != nil {
+		paymentRef = *paymentRefID
+	}
+
+	return r.releasePendingBooking(ctx, bookingID, models.BookingStatusFailed, paymentRef)
+}
+
+// ExpireBookingAndReleaseSeats marks a pending booking as expired and returns
+// its seats to the flight atomically
+func (r *BookingRepository) ExpireBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID string) error {
+	return r.releasePendingBooking(ctx, bookingID, models.BookingStatusExpired, paymentRefID)
+}
+
+// releasePendingBooking moves a pending booking to a terminal status and
+// restores its seats, including those of its fare class, in one statement.
+// Seats reserved from a seat inventory that were never deducted from the
+// flight are dropped from the pending deductions instead.
+func (r *BookingRepository) releasePendingBooking(ctx context.Context, bookingID int64, status models.BookingStatus, paymentRef interface{}) error {
+	query := `
+		WITH failed AS (
+			UPDATE bookings 
+			SET status = $1, payment_reference_id = $2, updated_at = $3
+			WHERE id = $4 AND status = $5
+			RETURNING id, flight_id, seats_booked, fare_class_code
+		), undeducted AS (
+			DELETE FROM pending_seat_deductions 
+			USING failed
+			WHERE pending_seat_deductions.booking_id = failed.id
+			RETURNING pending_seat_deductions.booking_id
+		), released_fare AS (
+			UPDATE fare_classes 
+			SET available_seats = fare_classes.available_seats + failed.seats_booked, 
+			    version = fare_classes.version + 1, 
+			    updated_at = $3
+			FROM failed
+			WHERE fare_classes.flight_id = failed.flight_id AND fare_classes.code = failed.fare_class_code
+		)
+		UPDATE flights 
+		SET available_seats = flights.available_seats + 
+		        CASE WHEN EXISTS (SELECT 1 FROM undeducted) THEN 0 ELSE failed.seats_booked END, 
+		    version = flights.version + 1, 
+		    updated_at = $3
+		FROM failed
+		WHERE flights.id = failed.flight_id
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query,
+		status, paymentRef, time.Now(), bookingID, models.BookingStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release seats for booking: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrBookingStatusConflict
+	}
+
+	return nil
+}
+
+// GetPendingBookingsCreatedBefore gets the oldest pending bookings created before a cutoff
+func (r *BookingRepository) GetPendingBookingsCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error) {
+	query := `
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE status = $1 AND created_at < $2 AND order_id IS NULL
+		ORDER BY created_at ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, models.BookingStatusPending, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		var booking models.Booking
+		var metadataJSON string
+
+		err := rows.Scan(
+			&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
+			&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
+			&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+
+		// Unmarshal booking metadata
+		err = json.Unmarshal([]byte(metadataJSON), &booking.BookingMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal booking metadata: %w", err)
+		}
+
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, rows.Err()
+}
+
 // GetBookingsByUserID gets bookings for a user
 func (r *BookingRepository) GetBookingsByUserID(ctx context.Context, userID int64) ([]models.Booking, error) {
 	query := `
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user bookings: %w", err)
 	}
@@ -140,7 +301,7 @@ func (r *BookingRepository) GetBookingsByUserID(ctx context.Context, userID int6
 		err := rows.Scan(
 			&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
 			&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
-			&metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+			&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan booking: %w", err)
@@ -162,13 +323,14 @@ func (r *BookingRepository) GetBookingsByUserID(ctx context.Context, userID int6
 func (r *BookingRepository) GetBookingsByFlightID(ctx context.Context, flightID int64) ([]models.Booking, error) {
 	query := `
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE flight_id = $1
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, flightID)
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, flightID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get flight bookings: %w", err)
 	}
@@ -182,7 +344,50 @@ func (r *BookingRepository) GetBookingsByFlightID(ctx context.Context, flightID
 		err := rows.Scan(
 			&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
 			&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
-			&metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+			&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+
+		// Unmarshal booking metadata
+		err = json.Unmarshal([]byte(metadataJSON), &booking.BookingMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal booking metadata: %w", err)
+		}
+
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, rows.Err()
+}
+
+// GetBookingsByOrderID gets the segment bookings of an order in travel order
+func (r *BookingRepository) GetBookingsByOrderID(ctx context.Context, orderID int64) ([]models.Booking, error) {
+	query := `
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE order_id = $1
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		var booking models.Booking
+		var metadataJSON string
+
+		err := rows.Scan(
+			&booking.ID, &booking.FlightID, &booking.UserID, &booking.Status,
+			&booking.PaymentReferenceID, &booking.BookingPrice, &booking.SeatsBooked,
+			&booking.FareClassCode, &booking.OrderID, &metadataJSON, &booking.CreatedAt, &booking.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan booking: %w", err)
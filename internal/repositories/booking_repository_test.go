@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -48,14 +49,14 @@ func TestBookingRepository_CreateBooking_Success(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		INSERT INTO bookings (flight_id, user_id, status, payment_reference_id, 
-		                     booking_price, seats_booked, booking_metadata, 
+		                     booking_price, seats_booked, fare_class_code, order_id, booking_metadata, 
 		                     created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, 0), $9, $10, $11)
 		RETURNING id
 	`)).
 		WithArgs(
 			booking.FlightID, booking.UserID, booking.Status, booking.PaymentReferenceID,
-			booking.BookingPrice, booking.SeatsBooked, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			booking.BookingPrice, booking.SeatsBooked, booking.FareClassCode, booking.OrderID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
 
@@ -69,13 +70,111 @@ func TestBookingRepository_CreateBooking_Success(t *testing.T) {
 	}
 }
 
+func TestBookingRepository_CreateBooking_WithinTxCommits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	wrapped := &database.DB{DB: db}
+	bookingRepo := NewBookingRepository(wrapped)
+	flightRepo := NewFlightRepository(wrapped)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bookings`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE flights`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(1), 1, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = wrapped.WithinTx(context.Background(), func(ctx context.Context) error {
+		booking := &models.Booking{FlightID: 1, UserID: 123, Status: models.BookingStatusPending, BookingPrice: 5000.0, SeatsBooked: 2}
+		if _, err := bookingRepo.CreateBooking(ctx, booking); err != nil {
+			return err
+		}
+		return flightRepo.UpdateAvailableSeats(ctx, 1, 2, 1, 5)
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBookingRepository_CreateBooking_WithinTxRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	wrapped := &database.DB{DB: db}
+	bookingRepo := NewBookingRepository(wrapped)
+	flightRepo := NewFlightRepository(wrapped)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bookings`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE flights`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(1), 1, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = wrapped.WithinTx(context.Background(), func(ctx context.Context) error {
+		booking := &models.Booking{FlightID: 1, UserID: 123, Status: models.BookingStatusPending, BookingPrice: 5000.0, SeatsBooked: 2}
+		if _, err := bookingRepo.CreateBooking(ctx, booking); err != nil {
+			return err
+		}
+		return flightRepo.UpdateAvailableSeats(ctx, 1, 2, 1, 5)
+	})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBookingRepository_WithinTx_NestedCallsJoinOuterTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	wrapped := &database.DB{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	innerErr := errors.New("inner failure")
+	err = wrapped.WithinTx(context.Background(), func(ctx context.Context) error {
+		return wrapped.WithinTx(ctx, func(ctx context.Context) error {
+			return innerErr
+		})
+	})
+	if !errors.Is(err, innerErr) {
+		t.Fatalf("expected inner error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestBookingRepository_GetBookingByID_NotFound(t *testing.T) {
 	repo, mock, cleanup := newMockBookingRepo(t)
 	defer cleanup()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE id = $1
 	`)).
@@ -83,8 +182,8 @@ func TestBookingRepository_GetBookingByID_NotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 
 	booking, err := repo.GetBookingByID(context.Background(), 1)
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	if !errors.Is(err, ErrBookingNotFound) {
+		t.Fatalf("expected ErrBookingNotFound, got %v", err)
 	}
 
 	if booking != nil {
@@ -92,6 +191,39 @@ func TestBookingRepository_GetBookingByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestBookingRepository_GetBookingByPaymentReferenceID_Success(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "flight_id", "user_id", "status", "payment_reference_id",
+		"booking_price", "seats_booked", "fare_class_code", "order_id", "booking_metadata", "created_at", "updated_at",
+	}).AddRow(
+		int64(1), int64(1), int64(123), models.BookingStatusPending, "PAY-1",
+		5000.0, 2, "", int64(0), `[]`, now, now,
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE payment_reference_id = $1
+	`)).
+		WithArgs("PAY-1").
+		WillReturnRows(rows)
+
+	booking, err := repo.GetBookingByPaymentReferenceID(context.Background(), "PAY-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if booking.ID != 1 || booking.PaymentReferenceID != "PAY-1" {
+		t.Fatalf("unexpected booking: %+v", booking)
+	}
+}
+
 func TestBookingRepository_UpdateBookingStatus_Success(t *testing.T) {
 	repo, mock, cleanup := newMockBookingRepo(t)
 	defer cleanup()
@@ -102,12 +234,12 @@ func TestBookingRepository_UpdateBookingStatus_Success(t *testing.T) {
 	mock.ExpectExec(regexp.QuoteMeta(`
 		UPDATE bookings 
 		SET status = $1, payment_reference_id = $2, updated_at = $3
-		WHERE id = $4
+		WHERE id = $4 AND status = $5
 	`)).
-		WithArgs(status, paymentRef, sqlmock.AnyArg(), int64(1)).
+		WithArgs(status, paymentRef, sqlmock.AnyArg(), int64(1), models.BookingStatusPending).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := repo.UpdateBookingStatus(context.Background(), 1, status, &paymentRef)
+	err := repo.UpdateBookingStatus(context.Background(), 1, models.BookingStatusPending, status, &paymentRef)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -122,17 +254,124 @@ func TestBookingRepository_UpdateBookingStatus_NoRows(t *testing.T) {
 	mock.ExpectExec(regexp.QuoteMeta(`
 		UPDATE bookings 
 		SET status = $1, payment_reference_id = $2, updated_at = $3
-		WHERE id = $4
+		WHERE id = $4 AND status = $5
+	`)).
+		WithArgs(status, nil, sqlmock.AnyArg(), int64(1), models.BookingStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The booking was cancelled or expired before its payment completed
+	err := repo.UpdateBookingStatus(context.Background(), 1, models.BookingStatusPending, status, nil)
+	if !errors.Is(err, ErrBookingStatusConflict) {
+		t.Fatalf("expected a status conflict, got %v", err)
+	}
+}
+
+func TestBookingRepository_FailBookingAndReleaseSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	paymentRef := "PAY-123"
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		WITH failed AS (
+			UPDATE bookings 
+			SET status = $1, payment_reference_id = $2, updated_at = $3
+			WHERE id = $4 AND status = $5
+			RETURNING id, flight_id, seats_booked, fare_class_code
+		), undeducted AS (
+			DELETE FROM pending_seat_deductions 
+			USING failed
+			WHERE pending_seat_deductions.booking_id = failed.id
+			RETURNING pending_seat_deductions.booking_id
+		), released_fare AS (
+			UPDATE fare_classes 
+			SET available_seats = fare_classes.available_seats + failed.seats_booked, 
+			    version = fare_classes.version + 1, 
+			    updated_at = $3
+			FROM failed
+			WHERE fare_classes.flight_id = failed.flight_id AND fare_classes.code = failed.fare_class_code
+		)
+		UPDATE flights 
+		SET available_seats = flights.available_seats + 
+		        CASE WHEN EXISTS (SELECT 1 FROM undeducted) THEN 0 ELSE failed.seats_booked END, 
+		    version = flights.version + 1, 
+		    updated_at = $3
+		FROM failed
+		WHERE flights.id = failed.flight_id
 	`)).
-		WithArgs(status, nil, sqlmock.AnyArg(), int64(1)).
+		WithArgs(models.BookingStatusFailed, paymentRef, sqlmock.AnyArg(), int64(1), models.BookingStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.FailBookingAndReleaseSeats(context.Background(), 1, &paymentRef)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestBookingRepository_FailBookingAndReleaseSeats_NotPending(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`WITH failed AS (`)).
+		WithArgs(models.BookingStatusFailed, nil, sqlmock.AnyArg(), int64(1), models.BookingStatusPending).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	err := repo.UpdateBookingStatus(context.Background(), 1, status, nil)
+	err := repo.FailBookingAndReleaseSeats(context.Background(), 1, nil)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
 
+func TestBookingRepository_ExpireBookingAndReleaseSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`WITH failed AS (`)).
+		WithArgs(models.BookingStatusExpired, "PAY-1", sqlmock.AnyArg(), int64(1), models.BookingStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.ExpireBookingAndReleaseSeats(context.Background(), 1, "PAY-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestBookingRepository_GetPendingBookingsCreatedBefore_Success(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	cutoff := now.Add(-15 * time.Minute)
+	rows := sqlmock.NewRows([]string{
+		"id", "flight_id", "user_id", "status", "payment_reference_id",
+		"booking_price", "seats_booked", "fare_class_code", "order_id", "booking_metadata", "created_at", "updated_at",
+	}).AddRow(
+		int64(1), int64(1), int64(123), models.BookingStatusPending, "PAY-1",
+		5000.0, 2, "", int64(0), `[]`, cutoff.Add(-time.Minute), cutoff.Add(-time.Minute),
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE status = $1 AND created_at < $2 AND order_id IS NULL
+		ORDER BY created_at ASC
+		LIMIT $3
+	`)).
+		WithArgs(models.BookingStatusPending, cutoff, 100).
+		WillReturnRows(rows)
+
+	bookings, err := repo.GetPendingBookingsCreatedBefore(context.Background(), cutoff, 100)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(bookings) != 1 {
+		t.Fatalf("expected 1 booking, got %d", len(bookings))
+	}
+}
+
 func TestBookingRepository_GetBookingsByUserID_Success(t *testing.T) {
 	repo, mock, cleanup := newMockBookingRepo(t)
 	defer cleanup()
@@ -140,15 +379,16 @@ func TestBookingRepository_GetBookingsByUserID_Success(t *testing.T) {
 	now := time.Now()
 	rows := sqlmock.NewRows([]string{
 		"id", "flight_id", "user_id", "status", "payment_reference_id",
-		"booking_price", "seats_booked", "booking_metadata", "created_at", "updated_at",
+		"booking_price", "seats_booked", "fare_class_code", "order_id", "booking_metadata", "created_at", "updated_at",
 	}).AddRow(
 		int64(1), int64(1), int64(123), models.BookingStatusCompleted, "PAY-1",
-		5000.0, 2, `[]`, now, now,
+		5000.0, 2, "", int64(0), `[]`, now, now,
 	)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -173,15 +413,16 @@ func TestBookingRepository_GetBookingsByFlightID_Success(t *testing.T) {
 	now := time.Now()
 	rows := sqlmock.NewRows([]string{
 		"id", "flight_id", "user_id", "status", "payment_reference_id",
-		"booking_price", "seats_booked", "booking_metadata", "created_at", "updated_at",
+		"booking_price", "seats_booked", "fare_class_code", "order_id", "booking_metadata", "created_at", "updated_at",
 	}).AddRow(
 		int64(1), int64(1), int64(123), models.BookingStatusCompleted, "PAY-1",
-		5000.0, 2, `[]`, now, now,
+		5000.0, 2, "", int64(0), `[]`, now, now,
 	)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id, flight_id, user_id, status, payment_reference_id, 
-		       booking_price, seats_booked, booking_metadata, created_at, updated_at
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
 		FROM bookings
 		WHERE flight_id = $1
 		ORDER BY created_at DESC
@@ -200,3 +441,40 @@ func TestBookingRepository_GetBookingsByFlightID_Success(t *testing.T) {
 }
 
 
+
+func TestBookingRepository_GetBookingsByOrderID_Success(t *testing.T) {
+	repo, mock, cleanup := newMockBookingRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "flight_id", "user_id", "status", "payment_reference_id",
+		"booking_price", "seats_booked", "fare_class_code", "order_id", "booking_metadata", "created_at", "updated_at",
+	}).AddRow(
+		int64(1), int64(1), int64(123), models.BookingStatusPending, "PAY-1",
+		5000.0, 2, "", int64(7), `[]`, now, now,
+	).AddRow(
+		int64(2), int64(2), int64(123), models.BookingStatusPending, "PAY-1",
+		4000.0, 2, "", int64(7), `[]`, now, now,
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, user_id, status, payment_reference_id, 
+		       booking_price, seats_booked, COALESCE(fare_class_code, ''), COALESCE(order_id, 0), booking_metadata, 
+		       created_at, updated_at
+		FROM bookings
+		WHERE order_id = $1
+		ORDER BY id ASC
+	`)).
+		WithArgs(int64(7)).
+		WillReturnRows(rows)
+
+	bookings, err := repo.GetBookingsByOrderID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(bookings) != 2 || bookings[0].OrderID != 7 || bookings[1].FlightID != 2 {
+		t.Fatalf("expected both segments of order 7, got %+v", bookings)
+	}
+}
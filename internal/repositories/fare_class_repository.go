@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/lib/pq"
+)
+
+// ErrFareClassNotFound is returned when a flight does not sell the requested
+// fare class
+var ErrFareClassNotFound = errors.New("fare class not found")
+
+// FareClassRepository handles fare class database operations
+type FareClassRepository struct {
+	db *database.DB
+}
+
+// NewFareClassRepository creates a new fare class repository
+func NewFareClassRepository(db *database.DB) *FareClassRepository {
+	return &FareClassRepository{db: db}
+}
+
+// CreateFareClasses inserts the fare classes of a flight in a single
+// statement. Every class starts with all of its seats available.
+func (r *FareClassRepository) CreateFareClasses(ctx context.Context, flightID int64, fareClasses []models.FareClass) ([]models.FareClass, error) {
+	if len(fareClasses) == 0 {
+		return nil, nil
+	}
+
+	codes := make([]string, 0, len(fareClasses))
+	cabins := make([]string, 0, len(fareClasses))
+	prices := make([]float64, 0, len(fareClasses))
+	seats := make([]int64, 0, len(fareClasses))
+	refundables := make([]bool, 0, len(fareClasses))
+	for _, fareClass := range fareClasses {
+		codes = append(codes, fareClass.Code)
+		cabins = append(cabins, string(fareClass.Cabin))
+		prices = append(prices, fareClass.Price)
+		seats = append(seats, int64(fareClass.TotalSeats))
+		refundables = append(refundables, fareClass.Refundable)
+	}
+
+	query := `
+		INSERT INTO fare_classes (flight_id, code, cabin, price, total_seats, available_seats, 
+		                          refundable, version, created_at, updated_at)
+		SELECT $1, code, cabin, price, seats, seats, refundable, 1, $7, $7
+		FROM unnest($2::text[], $3::text[], $4::numeric[], $5::int[], $6::bool[]) 
+		     AS t(code, cabin, price, seats, refundable)
+		RETURNING id, code
+	`
+
+	now := time.Now()
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, flightID,
+		pq.Array(codes), pq.Array(cabins), pq.Array(prices), pq.Array(seats), pq.Array(refundables), now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fare classes: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int64, len(fareClasses))
+	for rows.Next() {
+		var id int64
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, fmt.Errorf("failed to scan fare class: %w", err)
+		}
+		ids[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create fare classes: %w", err)
+	}
+
+	created := make([]models.FareClass, len(fareClasses))
+	for i, fareClass := range fareClasses {
+		fareClass.ID = ids[fareClass.Code]
+		fareClass.FlightID = flightID
+		fareClass.AvailableSeats = fareClass.TotalSeats
+		fareClass.Version = 1
+		fareClass.CreatedAt = now
+		fareClass.UpdatedAt = now
+		created[i] = fareClass
+	}
+
+	return created, nil
+}
+
+// GetFareClassesByFlightIDs gets the fare classes of several flights, cheapest
+// first within each flight
+func (r *FareClassRepository) GetFareClassesByFlightIDs(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+	query := `
+		SELECT id, flight_id, code, cabin, price, total_seats, available_seats, 
+		       refundable, version, created_at, updated_at
+		FROM fare_classes 
+		WHERE flight_id = ANY($1)
+		ORDER BY flight_id ASC, price ASC
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, pq.Array(flightIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fare classes: %w", err)
+	}
+	defer rows.Close()
+
+	var fareClasses []models.FareClass
+	for rows.Next() {
+		var fareClass models.FareClass
+		err := rows.Scan(
+			&fareClass.ID, &fareClass.FlightID, &fareClass.Code, &fareClass.Cabin, &fareClass.Price,
+			&fareClass.TotalSeats, &fareClass.AvailableSeats, &fareClass.Refundable,
+			&fareClass.Version, &fareClass.CreatedAt, &fareClass.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fare class: %w", err)
+		}
+		fareClasses = append(fareClasses, fareClass)
+	}
+
+	return fareClasses, rows.Err()
+}
+
+// GetFareClass gets a fare class of a flight by its code
+func (r *FareClassRepository) GetFareClass(ctx context.Context, flightID int64, code string) (*models.FareClass, error) {
+	query := `
+		SELECT id, flight_id, code, cabin, price, total_seats, available_seats, 
+		       refundable, version, created_at, updated_at
+		FROM fare_classes 
+		WHERE flight_id = $1 AND code = $2
+	`
+
+	var fareClass models.FareClass
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, flightID, code).Scan(
+		&fareClass.ID, &fareClass.FlightID, &fareClass.Code, &fareClass.Cabin, &fareClass.Price,
+		&fareClass.TotalSeats, &fareClass.AvailableSeats, &fareClass.Refundable,
+		&fareClass.Version, &fareClass.CreatedAt, &fareClass.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFareClassNotFound
+		}
+		return nil, fmt.Errorf("failed to get fare class: %w", err)
+	}
+
+	return &fareClass, nil
+}
+
+// ReserveFareClassSeats deducts seats from a fare class using optimistic locking
+func (r *FareClassRepository) ReserveFareClassSeats(ctx context.Context, fareClassID int64, seatsToBook int, version int) error {
+	query := `
+		UPDATE fare_classes 
+		SET available_seats = available_seats - $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4 AND available_seats >= $1
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seatsToBook, time.Now(), fareClassID, version)
+	if err != nil {
+		return fmt.Errorf("failed to reserve fare class seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("optimistic lock failed or insufficient fare class seats")
+	}
+
+	return nil
+}
+
+// ReleaseFareClassSeats returns seats to a fare class of a flight
+func (r *FareClassRepository) ReleaseFareClassSeats(ctx context.Context, flightID int64, code string, seatsToRelease int) error {
+	query := `
+		UPDATE fare_classes 
+		SET available_seats = available_seats + $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE flight_id = $3 AND code = $4 AND available_seats + $1 <= total_seats
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seatsToRelease, time.Now(), flightID, code)
+	if err != nil {
+		return fmt.Errorf("failed to release fare class seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("fare class not found or seats exceed total")
+	}
+
+	return nil
+}
+
+// GetFareClassSeatCounts gets the available seats of every fare class of
+// the flights departing after a cutoff together with the seats held by
+// pending and completed bookings in that class, all in one snapshot
+func (r *FareClassRepository) GetFareClassSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error) {
+	query := `
+		SELECT fc.id, fc.flight_id, fc.code, fc.total_seats, fc.available_seats, fc.version, 
+		       COALESCE(SUM(b.seats_booked), 0)
+		FROM fare_classes fc
+		JOIN flights f ON f.id = fc.flight_id
+		LEFT JOIN bookings b ON b.flight_id = fc.flight_id 
+		                    AND b.fare_class_code = fc.code 
+		                    AND b.status IN ($1, $2)
+		WHERE f.timestamp > $3
+		GROUP BY fc.id
+		ORDER BY fc.flight_id, fc.code
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query,
+		models.BookingStatusPending, models.BookingStatusCompleted, departingAfter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fare class seat counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.FareClassSeatCount
+	for rows.Next() {
+		var count models.FareClassSeatCount
+		err := rows.Scan(
+			&count.FareClassID, &count.FlightID, &count.Code, &count.TotalSeats,
+			&count.AvailableSeats, &count.Version, &count.BookedSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fare class seat count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// RepairFareClassSeats overwrites a fare class's available seats if it has
+// not changed since the given version, and reports whether it did
+func (r *FareClassRepository) RepairFareClassSeats(ctx context.Context, fareClassID int64, version int, seats int) (bool, error) {
+	query := `
+		UPDATE fare_classes 
+		SET available_seats = $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seats, time.Now(), fareClassID, version)
+	if err != nil {
+		return false, fmt.Errorf("failed to repair fare class seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// helper to create a fare class repository with sqlmock
+func newMockFareClassRepo(t *testing.T) (*FareClassRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	wrapped := &database.DB{DB: db}
+
+	cleanup := func() {
+		db.Close()
+	}
+
+	return NewFareClassRepository(wrapped), mock, cleanup
+}
+
+func TestFareClassRepository_CreateFareClasses_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO fare_classes`)).
+		WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(int64(11), "J").AddRow(int64(10), "Y"))
+
+	fareClasses := []models.FareClass{
+		{Code: "Y", Cabin: models.SeatCabinEconomy, Price: 100, TotalSeats: 48},
+		{Code: "J", Cabin: models.SeatCabinBusiness, Price: 400, TotalSeats: 12, Refundable: true},
+	}
+
+	created, err := repo.CreateFareClasses(context.Background(), 1, fareClasses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if created[0].ID != 10 || created[1].ID != 11 {
+		t.Fatalf("expected ids to be matched by code, got %d and %d", created[0].ID, created[1].ID)
+	}
+
+	if created[0].AvailableSeats != 48 || created[0].FlightID != 1 || created[0].Version != 1 {
+		t.Fatalf("unexpected fare class: %+v", created[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFareClassRepository_GetFareClassesByFlightIDs_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "flight_id", "code", "cabin", "price", "total_seats", "available_seats",
+		"refundable", "version", "created_at", "updated_at",
+	}).
+		AddRow(int64(10), int64(1), "Y", "economy", 100.0, 48, 40, false, 3, now, now).
+		AddRow(int64(11), int64(1), "J", "business", 400.0, 12, 12, true, 1, now, now)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, code, cabin, price, total_seats, available_seats, 
+		       refundable, version, created_at, updated_at
+		FROM fare_classes 
+		WHERE flight_id = ANY($1)
+		ORDER BY flight_id ASC, price ASC
+	`)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	fareClasses, err := repo.GetFareClassesByFlightIDs(context.Background(), []int64{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fareClasses) != 2 || fareClasses[0].AvailableSeats != 40 || !fareClasses[1].Refundable {
+		t.Fatalf("unexpected fare classes: %+v", fareClasses)
+	}
+}
+
+func TestFareClassRepository_GetFareClass_NotFound(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, code, cabin, price, total_seats, available_seats, 
+		       refundable, version, created_at, updated_at
+		FROM fare_classes 
+		WHERE flight_id = $1 AND code = $2
+	`)).
+		WithArgs(int64(1), "Q").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.GetFareClass(context.Background(), 1, "Q")
+	if !errors.Is(err, ErrFareClassNotFound) {
+		t.Fatalf("expected ErrFareClassNotFound, got %v", err)
+	}
+}
+
+func TestFareClassRepository_ReserveFareClassSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE fare_classes 
+		SET available_seats = available_seats - $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4 AND available_seats >= $1
+	`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(10), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.ReserveFareClassSeats(context.Background(), 10, 2, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFareClassRepository_ReserveFareClassSeats_Conflict(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE fare_classes`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(10), 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.ReserveFareClassSeats(context.Background(), 10, 2, 3); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestFareClassRepository_ReleaseFareClassSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE fare_classes 
+		SET available_seats = available_seats + $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE flight_id = $3 AND code = $4 AND available_seats + $1 <= total_seats
+	`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(1), "Y").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.ReleaseFareClassSeats(context.Background(), 1, "Y", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFareClassRepository_GetFareClassSeatCounts_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	cutoff := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM fare_classes fc`)).
+		WithArgs(models.BookingStatusPending, models.BookingStatusCompleted, cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "flight_id", "code", "total_seats", "available_seats", "version", "booked",
+		}).AddRow(int64(10), int64(1), "Y", 120, 100, 3, 18))
+
+	counts, err := repo.GetFareClassSeatCounts(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(counts) != 1 || counts[0].Code != "Y" || counts[0].ExpectedAvailableSeats() != 102 {
+		t.Fatalf("expected class Y to have 102 seats available, got %+v", counts)
+	}
+}
+
+func TestFareClassRepository_RepairFareClassSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFareClassRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE fare_classes 
+		SET available_seats = $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4
+	`)).
+		WithArgs(102, sqlmock.AnyArg(), int64(10), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repaired, err := repo.RepairFareClassSeats(context.Background(), 10, 3, 102)
+	if err != nil || !repaired {
+		t.Fatalf("expected the fare class repaired, got %v, %v", repaired, err)
+	}
+}
@@ -20,31 +20,158 @@ func NewFlightRepository(db *database.DB) *FlightRepository {
 	return &FlightRepository{db: db}
 }
 
-// SearchFlights searches for flights based on criteria
+// searchSortColumns maps each stored search sort field to the column it
+// orders by. Quoted prices are not stored, so searches by price are sorted
+// once priced.
+var searchSortColumns = map[models.FlightSortField]string{
+	models.FlightSortDeparture: "timestamp",
+	models.FlightSortDuration:  "EXTRACT(EPOCH FROM (arrival_timestamp - timestamp))",
+}
+
+// SearchFlights searches for flights based on criteria, across every date in
+// the request's flexible-date window. Results are ordered by the requested
+// sort field, then ID, starting after the request's cursor. It returns up to
+// one flight more than the page size so callers can tell whether another
+// page follows. A search by price returns every flight matching its other
+// criteria, by departure, for the caller to price, filter and page.
 func (r *FlightRepository) SearchFlights(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error) {
+	from, to := req.DateRange()
+	args := []interface{}{req.Source, req.Destination, from.Format("2006-01-02"), to.Format("2006-01-02"), req.SeatsNeeded()}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
+		       flight_status, price, version, created_at, updated_at
+		FROM flights
+		WHERE source = $1 
+		  AND destination = $2 
+		  AND DATE(timestamp) BETWEEN $3 AND $4
+		  AND available_seats >= $5
+		  AND flight_status IN ('scheduled', 'on_time')`
+
+	if req.Status != "" {
+		query += `
+		  AND flight_status = ` + arg(req.Status)
+	}
+	// A window whose end is before its start runs overnight
+	switch {
+	case req.DepartureAfter != "" && req.DepartureBefore != "" && req.DepartureBefore < req.DepartureAfter:
+		query += `
+		  AND (CAST(timestamp AS TIME) >= ` + arg(req.DepartureAfter) + ` OR CAST(timestamp AS TIME) < ` + arg(req.DepartureBefore) + `)`
+	default:
+		if req.DepartureAfter != "" {
+			query += `
+		  AND CAST(timestamp AS TIME) >= ` + arg(req.DepartureAfter)
+		}
+		if req.DepartureBefore != "" {
+			query += `
+		  AND CAST(timestamp AS TIME) < ` + arg(req.DepartureBefore)
+		}
+	}
+
+	if req.FiltersByPrice() {
+		query += `
+		ORDER BY timestamp ASC, id ASC
+	`
+	} else if err := appendSearchPage(req, &query, arg); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search flights: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFlights(rows)
+}
+
+// appendSearchPage adds the sort order, cursor and page size of a search to
+// its query, taking bind parameters from arg
+func appendSearchPage(req *models.FlightSearchRequest, query *string, arg func(interface{}) string) error {
+	sortColumn := searchSortColumns[req.SortField()]
+	if req.Cursor != "" {
+		cursor, err := req.DecodeCursor()
+		if err != nil {
+			return err
+		}
+
+		var after interface{}
+		switch cursor.SortBy {
+		case models.FlightSortDuration:
+			after = cursor.Duration
+		default:
+			after = cursor.Departure
+		}
+		*query += `
+		  AND (` + sortColumn + `, id) > (` + arg(after) + `, ` + arg(cursor.ID) + `)`
+	}
+
+	*query += `
+		ORDER BY ` + sortColumn + ` ASC, id ASC
+		LIMIT ` + arg(req.PageSize()+1) + `
+	`
+	return nil
+}
+
+// SearchFlightsDepartingBetween gets every bookable flight departing in
+// [from, to) with at least minSeats seats left, on any route
+func (r *FlightRepository) SearchFlightsDepartingBetween(ctx context.Context, from, to time.Time, minSeats int) ([]models.Flight, error) {
+	query := `
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
+		       flight_status, price, version, created_at, updated_at
+		FROM flights
+		WHERE timestamp >= $1 
+		  AND timestamp < $2
+		  AND available_seats >= $3
+		  AND flight_status IN ('scheduled', 'on_time')
+		ORDER BY timestamp ASC
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, from, to, minSeats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search flights: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFlights(rows)
+}
+
+// SearchRouteFlightsDepartingBetween gets the bookable flights on a route
+// departing in [from, to) with at least minSeats seats left, in departure order
+func (r *FlightRepository) SearchRouteFlightsDepartingBetween(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error) {
 	query := `
-		SELECT id, source, destination, timestamp, available_seats, total_seats, 
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		       flight_status, price, version, created_at, updated_at
 		FROM flights
 		WHERE source = $1 
 		  AND destination = $2 
-		  AND DATE(timestamp) = $3
-		  AND available_seats > 0
+		  AND timestamp >= $3 
+		  AND timestamp < $4
+		  AND available_seats >= $5
 		  AND flight_status IN ('scheduled', 'on_time')
 		ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, req.Source, req.Destination, req.Date.Format("2006-01-02"))
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, source, destination, from, to, minSeats)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search flights: %w", err)
 	}
 	defer rows.Close()
 
+	return scanFlights(rows)
+}
+
+// scanFlights reads flight rows selected in the standard column order
+func scanFlights(rows *sql.Rows) ([]models.Flight, error) {
 	var flights []models.Flight
 	for rows.Next() {
 		var flight models.Flight
 		err := rows.Scan(
-			&flight.ID, &flight.Source, &flight.Destination, &flight.Timestamp,
+			&flight.ID, &flight.Source, &flight.Destination, &flight.Timestamp, &flight.ArrivalTimestamp,
 			&flight.AvailableSeats, &flight.TotalSeats, &flight.FlightStatus,
 			&flight.Price, &flight.Version, &flight.CreatedAt, &flight.UpdatedAt,
 		)
@@ -60,15 +187,15 @@ func (r *FlightRepository) SearchFlights(ctx context.Context, req *models.Flight
 // GetFlightByID gets a flight by ID
 func (r *FlightRepository) GetFlightByID(ctx context.Context, id int64) (*models.Flight, error) {
 	query := `
-		SELECT id, source, destination, timestamp, available_seats, total_seats, 
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		       flight_status, price, version, created_at, updated_at
 		FROM flights
 		WHERE id = $1
 	`
 
 	var flight models.Flight
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&flight.ID, &flight.Source, &flight.Destination, &flight.Timestamp,
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, id).Scan(
+		&flight.ID, &flight.Source, &flight.Destination, &flight.Timestamp, &flight.ArrivalTimestamp,
 		&flight.AvailableSeats, &flight.TotalSeats, &flight.FlightStatus,
 		&flight.Price, &flight.Version, &flight.CreatedAt, &flight.UpdatedAt,
 	)
@@ -83,17 +210,20 @@ func (r *FlightRepository) GetFlightByID(ctx context.Context, id int64) (*models
 	return &flight, nil
 }
 
-// UpdateAvailableSeats updates available seats for a flight with optimistic locking
-func (r *FlightRepository) UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int) error {
+// UpdateAvailableSeats updates available seats for a flight with optimistic
+// locking. fence is the fencing token of the flight lock the caller holds; the
+// update is refused if a later lock holder has already written to the flight.
+func (r *FlightRepository) UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
 	query := `
 		UPDATE flights 
 		SET available_seats = available_seats - $1, 
 		    version = version + 1, 
+		    lock_fence = $5,
 		    updated_at = $2
-		WHERE id = $3 AND version = $4 AND available_seats >= $1
+		WHERE id = $3 AND version = $4 AND available_seats >= $1 AND lock_fence <= $5
 	`
 
-	result, err := r.db.ExecContext(ctx, query, seatsToBook, time.Now(), flightID, version)
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seatsToBook, time.Now(), flightID, version, fence)
 	if err != nil {
 		return fmt.Errorf("failed to update available seats: %w", err)
 	}
@@ -104,7 +234,37 @@ func (r *FlightRepository) UpdateAvailableSeats(ctx context.Context, flightID in
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("optimistic lock failed or insufficient seats")
+		return fmt.Errorf("optimistic lock failed, stale lock or insufficient seats")
+	}
+
+	return nil
+}
+
+// ReleaseSeats returns seats to a flight. The increment is relative and does
+// not check the version: background jobs such as the seat inventory sync
+// move the version without the flight lock, and seats must be returned
+// regardless. The version is still bumped so optimistic writers see the change.
+func (r *FlightRepository) ReleaseSeats(ctx context.Context, flightID int64, seatsToRelease int) error {
+	query := `
+		UPDATE flights 
+		SET available_seats = available_seats + $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND available_seats + $1 <= total_seats
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seatsToRelease, time.Now(), flightID)
+	if err != nil {
+		return fmt.Errorf("failed to release seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("flight not found or seats exceed total")
 	}
 
 	return nil
@@ -113,15 +273,15 @@ func (r *FlightRepository) UpdateAvailableSeats(ctx context.Context, flightID in
 // CreateFlight creates a new flight
 func (r *FlightRepository) CreateFlight(ctx context.Context, flight *models.Flight) (*models.Flight, error) {
 	query := `
-		INSERT INTO flights (source, destination, timestamp, available_seats, total_seats, 
+		INSERT INTO flights (source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		                    flight_status, price, version, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
 	`
 
 	now := time.Now()
-	err := r.db.QueryRowContext(ctx, query,
-		flight.Source, flight.Destination, flight.Timestamp,
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query,
+		flight.Source, flight.Destination, flight.Timestamp, flight.ArrivalTimestamp,
 		flight.AvailableSeats, flight.TotalSeats, flight.FlightStatus,
 		flight.Price, flight.Version, now, now,
 	).Scan(&flight.ID)
@@ -140,15 +300,15 @@ func (r *FlightRepository) CreateFlight(ctx context.Context, flight *models.Flig
 func (r *FlightRepository) UpdateFlight(ctx context.Context, flight *models.Flight) error {
 	query := `
 		UPDATE flights 
-		SET source = $1, destination = $2, timestamp = $3, available_seats = $4, 
-		    total_seats = $5, flight_status = $6, price = $7, version = version + 1, 
-		    updated_at = $8
-		WHERE id = $9 AND version = $10
+		SET source = $1, destination = $2, timestamp = $3, arrival_timestamp = $4, 
+		    available_seats = $5, total_seats = $6, flight_status = $7, price = $8, 
+		    version = version + 1, updated_at = $9
+		WHERE id = $10 AND version = $11
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
-		flight.Source, flight.Destination, flight.Timestamp, flight.AvailableSeats,
-		flight.TotalSeats, flight.FlightStatus, flight.Price, time.Now(),
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query,
+		flight.Source, flight.Destination, flight.Timestamp, flight.ArrivalTimestamp,
+		flight.AvailableSeats, flight.TotalSeats, flight.FlightStatus, flight.Price, time.Now(),
 		flight.ID, flight.Version,
 	)
 
@@ -167,3 +327,217 @@ func (r *FlightRepository) UpdateFlight(ctx context.Context, flight *models.Flig
 
 	return nil
 }
+
+// EnableSeatInventory records that a flight is sold from a seat inventory
+func (r *FlightRepository) EnableSeatInventory(ctx context.Context, flightID int64) error {
+	query := `
+		INSERT INTO seat_inventory_flights (flight_id, created_at)
+		VALUES ($1, $2)
+		ON CONFLICT (flight_id) DO NOTHING
+	`
+
+	if _, err := r.db.Executor(ctx).ExecContext(ctx, query, flightID, time.Now()); err != nil {
+		return fmt.Errorf("failed to enable seat inventory: %w", err)
+	}
+
+	return nil
+}
+
+// DisableSeatInventory records that a flight is no longer sold from a seat inventory
+func (r *FlightRepository) DisableSeatInventory(ctx context.Context, flightID int64) error {
+	query := `DELETE FROM seat_inventory_flights WHERE flight_id = $1`
+
+	if _, err := r.db.Executor(ctx).ExecContext(ctx, query, flightID); err != nil {
+		return fmt.Errorf("failed to disable seat inventory: %w", err)
+	}
+
+	return nil
+}
+
+// GetSeatInventoryFlightIDs gets the IDs of the flights sold from a seat inventory
+func (r *FlightRepository) GetSeatInventoryFlightIDs(ctx context.Context) ([]int64, error) {
+	return r.queryFlightIDs(ctx, `SELECT flight_id FROM seat_inventory_flights ORDER BY flight_id`)
+}
+
+// GetFlightIDsWithPendingSeatDeductions gets the IDs of the flights with
+// seats reserved from a seat inventory but not yet deducted
+func (r *FlightRepository) GetFlightIDsWithPendingSeatDeductions(ctx context.Context) ([]int64, error) {
+	return r.queryFlightIDs(ctx, `SELECT DISTINCT flight_id FROM pending_seat_deductions ORDER BY flight_id`)
+}
+
+// queryFlightIDs runs a query selecting a single column of flight IDs
+func (r *FlightRepository) queryFlightIDs(ctx context.Context, query string) ([]int64, error) {
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var flightIDs []int64
+	for rows.Next() {
+		var flightID int64
+		if err := rows.Scan(&flightID); err != nil {
+			return nil, fmt.Errorf("failed to scan flight ID: %w", err)
+		}
+		flightIDs = append(flightIDs, flightID)
+	}
+
+	return flightIDs, rows.Err()
+}
+
+// AddPendingSeatDeduction records seats a booking reserved from a seat
+// inventory, to be deducted from the flight later
+func (r *FlightRepository) AddPendingSeatDeduction(ctx context.Context, bookingID int64, flightID int64, seats int) error {
+	query := `
+		INSERT INTO pending_seat_deductions (booking_id, flight_id, seats, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Executor(ctx).ExecContext(ctx, query, bookingID, flightID, seats, time.Now()); err != nil {
+		return fmt.Errorf("failed to add pending seat deduction: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyPendingSeatDeductions deducts every pending seat deduction of a flight
+// from its available seats in one statement and returns the seats deducted
+func (r *FlightRepository) ApplyPendingSeatDeductions(ctx context.Context, flightID int64) (int, error) {
+	query := `
+		WITH applied AS (
+			DELETE FROM pending_seat_deductions
+			WHERE flight_id = $1
+			RETURNING seats
+		)
+		UPDATE flights 
+		SET available_seats = available_seats - (SELECT SUM(seats) FROM applied), 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM applied)
+		RETURNING (SELECT SUM(seats) FROM applied)
+	`
+
+	var seats int
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, flightID, time.Now()).Scan(&seats)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply pending seat deductions: %w", err)
+	}
+
+	return seats, nil
+}
+
+// DropPendingSeatDeduction removes the pending seat deduction of a booking
+// whose seats are being released. It returns false if there was none, as the
+// deduction was already applied and the flight's seats must be restored.
+func (r *FlightRepository) DropPendingSeatDeduction(ctx context.Context, bookingID int64) (bool, error) {
+	query := `DELETE FROM pending_seat_deductions WHERE booking_id = $1`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, bookingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to drop pending seat deduction: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetSeatInventory gets a flight's available seats and its pending seat
+// deductions as of one snapshot
+func (r *FlightRepository) GetSeatInventory(ctx context.Context, flightID int64) (int, int, error) {
+	query := `
+		SELECT f.available_seats, 
+		       COALESCE((SELECT SUM(p.seats) FROM pending_seat_deductions p WHERE p.flight_id = f.id), 0)
+		FROM flights f
+		WHERE f.id = $1
+	`
+
+	var available, pending int
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, flightID).Scan(&available, &pending)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, fmt.Errorf("flight not found")
+		}
+		return 0, 0, fmt.Errorf("failed to get seat inventory: %w", err)
+	}
+
+	return available, pending, nil
+}
+
+// GetFlightSeatCounts gets the available seats of every flight departing
+// after a cutoff together with the seats held by its pending and completed
+// bookings and its pending seat deductions, all in one snapshot
+func (r *FlightRepository) GetFlightSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+	query := `
+		SELECT f.id, f.total_seats, f.available_seats, f.version, 
+		       COALESCE(b.seats, 0), COALESCE(p.seats, 0), s.flight_id IS NOT NULL
+		FROM flights f
+		LEFT JOIN (
+			SELECT flight_id, SUM(seats_booked) AS seats
+			FROM bookings
+			WHERE status IN ($1, $2)
+			GROUP BY flight_id
+		) b ON b.flight_id = f.id
+		LEFT JOIN (
+			SELECT flight_id, SUM(seats) AS seats
+			FROM pending_seat_deductions
+			GROUP BY flight_id
+		) p ON p.flight_id = f.id
+		LEFT JOIN seat_inventory_flights s ON s.flight_id = f.id
+		WHERE f.timestamp > $3
+		ORDER BY f.id
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query,
+		models.BookingStatusPending, models.BookingStatusCompleted, departingAfter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight seat counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.FlightSeatCount
+	for rows.Next() {
+		var count models.FlightSeatCount
+		err := rows.Scan(
+			&count.FlightID, &count.TotalSeats, &count.AvailableSeats, &count.Version,
+			&count.BookedSeats, &count.PendingSeats, &count.InventoryTracked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flight seat count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// RepairAvailableSeats overwrites a flight's available seats if the flight
+// has not changed since the given version, and reports whether it did
+func (r *FlightRepository) RepairAvailableSeats(ctx context.Context, flightID int64, version int, seats int) (bool, error) {
+	query := `
+		UPDATE flights 
+		SET available_seats = $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, seats, time.Now(), flightID, version)
+	if err != nil {
+		return false, fmt.Errorf("failed to repair available seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
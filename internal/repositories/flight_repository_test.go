@@ -42,27 +42,28 @@ func TestFlightRepository_SearchFlights_Success(t *testing.T) {
 	}
 
 	rows := sqlmock.NewRows([]string{
-		"id", "source", "destination", "timestamp",
+		"id", "source", "destination", "timestamp", "arrival_timestamp",
 		"available_seats", "total_seats", "flight_status",
 		"price", "version", "created_at", "updated_at",
 	}).AddRow(
-		int64(1), "Delhi", "Mumbai", time.Now(),
+		int64(1), "Delhi", "Mumbai", time.Now(), time.Now().Add(2*time.Hour),
 		150, 180, models.FlightStatusScheduled,
 		2500.0, 1, time.Now(), time.Now(),
 	)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, source, destination, timestamp, available_seats, total_seats, 
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		       flight_status, price, version, created_at, updated_at
 		FROM flights
 		WHERE source = $1 
 		  AND destination = $2 
-		  AND DATE(timestamp) = $3
-		  AND available_seats > 0
+		  AND DATE(timestamp) BETWEEN $3 AND $4
+		  AND available_seats >= $5
 		  AND flight_status IN ('scheduled', 'on_time')
-		ORDER BY timestamp ASC
+		ORDER BY timestamp ASC, id ASC
+		LIMIT $6
 	`)).
-		WithArgs(req.Source, req.Destination, req.Date.Format("2006-01-02")).
+		WithArgs(req.Source, req.Destination, "2025-01-20", "2025-01-20", 1, models.DefaultSearchPageSize+1).
 		WillReturnRows(rows)
 
 	flights, err := repo.SearchFlights(context.Background(), req)
@@ -75,12 +76,241 @@ func TestFlightRepository_SearchFlights_Success(t *testing.T) {
 	}
 }
 
+func TestFlightRepository_SearchFlights_FlexibleDates(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	req := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		FlexDays:    2,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND DATE(timestamp) BETWEEN $3 AND $4`)).
+		WithArgs(req.Source, req.Destination, "2025-01-18", "2025-01-22", 1, models.DefaultSearchPageSize+1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.SearchFlights(context.Background(), req); err != nil {
+		t.Fatalf("SearchFlights returned error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFlightRepository_SearchFlights_FiltersAndSort(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	req := &models.FlightSearchRequest{
+		Source:          "Delhi",
+		Destination:     "Mumbai",
+		Date:            time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		DepartureAfter:  "06:00",
+		DepartureBefore: "12:00",
+		MinSeats:        2,
+		Status:          models.FlightStatusOnTime,
+		SortBy:          models.FlightSortDuration,
+		Limit:           10,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		  AND available_seats >= $5
+		  AND flight_status IN ('scheduled', 'on_time')
+		  AND flight_status = $6
+		  AND CAST(timestamp AS TIME) >= $7
+		  AND CAST(timestamp AS TIME) < $8
+		ORDER BY EXTRACT(EPOCH FROM (arrival_timestamp - timestamp)) ASC, id ASC
+		LIMIT $9
+	`)).
+		WithArgs(req.Source, req.Destination, "2025-01-20", "2025-01-20", 2,
+			models.FlightStatusOnTime, "06:00", "12:00", 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.SearchFlights(context.Background(), req); err != nil {
+		t.Fatalf("SearchFlights returned error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFlightRepository_SearchFlights_ByPriceLoadsEveryMatch(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	req := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		MaxPrice:    5000,
+		SortBy:      models.FlightSortPrice,
+		Limit:       10,
+	}
+	req.Cursor = req.CursorAfter(models.Flight{ID: 42, QuotedPrice: 2500})
+
+	// Quoted prices are not stored: no price clause, cursor or limit
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		  AND available_seats >= $5
+		  AND flight_status IN ('scheduled', 'on_time')
+		ORDER BY timestamp ASC, id ASC
+	`)).
+		WithArgs(req.Source, req.Destination, "2025-01-20", "2025-01-20", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.SearchFlights(context.Background(), req); err != nil {
+		t.Fatalf("SearchFlights returned error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFlightRepository_SearchFlights_OvernightWindowAndCursor(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	req := &models.FlightSearchRequest{
+		Source:          "Delhi",
+		Destination:     "Mumbai",
+		Date:            time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		DepartureAfter:  "22:00",
+		DepartureBefore: "02:00",
+		SortBy:          models.FlightSortDuration,
+	}
+	req.Cursor = req.CursorAfter(models.Flight{
+		ID:               42,
+		Timestamp:        req.Date.Add(23 * time.Hour),
+		ArrivalTimestamp: req.Date.Add(25 * time.Hour),
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		  AND (CAST(timestamp AS TIME) >= $6 OR CAST(timestamp AS TIME) < $7)
+		  AND (EXTRACT(EPOCH FROM (arrival_timestamp - timestamp)), id) > ($8, $9)
+		ORDER BY EXTRACT(EPOCH FROM (arrival_timestamp - timestamp)) ASC, id ASC
+		LIMIT $10
+	`)).
+		WithArgs(req.Source, req.Destination, "2025-01-20", "2025-01-20", 1,
+			"22:00", "02:00", int64(7200), int64(42), models.DefaultSearchPageSize+1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.SearchFlights(context.Background(), req); err != nil {
+		t.Fatalf("SearchFlights returned error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFlightRepository_SearchRouteFlightsDepartingBetween_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	rows := sqlmock.NewRows([]string{
+		"id", "source", "destination", "timestamp", "arrival_timestamp",
+		"available_seats", "total_seats", "flight_status",
+		"price", "version", "created_at", "updated_at",
+	}).AddRow(
+		int64(1), "Delhi", "Mumbai", from.AddDate(0, 0, 14).Add(10*time.Hour), from.AddDate(0, 0, 14).Add(12*time.Hour),
+		150, 180, models.FlightStatusScheduled,
+		2200.0, 1, time.Now(), time.Now(),
+	).AddRow(
+		int64(2), "Delhi", "Mumbai", from.AddDate(0, 0, 15).Add(8*time.Hour), from.AddDate(0, 0, 15).Add(10*time.Hour),
+		20, 180, models.FlightStatusOnTime,
+		2500.0, 1, time.Now(), time.Now(),
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
+		       flight_status, price, version, created_at, updated_at
+		FROM flights
+		WHERE source = $1 
+		  AND destination = $2 
+		  AND timestamp >= $3 
+		  AND timestamp < $4
+		  AND available_seats >= $5
+		  AND flight_status IN ('scheduled', 'on_time')
+		ORDER BY timestamp ASC
+	`)).
+		WithArgs("Delhi", "Mumbai", from, to, 2).
+		WillReturnRows(rows)
+
+	flights, err := repo.SearchRouteFlightsDepartingBetween(context.Background(), "Delhi", "Mumbai", from, to, 2)
+	if err != nil {
+		t.Fatalf("SearchRouteFlightsDepartingBetween returned error: %v", err)
+	}
+
+	if len(flights) != 2 || flights[0].Price != 2200 || flights[1].AvailableSeats != 20 {
+		t.Fatalf("unexpected flights: %+v", flights)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFlightRepository_SearchFlightsDepartingBetween_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	from := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	to := from.Add(48 * time.Hour)
+
+	rows := sqlmock.NewRows([]string{
+		"id", "source", "destination", "timestamp", "arrival_timestamp",
+		"available_seats", "total_seats", "flight_status",
+		"price", "version", "created_at", "updated_at",
+	}).AddRow(
+		int64(1), "Delhi", "Mumbai", from.Add(10*time.Hour), from.Add(12*time.Hour),
+		150, 180, models.FlightStatusScheduled,
+		2500.0, 1, time.Now(), time.Now(),
+	).AddRow(
+		int64(2), "Mumbai", "Chennai", from.Add(14*time.Hour), from.Add(16*time.Hour),
+		90, 120, models.FlightStatusOnTime,
+		2400.0, 1, time.Now(), time.Now(),
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
+		       flight_status, price, version, created_at, updated_at
+		FROM flights
+		WHERE timestamp >= $1 
+		  AND timestamp < $2
+		  AND available_seats >= $3
+		  AND flight_status IN ('scheduled', 'on_time')
+		ORDER BY timestamp ASC
+	`)).
+		WithArgs(from, to, 2).
+		WillReturnRows(rows)
+
+	flights, err := repo.SearchFlightsDepartingBetween(context.Background(), from, to, 2)
+	if err != nil {
+		t.Fatalf("SearchFlightsDepartingBetween returned error: %v", err)
+	}
+
+	if len(flights) != 2 || !flights[1].ArrivalTimestamp.Equal(from.Add(16*time.Hour)) {
+		t.Fatalf("unexpected flights: %+v", flights)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestFlightRepository_GetFlightByID_NotFound(t *testing.T) {
 	repo, mock, cleanup := newMockFlightRepo(t)
 	defer cleanup()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, source, destination, timestamp, available_seats, total_seats, 
+		SELECT id, source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		       flight_status, price, version, created_at, updated_at
 		FROM flights
 		WHERE id = $1
@@ -106,13 +336,14 @@ func TestFlightRepository_UpdateAvailableSeats_Success(t *testing.T) {
 		UPDATE flights 
 		SET available_seats = available_seats - $1, 
 		    version = version + 1, 
+		    lock_fence = $5,
 		    updated_at = $2
-		WHERE id = $3 AND version = $4 AND available_seats >= $1
+		WHERE id = $3 AND version = $4 AND available_seats >= $1 AND lock_fence <= $5
 	`)).
-		WithArgs(2, sqlmock.AnyArg(), int64(1), 1).
+		WithArgs(2, sqlmock.AnyArg(), int64(1), 1, int64(5)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := repo.UpdateAvailableSeats(context.Background(), 1, 2, 1)
+	err := repo.UpdateAvailableSeats(context.Background(), 1, 2, 1, 5)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -125,14 +356,55 @@ func TestFlightRepository_UpdateAvailableSeats_NoRows(t *testing.T) {
 	mock.ExpectExec(regexp.QuoteMeta(`
 		UPDATE flights 
 		SET available_seats = available_seats - $1, 
+		    version = version + 1, 
+		    lock_fence = $5,
+		    updated_at = $2
+		WHERE id = $3 AND version = $4 AND available_seats >= $1 AND lock_fence <= $5
+	`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(1), 1, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.UpdateAvailableSeats(context.Background(), 1, 2, 1, 5)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestFlightRepository_ReleaseSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE flights 
+		SET available_seats = available_seats + $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND available_seats + $1 <= total_seats
+	`)).
+		WithArgs(2, sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.ReleaseSeats(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestFlightRepository_ReleaseSeats_NoRows(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE flights 
+		SET available_seats = available_seats + $1, 
 		    version = version + 1, 
 		    updated_at = $2
-		WHERE id = $3 AND version = $4 AND available_seats >= $1
+		WHERE id = $3 AND available_seats + $1 <= total_seats
 	`)).
-		WithArgs(2, sqlmock.AnyArg(), int64(1), 1).
+		WithArgs(2, sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	err := repo.UpdateAvailableSeats(context.Background(), 1, 2, 1)
+	err := repo.ReleaseSeats(context.Background(), 1, 2)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
@@ -154,13 +426,13 @@ func TestFlightRepository_CreateFlight_Success(t *testing.T) {
 	}
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO flights (source, destination, timestamp, available_seats, total_seats, 
+		INSERT INTO flights (source, destination, timestamp, arrival_timestamp, available_seats, total_seats, 
 		                    flight_status, price, version, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
 	`)).
 		WithArgs(
-			flight.Source, flight.Destination, flight.Timestamp,
+			flight.Source, flight.Destination, flight.Timestamp, flight.ArrivalTimestamp,
 			flight.AvailableSeats, flight.TotalSeats, flight.FlightStatus,
 			flight.Price, flight.Version, sqlmock.AnyArg(), sqlmock.AnyArg(),
 		).
@@ -194,14 +466,14 @@ func TestFlightRepository_UpdateFlight_Success(t *testing.T) {
 
 	mock.ExpectExec(regexp.QuoteMeta(`
 		UPDATE flights 
-		SET source = $1, destination = $2, timestamp = $3, available_seats = $4, 
-		    total_seats = $5, flight_status = $6, price = $7, version = version + 1, 
-		    updated_at = $8
-		WHERE id = $9 AND version = $10
+		SET source = $1, destination = $2, timestamp = $3, arrival_timestamp = $4, 
+		    available_seats = $5, total_seats = $6, flight_status = $7, price = $8, 
+		    version = version + 1, updated_at = $9
+		WHERE id = $10 AND version = $11
 	`)).
 		WithArgs(
-			flight.Source, flight.Destination, flight.Timestamp, flight.AvailableSeats,
-			flight.TotalSeats, flight.FlightStatus, flight.Price, sqlmock.AnyArg(),
+			flight.Source, flight.Destination, flight.Timestamp, flight.ArrivalTimestamp,
+			flight.AvailableSeats, flight.TotalSeats, flight.FlightStatus, flight.Price, sqlmock.AnyArg(),
 			flight.ID, flight.Version,
 		).
 		WillReturnResult(sqlmock.NewResult(0, 1))
@@ -223,14 +495,14 @@ func TestFlightRepository_UpdateFlight_NoRows(t *testing.T) {
 
 	mock.ExpectExec(regexp.QuoteMeta(`
 		UPDATE flights 
-		SET source = $1, destination = $2, timestamp = $3, available_seats = $4, 
-		    total_seats = $5, flight_status = $6, price = $7, version = version + 1, 
-		    updated_at = $8
-		WHERE id = $9 AND version = $10
+		SET source = $1, destination = $2, timestamp = $3, arrival_timestamp = $4, 
+		    available_seats = $5, total_seats = $6, flight_status = $7, price = $8, 
+		    version = version + 1, updated_at = $9
+		WHERE id = $10 AND version = $11
 	`)).
 		WithArgs(
-			flight.Source, flight.Destination, flight.Timestamp, flight.AvailableSeats,
-			flight.TotalSeats, flight.FlightStatus, flight.Price, sqlmock.AnyArg(),
+			flight.Source, flight.Destination, flight.Timestamp, flight.ArrivalTimestamp,
+			flight.AvailableSeats, flight.TotalSeats, flight.FlightStatus, flight.Price, sqlmock.AnyArg(),
 			flight.ID, flight.Version,
 		).
 		WillReturnResult(sqlmock.NewResult(0, 0))
@@ -241,4 +513,151 @@ func TestFlightRepository_UpdateFlight_NoRows(t *testing.T) {
 	}
 }
 
+func TestFlightRepository_ApplyPendingSeatDeductions_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		WITH applied AS (
+			DELETE FROM pending_seat_deductions
+			WHERE flight_id = $1
+			RETURNING seats
+		)
+		UPDATE flights 
+		SET available_seats = available_seats - (SELECT SUM(seats) FROM applied), 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM applied)
+		RETURNING (SELECT SUM(seats) FROM applied)
+	`)).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(5))
+
+	seats, err := repo.ApplyPendingSeatDeductions(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if seats != 5 {
+		t.Fatalf("expected 5 seats deducted, got %d", seats)
+	}
+}
+
+func TestFlightRepository_ApplyPendingSeatDeductions_NonePending(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WITH applied AS (`)).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}))
 
+	seats, err := repo.ApplyPendingSeatDeductions(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if seats != 0 {
+		t.Fatalf("expected no seats deducted, got %d", seats)
+	}
+}
+
+func TestFlightRepository_DropPendingSeatDeduction(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM pending_seat_deductions WHERE booking_id = $1`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM pending_seat_deductions WHERE booking_id = $1`)).
+		WithArgs(int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	dropped, err := repo.DropPendingSeatDeduction(context.Background(), 7)
+	if err != nil || !dropped {
+		t.Fatalf("expected the pending deduction dropped, got %v, %v", dropped, err)
+	}
+
+	dropped, err = repo.DropPendingSeatDeduction(context.Background(), 8)
+	if err != nil || dropped {
+		t.Fatalf("expected no pending deduction, got %v, %v", dropped, err)
+	}
+}
+
+func TestFlightRepository_GetSeatInventory_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT f.available_seats, 
+		       COALESCE((SELECT SUM(p.seats) FROM pending_seat_deductions p WHERE p.flight_id = f.id), 0)
+		FROM flights f
+		WHERE f.id = $1
+	`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"available_seats", "pending"}).AddRow(40, 3))
+
+	available, pending, err := repo.GetSeatInventory(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if available != 40 || pending != 3 {
+		t.Fatalf("expected 40 available and 3 pending, got %d and %d", available, pending)
+	}
+}
+
+func TestFlightRepository_GetFlightSeatCounts_Success(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	cutoff := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM flights f`)).
+		WithArgs(models.BookingStatusPending, models.BookingStatusCompleted, cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "total_seats", "available_seats", "version", "booked", "pending", "inventory",
+		}).
+			AddRow(int64(1), 180, 150, 4, 30, 0, false).
+			AddRow(int64(2), 100, 98, 2, 5, 3, true))
+
+	counts, err := repo.GetFlightSeatCounts(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(counts) != 2 {
+		t.Fatalf("expected 2 seat counts, got %d", len(counts))
+	}
+
+	if counts[0].ExpectedAvailableSeats() != 150 || counts[1].ExpectedAvailableSeats() != 98 || counts[1].ExpectedInventorySeats() != 95 {
+		t.Fatalf("unexpected seat counts %+v", counts)
+	}
+
+	if !counts[1].InventoryTracked || counts[1].Version != 2 {
+		t.Fatalf("expected flight 2 sold from a seat inventory at version 2, got %+v", counts[1])
+	}
+}
+
+func TestFlightRepository_RepairAvailableSeats_VersionConflict(t *testing.T) {
+	repo, mock, cleanup := newMockFlightRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE flights 
+		SET available_seats = $1, 
+		    version = version + 1, 
+		    updated_at = $2
+		WHERE id = $3 AND version = $4
+	`)).
+		WithArgs(150, sqlmock.AnyArg(), int64(1), 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repaired, err := repo.RepairAvailableSeats(context.Background(), 1, 4, 150)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if repaired {
+		t.Fatalf("did not expect a flight changed since it was read to be repaired")
+	}
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+)
+
+// ErrOrderStatusConflict is returned when an order status update finds the
+// order missing or no longer pending
+var ErrOrderStatusConflict = errors.New("order not found or not pending")
+
+// OrderRepository handles order database operations. The segments of an
+// order are bookings and are read through BookingRepository.
+type OrderRepository struct {
+	db *database.DB
+}
+
+// NewOrderRepository creates a new order repository
+func NewOrderRepository(db *database.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+// CreateOrder creates a new order
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) (*models.Order, error) {
+	query := `
+		INSERT INTO orders (user_id, status, payment_reference_id, total_price, seats_booked,
+		                    created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query,
+		order.UserID, order.Status, order.PaymentReferenceID, order.TotalPrice, order.SeatsBooked, now, now,
+	).Scan(&order.ID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	return order, nil
+}
+
+// GetOrderByID gets an order by ID, without its segments
+func (r *OrderRepository) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	query := `
+		SELECT id, user_id, status, payment_reference_id, total_price, seats_booked,
+		       created_at, updated_at
+		FROM orders
+		WHERE id = $1
+	`
+
+	var order models.Order
+	err := r.db.Executor(ctx).QueryRowContext(ctx, query, id).Scan(
+		&order.ID, &order.UserID, &order.Status, &order.PaymentReferenceID,
+		&order.TotalPrice, &order.SeatsBooked, &order.CreatedAt, &order.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	return &order, nil
+}
+
+// UpdateOrderStatus moves a pending order to a new status. Orders settled by
+// a concurrent payment or expiry are left untouched and reported as an error.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, status models.BookingStatus) error {
+	query := `
+		UPDATE orders
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, status, time.Now(), orderID, models.BookingStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrOrderStatusConflict
+	}
+
+	return nil
+}
+
+// GetPendingOrdersCreatedBefore gets the oldest pending orders created before a cutoff
+func (r *OrderRepository) GetPendingOrdersCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Order, error) {
+	query := `
+		SELECT id, user_id, status, payment_reference_id, total_price, seats_booked,
+		       created_at, updated_at
+		FROM orders
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, models.BookingStatusPending, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		err := rows.Scan(
+			&order.ID, &order.UserID, &order.Status, &order.PaymentReferenceID,
+			&order.TotalPrice, &order.SeatsBooked, &order.CreatedAt, &order.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// helper to create an order repository with sqlmock
+func newMockOrderRepo(t *testing.T) (*OrderRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	return NewOrderRepository(&database.DB{DB: db}), mock, func() { db.Close() }
+}
+
+func TestOrderRepository_CreateOrder_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOrderRepo(t)
+	defer cleanup()
+
+	order := &models.Order{
+		UserID:             123,
+		Status:             models.BookingStatusPending,
+		PaymentReferenceID: "PAY-1",
+		TotalPrice:         9000,
+		SeatsBooked:        2,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		INSERT INTO orders (user_id, status, payment_reference_id, total_price, seats_booked,
+		                    created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`)).
+		WithArgs(order.UserID, order.Status, order.PaymentReferenceID, order.TotalPrice, order.SeatsBooked, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+
+	created, err := repo.CreateOrder(context.Background(), order)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if created.ID != 7 {
+		t.Fatalf("expected order ID 7, got %d", created.ID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_GetOrderByID_NotFound(t *testing.T) {
+	repo, mock, cleanup := newMockOrderRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM orders`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.GetOrderByID(context.Background(), 7); err == nil || err.Error() != "order not found" {
+		t.Fatalf("expected order not found, got %v", err)
+	}
+}
+
+func TestOrderRepository_UpdateOrderStatus_OnlyPending(t *testing.T) {
+	repo, mock, cleanup := newMockOrderRepo(t)
+	defer cleanup()
+
+	query := regexp.QuoteMeta(`
+		UPDATE orders
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`)
+	mock.ExpectExec(query).
+		WithArgs(models.BookingStatusCompleted, sqlmock.AnyArg(), int64(7), models.BookingStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs(models.BookingStatusExpired, sqlmock.AnyArg(), int64(7), models.BookingStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.UpdateOrderStatus(context.Background(), 7, models.BookingStatusCompleted); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := repo.UpdateOrderStatus(context.Background(), 7, models.BookingStatusExpired); !errors.Is(err, ErrOrderStatusConflict) {
+		t.Fatalf("expected ErrOrderStatusConflict for an order that is no longer pending, got %v", err)
+	}
+}
+
+func TestOrderRepository_GetPendingOrdersCreatedBefore_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOrderRepo(t)
+	defer cleanup()
+
+	cutoff := time.Now().Add(-15 * time.Minute)
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "status", "payment_reference_id", "total_price", "seats_booked", "created_at", "updated_at",
+	}).AddRow(int64(7), int64(123), models.BookingStatusPending, "PAY-1", 9000.0, 2, cutoff.Add(-time.Minute), cutoff.Add(-time.Minute))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, user_id, status, payment_reference_id, total_price, seats_booked,
+		       created_at, updated_at
+		FROM orders
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`)).
+		WithArgs(models.BookingStatusPending, cutoff, 50).
+		WillReturnRows(rows)
+
+	orders, err := repo.GetPendingOrdersCreatedBefore(context.Background(), cutoff, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(orders) != 1 || orders[0].ID != 7 {
+		t.Fatalf("expected pending order 7, got %+v", orders)
+	}
+}
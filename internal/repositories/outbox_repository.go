@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/lib/pq"
+)
+
+// OutboxRepository handles outbox database operations
+type OutboxRepository struct {
+	db *database.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *database.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue stores an event in the outbox. Called with a transactional context,
+// the event is committed or rolled back together with that transaction.
+func (r *OutboxRepository) Enqueue(ctx context.Context, topic string, key string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+
+	query := `
+		INSERT INTO outbox (topic, message_key, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err = r.db.Executor(ctx).ExecContext(ctx, query, topic, key, string(payload), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimUnsent claims every unsent message of up to limit message keys for
+// claimTTL and returns them oldest first. A key is claimed only when its
+// oldest unsent message is due and unclaimed, so messages of one key are
+// never published by two relays at once or ahead of an earlier message that
+// is waiting to be retried. It must run inside a transaction, which should
+// end right after: the claim outlives it, so nothing is held while publishing.
+func (r *OutboxRepository) ClaimUnsent(ctx context.Context, limit int, claimTTL time.Duration) ([]models.OutboxMessage, error) {
+	now := time.Now()
+
+	// Lock the oldest unsent message of each claimable key; the lock keeps
+	// other relays off these keys until the claim commits
+	headsQuery := `
+		SELECT id, topic, message_key
+		FROM outbox o
+		WHERE sent_at IS NULL
+		  AND next_attempt_at <= $1
+		  AND (claimed_until IS NULL OR claimed_until < $1)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM outbox earlier
+		      WHERE earlier.sent_at IS NULL
+		        AND earlier.topic = o.topic
+		        AND earlier.message_key = o.message_key
+		        AND earlier.id < o.id
+		  )
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, headsQuery, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	var topics, keys []string
+	for rows.Next() {
+		var id int64
+		var topic, key string
+		if err := rows.Scan(&id, &topic, &key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		topics = append(topics, topic)
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		UPDATE outbox 
+		SET claimed_until = $1
+		FROM UNNEST($2::text[], $3::text[]) AS claimed(topic, message_key)
+		WHERE outbox.sent_at IS NULL
+		  AND outbox.topic = claimed.topic
+		  AND outbox.message_key = claimed.message_key
+		RETURNING outbox.id, outbox.topic, outbox.message_key, outbox.payload, outbox.attempts, outbox.created_at
+	`
+
+	rows, err = r.db.Executor(ctx).QueryContext(ctx, query, now.Add(claimTTL), pq.Array(topics), pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var message models.OutboxMessage
+		err := rows.Scan(
+			&message.ID, &message.Topic, &message.MessageKey, &message.Payload,
+			&message.Attempts, &message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	// RETURNING has no order; publish in insertion order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
+	return messages, rows.Err()
+}
+
+// MarkSent marks outbox messages as published
+func (r *OutboxRepository) MarkSent(ctx context.Context, ids []int64) error {
+	query := `
+		UPDATE outbox 
+		SET sent_at = $1
+		WHERE id = ANY($2)
+	`
+
+	_, err := r.db.Executor(ctx).ExecContext(ctx, query, time.Now(), pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox messages sent: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure records a failed publish attempt for outbox messages and
+// releases their claim. They stay unsent and are retried from retryAt.
+func (r *OutboxRepository) RecordFailure(ctx context.Context, ids []int64, publishErr string, retryAt time.Time) error {
+	query := `
+		UPDATE outbox 
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, claimed_until = NULL
+		WHERE id = ANY($3)
+	`
+
+	_, err := r.db.Executor(ctx).ExecContext(ctx, query, publishErr, retryAt, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to record outbox failure: %w", err)
+	}
+
+	return nil
+}
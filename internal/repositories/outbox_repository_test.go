@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// helper to create an outbox repository with sqlmock
+func newMockOutboxRepo(t *testing.T) (*OutboxRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	wrapped := &database.DB{DB: db}
+
+	cleanup := func() {
+		db.Close()
+	}
+
+	return NewOutboxRepository(wrapped), mock, cleanup
+}
+
+func TestOutboxRepository_Enqueue_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	event := &models.SeatUpdateEvent{FlightID: 1, SeatsBooked: 2, BookingID: 10}
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO outbox (topic, message_key, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`)).
+		WithArgs("flight-bookings", "1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := repo.Enqueue(context.Background(), "flight-bookings", "1", event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxRepository_Enqueue_JoinsTransaction(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO outbox").
+		WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	err := repo.db.WithinTx(context.Background(), func(ctx context.Context) error {
+		return repo.Enqueue(ctx, "payment-events", "10", &models.PaymentEvent{BookingID: 10})
+	})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxRepository_ClaimUnsent_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	heads := sqlmock.NewRows([]string{"id", "topic", "message_key"}).
+		AddRow(1, "flight-bookings", "1").
+		AddRow(2, "payment-events", "10")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, topic, message_key
+		FROM outbox o
+		WHERE sent_at IS NULL
+		  AND next_attempt_at <= $1
+		  AND (claimed_until IS NULL OR claimed_until < $1)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM outbox earlier
+		      WHERE earlier.sent_at IS NULL
+		        AND earlier.topic = o.topic
+		        AND earlier.message_key = o.message_key
+		        AND earlier.id < o.id
+		  )
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`)).
+		WithArgs(sqlmock.AnyArg(), 50).
+		WillReturnRows(heads)
+
+	// Every unsent message of the claimed keys, in no particular order
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "topic", "message_key", "payload", "attempts", "created_at"}).
+		AddRow(3, "flight-bookings", "1", []byte(`{"flight_id":1}`), 0, now).
+		AddRow(1, "flight-bookings", "1", []byte(`{"flight_id":1}`), 0, now).
+		AddRow(2, "payment-events", "10", []byte(`{"booking_id":10}`), 2, now)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		UPDATE outbox 
+		SET claimed_until = $1
+		FROM UNNEST($2::text[], $3::text[]) AS claimed(topic, message_key)
+		WHERE outbox.sent_at IS NULL
+		  AND outbox.topic = claimed.topic
+		  AND outbox.message_key = claimed.message_key
+		RETURNING outbox.id, outbox.topic, outbox.message_key, outbox.payload, outbox.attempts, outbox.created_at
+	`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	messages, err := repo.ClaimUnsent(context.Background(), 50, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 3 || messages[0].ID != 1 || messages[1].ID != 2 || messages[2].ID != 3 {
+		t.Fatalf("expected the claimed messages oldest first, got %+v", messages)
+	}
+
+	if messages[1].Topic != "payment-events" || messages[1].Attempts != 2 {
+		t.Fatalf("unexpected message: %+v", messages[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxRepository_ClaimUnsent_NothingDue(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "message_key"}))
+
+	messages, err := repo.ClaimUnsent(context.Background(), 50, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages, got %+v", messages)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxRepository_MarkSent_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE outbox 
+		SET sent_at = $1
+		WHERE id = ANY($2)
+	`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := repo.MarkSent(context.Background(), []int64{1, 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxRepository_RecordFailure_Success(t *testing.T) {
+	repo, mock, cleanup := newMockOutboxRepo(t)
+	defer cleanup()
+
+	retryAt := time.Now().Add(time.Minute)
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE outbox 
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, claimed_until = NULL
+		WHERE id = ANY($3)
+	`)).
+		WithArgs("broker unavailable", retryAt, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := repo.RecordFailure(context.Background(), []int64{1, 2}, "broker unavailable", retryAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
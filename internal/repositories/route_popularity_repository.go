@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+)
+
+// RoutePopularityRepository handles the stored snapshot of route search counts
+type RoutePopularityRepository struct {
+	db *database.DB
+}
+
+// NewRoutePopularityRepository creates a new route popularity repository
+func NewRoutePopularityRepository(db *database.DB) *RoutePopularityRepository {
+	return &RoutePopularityRepository{db: db}
+}
+
+// SaveRoutePopularity stores the search counts of the given routes,
+// replacing the counts stored earlier for the same routes
+func (r *RoutePopularityRepository) SaveRoutePopularity(ctx context.Context, routes []models.RoutePopularity) error {
+	query := `
+		INSERT INTO route_search_popularity (source, destination, searches, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source, destination)
+		DO UPDATE SET searches = EXCLUDED.searches, updated_at = EXCLUDED.updated_at
+	`
+
+	now := time.Now()
+	for _, route := range routes {
+		_, err := r.db.Executor(ctx).ExecContext(ctx, query, route.Source, route.Destination, route.Searches, now)
+		if err != nil {
+			return fmt.Errorf("failed to save route popularity: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetTopRoutes gets the most searched routes, most searched first
+func (r *RoutePopularityRepository) GetTopRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+	query := `
+		SELECT source, destination, searches
+		FROM route_search_popularity
+		ORDER BY searches DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top routes: %w", err)
+	}
+	defer rows.Close()
+
+	var routes []models.RoutePopularity
+	for rows.Next() {
+		var route models.RoutePopularity
+		if err := rows.Scan(&route.Source, &route.Destination, &route.Searches); err != nil {
+			return nil, fmt.Errorf("failed to scan route popularity: %w", err)
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, rows.Err()
+}
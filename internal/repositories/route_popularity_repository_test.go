@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// helper to create a route popularity repository with sqlmock
+func newMockRoutePopularityRepo(t *testing.T) (*RoutePopularityRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	return NewRoutePopularityRepository(&database.DB{DB: db}), mock, func() { db.Close() }
+}
+
+func TestRoutePopularityRepository_SaveRoutePopularity_Upserts(t *testing.T) {
+	repo, mock, cleanup := newMockRoutePopularityRepo(t)
+	defer cleanup()
+
+	query := regexp.QuoteMeta(`
+		INSERT INTO route_search_popularity (source, destination, searches, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source, destination)
+		DO UPDATE SET searches = EXCLUDED.searches, updated_at = EXCLUDED.updated_at
+	`)
+	mock.ExpectExec(query).
+		WithArgs("Delhi", "Mumbai", int64(120), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs("Mumbai", "Chennai", int64(80), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SaveRoutePopularity(context.Background(), []models.RoutePopularity{
+		{Source: "Delhi", Destination: "Mumbai", Searches: 120},
+		{Source: "Mumbai", Destination: "Chennai", Searches: 80},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRoutePopularityRepository_GetTopRoutes_Success(t *testing.T) {
+	repo, mock, cleanup := newMockRoutePopularityRepo(t)
+	defer cleanup()
+
+	rows := sqlmock.NewRows([]string{"source", "destination", "searches"}).
+		AddRow("Delhi", "Mumbai", int64(120)).
+		AddRow("Mumbai", "Chennai", int64(80))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT source, destination, searches
+		FROM route_search_popularity
+		ORDER BY searches DESC
+		LIMIT $1
+	`)).
+		WithArgs(20).
+		WillReturnRows(rows)
+
+	routes, err := repo.GetTopRoutes(context.Background(), 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(routes) != 2 || routes[0].Source != "Delhi" || routes[0].Searches != 120 {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/lib/pq"
+)
+
+// ErrSeatsUnavailable is returned when requested seats do not exist or are
+// already held by another booking
+var ErrSeatsUnavailable = errors.New("requested seats are not available")
+
+// SeatRepository handles seat database operations
+type SeatRepository struct {
+	db *database.DB
+}
+
+// NewSeatRepository creates a new seat repository
+func NewSeatRepository(db *database.DB) *SeatRepository {
+	return &SeatRepository{db: db}
+}
+
+// CreateSeats inserts the seat map of a flight in a single statement
+func (r *SeatRepository) CreateSeats(ctx context.Context, flightID int64, seats []models.Seat) error {
+	if len(seats) == 0 {
+		return nil
+	}
+
+	rows := make([]int64, 0, len(seats))
+	letters := make([]string, 0, len(seats))
+	cabins := make([]string, 0, len(seats))
+	windows := make([]bool, 0, len(seats))
+	aisles := make([]bool, 0, len(seats))
+	exitRows := make([]bool, 0, len(seats))
+	for _, seat := range seats {
+		rows = append(rows, int64(seat.Row))
+		letters = append(letters, seat.Letter)
+		cabins = append(cabins, string(seat.Cabin))
+		windows = append(windows, seat.IsWindow)
+		aisles = append(aisles, seat.IsAisle)
+		exitRows = append(exitRows, seat.IsExitRow)
+	}
+
+	query := `
+		INSERT INTO seats (flight_id, seat_row, seat_letter, cabin, is_window, is_aisle, is_exit_row)
+		SELECT $1, * FROM unnest($2::int[], $3::text[], $4::text[], $5::bool[], $6::bool[], $7::bool[])
+	`
+
+	_, err := r.db.Executor(ctx).ExecContext(ctx, query, flightID,
+		pq.Array(rows), pq.Array(letters), pq.Array(cabins),
+		pq.Array(windows), pq.Array(aisles), pq.Array(exitRows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create seats: %w", err)
+	}
+
+	return nil
+}
+
+// GetSeatsByFlightID gets the seat map of a flight
+func (r *SeatRepository) GetSeatsByFlightID(ctx context.Context, flightID int64) ([]models.Seat, error) {
+	query := `
+		SELECT id, flight_id, seat_number, seat_row, seat_letter, cabin, 
+		       is_window, is_aisle, is_exit_row, booking_id
+		FROM seats 
+		WHERE flight_id = $1
+		ORDER BY seat_row ASC, seat_letter ASC
+	`
+
+	rows, err := r.db.Executor(ctx).QueryContext(ctx, query, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seats: %w", err)
+	}
+	defer rows.Close()
+
+	var seats []models.Seat
+	for rows.Next() {
+		var seat models.Seat
+		err := rows.Scan(
+			&seat.ID, &seat.FlightID, &seat.SeatNumber, &seat.Row, &seat.Letter, &seat.Cabin,
+			&seat.IsWindow, &seat.IsAisle, &seat.IsExitRow, &seat.BookingID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan seat: %w", err)
+		}
+		seat.Available = seat.BookingID == nil
+		seats = append(seats, seat)
+	}
+
+	return seats, rows.Err()
+}
+
+// AssignSeats holds the given seats for a booking. Seats already held by
+// another booking are never taken over; if any requested seat is missing or
+// taken, ErrSeatsUnavailable is returned and the caller's transaction should
+// be rolled back. A non-empty cabin also rejects seats outside that cabin.
+func (r *SeatRepository) AssignSeats(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+	query := `
+		UPDATE seats 
+		SET booking_id = $1, updated_at = $2
+		WHERE flight_id = $3 AND seat_number = ANY($4) AND booking_id IS NULL
+		  AND ($5 = '' OR cabin = $5)
+	`
+
+	result, err := r.db.Executor(ctx).ExecContext(ctx, query, bookingID, time.Now(), flightID, pq.Array(seatNumbers), cabin)
+	if err != nil {
+		return fmt.Errorf("failed to assign seats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected != int64(len(seatNumbers)) {
+		return ErrSeatsUnavailable
+	}
+
+	return nil
+}
+
+// ReleaseBookingSeats frees every seat held by a booking
+func (r *SeatRepository) ReleaseBookingSeats(ctx context.Context, bookingID int64) error {
+	query := `
+		UPDATE seats 
+		SET booking_id = NULL, updated_at = $1
+		WHERE booking_id = $2
+	`
+
+	_, err := r.db.Executor(ctx).ExecContext(ctx, query, time.Now(), bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to release seats: %w", err)
+	}
+
+	return nil
+}
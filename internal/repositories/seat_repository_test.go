@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// helper to create a seat repository with sqlmock
+func newMockSeatRepo(t *testing.T) (*SeatRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	wrapped := &database.DB{DB: db}
+
+	cleanup := func() {
+		db.Close()
+	}
+
+	return NewSeatRepository(wrapped), mock, cleanup
+}
+
+func TestSeatRepository_CreateSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO seats (flight_id, seat_row, seat_letter, cabin, is_window, is_aisle, is_exit_row)
+		SELECT $1, * FROM unnest($2::int[], $3::text[], $4::text[], $5::bool[], $6::bool[], $7::bool[])
+	`)).
+		WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	if err := repo.CreateSeats(context.Background(), 1, models.GenerateSeatMap(1, 12)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatRepository_GetSeatsByFlightID_Success(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "flight_id", "seat_number", "seat_row", "seat_letter", "cabin",
+		"is_window", "is_aisle", "is_exit_row", "booking_id",
+	}).
+		AddRow(1, 1, "1A", 1, "A", "economy", true, false, false, nil).
+		AddRow(2, 1, "1B", 1, "B", "economy", false, false, false, 7)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, flight_id, seat_number, seat_row, seat_letter, cabin, 
+		       is_window, is_aisle, is_exit_row, booking_id
+		FROM seats 
+		WHERE flight_id = $1
+		ORDER BY seat_row ASC, seat_letter ASC
+	`)).
+		WithArgs(int64(1)).
+		WillReturnRows(rows)
+
+	seats, err := repo.GetSeatsByFlightID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seats) != 2 || !seats[0].Available || seats[1].Available {
+		t.Fatalf("expected 1A available and 1B taken, got %+v", seats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatRepository_AssignSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE seats 
+		SET booking_id = $1, updated_at = $2
+		WHERE flight_id = $3 AND seat_number = ANY($4) AND booking_id IS NULL
+		  AND ($5 = '' OR cabin = $5)
+	`)).
+		WithArgs(int64(10), sqlmock.AnyArg(), int64(1), sqlmock.AnyArg(), models.SeatCabin("")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := repo.AssignSeats(context.Background(), 1, 10, []string{"12A", "12B"}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatRepository_AssignSeats_SeatTaken(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	// Only one of the two seats was still free
+	mock.ExpectExec("UPDATE seats").
+		WithArgs(int64(10), sqlmock.AnyArg(), int64(1), sqlmock.AnyArg(), models.SeatCabin("")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.AssignSeats(context.Background(), 1, 10, []string{"12A", "12B"}, "")
+	if !errors.Is(err, ErrSeatsUnavailable) {
+		t.Fatalf("expected ErrSeatsUnavailable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatRepository_AssignSeats_WrongCabin(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	// 1A is a business seat, so an economy fare cannot take it
+	mock.ExpectExec("UPDATE seats").
+		WithArgs(int64(10), sqlmock.AnyArg(), int64(1), sqlmock.AnyArg(), models.SeatCabinEconomy).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.AssignSeats(context.Background(), 1, 10, []string{"1A"}, models.SeatCabinEconomy)
+	if !errors.Is(err, ErrSeatsUnavailable) {
+		t.Fatalf("expected ErrSeatsUnavailable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatRepository_ReleaseBookingSeats_Success(t *testing.T) {
+	repo, mock, cleanup := newMockSeatRepo(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE seats 
+		SET booking_id = NULL, updated_at = $1
+		WHERE booking_id = $2
+	`)).
+		WithArgs(sqlmock.AnyArg(), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := repo.ReleaseBookingSeats(context.Background(), 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"time"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+	"airline-booking-system/pkg/database"
+
+	"go.opentelemetry.io/otel"
+)
+
+// bookingReaperJob is the leadership key shared by all reaper instances
+const bookingReaperJob = "booking-reaper"
+
+// BookingRepositoryReaper defines persistence operations used by BookingReaper.
+type BookingRepositoryReaper interface {
+	GetPendingBookingsCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error)
+	ExpireBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID string) error
+	GetBookingsByOrderID(ctx context.Context, orderID int64) ([]models.Booking, error)
+}
+
+// OrderRepositoryReaper defines order operations used by BookingReaper.
+type OrderRepositoryReaper interface {
+	GetPendingOrdersCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID int64, status models.BookingStatus) error
+}
+
+// SeatRepositoryReaper defines seat operations used by BookingReaper.
+type SeatRepositoryReaper interface {
+	ReleaseBookingSeats(ctx context.Context, bookingID int64) error
+}
+
+// FlightCacheReaper defines cache operations used by BookingReaper.
+type FlightCacheReaper interface {
+	AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	ResignLeadership(ctx context.Context, job string, instanceID string) error
+	DeleteCachedSeats(ctx context.Context, flightID int64) error
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+	ReturnInventorySeats(ctx context.Context, flightID int64, seats int) error
+}
+
+// ProducerReaper defines the Kafka producer operations used by BookingReaper.
+type ProducerReaper interface {
+	SendBookingExpiredEvent(ctx context.Context, event *models.BookingExpiredEvent) error
+}
+
+// BookingReaper expires pending bookings and orders whose seat hold has run
+// out and returns their seats to the flight. Only the replica holding
+// leadership in Redis reaps on each tick.
+type BookingReaper struct {
+	bookingRepo    BookingRepositoryReaper
+	orderRepo      OrderRepositoryReaper
+	seatRepo       SeatRepositoryReaper
+	cacheService   FlightCacheReaper
+	kafkaProducer  ProducerReaper
+	paymentGateway PaymentGateway
+	txManager      TxManager
+	config         *config.AppConfig
+	instanceID     string
+	tracerName     string
+}
+
+// NewBookingReaper creates a new booking reaper
+func NewBookingReaper(
+	bookingRepo *repositories.BookingRepository,
+	orderRepo *repositories.OrderRepository,
+	seatRepo *repositories.SeatRepository,
+	cacheService *cache.FlightCacheService,
+	kafkaProducer *OutboxProducer,
+	paymentGateway PaymentGateway,
+	db *database.DB,
+	config *config.AppConfig,
+) *BookingReaper {
+	return &BookingReaper{
+		bookingRepo:    bookingRepo,
+		orderRepo:      orderRepo,
+		seatRepo:       seatRepo,
+		cacheService:   cacheService,
+		kafkaProducer:  kafkaProducer,
+		paymentGateway: paymentGateway,
+		txManager:      db,
+		config:         config,
+		instanceID:     generateInstanceID(),
+		tracerName:     "airline-booking-system/booking-reaper",
+	}
+}
+
+// Run reaps expired bookings every ReaperInterval until ctx is cancelled
+func (r *BookingReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.ReaperInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		// Hand leadership over promptly instead of waiting for the lease to lapse
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.cacheService.ResignLeadership(resignCtx, bookingReaperJob, r.instanceID); err != nil {
+			log.Printf("Failed to resign booking reaper leadership: %v", err)
+		}
+	}()
+
+	for {
+		r.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs one reaping pass if this instance is the leader
+func (r *BookingReaper) tick(ctx context.Context) {
+	// The lease outlives one interval so a healthy leader keeps it between ticks
+	leader, err := r.cacheService.AcquireLeadership(ctx, bookingReaperJob, r.instanceID, 2*r.config.ReaperInterval)
+	if err != nil {
+		log.Printf("Failed to acquire booking reaper leadership: %v", err)
+		return
+	}
+
+	if !leader {
+		return
+	}
+
+	expired, err := r.ReapExpiredBookings(ctx)
+	if err != nil {
+		log.Printf("Failed to reap expired bookings: %v", err)
+		return
+	}
+
+	if expired > 0 {
+		log.Printf("Expired %d pending bookings", expired)
+	}
+
+	expired, err = r.ReapExpiredOrders(ctx)
+	if err != nil {
+		log.Printf("Failed to reap expired orders: %v", err)
+		return
+	}
+
+	if expired > 0 {
+		log.Printf("Expired %d pending orders", expired)
+	}
+}
+
+// ReapExpiredBookings expires one batch of pending bookings older than the hold
+// window and returns how many were expired
+func (r *BookingReaper) ReapExpiredBookings(ctx context.Context) (int, error) {
+	tr := otel.Tracer(r.tracerName)
+	ctx, span := tr.Start(ctx, "BookingReaper.ReapExpiredBookings")
+	defer span.End()
+
+	cutoff := time.Now().Add(-r.config.BookingHoldWindow)
+	bookings, err := r.bookingRepo.GetPendingBookingsCreatedBefore(ctx, cutoff, r.config.ReaperBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending bookings: %w", err)
+	}
+
+	expired := 0
+	for i := range bookings {
+		booking := &bookings[i]
+
+		// The booking may have been settled since it was read; the repository
+		// only expires bookings that are still pending
+		err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+			return r.expireBooking(ctx, booking)
+		})
+		if err != nil {
+			log.Printf("Failed to expire booking %d: %v", booking.ID, err)
+			continue
+		}
+		expired++
+
+		// Release any authorization the gateway may still hold for this
+		// booking, or refund the payment if it was captured just before expiry
+		if err := voidOrRefund(ctx, r.paymentGateway, booking.PaymentReferenceID, booking.BookingPrice); err != nil {
+			log.Printf("Failed to reverse payment for expired booking %d: %v", booking.ID, err)
+		}
+
+		r.invalidateFlightCache(ctx, booking.FlightID, booking.SeatsBooked)
+	}
+
+	return expired, nil
+}
+
+// ReapExpiredOrders expires one batch of pending orders older than the hold
+// window, together with all of their segments, and returns how many were
+// expired
+func (r *BookingReaper) ReapExpiredOrders(ctx context.Context) (int, error) {
+	tr := otel.Tracer(r.tracerName)
+	ctx, span := tr.Start(ctx, "BookingReaper.ReapExpiredOrders")
+	defer span.End()
+
+	cutoff := time.Now().Add(-r.config.BookingHoldWindow)
+	orders, err := r.orderRepo.GetPendingOrdersCreatedBefore(ctx, cutoff, r.config.ReaperBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+
+	expired := 0
+	for i := range orders {
+		order := &orders[i]
+
+		segments, err := r.bookingRepo.GetBookingsByOrderID(ctx, order.ID)
+		if err != nil {
+			log.Printf("Failed to get segments of order %d: %v", order.ID, err)
+			continue
+		}
+
+		// The order may have been settled since it was read; the repositories
+		// only expire orders and bookings that are still pending
+		err = r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+			if err := r.orderRepo.UpdateOrderStatus(ctx, order.ID, models.BookingStatusExpired); err != nil {
+				return err
+			}
+			for i := range segments {
+				if err := r.expireBooking(ctx, &segments[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to expire order %d: %v", order.ID, err)
+			continue
+		}
+		expired++
+
+		// Release any authorization the gateway may still hold for this
+		// order, or refund the payment if it was captured just before expiry
+		if err := voidOrRefund(ctx, r.paymentGateway, order.PaymentReferenceID, order.TotalPrice); err != nil {
+			log.Printf("Failed to reverse payment for expired order %d: %v", order.ID, err)
+		}
+
+		for _, segment := range segments {
+			r.invalidateFlightCache(ctx, segment.FlightID, segment.SeatsBooked)
+		}
+	}
+
+	return expired, nil
+}
+
+// expireBooking marks a pending booking expired, returns its seats and
+// records the expiry. It must run inside a transaction.
+func (r *BookingReaper) expireBooking(ctx context.Context, booking *models.Booking) error {
+	if err := r.bookingRepo.ExpireBookingAndReleaseSeats(ctx, booking.ID, booking.PaymentReferenceID); err != nil {
+		return err
+	}
+	if err := r.seatRepo.ReleaseBookingSeats(ctx, booking.ID); err != nil {
+		return err
+	}
+
+	expiredEvent := &models.BookingExpiredEvent{
+		BookingID:     booking.ID,
+		FlightID:      booking.FlightID,
+		UserID:        booking.UserID,
+		SeatsReleased: booking.SeatsBooked,
+		Timestamp:     time.Now(),
+	}
+
+	return r.kafkaProducer.SendBookingExpiredEvent(ctx, expiredEvent)
+}
+
+// generateInstanceID generates a unique ID for this process
+func generateInstanceID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return fmt.Sprintf("%x", bytes)
+}
+
+// invalidateFlightCache drops the cached seat count of a flight and the
+// cached searches listing it after an expiry returned seats, and gives the
+// seats back to the flight's seat inventory if it has one
+func (r *BookingReaper) invalidateFlightCache(ctx context.Context, flightID int64, seats int) {
+	r.cacheService.DeleteCachedSeats(ctx, flightID)
+	if err := r.cacheService.InvalidateFlightSearches(ctx, flightID); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flightID, err)
+	}
+	if err := r.cacheService.ReturnInventorySeats(ctx, flightID, seats); err != nil {
+		log.Printf("Failed to return %d seats to the seat inventory of flight %d: %v", seats, flightID, err)
+	}
+}
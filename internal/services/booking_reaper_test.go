@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// mockReaperBookingRepo implements BookingRepositoryReaper for testing.
+type mockReaperBookingRepo struct {
+	getPendingFn func(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error)
+	expireFn     func(ctx context.Context, bookingID int64, paymentRefID string) error
+	getByOrderFn func(ctx context.Context, orderID int64) ([]models.Booking, error)
+}
+
+func (m *mockReaperBookingRepo) GetPendingBookingsCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error) {
+	if m.getPendingFn != nil {
+		return m.getPendingFn(ctx, cutoff, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockReaperBookingRepo) ExpireBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID string) error {
+	if m.expireFn != nil {
+		return m.expireFn(ctx, bookingID, paymentRefID)
+	}
+	return nil
+}
+
+func (m *mockReaperBookingRepo) GetBookingsByOrderID(ctx context.Context, orderID int64) ([]models.Booking, error) {
+	if m.getByOrderFn != nil {
+		return m.getByOrderFn(ctx, orderID)
+	}
+	return nil, nil
+}
+
+// mockReaperOrderRepo implements OrderRepositoryReaper for testing.
+type mockReaperOrderRepo struct {
+	getPendingFn   func(ctx context.Context, cutoff time.Time, limit int) ([]models.Order, error)
+	updateStatusFn func(ctx context.Context, orderID int64, status models.BookingStatus) error
+}
+
+func (m *mockReaperOrderRepo) GetPendingOrdersCreatedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Order, error) {
+	if m.getPendingFn != nil {
+		return m.getPendingFn(ctx, cutoff, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockReaperOrderRepo) UpdateOrderStatus(ctx context.Context, orderID int64, status models.BookingStatus) error {
+	if m.updateStatusFn != nil {
+		return m.updateStatusFn(ctx, orderID, status)
+	}
+	return nil
+}
+
+// mockReaperCache implements FlightCacheReaper for testing.
+type mockReaperCache struct {
+	acquireFn    func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	resignFn     func(ctx context.Context, job string, instanceID string) error
+	deleteFn     func(ctx context.Context, flightID int64) error
+	invalidateFn func(ctx context.Context, flightID int64) error
+	returnFn     func(ctx context.Context, flightID int64, seats int) error
+}
+
+func (m *mockReaperCache) AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+	if m.acquireFn != nil {
+		return m.acquireFn(ctx, job, instanceID, ttl)
+	}
+	return true, nil
+}
+
+func (m *mockReaperCache) ResignLeadership(ctx context.Context, job string, instanceID string) error {
+	if m.resignFn != nil {
+		return m.resignFn(ctx, job, instanceID)
+	}
+	return nil
+}
+
+func (m *mockReaperCache) DeleteCachedSeats(ctx context.Context, flightID int64) error {
+	if m.deleteFn != nil {
+		return m.deleteFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockReaperCache) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	if m.invalidateFn != nil {
+		return m.invalidateFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockReaperCache) ReturnInventorySeats(ctx context.Context, flightID int64, seats int) error {
+	if m.returnFn != nil {
+		return m.returnFn(ctx, flightID, seats)
+	}
+	return nil
+}
+
+// mockReaperProducer implements ProducerReaper for testing.
+type mockReaperProducer struct {
+	sendExpiredFn func(ctx context.Context, event *models.BookingExpiredEvent) error
+}
+
+func (m *mockReaperProducer) SendBookingExpiredEvent(ctx context.Context, event *models.BookingExpiredEvent) error {
+	if m.sendExpiredFn != nil {
+		return m.sendExpiredFn(ctx, event)
+	}
+	return nil
+}
+
+func TestBookingReaper_ReapExpiredBookings_ExpiresOldPendingBookings(t *testing.T) {
+	var cutoffUsed time.Time
+	var expiredIDs []int64
+	events := 0
+
+	repo := &mockReaperBookingRepo{
+		getPendingFn: func(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error) {
+			cutoffUsed = cutoff
+			return []models.Booking{
+				{ID: 1, FlightID: 10, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1", SeatsBooked: 2},
+				{ID: 2, FlightID: 10, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-2", SeatsBooked: 1},
+			}, nil
+		},
+		expireFn: func(ctx context.Context, bookingID int64, paymentRefID string) error {
+			if bookingID == 2 {
+				return errors.New("booking not found or not pending")
+			}
+			expiredIDs = append(expiredIDs, bookingID)
+			return nil
+		},
+	}
+	producer := &mockReaperProducer{
+		sendExpiredFn: func(ctx context.Context, event *models.BookingExpiredEvent) error {
+			events++
+			return nil
+		},
+	}
+
+	reaper := &BookingReaper{
+		bookingRepo:    repo,
+		cacheService:   &mockReaperCache{},
+		kafkaProducer:  producer,
+		paymentGateway: NewFakePaymentGateway(FakePaymentOutcomeApprove, 0),
+		seatRepo:       &mockSeatRepo{},
+		txManager:      &mockTxManager{},
+		config:         &config.AppConfig{BookingHoldWindow: 15 * time.Minute, ReaperBatchSize: 100},
+	}
+
+	expired, err := reaper.ReapExpiredBookings(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expired != 1 || len(expiredIDs) != 1 || expiredIDs[0] != 1 || events != 1 {
+		t.Fatalf("expected only booking 1 to expire with one event, got expired=%d ids=%v events=%d", expired, expiredIDs, events)
+	}
+
+	if age := time.Since(cutoffUsed); age < 15*time.Minute || age > 16*time.Minute {
+		t.Fatalf("expected cutoff about 15 minutes ago, got %v", age)
+	}
+}
+
+func TestBookingReaper_ReapExpiredBookings_RefundsCapturedPayment(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakePaymentGateway(FakePaymentOutcomeApprove, 0)
+
+	// The payment was captured just as the hold ran out
+	if _, err := gateway.Authorize(ctx, &models.PaymentRequest{BookingID: 1, PaymentReferenceID: "PAY-1", Amount: 250}); err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	if _, err := gateway.Capture(ctx, "PAY-1", 250); err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+
+	repo := &mockReaperBookingRepo{
+		getPendingFn: func(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error) {
+			return []models.Booking{
+				{ID: 1, FlightID: 10, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1", BookingPrice: 250, SeatsBooked: 2},
+			}, nil
+		},
+	}
+
+	reaper := &BookingReaper{
+		bookingRepo:    repo,
+		cacheService:   &mockReaperCache{},
+		kafkaProducer:  &mockReaperProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		txManager:      &mockTxManager{},
+		config:         &config.AppConfig{BookingHoldWindow: 15 * time.Minute, ReaperBatchSize: 100},
+	}
+
+	expired, err := reaper.ReapExpiredBookings(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expired != 1 {
+		t.Fatalf("expected the booking to expire, got %d", expired)
+	}
+
+	// A refunded payment cannot be refunded again
+	if _, err := gateway.Refund(ctx, "PAY-1", 250); err == nil {
+		t.Fatalf("expected the captured payment to have been refunded")
+	}
+}
+
+func TestBookingReaper_ReapExpiredOrders_ExpiresEverySegment(t *testing.T) {
+	var expiredIDs []int64
+	var orderStatus models.BookingStatus
+	var voided []string
+
+	repo := &mockReaperBookingRepo{
+		getByOrderFn: func(ctx context.Context, orderID int64) ([]models.Booking, error) {
+			return []models.Booking{
+				{ID: 1, FlightID: 10, OrderID: orderID, PaymentReferenceID: "PAY-1", SeatsBooked: 2},
+				{ID: 2, FlightID: 20, OrderID: orderID, PaymentReferenceID: "PAY-1", SeatsBooked: 2},
+			}, nil
+		},
+		expireFn: func(ctx context.Context, bookingID int64, paymentRefID string) error {
+			expiredIDs = append(expiredIDs, bookingID)
+			return nil
+		},
+	}
+	orderRepo := &mockReaperOrderRepo{
+		getPendingFn: func(ctx context.Context, cutoff time.Time, limit int) ([]models.Order, error) {
+			return []models.Order{{ID: 7, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1"}}, nil
+		},
+		updateStatusFn: func(ctx context.Context, orderID int64, status models.BookingStatus) error {
+			orderStatus = status
+			return nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		voidFn: func(ctx context.Context, paymentRefID string) (*models.PaymentResult, error) {
+			voided = append(voided, paymentRefID)
+			return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+		},
+	}
+
+	reaper := &BookingReaper{
+		bookingRepo:    repo,
+		orderRepo:      orderRepo,
+		cacheService:   &mockReaperCache{},
+		kafkaProducer:  &mockReaperProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		txManager:      &mockTxManager{},
+		config:         &config.AppConfig{BookingHoldWindow: 15 * time.Minute, ReaperBatchSize: 100},
+	}
+
+	expired, err := reaper.ReapExpiredOrders(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expired != 1 || orderStatus != models.BookingStatusExpired || len(expiredIDs) != 2 {
+		t.Fatalf("expected order and both segments expired, got expired=%d status=%s ids=%v", expired, orderStatus, expiredIDs)
+	}
+
+	if len(voided) != 1 || voided[0] != "PAY-1" {
+		t.Fatalf("expected the order payment voided once, got %v", voided)
+	}
+}
+
+func TestBookingReaper_Tick_SkipsWhenNotLeader(t *testing.T) {
+	repo := &mockReaperBookingRepo{
+		getPendingFn: func(ctx context.Context, cutoff time.Time, limit int) ([]models.Booking, error) {
+			t.Fatalf("did not expect a follower to query pending bookings")
+			return nil, nil
+		},
+	}
+	cache := &mockReaperCache{
+		acquireFn: func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+			return false, nil
+		},
+	}
+
+	reaper := &BookingReaper{
+		bookingRepo:   repo,
+		cacheService:  cache,
+		kafkaProducer: &mockReaperProducer{},
+		config:        &config.AppConfig{ReaperInterval: time.Minute},
+	}
+
+	reaper.tick(context.Background())
+}
+
+func TestBookingReaper_Run_ResignsOnShutdown(t *testing.T) {
+	resigned := make(chan struct{})
+	cache := &mockReaperCache{
+		resignFn: func(ctx context.Context, job string, instanceID string) error {
+			close(resigned)
+			return nil
+		},
+	}
+
+	reaper := &BookingReaper{
+		bookingRepo:    &mockReaperBookingRepo{},
+		orderRepo:      &mockReaperOrderRepo{},
+		cacheService:   cache,
+		kafkaProducer:  &mockReaperProducer{},
+		paymentGateway: NewFakePaymentGateway(FakePaymentOutcomeApprove, 0),
+		config:         &config.AppConfig{ReaperInterval: time.Hour, ReaperBatchSize: 100},
+		instanceID:     "test-instance",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reaper.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected reaper to stop after context cancellation")
+	}
+
+	select {
+	case <-resigned:
+	default:
+		t.Fatalf("expected reaper to resign leadership on shutdown")
+	}
+}
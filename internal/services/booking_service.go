@@ -3,71 +3,181 @@ package services
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"time"
 
 	"airline-booking-system/internal/cache"
 	"airline-booking-system/internal/config"
 	"airline-booking-system/internal/models"
 	"airline-booking-system/internal/repositories"
-	"airline-booking-system/pkg/kafka"
+	"airline-booking-system/pkg/database"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
 )
 
+// errSeatReservationFailed marks a booking transaction rolled back because its
+// seats could not be reserved
+var errSeatReservationFailed = errors.New("failed to reserve seats")
+
+// errSelectedSeatsUnavailable marks a booking transaction rolled back because
+// a requested seat was missing or already taken
+var errSelectedSeatsUnavailable = errors.New("selected seats unavailable")
+
+// Retry policy for seat compensations after failed payments and payment
+// reversals after cancellations.
+var (
+	compensationMaxAttempts = 3
+	compensationBackoff     = 500 * time.Millisecond
+)
+
+// seatCompensationsTotal counts seat releases triggered by failed payments,
+// labeled by result (released, failed).
+var seatCompensationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "booking_seat_compensations_total",
+		Help: "Total number of seat compensations for failed payments, labeled by result.",
+	},
+	[]string{"result"},
+)
+
+// paymentReversalsTotal counts refunds and voids issued for cancelled
+// bookings, labeled by result (reversed, failed).
+var paymentReversalsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "booking_payment_reversals_total",
+		Help: "Total number of payment reversals for cancelled bookings, labeled by result.",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(seatCompensationsTotal, paymentReversalsTotal)
+}
+
 // BookingRepository defines persistence operations used by BookingService.
 type BookingRepository interface {
 	CreateBooking(ctx context.Context, booking *models.Booking) (*models.Booking, error)
 	GetBookingByID(ctx context.Context, id int64) (*models.Booking, error)
 	GetBookingsByUserID(ctx context.Context, userID int64) ([]models.Booking, error)
-	UpdateBookingStatus(ctx context.Context, bookingID int64, status models.BookingStatus, paymentRefID *string) error
+	UpdateBookingStatus(ctx context.Context, bookingID int64, from, to models.BookingStatus, paymentRefID *string) error
+	FailBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID *string) error
+	GetBookingByPaymentReferenceID(ctx context.Context, paymentRefID string) (*models.Booking, error)
+	GetBookingsByOrderID(ctx context.Context, orderID int64) ([]models.Booking, error)
+}
+
+// OrderRepository defines order persistence operations used by BookingService.
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, order *models.Order) (*models.Order, error)
+	GetOrderByID(ctx context.Context, id int64) (*models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID int64, status models.BookingStatus) error
 }
 
 // FlightRepositoryBooking defines flight operations used by BookingService.
 type FlightRepositoryBooking interface {
 	GetFlightByID(ctx context.Context, id int64) (*models.Flight, error)
-	UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int) error
+	UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error
+	ReleaseSeats(ctx context.Context, flightID int64, seatsToRelease int) error
+	AddPendingSeatDeduction(ctx context.Context, bookingID int64, flightID int64, seats int) error
+	ApplyPendingSeatDeductions(ctx context.Context, flightID int64) (int, error)
+	DropPendingSeatDeduction(ctx context.Context, bookingID int64) (bool, error)
+}
+
+// SeatRepositoryBooking defines seat operations used by BookingService.
+type SeatRepositoryBooking interface {
+	AssignSeats(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error
+	ReleaseBookingSeats(ctx context.Context, bookingID int64) error
+}
+
+// FareClassRepositoryBooking defines fare class operations used by BookingService.
+type FareClassRepositoryBooking interface {
+	GetFareClassesByFlightIDs(ctx context.Context, flightIDs []int64) ([]models.FareClass, error)
+	GetFareClass(ctx context.Context, flightID int64, code string) (*models.FareClass, error)
+	ReserveFareClassSeats(ctx context.Context, fareClassID int64, seatsToBook int, version int) error
+	ReleaseFareClassSeats(ctx context.Context, flightID int64, code string, seatsToRelease int) error
 }
 
 // FlightCacheBooking defines cache operations used by BookingService.
 type FlightCacheBooking interface {
-	AcquireFlightLock(ctx context.Context, key string) (bool, error)
-	ReleaseFlightLock(ctx context.Context, key string) error
+	AcquireFlightLock(ctx context.Context, flightID int64) (*models.FlightLock, error)
+	ExtendFlightLock(ctx context.Context, lock *models.FlightLock) (bool, error)
+	ReleaseFlightLock(ctx context.Context, lock *models.FlightLock) error
+	JoinBookingQueue(ctx context.Context, flightID int64) (string, int64, error)
+	BookingQueuePosition(ctx context.Context, flightID int64, ticket string) (int64, error)
+	LeaveBookingQueue(ctx context.Context, flightID int64, ticket string) error
+	BookingQueueLength(ctx context.Context, flightID int64) (int64, error)
+	ReserveInventorySeats(ctx context.Context, flightID int64, seats int) (bool, error)
+	ReturnInventorySeats(ctx context.Context, flightID int64, seats int) error
+	ClaimQuote(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error)
+	ReleaseQuote(ctx context.Context, quoteID string) error
+	IsInventoryTracked(ctx context.Context, flightID int64) (bool, error)
 	DeleteCachedSeats(ctx context.Context, flightID int64) error
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+	InvalidateRouteSearches(ctx context.Context, source, destination string, departure time.Time) error
+	MarkWebhookEventProcessed(ctx context.Context, eventID string) (bool, error)
+	ClearWebhookEvent(ctx context.Context, eventID string) error
 }
 
 // Producer defines the Kafka producer operations used by BookingService.
 type Producer interface {
 	SendSeatUpdateEvent(ctx context.Context, event *models.SeatUpdateEvent) error
 	SendPaymentEvent(ctx context.Context, event *models.PaymentEvent) error
+	SendBookingCancellationEvent(ctx context.Context, event *models.BookingCancellationEvent) error
+}
+
+// TxManager runs a function inside a database transaction. Repository calls
+// made with the context passed to fn take part in that transaction.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // BookingService handles booking business logic
 type BookingService struct {
-	bookingRepo   BookingRepository
-	flightRepo    FlightRepositoryBooking
-	cacheService  FlightCacheBooking
-	kafkaProducer Producer
-	config        *config.AppConfig
-	tracerName    string
+	bookingRepo    BookingRepository
+	orderRepo      OrderRepository
+	flightRepo     FlightRepositoryBooking
+	seatRepo       SeatRepositoryBooking
+	fareClassRepo  FareClassRepositoryBooking
+	cacheService   FlightCacheBooking
+	pricing        PriceQuoter
+	kafkaProducer  Producer
+	paymentGateway PaymentGateway
+	txManager      TxManager
+	config         *config.AppConfig
+	tracerName     string
 }
 
 // NewBookingService creates a new booking service
 func NewBookingService(
 	bookingRepo *repositories.BookingRepository,
+	orderRepo *repositories.OrderRepository,
 	flightRepo *repositories.FlightRepository,
+	seatRepo *repositories.SeatRepository,
+	fareClassRepo *repositories.FareClassRepository,
 	cacheService *cache.FlightCacheService,
-	kafkaProducer *kafka.Producer,
+	pricing *PricingService,
+	kafkaProducer *OutboxProducer,
+	paymentGateway PaymentGateway,
+	db *database.DB,
 	config *config.AppConfig,
 ) *BookingService {
 	return &BookingService{
-		bookingRepo:   bookingRepo,
-		flightRepo:    flightRepo,
-		cacheService:  cacheService,
-		kafkaProducer: kafkaProducer,
-		config:        config,
-		tracerName:    "airline-booking-system/booking-service",
+		bookingRepo:    bookingRepo,
+		orderRepo:      orderRepo,
+		flightRepo:     flightRepo,
+		seatRepo:       seatRepo,
+		fareClassRepo:  fareClassRepo,
+		cacheService:   cacheService,
+		pricing:        pricing,
+		kafkaProducer:  kafkaProducer,
+		paymentGateway: paymentGateway,
+		txManager:      db,
+		config:         config,
+		tracerName:     "airline-booking-system/booking-service",
 	}
 }
 
@@ -81,6 +191,44 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		return nil, fmt.Errorf("invalid booking request")
 	}
 
+	if req.QuoteToken == "" {
+		return s.createBooking(ctx, req, nil)
+	}
+
+	// A quote token pins the price for one booking; check and claim it
+	// before taking the flight lock
+	quote, err := s.verifyQuoteToken(req.QuoteToken)
+	if err != nil || !quote.Matches(req.FlightID, models.NormalizeFareClassCode(req.FareClassCode), req.SeatsBooked) || !quote.IssuedTo(req.UserID) {
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Quote is invalid, expired or does not match this booking",
+		}, nil
+	}
+
+	claimed, err := s.cacheService.ClaimQuote(ctx, quote.QuoteID, quote.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim quote: %w", err)
+	}
+	if !claimed {
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Quote was already used for another booking",
+		}, nil
+	}
+
+	resp, err := s.createBooking(ctx, req, quote)
+	if err != nil || resp.Status == models.BookingStatusFailed {
+		// Nothing was booked with the quote, so it can still be used
+		if releaseErr := s.cacheService.ReleaseQuote(ctx, quote.QuoteID); releaseErr != nil {
+			log.Printf("Failed to release quote %s: %v", quote.QuoteID, releaseErr)
+		}
+	}
+	return resp, err
+}
+
+// createBooking books a flight under its lock, or from its seat inventory,
+// charging the price held by quote when there is one
+func (s *BookingService) createBooking(ctx context.Context, req *models.BookingRequest, quote *models.PriceQuote) (*models.BookingResponse, error) {
 	// Get flight details
 	flight, err := s.flightRepo.GetFlightByID(ctx, req.FlightID)
 	if err != nil {
@@ -103,26 +251,59 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		}, nil
 	}
 
-	// Acquire distributed lock
-	lockKey := req.GetLockKey()
-	locked, err := s.cacheService.AcquireFlightLock(ctx, lockKey)
+	// Flights on a high-demand sale are sold from their seat inventory
+	// without taking the flight lock
+	reserved, err := s.cacheService.ReserveInventorySeats(ctx, req.FlightID, req.SeatsBooked)
+	switch {
+	case errors.Is(err, cache.ErrSeatInventoryNotTracked):
+	case err != nil:
+		return nil, fmt.Errorf("failed to reserve seats: %w", err)
+	case !reserved:
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Insufficient seats available",
+		}, nil
+	default:
+		return s.createInventoryBooking(ctx, req, flight, quote)
+	}
+
+	// Take our turn in the flight's booking queue; a request whose turn has
+	// not come gets a ticket to retry with instead of waiting here
+	locks, ticket, position, err := s.takeFlightLockTurn(ctx, req.FlightID, req.QueueTicket)
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		return nil, err
 	}
 
-	if !locked {
+	if locks == nil {
+		if ticket == "" {
+			return &models.BookingResponse{
+				Status:  models.BookingStatusFailed,
+				Message: "Too many bookings are waiting for this flight, please try again later",
+			}, nil
+		}
 		return &models.BookingResponse{
-			Status:  models.BookingStatusFailed,
-			Message: "Flight is currently being booked by another user",
+			Status:        models.BookingStatusFailed,
+			Message:       "Flight is busy, retry with the queue ticket to keep your place",
+			QueuePosition: position,
+			QueueTicket:   ticket,
+			RetryAfterMs:  s.config.BookingQueuePollInterval.Milliseconds(),
 		}, nil
 	}
 
 	// Ensure lock is released
-	defer func() {
-		if err := s.cacheService.ReleaseFlightLock(ctx, lockKey); err != nil {
-			log.Printf("Failed to release lock: %v", err)
-		}
-	}()
+	defer s.releaseFlightLocks(ctx, locks)
+
+	// The flight may have gone on sale from a seat inventory while we waited
+	lockable, err := s.settleSeatInventory(ctx, req.FlightID)
+	if err != nil {
+		return nil, err
+	}
+	if !lockable {
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Flight is busy, please try again",
+		}, nil
+	}
 
 	// Double-check seat availability after acquiring lock
 	flight, err = s.flightRepo.GetFlightByID(ctx, req.FlightID)
@@ -137,112 +318,1095 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		}, nil
 	}
 
-	// Calculate booking price
-	bookingPrice := flight.Price * float64(req.SeatsBooked)
+	// Resolve the fare class; flights sold by class must be booked in one
+	fareClass, failure, err := s.resolveFareClass(ctx, req.FlightID, req.FareClassCode, req.SeatsBooked)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	// Charge the current price, or the price held by the customer's quote
+	pricePerSeat, held := s.chargedPrice(ctx, flight, fareClass, quote)
+	if !held {
+		return &models.BookingResponse{
+			Status:       models.BookingStatusFailed,
+			Message:      "Fare changed since the quote was issued; request a new quote to confirm it",
+			CurrentPrice: pricePerSeat,
+		}, nil
+	}
+	bookingPrice := pricePerSeat * float64(req.SeatsBooked)
+	var fareClassCode string
+	if fareClass != nil {
+		fareClassCode = fareClass.Code
+	}
 
 	// Create booking record with PENDING status
-	booking := &models.Booking{
-		FlightID:        req.FlightID,
-		UserID:          req.UserID,
-		Status:          models.BookingStatusPending,
-		BookingPrice:    bookingPrice,
-		SeatsBooked:     req.SeatsBooked,
-		BookingMetadata: req.PassengerDetails,
+	booking := newPendingBooking(req, bookingPrice, fareClassCode)
+
+	// Insert the booking and deduct its seats in one transaction so a crash
+	// between the two can never leave a booking without reserved seats
+	var createdBooking *models.Booking
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdBooking, err = s.reserveBooking(ctx, booking, flight, locks.fence(flight.ID), fareClass, req.SelectedSeats())
+		return err
+	})
+	if err != nil {
+		if failure := reservationFailure(err); failure != "" {
+			return &models.BookingResponse{
+				Status:  models.BookingStatusFailed,
+				Message: failure,
+			}, nil
+		}
+		return nil, err
+	}
+
+	return s.startBookingPayment(ctx, createdBooking, booking.PaymentReferenceID), nil
+}
+
+// newPendingBooking builds the pending booking record of a request
+func newPendingBooking(req *models.BookingRequest, bookingPrice float64, fareClassCode string) *models.Booking {
+	// Store selected seats in their normalized form
+	passengers := make([]models.PassengerDetails, len(req.PassengerDetails))
+	for i, passenger := range req.PassengerDetails {
+		passenger.SeatNumber = models.NormalizeSeatNumber(passenger.SeatNumber)
+		passengers[i] = passenger
+	}
+
+	// Generate payment reference ID up front so gateway callbacks can find the booking
+	return &models.Booking{
+		FlightID:           req.FlightID,
+		UserID:             req.UserID,
+		Status:             models.BookingStatusPending,
+		PaymentReferenceID: generatePaymentReferenceID(),
+		BookingPrice:       bookingPrice,
+		SeatsBooked:        req.SeatsBooked,
+		FareClassCode:      fareClassCode,
+		BookingMetadata:    passengers,
+	}
+}
+
+// startBookingPayment invalidates the cached seats of a newly reserved
+// booking's flight and starts processing its payment
+func (s *BookingService) startBookingPayment(ctx context.Context, booking *models.Booking, paymentRefID string) *models.BookingResponse {
+	// Invalidate cache for this flight's seats
+	s.invalidateFlightCache(ctx, booking.FlightID)
+
+	// Process payment through the gateway in the background.
+	// The request context is cancelled once the response is written, so detach from it.
+	go s.processPaymentAsync(context.WithoutCancel(ctx), booking, paymentRefID)
+
+	return &models.BookingResponse{
+		BookingID:         booking.ID,
+		Status:           models.BookingStatusPending,
+		PaymentReferenceID: paymentRefID,
+		Message:          "Booking created, processing payment",
+	}
+}
+
+// itineraryLeg is a leg of an itinerary booking ready to be reserved
+type itineraryLeg struct {
+	flight    *models.Flight
+	fareClass *models.FareClass
+	price     float64
+}
+
+// CreateItineraryBooking books the same passengers on every leg of an
+// itinerary. The legs are booked as the segments of an order: all of them
+// are reserved in one transaction, so either every leg is booked or none is,
+// and the whole itinerary is paid at once.
+func (s *BookingService) CreateItineraryBooking(ctx context.Context, req *models.ItineraryBookingRequest) (*models.ItineraryBookingResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.CreateItineraryBooking")
+	defer span.End()
+
+	if !req.IsValid() {
+		return nil, fmt.Errorf("invalid itinerary booking request")
+	}
+
+	// The legs must form a journey itinerary search would offer
+	flights, err := s.getItineraryFlights(ctx, req.Legs)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validItinerary(flights, s.config) {
+		return &models.ItineraryBookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Flights do not form a valid connection",
+		}, nil
+	}
+
+	// Acquire the lock of every leg, in flight ID order
+	locks, err := s.acquireFlightLocks(ctx, itineraryLockOrder(req.Legs))
+	if err != nil {
+		return nil, err
+	}
+
+	if locks == nil {
+		return &models.ItineraryBookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Flight is currently being booked by another user",
+		}, nil
 	}
 
+	// Ensure locks are released
+	defer s.releaseFlightLocks(ctx, locks)
+
+	// Double-check every leg after acquiring the locks and price it
+	legs, totalPrice, failure, err := s.priceItineraryLegs(ctx, req.Legs, req.SeatsBooked)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.ItineraryBookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	// Book the legs as the segments of an order, so the whole itinerary is
+	// charged in one payment and a declined payment fails every leg
+	order, failure, err := s.reserveOrder(ctx, req.UserID, req.SeatsBooked, req.PassengerDetails, legs, totalPrice, locks)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.ItineraryBookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	s.startOrderPayment(ctx, order)
+
+	bookingIDs := make([]int64, len(order.Segments))
+	for i, segment := range order.Segments {
+		bookingIDs[i] = segment.ID
+	}
+
+	return &models.ItineraryBookingResponse{
+		OrderID:            order.ID,
+		BookingIDs:         bookingIDs,
+		Status:             models.BookingStatusPending,
+		PaymentReferenceID: order.PaymentReferenceID,
+		TotalPrice:         order.TotalPrice,
+		Message:            "Itinerary booked, processing payment",
+	}, nil
+}
+
+// getItineraryFlights loads the flight of every leg in travel order
+func (s *BookingService) getItineraryFlights(ctx context.Context, legs []models.ItineraryLeg) ([]models.Flight, error) {
+	flights := make([]models.Flight, len(legs))
+	for i, leg := range legs {
+		flight, err := s.flightRepo.GetFlightByID(ctx, leg.FlightID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get flight: %w", err)
+		}
+		flights[i] = *flight
+	}
+	return flights, nil
+}
+
+// itineraryLockOrder returns the flight IDs of the legs sorted, so concurrent
+// itinerary bookings sharing a flight lock in the same order
+func itineraryLockOrder(legs []models.ItineraryLeg) []int64 {
+	flightIDs := make([]int64, len(legs))
+	for i, leg := range legs {
+		flightIDs[i] = leg.FlightID
+	}
+	sort.Slice(flightIDs, func(i, j int) bool { return flightIDs[i] < flightIDs[j] })
+	return flightIDs
+}
+
+// takeFlightLockTurn queues a booking for the lock of a flight, so requests
+// for a busy flight take the lock in arrival order instead of racing for it.
+// It does not wait: a request joins the queue, or checks its place with the
+// ticket it was given, and only the head of the queue tries the lock. When
+// the turn has not come it returns nil locks with the ticket to retry with
+// every BookingQueuePollInterval and the number of requests ahead, or no
+// ticket when the queue is full.
+func (s *BookingService) takeFlightLockTurn(ctx context.Context, flightID int64, ticket string) (*flightLocks, string, int64, error) {
+	position := int64(-1)
+	if ticket != "" {
+		var err error
+		position, err = s.cacheService.BookingQueuePosition(ctx, flightID, ticket)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to get booking queue position: %w", err)
+		}
+	}
+
+	// New requests, and requests that stopped retrying long enough to lose
+	// their place, join at the back
+	if position < 0 {
+		var err error
+		ticket, position, err = s.cacheService.JoinBookingQueue(ctx, flightID)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to join booking queue: %w", err)
+		}
+		if ticket == "" {
+			return nil, "", 0, nil
+		}
+	}
+
+	if position > 0 {
+		return nil, ticket, position, nil
+	}
+
+	// The head of the queue takes the lock as soon as its holder lets go,
+	// and leaves the queue once it holds the lock or fails to get it
+	locks, err := s.acquireFlightLocks(ctx, []int64{flightID})
+	if err == nil && locks == nil {
+		return nil, ticket, 0, nil
+	}
+	if err := s.cacheService.LeaveBookingQueue(ctx, flightID, ticket); err != nil {
+		log.Printf("Failed to leave booking queue of flight %d: %v", flightID, err)
+	}
+	return locks, "", 0, err
+}
+
+// GetBookingQueue reports how many booking requests are waiting for a flight
+func (s *BookingService) GetBookingQueue(ctx context.Context, flightID int64) (*models.BookingQueueStatus, error) {
+	waiting, err := s.cacheService.BookingQueueLength(ctx, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking queue: %w", err)
+	}
+
+	return &models.BookingQueueStatus{FlightID: flightID, Waiting: waiting}, nil
+}
+
+// flightLocks are the flight locks held by one booking operation, by flight ID
+type flightLocks struct {
+	locks map[int64]*models.FlightLock
+	stop  context.CancelFunc
+	done  chan struct{}
+}
+
+// fence returns the fencing token of the lock held on a flight
+func (l *flightLocks) fence(flightID int64) int64 {
+	return l.locks[flightID].Fence
+}
+
+// acquireFlightLocks takes the lock of every flight in order and keeps them
+// extended until they are released, so a slow payment gateway or database
+// cannot outlast the lock TTL. If one is held elsewhere the locks taken so far
+// are released and nil is returned.
+func (s *BookingService) acquireFlightLocks(ctx context.Context, flightIDs []int64) (*flightLocks, error) {
+	locks := make(map[int64]*models.FlightLock, len(flightIDs))
+	for _, flightID := range flightIDs {
+		lock, err := s.cacheService.AcquireFlightLock(ctx, flightID)
+		if err != nil || lock == nil {
+			s.releaseLocks(ctx, locks)
+			if err != nil {
+				return nil, fmt.Errorf("failed to acquire lock: %w", err)
+			}
+			return nil, nil
+		}
+		locks[flightID] = lock
+	}
+
+	// Renew every lock well before the shortest TTL runs out
+	var interval time.Duration
+	for _, lock := range locks {
+		if interval == 0 || lock.TTL/3 < interval {
+			interval = lock.TTL / 3
+		}
+	}
+
+	extendCtx, stop := context.WithCancel(ctx)
+	held := &flightLocks{locks: locks, stop: stop, done: make(chan struct{})}
+	if interval > 0 {
+		go s.extendFlightLocks(extendCtx, held, interval)
+	} else {
+		close(held.done)
+	}
+
+	return held, nil
+}
+
+// extendFlightLocks renews held flight locks every interval until ctx is
+// cancelled. A lock that could not be renewed in time may already be held by
+// another request; the fencing token then keeps this request from reserving
+// seats on its flight.
+func (s *BookingService) extendFlightLocks(ctx context.Context, held *flightLocks, interval time.Duration) {
+	defer close(held.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, lock := range held.locks {
+			extended, err := s.cacheService.ExtendFlightLock(ctx, lock)
+			if err != nil {
+				log.Printf("Failed to extend lock on flight %d: %v", lock.FlightID, err)
+			} else if !extended {
+				log.Printf("Lock on flight %d expired before it could be extended", lock.FlightID)
+			}
+		}
+	}
+}
+
+// priceItineraryLegs reloads the flight of every leg, checks it can still sell
+// the seats and prices it. It returns a failure message for the first leg
+// that cannot be booked. Callers must hold the flight locks of all legs.
+func (s *BookingService) priceItineraryLegs(ctx context.Context, reqLegs []models.ItineraryLeg, seats int) ([]itineraryLeg, float64, string, error) {
+	// Flights sold from a seat inventory are only sold on their own
+	for _, leg := range reqLegs {
+		lockable, err := s.settleSeatInventory(ctx, leg.FlightID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if !lockable {
+			return nil, 0, "Flight is on a high-demand sale and must be booked on its own", nil
+		}
+	}
+
+	flights, err := s.getItineraryFlights(ctx, reqLegs)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	legs := make([]itineraryLeg, len(reqLegs))
+	totalPrice := 0.0
+	for i, leg := range reqLegs {
+		flight := &flights[i]
+		if flight.FlightStatus == models.FlightStatusCancelled || flight.FlightStatus == models.FlightStatusDeparted {
+			return nil, 0, "Flight is not available for booking", nil
+		}
+
+		if flight.AvailableSeats < seats {
+			return nil, 0, "Seats no longer available", nil
+		}
+
+		fareClass, failure, err := s.resolveFareClass(ctx, flight.ID, leg.FareClassCode, seats)
+		if err != nil || failure != "" {
+			return nil, 0, failure, err
+		}
+
+		price := s.pricing.QuotePrice(ctx, flight, fareClass) * float64(seats)
+		legs[i] = itineraryLeg{flight: flight, fareClass: fareClass, price: price}
+		totalPrice += price
+	}
+
+	return legs, totalPrice, "", nil
+}
+
+// releaseFlightLocks stops extending held flight locks and releases them
+func (s *BookingService) releaseFlightLocks(ctx context.Context, held *flightLocks) {
+	held.stop()
+	<-held.done
+	s.releaseLocks(ctx, held.locks)
+}
+
+// releaseLocks releases flight locks, logging failures
+func (s *BookingService) releaseLocks(ctx context.Context, locks map[int64]*models.FlightLock) {
+	for _, lock := range locks {
+		if err := s.cacheService.ReleaseFlightLock(ctx, lock); err != nil {
+			log.Printf("Failed to release lock on flight %d: %v", lock.FlightID, err)
+		}
+	}
+}
+
+// reserveBooking inserts a booking and deducts its seats from the flight and
+// fare class, holding any selected seats. fence is the fencing token of the
+// flight lock held by the caller. It must run inside a transaction so a failed
+// reservation leaves nothing behind.
+func (s *BookingService) reserveBooking(ctx context.Context, booking *models.Booking, flight *models.Flight, fence int64, fareClass *models.FareClass, selectedSeats []string) (*models.Booking, error) {
 	createdBooking, err := s.bookingRepo.CreateBooking(ctx, booking)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create booking: %w", err)
 	}
 
 	// Update available seats in database
-	err = s.flightRepo.UpdateAvailableSeats(ctx, req.FlightID, req.SeatsBooked, flight.Version)
+	err = s.flightRepo.UpdateAvailableSeats(ctx, flight.ID, booking.SeatsBooked, flight.Version, fence)
 	if err != nil {
-		// If seat update fails, mark booking as failed
-		s.bookingRepo.UpdateBookingStatus(ctx, createdBooking.ID, models.BookingStatusFailed, nil)
-		return &models.BookingResponse{
-			BookingID: createdBooking.ID,
-			Status:    models.BookingStatusFailed,
-			Message:   "Failed to reserve seats",
-		}, nil
+		return nil, fmt.Errorf("%w: %v", errSeatReservationFailed, err)
 	}
 
-	// Invalidate cache for this flight's seats
-	s.cacheService.DeleteCachedSeats(ctx, req.FlightID)
+	var seatCabin models.SeatCabin
+	if fareClass != nil {
+		err = s.fareClassRepo.ReserveFareClassSeats(ctx, fareClass.ID, booking.SeatsBooked, fareClass.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errSeatReservationFailed, err)
+		}
+		seatCabin = fareClass.Cabin
+	}
+
+	if err := s.assignSelectedSeats(ctx, flight.ID, createdBooking.ID, selectedSeats, seatCabin); err != nil {
+		return nil, err
+	}
 
-	// Generate payment reference ID
-	paymentRefID := generatePaymentReferenceID()
+	return createdBooking, nil
+}
 
-	// Simulate payment processing (in real implementation, this would call payment gateway)
-	go s.processPaymentAsync(ctx, createdBooking.ID, paymentRefID, bookingPrice)
+// assignSelectedSeats holds the selected seats of a booking; a seat taken by
+// a concurrent booking or outside the fare class cabin rolls this one back
+func (s *BookingService) assignSelectedSeats(ctx context.Context, flightID int64, bookingID int64, selectedSeats []string, seatCabin models.SeatCabin) error {
+	if len(selectedSeats) == 0 {
+		return nil
+	}
 
-	return &models.BookingResponse{
-		BookingID:         createdBooking.ID,
-		Status:           models.BookingStatusPending,
-		PaymentReferenceID: paymentRefID,
-		Message:          "Booking created, processing payment",
+	err := s.seatRepo.AssignSeats(ctx, flightID, bookingID, selectedSeats, seatCabin)
+	if errors.Is(err, repositories.ErrSeatsUnavailable) {
+		return errSelectedSeatsUnavailable
+	}
+	return err
+}
+
+// reservationFailure returns the customer-facing message for a reservation
+// rolled back because seats could not be had, or "" for any other error
+func reservationFailure(err error) string {
+	switch {
+	case errors.Is(err, errSeatReservationFailed):
+		return "Failed to reserve seats"
+	case errors.Is(err, errSelectedSeatsUnavailable):
+		return "One or more selected seats are not available"
+	default:
+		return ""
+	}
+}
+
+// resolveFareClass loads the fare class a request for seats targets. It
+// returns a failure message when the seats cannot be sold: the class does not
+// exist or lacks seats, or the flight is only sold by class and none was given.
+func (s *BookingService) resolveFareClass(ctx context.Context, flightID int64, fareClassCode string, seats int) (*models.FareClass, string, error) {
+	code := models.NormalizeFareClassCode(fareClassCode)
+	if code == "" {
+		fareClasses, err := s.fareClassRepo.GetFareClassesByFlightIDs(ctx, []int64{flightID})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get fare classes: %w", err)
+		}
+		if len(fareClasses) > 0 {
+			return nil, "A fare class is required for this flight", nil
+		}
+		return nil, "", nil
+	}
+
+	fareClass, err := s.fareClassRepo.GetFareClass(ctx, flightID, code)
+	if errors.Is(err, repositories.ErrFareClassNotFound) {
+		return nil, "Fare class is not sold on this flight", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get fare class: %w", err)
+	}
+
+	if fareClass.AvailableSeats < seats {
+		return nil, "Insufficient seats available in fare class", nil
+	}
+
+	return fareClass, "", nil
+}
+
+// chargedPrice returns the per-seat price a booking is charged. Without a
+// quote it is the current price. A quote holds its price for the customer
+// carrying it, even if other bookings moved the current price since, until
+// the base fare it was computed from changes; it then reports false with the
+// current price, which the customer has to re-confirm.
+func (s *BookingService) chargedPrice(ctx context.Context, flight *models.Flight, fareClass *models.FareClass, quote *models.PriceQuote) (float64, bool) {
+	if quote != nil && quote.HoldsPrice(baseFareOf(flight, fareClass)) {
+		return quote.PricePerSeat, true
+	}
+
+	return s.pricing.QuotePrice(ctx, flight, fareClass), quote == nil
+}
+
+// baseFareOf returns the base fare of a fare class, or of the flight when it
+// is not sold by fare class
+func baseFareOf(flight *models.Flight, fareClass *models.FareClass) float64 {
+	if fareClass != nil {
+		return fareClass.Price
+	}
+	return flight.Price
+}
+
+// CreateQuote prices seats on a flight for a user and returns the price
+// together with a signed token. CreateBooking charges the quoted price for
+// one booking by that user, as long as the base fare it was computed from is
+// unchanged.
+func (s *BookingService) CreateQuote(ctx context.Context, req *models.QuoteRequest) (*models.QuoteResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.CreateQuote")
+	defer span.End()
+
+	if !req.IsValid() {
+		return nil, fmt.Errorf("invalid quote request")
+	}
+
+	flight, err := s.flightRepo.GetFlightByID(ctx, req.FlightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight: %w", err)
+	}
+
+	if flight.FlightStatus == models.FlightStatusCancelled || flight.FlightStatus == models.FlightStatusDeparted {
+		return nil, fmt.Errorf("%w: flight is not available for booking", models.ErrNotQuotable)
+	}
+
+	if flight.AvailableSeats < req.Seats {
+		return nil, fmt.Errorf("%w: insufficient seats available", models.ErrNotQuotable)
+	}
+
+	fareClass, failure, err := s.resolveFareClass(ctx, req.FlightID, req.FareClassCode, req.Seats)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotQuotable, failure)
+	}
+
+	pricePerSeat := s.pricing.QuotePrice(ctx, flight, fareClass)
+	quote := models.PriceQuote{
+		UserID:        req.UserID,
+		FlightID:      req.FlightID,
+		FareClassCode: models.NormalizeFareClassCode(req.FareClassCode),
+		Seats:         req.Seats,
+		BaseFare:      baseFareOf(flight, fareClass),
+		PricePerSeat:  pricePerSeat,
+		TotalPrice:    math.Round(pricePerSeat*float64(req.Seats)*100) / 100,
+	}
+
+	token, err := issueQuote([]byte(s.config.QuoteSigningSecret), s.config.QuoteTTL, &quote, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuoteResponse{
+		PriceQuote: quote,
+		QuoteToken: token,
 	}, nil
 }
 
-// processPaymentAsync simulates async payment processing
-func (s *BookingService) processPaymentAsync(ctx context.Context, bookingID int64, paymentRefID string, amount float64) {
+// verifyQuoteToken returns the quote carried by a token signed by this service
+func (s *BookingService) verifyQuoteToken(token string) (*models.PriceQuote, error) {
+	return verifyQuote([]byte(s.config.QuoteSigningSecret), token, time.Now())
+}
+
+// processPaymentAsync authorizes and captures the booking payment through the gateway
+func (s *BookingService) processPaymentAsync(ctx context.Context, booking *models.Booking, paymentRefID string) {
 	tr := otel.Tracer(s.tracerName)
 	ctx, span := tr.Start(ctx, "BookingService.processPaymentAsync")
 	defer span.End()
 
-	// Simulate payment processing delay
-	time.Sleep(2 * time.Second)
+	paymentSuccessful := s.chargePayment(ctx, &models.PaymentRequest{
+		BookingID:          booking.ID,
+		PaymentReferenceID: paymentRefID,
+		Amount:             booking.BookingPrice,
+	})
+
+	newStatus, err := s.settleBookingPayment(ctx, booking, paymentRefID, paymentSuccessful)
+	if err != nil {
+		log.Printf("Failed to settle payment for booking %d: %v", booking.ID, err)
+		return
+	}
+
+	log.Printf("Booking %d payment processing completed: %s", booking.ID, newStatus)
+}
+
+// settleBookingPayment moves a pending booking to completed or failed based on
+// the payment outcome. The resulting events are written to the outbox in the
+// same transaction as the status change.
+//
+// A booking cancelled or expired while its payment was being taken is left
+// as it is, and a payment captured for it is refunded.
+func (s *BookingService) settleBookingPayment(ctx context.Context, booking *models.Booking, paymentRefID string, paymentSuccessful bool) (models.BookingStatus, error) {
+	if !paymentSuccessful {
+		// Mark the booking failed and give its seats back to the flight
+		err := s.releaseSeatsForFailedPayment(ctx, booking, paymentRefID)
+		if errors.Is(err, repositories.ErrBookingStatusConflict) {
+			return s.currentBookingStatus(ctx, booking.ID)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to release seats: %w", err)
+		}
+		return models.BookingStatusFailed, nil
+	}
 
-	// Simulate payment success (90% success rate)
-	paymentSuccessful := simulatePaymentSuccess()
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		return s.completeBooking(ctx, booking, paymentRefID)
+	})
+	if errors.Is(err, repositories.ErrBookingStatusConflict) {
+		return s.refundUnclaimedPayment(ctx, booking, paymentRefID)
+	}
+	if err != nil {
+		return "", err
+	}
 
-	var newStatus models.BookingStatus
-	var message string
+	return models.BookingStatusCompleted, nil
+}
 
-	if paymentSuccessful {
-		newStatus = models.BookingStatusCompleted
-		message = "Payment successful"
+// refundUnclaimedPayment refunds a payment captured for a booking that was
+// settled without it, as when the booking was cancelled or expired while the
+// payment was being taken. A booking completed by another delivery of the
+// same payment keeps it. It returns the booking's current status.
+func (s *BookingService) refundUnclaimedPayment(ctx context.Context, booking *models.Booking, paymentRefID string) (models.BookingStatus, error) {
+	status, err := s.currentBookingStatus(ctx, booking.ID)
+	if err != nil || status == models.BookingStatusCompleted {
+		return status, err
+	}
 
-		// Send seat update event
-		seatEvent := &models.SeatUpdateEvent{
-			FlightID:    0, // Would be retrieved from booking
-			SeatsBooked: 0, // Would be retrieved from booking
-			Timestamp:   time.Now(),
-			BookingID:   bookingID,
+	log.Printf("Booking %d was %s before its payment %s was captured; refunding it", booking.ID, status, paymentRefID)
+	result, err := s.paymentGateway.Refund(ctx, paymentRefID, booking.BookingPrice)
+	if err != nil {
+		return status, fmt.Errorf("failed to refund payment %s: %w", paymentRefID, err)
+	}
+	if !result.IsApproved() {
+		return status, fmt.Errorf("refund of payment %s declined: %s", paymentRefID, result.Message)
+	}
+
+	return status, nil
+}
+
+// currentBookingStatus reads a booking's status as it is now
+func (s *BookingService) currentBookingStatus(ctx context.Context, bookingID int64) (models.BookingStatus, error) {
+	booking, err := s.bookingRepo.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get booking: %w", err)
+	}
+	return booking.Status, nil
+}
+
+// completeBooking marks a paid pending booking completed and records its
+// seat update and payment events. It must run inside a transaction.
+func (s *BookingService) completeBooking(ctx context.Context, booking *models.Booking, paymentRefID string) error {
+	err := s.bookingRepo.UpdateBookingStatus(ctx, booking.ID, models.BookingStatusPending, models.BookingStatusCompleted, &paymentRefID)
+	if err != nil {
+		return fmt.Errorf("failed to update booking status: %w", err)
+	}
+
+	seatEvent := &models.SeatUpdateEvent{
+		FlightID:    booking.FlightID,
+		SeatsBooked: booking.SeatsBooked,
+		Timestamp:   time.Now(),
+		BookingID:   booking.ID,
+	}
+
+	if err := s.kafkaProducer.SendSeatUpdateEvent(ctx, seatEvent); err != nil {
+		return fmt.Errorf("failed to send seat update event: %w", err)
+	}
+
+	if err := s.kafkaProducer.SendPaymentEvent(ctx, newPaymentEvent(booking, paymentRefID, models.BookingStatusCompleted)); err != nil {
+		return fmt.Errorf("failed to send payment event: %w", err)
+	}
+
+	return nil
+}
+
+// newPaymentEvent builds the payment event for a settled booking
+func newPaymentEvent(booking *models.Booking, paymentRefID string, status models.BookingStatus) *models.PaymentEvent {
+	return &models.PaymentEvent{
+		BookingID:          booking.ID,
+		OrderID:            booking.OrderID,
+		PaymentReferenceID: paymentRefID,
+		Amount:             booking.BookingPrice,
+		Status:             string(status),
+		Timestamp:          time.Now(),
+	}
+}
+
+// HandlePaymentWebhook applies an asynchronous gateway callback to the booking
+// it refers to. Redeliveries of an event that was already applied are no-ops.
+func (s *BookingService) HandlePaymentWebhook(ctx context.Context, event *models.PaymentWebhookEvent) (*models.BookingResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.HandlePaymentWebhook")
+	defer span.End()
+
+	if !event.IsValid() {
+		return nil, fmt.Errorf("invalid payment webhook event")
+	}
+
+	booking, err := s.bookingRepo.GetBookingByPaymentReferenceID(ctx, event.PaymentReferenceID)
+	if errors.Is(err, repositories.ErrBookingNotFound) {
+		return nil, fmt.Errorf("%w: %s", models.ErrPaymentReferenceNotFound, event.PaymentReferenceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	// The segments of an order share its payment, so the event settles the order
+	var order *models.Order
+	charged := booking.BookingPrice
+	if booking.OrderID != 0 {
+		order, err = s.getOrder(ctx, booking.OrderID)
+		if err != nil {
+			return nil, err
 		}
+		charged = order.TotalPrice
+	}
+
+	if !event.MatchesAmount(charged) {
+		return nil, fmt.Errorf("%w: event reports %.2f, payment %s was charged %.2f",
+			models.ErrPaymentAmountMismatch, event.Amount, event.PaymentReferenceID, charged)
+	}
+
+	firstDelivery, err := s.cacheService.MarkWebhookEventProcessed(ctx, event.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	status := booking.Status
+	if order != nil {
+		status = order.Status
+	}
 
-		if err := s.kafkaProducer.SendSeatUpdateEvent(ctx, seatEvent); err != nil {
-			log.Printf("Failed to send seat update event: %v", err)
+	// Only pending bookings can be settled; anything else was already
+	// settled by an earlier delivery or another payment path. The check is
+	// only a shortcut: the asynchronous payment may settle the booking at
+	// any moment, so settling moves the status with a compare-and-set and
+	// reports whatever status won.
+	if !firstDelivery || status != models.BookingStatusPending {
+		return &models.BookingResponse{
+			BookingID:          booking.ID,
+			Status:             status,
+			PaymentReferenceID: booking.PaymentReferenceID,
+			Message:            "Payment event already processed",
+		}, nil
+	}
+
+	paymentSuccessful := event.Type == models.PaymentWebhookEventSucceeded
+	var newStatus models.BookingStatus
+	if order != nil {
+		newStatus, err = s.settleOrderPayment(ctx, order, paymentSuccessful)
+	} else {
+		newStatus, err = s.settleBookingPayment(ctx, booking, event.PaymentReferenceID, paymentSuccessful)
+	}
+	if err != nil {
+		// Forget the event so the gateway's redelivery is processed again
+		if clearErr := s.cacheService.ClearWebhookEvent(ctx, event.EventID); clearErr != nil {
+			log.Printf("Failed to clear webhook event %s: %v", event.EventID, clearErr)
 		}
+		return nil, err
+	}
+
+	return &models.BookingResponse{
+		BookingID:          booking.ID,
+		Status:             newStatus,
+		PaymentReferenceID: booking.PaymentReferenceID,
+		Message:            "Payment event processed",
+	}, nil
+}
+
+// chargePayment authorizes and captures a payment, voiding the authorization
+// if capture does not go through
+func (s *BookingService) chargePayment(ctx context.Context, req *models.PaymentRequest) bool {
+	paymentRefID := req.PaymentReferenceID
+
+	authResult, err := s.paymentGateway.Authorize(ctx, req)
+	if err != nil {
+		log.Printf("Payment authorization for %s failed: %v", paymentRefID, err)
+		return false
+	}
+
+	if !authResult.IsApproved() {
+		log.Printf("Payment authorization for %s declined: %s", paymentRefID, authResult.Message)
+		return false
+	}
+
+	captureResult, err := s.paymentGateway.Capture(ctx, paymentRefID, req.Amount)
+	if err == nil && captureResult.IsApproved() {
+		return true
+	}
+
+	if err != nil {
+		log.Printf("Payment capture for %s failed: %v", paymentRefID, err)
 	} else {
-		newStatus = models.BookingStatusFailed
-		message = "Payment failed"
+		log.Printf("Payment capture for %s declined: %s", paymentRefID, captureResult.Message)
+	}
 
-		// In a real implementation, you would need to release the seats back
-		// This is simplified for the demo
+	if _, err := s.paymentGateway.Void(ctx, paymentRefID); err != nil {
+		log.Printf("Failed to void payment authorization for %s: %v", paymentRefID, err)
 	}
 
-	// Update booking status
-	err := s.bookingRepo.UpdateBookingStatus(ctx, bookingID, newStatus, &paymentRefID)
+	return false
+}
+
+// releaseSeatsForFailedPayment marks a booking failed and restores its seats,
+// retrying with a linear backoff before giving up
+func (s *BookingService) releaseSeatsForFailedPayment(ctx context.Context, booking *models.Booking, paymentRefID string) error {
+	err := s.compensate(ctx, fmt.Sprintf("booking %d", booking.ID), func(ctx context.Context) error {
+		return s.failBooking(ctx, booking, paymentRefID)
+	})
 	if err != nil {
-		log.Printf("Failed to update booking status: %v", err)
-		return
+		return err
 	}
 
-	// Send payment event
-	paymentEvent := &models.PaymentEvent{
-		BookingID:         bookingID,
-		PaymentReferenceID: paymentRefID,
-		Amount:           amount,
-		Status:           string(newStatus),
-		Timestamp:        time.Now(),
+	s.invalidateFlightCache(ctx, booking.FlightID)
+	s.returnInventorySeats(ctx, booking.FlightID, booking.SeatsBooked)
+	return nil
+}
+
+// failBooking marks a pending booking failed, restores its seats and records
+// the failed payment. It must run inside a transaction.
+func (s *BookingService) failBooking(ctx context.Context, booking *models.Booking, paymentRefID string) error {
+	if err := s.bookingRepo.FailBookingAndReleaseSeats(ctx, booking.ID, &paymentRefID); err != nil {
+		return err
+	}
+	if err := s.seatRepo.ReleaseBookingSeats(ctx, booking.ID); err != nil {
+		return err
+	}
+	return s.kafkaProducer.SendPaymentEvent(ctx, newPaymentEvent(booking, paymentRefID, models.BookingStatusFailed))
+}
+
+// invalidateFlightCache drops the cached seat count of a flight and the
+// cached searches listing it after a booking changed its seats
+func (s *BookingService) invalidateFlightCache(ctx context.Context, flightID int64) {
+	s.cacheService.DeleteCachedSeats(ctx, flightID)
+	if err := s.cacheService.InvalidateFlightSearches(ctx, flightID); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flightID, err)
 	}
+}
+
+// compensate runs a seat compensation in a transaction, retrying with a
+// linear backoff before giving up
+func (s *BookingService) compensate(ctx context.Context, target string, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= compensationMaxAttempts; attempt++ {
+		err = s.txManager.WithinTx(ctx, fn)
+		if err == nil {
+			seatCompensationsTotal.WithLabelValues("released").Inc()
+			return nil
+		}
+
+		// Settled by another path, which already took care of the seats
+		if errors.Is(err, repositories.ErrBookingStatusConflict) || errors.Is(err, repositories.ErrOrderStatusConflict) {
+			return err
+		}
+
+		log.Printf("Seat compensation attempt %d for %s failed: %v", attempt, target, err)
+		if attempt == compensationMaxAttempts {
+			break
+		}
 
-	if err := s.kafkaProducer.SendPaymentEvent(ctx, paymentEvent); err != nil {
-		log.Printf("Failed to send payment event: %v", err)
+		select {
+		case <-ctx.Done():
+			seatCompensationsTotal.WithLabelValues("failed").Inc()
+			return ctx.Err()
+		case <-time.After(compensationBackoff * time.Duration(attempt)):
+		}
 	}
 
-	log.Printf("Booking %d payment processing completed: %s", bookingID, message)
+	seatCompensationsTotal.WithLabelValues("failed").Inc()
+	return err
+}
+
+// CancelBooking cancels a booking and releases its seats back to the flight
+func (s *BookingService) CancelBooking(ctx context.Context, id int64) (*models.BookingResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.CancelBooking")
+	defer span.End()
+
+	booking, err := s.bookingRepo.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if !booking.IsCancellable() {
+		return &models.BookingResponse{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Booking cannot be cancelled",
+		}, nil
+	}
+
+	// Order segments share one payment, which cannot be reversed for a
+	// single segment
+	if booking.OrderID != 0 {
+		return &models.BookingResponse{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Booking is part of an order and cannot be cancelled on its own",
+		}, nil
+	}
+
+	// Acquire the same flight lock used for booking creation
+	locks, err := s.acquireFlightLocks(ctx, []int64{booking.FlightID})
+	if err != nil {
+		return nil, err
+	}
+
+	if locks == nil {
+		return &models.BookingResponse{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Flight is currently being booked by another user",
+		}, nil
+	}
+
+	defer s.releaseFlightLocks(ctx, locks)
+
+	// A payment, expiry or another cancellation may have settled the booking
+	// while we waited for the lock
+	booking, err = s.bookingRepo.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if !booking.IsCancellable() {
+		return &models.BookingResponse{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Booking cannot be cancelled",
+		}, nil
+	}
+
+	flight, err := s.flightRepo.GetFlightByID(ctx, booking.FlightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight: %w", err)
+	}
+
+	// Non-refundable fares keep the captured payment on cancellation
+	refundable := true
+	if booking.FareClassCode != "" {
+		fareClass, err := s.fareClassRepo.GetFareClass(ctx, booking.FlightID, booking.FareClassCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fare class: %w", err)
+		}
+		refundable = fareClass.Refundable
+	}
+
+	// Cancel the booking, return its seats and record the event in one transaction
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// A pending booking can still be completed by its payment, which
+		// does not take the flight lock
+		err := s.bookingRepo.UpdateBookingStatus(ctx, booking.ID, booking.Status, models.BookingStatusCancelled, &booking.PaymentReferenceID)
+		if err != nil {
+			return fmt.Errorf("failed to cancel booking: %w", err)
+		}
+
+		// Seats booked from a seat inventory may not have been deducted yet
+		undeducted, err := s.flightRepo.DropPendingSeatDeduction(ctx, booking.ID)
+		if err != nil {
+			return err
+		}
+
+		if !undeducted {
+			err = s.flightRepo.ReleaseSeats(ctx, booking.FlightID, booking.SeatsBooked)
+			if err != nil {
+				return fmt.Errorf("failed to release seats: %w", err)
+			}
+		}
+
+		if booking.FareClassCode != "" {
+			err = s.fareClassRepo.ReleaseFareClassSeats(ctx, booking.FlightID, booking.FareClassCode, booking.SeatsBooked)
+			if err != nil {
+				return fmt.Errorf("failed to release fare class seats: %w", err)
+			}
+		}
+
+		if err := s.seatRepo.ReleaseBookingSeats(ctx, booking.ID); err != nil {
+			return err
+		}
+
+		cancellationEvent := &models.BookingCancellationEvent{
+			BookingID:     booking.ID,
+			FlightID:      booking.FlightID,
+			UserID:        booking.UserID,
+			SeatsReleased: booking.SeatsBooked,
+			Timestamp:     time.Now(),
+		}
+
+		if err := s.kafkaProducer.SendBookingCancellationEvent(ctx, cancellationEvent); err != nil {
+			return fmt.Errorf("failed to send booking cancellation event: %w", err)
+		}
+
+		return nil
+	})
+	if errors.Is(err, repositories.ErrBookingStatusConflict) {
+		return &models.BookingResponse{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Booking changed while it was being cancelled, please try again",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Invalidate cache for this flight's seats. The returned seats can also
+	// bring the flight back into searches it had dropped out of.
+	s.invalidateFlightCache(ctx, booking.FlightID)
+	s.returnInventorySeats(ctx, booking.FlightID, booking.SeatsBooked)
+	if err := s.cacheService.InvalidateRouteSearches(ctx, flight.Source, flight.Destination, flight.Timestamp); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flight.ID, err)
+	}
+
+	// Return the customer's money only once the cancellation is committed,
+	// so a rolled back cancellation never leaves a live booking unpaid
+	message := "Booking cancelled"
+	if booking.Status == models.BookingStatusCompleted && !refundable {
+		message = "Booking cancelled without refund: fare is non-refundable"
+	} else if err := s.reversePaymentWithRetry(ctx, booking); err != nil {
+		log.Printf("Failed to reverse payment %s of cancelled booking %d: %v", booking.PaymentReferenceID, booking.ID, err)
+		message = "Booking cancelled, but the refund could not be issued yet"
+	}
+
+	return &models.BookingResponse{
+		BookingID:          booking.ID,
+		Status:             models.BookingStatusCancelled,
+		PaymentReferenceID: booking.PaymentReferenceID,
+		Message:            message,
+	}, nil
+}
+
+// reversePaymentWithRetry reverses the payment of a cancelled booking,
+// retrying with backoff as the booking can no longer be rolled back
+func (s *BookingService) reversePaymentWithRetry(ctx context.Context, booking *models.Booking) error {
+	var err error
+	for attempt := 1; attempt <= compensationMaxAttempts; attempt++ {
+		err = s.reversePayment(ctx, booking)
+		if err == nil {
+			paymentReversalsTotal.WithLabelValues("reversed").Inc()
+			return nil
+		}
+
+		log.Printf("Payment reversal attempt %d for booking %d failed: %v", attempt, booking.ID, err)
+		if attempt == compensationMaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			paymentReversalsTotal.WithLabelValues("failed").Inc()
+			return ctx.Err()
+		case <-time.After(compensationBackoff * time.Duration(attempt)):
+		}
+	}
+
+	paymentReversalsTotal.WithLabelValues("failed").Inc()
+	return err
+}
+
+// reversePayment refunds a completed booking or voids the authorization of a
+// pending one, refunding it instead if it was captured in the meantime. A
+// pending booking may not have been authorized yet, so a gateway holding no
+// payment for it is not a failure; a payment captured for it later is
+// refunded when it fails to complete the cancelled booking.
+func (s *BookingService) reversePayment(ctx context.Context, booking *models.Booking) error {
+	if booking.Status == models.BookingStatusCompleted {
+		result, err := s.paymentGateway.Refund(ctx, booking.PaymentReferenceID, booking.BookingPrice)
+		if err != nil {
+			return err
+		}
+		if !result.IsApproved() {
+			return fmt.Errorf("refund declined: %s", result.Message)
+		}
+		return nil
+	}
+
+	err := voidOrRefund(ctx, s.paymentGateway, booking.PaymentReferenceID, booking.BookingPrice)
+	if errors.Is(err, ErrPaymentNotFound) {
+		return nil
+	}
+	return err
 }
 
 // GetBookingByID gets a booking by ID
@@ -261,8 +1425,3 @@ func generatePaymentReferenceID() string {
 	rand.Read(bytes)
 	return fmt.Sprintf("PAY-%x", bytes)
 }
-
-// simulatePaymentSuccess simulates payment success/failure (90% success rate)
-func simulatePaymentSuccess() bool {
-	return time.Now().UnixNano()%10 != 0
-}
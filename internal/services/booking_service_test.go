@@ -2,9 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
 	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
 )
 
 // mockBookingRepo implements BookingRepository for testing.
@@ -12,7 +20,10 @@ type mockBookingRepo struct {
 	createFn           func(ctx context.Context, booking *models.Booking) (*models.Booking, error)
 	getByIDFn          func(ctx context.Context, id int64) (*models.Booking, error)
 	getByUserFn        func(ctx context.Context, userID int64) ([]models.Booking, error)
-	updateStatusFn     func(ctx context.Context, bookingID int64, status models.BookingStatus, paymentRefID *string) error
+	updateStatusFn     func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error
+	failAndReleaseFn   func(ctx context.Context, bookingID int64, paymentRefID *string) error
+	getByPaymentRefFn  func(ctx context.Context, paymentRefID string) (*models.Booking, error)
+	getByOrderFn       func(ctx context.Context, orderID int64) ([]models.Booking, error)
 }
 
 func (m *mockBookingRepo) CreateBooking(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
@@ -36,17 +47,42 @@ func (m *mockBookingRepo) GetBookingsByUserID(ctx context.Context, userID int64)
 	return nil, nil
 }
 
-func (m *mockBookingRepo) UpdateBookingStatus(ctx context.Context, bookingID int64, status models.BookingStatus, paymentRefID *string) error {
+func (m *mockBookingRepo) UpdateBookingStatus(ctx context.Context, bookingID int64, from, to models.BookingStatus, paymentRefID *string) error {
 	if m.updateStatusFn != nil {
-		return m.updateStatusFn(ctx, bookingID, status, paymentRefID)
+		return m.updateStatusFn(ctx, bookingID, from, to, paymentRefID)
 	}
 	return nil
 }
 
+func (m *mockBookingRepo) FailBookingAndReleaseSeats(ctx context.Context, bookingID int64, paymentRefID *string) error {
+	if m.failAndReleaseFn != nil {
+		return m.failAndReleaseFn(ctx, bookingID, paymentRefID)
+	}
+	return nil
+}
+
+func (m *mockBookingRepo) GetBookingByPaymentReferenceID(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+	if m.getByPaymentRefFn != nil {
+		return m.getByPaymentRefFn(ctx, paymentRefID)
+	}
+	return nil, nil
+}
+
+func (m *mockBookingRepo) GetBookingsByOrderID(ctx context.Context, orderID int64) ([]models.Booking, error) {
+	if m.getByOrderFn != nil {
+		return m.getByOrderFn(ctx, orderID)
+	}
+	return nil, nil
+}
+
 // mockFlightRepoBooking implements FlightRepositoryBooking for testing.
 type mockFlightRepoBooking struct {
 	getByIDFn           func(ctx context.Context, id int64) (*models.Flight, error)
-	updateAvailableFn   func(ctx context.Context, flightID int64, seatsToBook int, version int) error
+	updateAvailableFn   func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error
+	releaseSeatsFn      func(ctx context.Context, flightID int64, seatsToRelease int) error
+	addPendingFn        func(ctx context.Context, bookingID int64, flightID int64, seats int) error
+	applyPendingFn      func(ctx context.Context, flightID int64) (int, error)
+	dropPendingFn       func(ctx context.Context, bookingID int64) (bool, error)
 }
 
 func (m *mockFlightRepoBooking) GetFlightByID(ctx context.Context, id int64) (*models.Flight, error) {
@@ -56,34 +92,128 @@ func (m *mockFlightRepoBooking) GetFlightByID(ctx context.Context, id int64) (*m
 	return nil, nil
 }
 
-func (m *mockFlightRepoBooking) UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int) error {
+func (m *mockFlightRepoBooking) UpdateAvailableSeats(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
 	if m.updateAvailableFn != nil {
-		return m.updateAvailableFn(ctx, flightID, seatsToBook, version)
+		return m.updateAvailableFn(ctx, flightID, seatsToBook, version, fence)
+	}
+	return nil
+}
+
+func (m *mockFlightRepoBooking) ReleaseSeats(ctx context.Context, flightID int64, seatsToRelease int) error {
+	if m.releaseSeatsFn != nil {
+		return m.releaseSeatsFn(ctx, flightID, seatsToRelease)
 	}
 	return nil
 }
 
+func (m *mockFlightRepoBooking) AddPendingSeatDeduction(ctx context.Context, bookingID int64, flightID int64, seats int) error {
+	if m.addPendingFn != nil {
+		return m.addPendingFn(ctx, bookingID, flightID, seats)
+	}
+	return nil
+}
+
+func (m *mockFlightRepoBooking) ApplyPendingSeatDeductions(ctx context.Context, flightID int64) (int, error) {
+	if m.applyPendingFn != nil {
+		return m.applyPendingFn(ctx, flightID)
+	}
+	return 0, nil
+}
+
+func (m *mockFlightRepoBooking) DropPendingSeatDeduction(ctx context.Context, bookingID int64) (bool, error) {
+	if m.dropPendingFn != nil {
+		return m.dropPendingFn(ctx, bookingID)
+	}
+	return false, nil
+}
+
 // mockFlightCacheBooking implements FlightCacheBooking for testing.
 type mockFlightCacheBooking struct {
-	acquireFn func(ctx context.Context, key string) (bool, error)
-	releaseFn func(ctx context.Context, key string) error
+	acquireFn func(ctx context.Context, flightID int64) (*models.FlightLock, error)
+	extendFn  func(ctx context.Context, lock *models.FlightLock) (bool, error)
+	releaseFn func(ctx context.Context, lock *models.FlightLock) error
+	joinQueueFn     func(ctx context.Context, flightID int64) (string, int64, error)
+	queuePositionFn func(ctx context.Context, flightID int64, ticket string) (int64, error)
+	leaveQueueFn    func(ctx context.Context, flightID int64, ticket string) error
+	reserveInventoryFn func(ctx context.Context, flightID int64, seats int) (bool, error)
+	returnInventoryFn  func(ctx context.Context, flightID int64, seats int) error
+	inventoryTrackedFn func(ctx context.Context, flightID int64) (bool, error)
+	claimQuoteFn   func(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error)
+	releaseQuoteFn func(ctx context.Context, quoteID string) error
 	deleteFn  func(ctx context.Context, flightID int64) error
+	invalidateFn func(ctx context.Context, flightID int64) error
+	invalidateRouteFn func(ctx context.Context, source, destination string, departure time.Time) error
+	markFn    func(ctx context.Context, eventID string) (bool, error)
+	clearFn   func(ctx context.Context, eventID string) error
 }
 
-func (m *mockFlightCacheBooking) AcquireFlightLock(ctx context.Context, key string) (bool, error) {
+func (m *mockFlightCacheBooking) AcquireFlightLock(ctx context.Context, flightID int64) (*models.FlightLock, error) {
 	if m.acquireFn != nil {
-		return m.acquireFn(ctx, key)
+		return m.acquireFn(ctx, flightID)
+	}
+	return &models.FlightLock{FlightID: flightID, Token: "token", Fence: 1}, nil
+}
+
+func (m *mockFlightCacheBooking) ExtendFlightLock(ctx context.Context, lock *models.FlightLock) (bool, error) {
+	if m.extendFn != nil {
+		return m.extendFn(ctx, lock)
 	}
 	return true, nil
 }
 
-func (m *mockFlightCacheBooking) ReleaseFlightLock(ctx context.Context, key string) error {
+func (m *mockFlightCacheBooking) ReleaseFlightLock(ctx context.Context, lock *models.FlightLock) error {
 	if m.releaseFn != nil {
-		return m.releaseFn(ctx, key)
+		return m.releaseFn(ctx, lock)
+	}
+	return nil
+}
+
+func (m *mockFlightCacheBooking) JoinBookingQueue(ctx context.Context, flightID int64) (string, int64, error) {
+	if m.joinQueueFn != nil {
+		return m.joinQueueFn(ctx, flightID)
+	}
+	return "ticket", 0, nil
+}
+
+func (m *mockFlightCacheBooking) BookingQueuePosition(ctx context.Context, flightID int64, ticket string) (int64, error) {
+	if m.queuePositionFn != nil {
+		return m.queuePositionFn(ctx, flightID, ticket)
+	}
+	return 0, nil
+}
+
+func (m *mockFlightCacheBooking) LeaveBookingQueue(ctx context.Context, flightID int64, ticket string) error {
+	if m.leaveQueueFn != nil {
+		return m.leaveQueueFn(ctx, flightID, ticket)
+	}
+	return nil
+}
+
+func (m *mockFlightCacheBooking) BookingQueueLength(ctx context.Context, flightID int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockFlightCacheBooking) ReserveInventorySeats(ctx context.Context, flightID int64, seats int) (bool, error) {
+	if m.reserveInventoryFn != nil {
+		return m.reserveInventoryFn(ctx, flightID, seats)
+	}
+	return false, cache.ErrSeatInventoryNotTracked
+}
+
+func (m *mockFlightCacheBooking) ReturnInventorySeats(ctx context.Context, flightID int64, seats int) error {
+	if m.returnInventoryFn != nil {
+		return m.returnInventoryFn(ctx, flightID, seats)
 	}
 	return nil
 }
 
+func (m *mockFlightCacheBooking) IsInventoryTracked(ctx context.Context, flightID int64) (bool, error) {
+	if m.inventoryTrackedFn != nil {
+		return m.inventoryTrackedFn(ctx, flightID)
+	}
+	return false, nil
+}
+
 func (m *mockFlightCacheBooking) DeleteCachedSeats(ctx context.Context, flightID int64) error {
 	if m.deleteFn != nil {
 		return m.deleteFn(ctx, flightID)
@@ -91,10 +221,53 @@ func (m *mockFlightCacheBooking) DeleteCachedSeats(ctx context.Context, flightID
 	return nil
 }
 
+func (m *mockFlightCacheBooking) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	if m.invalidateFn != nil {
+		return m.invalidateFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockFlightCacheBooking) InvalidateRouteSearches(ctx context.Context, source, destination string, departure time.Time) error {
+	if m.invalidateRouteFn != nil {
+		return m.invalidateRouteFn(ctx, source, destination, departure)
+	}
+	return nil
+}
+
+func (m *mockFlightCacheBooking) ClaimQuote(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error) {
+	if m.claimQuoteFn != nil {
+		return m.claimQuoteFn(ctx, quoteID, expiresAt)
+	}
+	return true, nil
+}
+
+func (m *mockFlightCacheBooking) ReleaseQuote(ctx context.Context, quoteID string) error {
+	if m.releaseQuoteFn != nil {
+		return m.releaseQuoteFn(ctx, quoteID)
+	}
+	return nil
+}
+
+func (m *mockFlightCacheBooking) MarkWebhookEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	if m.markFn != nil {
+		return m.markFn(ctx, eventID)
+	}
+	return true, nil
+}
+
+func (m *mockFlightCacheBooking) ClearWebhookEvent(ctx context.Context, eventID string) error {
+	if m.clearFn != nil {
+		return m.clearFn(ctx, eventID)
+	}
+	return nil
+}
+
 // mockProducer implements Producer for testing.
 type mockProducer struct {
 	sendSeatFn    func(ctx context.Context, event *models.SeatUpdateEvent) error
 	sendPaymentFn func(ctx context.Context, event *models.PaymentEvent) error
+	sendCancelFn  func(ctx context.Context, event *models.BookingCancellationEvent) error
 }
 
 func (m *mockProducer) SendSeatUpdateEvent(ctx context.Context, event *models.SeatUpdateEvent) error {
@@ -111,6 +284,62 @@ func (m *mockProducer) SendPaymentEvent(ctx context.Context, event *models.Payme
 	return nil
 }
 
+func (m *mockProducer) SendBookingCancellationEvent(ctx context.Context, event *models.BookingCancellationEvent) error {
+	if m.sendCancelFn != nil {
+		return m.sendCancelFn(ctx, event)
+	}
+	return nil
+}
+
+// mockPaymentGateway implements PaymentGateway for testing.
+type mockPaymentGateway struct {
+	authorizeFn func(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error)
+	captureFn   func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error)
+	refundFn    func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error)
+	voidFn      func(ctx context.Context, paymentRefID string) (*models.PaymentResult, error)
+}
+
+func (m *mockPaymentGateway) Authorize(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error) {
+	if m.authorizeFn != nil {
+		return m.authorizeFn(ctx, req)
+	}
+	return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+}
+
+func (m *mockPaymentGateway) Capture(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+	if m.captureFn != nil {
+		return m.captureFn(ctx, paymentRefID, amount)
+	}
+	return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+}
+
+func (m *mockPaymentGateway) Refund(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+	if m.refundFn != nil {
+		return m.refundFn(ctx, paymentRefID, amount)
+	}
+	return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+}
+
+func (m *mockPaymentGateway) Void(ctx context.Context, paymentRefID string) (*models.PaymentResult, error) {
+	if m.voidFn != nil {
+		return m.voidFn(ctx, paymentRefID)
+	}
+	return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+}
+
+// mockTxManager implements TxManager for testing by running fn directly.
+type mockTxManager struct {
+	calls int
+	open  bool
+}
+
+func (m *mockTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	m.open = true
+	defer func() { m.open = false }()
+	return fn(ctx)
+}
+
 func TestBookingService_CreateBooking_InvalidRequest(t *testing.T) {
 	svc := &BookingService{}
 
@@ -204,6 +433,13 @@ func TestBookingService_CreateBooking_FlightNotBookable(t *testing.T) {
 	}
 }
 
+// queueConfig tells queued bookings to retry every 500ms
+var queueConfig = &config.AppConfig{
+	BookingQueuePollInterval: 500 * time.Millisecond,
+	BookingQueueTicketTTL:    5 * time.Second,
+	BookingQueueMaxLength:    10,
+}
+
 func TestBookingService_CreateBooking_LockNotAcquired(t *testing.T) {
 	bookingRepo := &mockBookingRepo{}
 	flightRepo := &mockFlightRepoBooking{
@@ -217,9 +453,22 @@ func TestBookingService_CreateBooking_LockNotAcquired(t *testing.T) {
 			}, nil
 		},
 	}
+	left := ""
 	cache := &mockFlightCacheBooking{
-		acquireFn: func(ctx context.Context, key string) (bool, error) {
-			return false, nil
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			t.Fatalf("did not expect a request behind others to take the lock")
+			return nil, nil
+		},
+		joinQueueFn: func(ctx context.Context, flightID int64) (string, int64, error) {
+			return "ticket-1", 3, nil
+		},
+		queuePositionFn: func(ctx context.Context, flightID int64, ticket string) (int64, error) {
+			t.Fatalf("did not expect a new request to have a position to check")
+			return 0, nil
+		},
+		leaveQueueFn: func(ctx context.Context, flightID int64, ticket string) error {
+			left = ticket
+			return nil
 		},
 	}
 	producer := &mockProducer{}
@@ -229,6 +478,7 @@ func TestBookingService_CreateBooking_LockNotAcquired(t *testing.T) {
 		flightRepo:    flightRepo,
 		cacheService:  cache,
 		kafkaProducer: producer,
+		config:        queueConfig,
 	}
 
 	req := &models.BookingRequest{
@@ -245,8 +495,133 @@ func TestBookingService_CreateBooking_LockNotAcquired(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if resp.Status != models.BookingStatusFailed {
-		t.Fatalf("expected failed status, got %s", resp.Status)
+	if resp.Status != models.BookingStatusFailed || resp.QueuePosition != 3 || resp.QueueTicket != "ticket-1" || resp.RetryAfterMs != 500 {
+		t.Fatalf("expected a ticket with 3 requests ahead, got %+v", resp)
+	}
+
+	if left != "" {
+		t.Fatalf("expected the request to keep its place in the queue, but it left with %q", left)
+	}
+}
+
+func TestBookingService_CreateBooking_QueueFull(t *testing.T) {
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled}, nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			t.Fatalf("did not expect a request turned away to take the lock")
+			return nil, nil
+		},
+		joinQueueFn: func(ctx context.Context, flightID int64) (string, int64, error) {
+			return "", -1, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   &mockBookingRepo{},
+		flightRepo:    flightRepo,
+		cacheService:  cache,
+		kafkaProducer: &mockProducer{},
+		config:        queueConfig,
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.QueueTicket != "" {
+		t.Fatalf("expected the request to be turned away without a ticket, got %+v", resp)
+	}
+}
+
+func TestBookingService_CreateBooking_RetriesWithTicketUntilItsTurn(t *testing.T) {
+	positions := []int64{1, 0, 0}
+	var events []string
+
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled}, nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		joinQueueFn: func(ctx context.Context, flightID int64) (string, int64, error) {
+			events = append(events, "join")
+			return "ticket-1", 2, nil
+		},
+		queuePositionFn: func(ctx context.Context, flightID int64, ticket string) (int64, error) {
+			position := positions[0]
+			positions = positions[1:]
+			events = append(events, fmt.Sprintf("position %d", position))
+			return position, nil
+		},
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			events = append(events, "acquire")
+			// The previous holder lets go between the two retries at the head
+			if len(positions) > 0 {
+				return nil, nil
+			}
+			return &models.FlightLock{FlightID: flightID}, nil
+		},
+		leaveQueueFn: func(ctx context.Context, flightID int64, ticket string) error {
+			events = append(events, "leave")
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+				booking.ID = 1
+				return booking, nil
+			},
+		},
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+		config:         queueConfig,
+	}
+
+	req := &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	}
+
+	var resp *models.BookingResponse
+	for attempt := 0; attempt < 4; attempt++ {
+		var err error
+		resp, err = svc.CreateBooking(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.QueueTicket == "" {
+			break
+		}
+		req.QueueTicket = resp.QueueTicket
+	}
+
+	if resp.Status != models.BookingStatusPending {
+		t.Fatalf("expected the booking to go ahead once at the head of the queue, got %+v", resp)
+	}
+
+	expected := "join,position 1,position 0,acquire,position 0,acquire,leave"
+	if got := strings.Join(events, ","); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
 	}
 }
 
@@ -326,24 +701,34 @@ func TestBookingService_CreateBooking_SuccessBasicFlow(t *testing.T) {
 				Version:        1,
 			}, nil
 		},
-		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int) error {
+		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
 			seatsUpdated = true
 			return nil
 		},
 	}
+	searchesInvalidated := false
 	cache := &mockFlightCacheBooking{
 		deleteFn: func(ctx context.Context, flightID int64) error {
 			cacheDeleted = true
 			return nil
 		},
+		invalidateFn: func(ctx context.Context, flightID int64) error {
+			searchesInvalidated = flightID == 1
+			return nil
+		},
 	}
 	producer := &mockProducer{}
 
 	svc := &BookingService{
-		bookingRepo:   bookingRepo,
-		flightRepo:    flightRepo,
-		cacheService:  cache,
-		kafkaProducer: producer,
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  producer,
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
 	}
 
 	req := &models.BookingRequest{
@@ -365,8 +750,1738 @@ func TestBookingService_CreateBooking_SuccessBasicFlow(t *testing.T) {
 		t.Fatalf("expected pending status, got %s", resp.Status)
 	}
 
-	if !bookingCreated || !seatsUpdated || !cacheDeleted {
-		t.Fatalf("expected bookingCreated=%v, seatsUpdated=%v, cacheDeleted=%v to all be true", bookingCreated, seatsUpdated, cacheDeleted)
+	if !bookingCreated || !seatsUpdated || !cacheDeleted || !searchesInvalidated {
+		t.Fatalf("expected bookingCreated=%v, seatsUpdated=%v, cacheDeleted=%v, searchesInvalidated=%v to all be true", bookingCreated, seatsUpdated, cacheDeleted, searchesInvalidated)
+	}
+}
+
+func TestBookingService_CreateBooking_ExtendsLockAndFencesSeatUpdate(t *testing.T) {
+	var mu sync.Mutex
+	extended := 0
+	var fenced int64
+	var released *models.FlightLock
+
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled}, nil
+		},
+		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
+			fenced = fence
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			return &models.FlightLock{FlightID: flightID, Token: "owner", Fence: 42, TTL: 30 * time.Millisecond}, nil
+		},
+		extendFn: func(ctx context.Context, lock *models.FlightLock) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if released != nil {
+				t.Errorf("did not expect a released lock to be extended")
+			}
+			extended++
+			return true, nil
+		},
+		releaseFn: func(ctx context.Context, lock *models.FlightLock) error {
+			mu.Lock()
+			defer mu.Unlock()
+			released = lock
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			// Outlast the lock TTL so the lock has to be extended
+			createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+				time.Sleep(60 * time.Millisecond)
+				booking.ID = 1
+				return booking, nil
+			},
+		},
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	req := &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending || fenced != 42 {
+		t.Fatalf("expected seats reserved under fencing token 42, got fence=%d response=%+v", fenced, resp)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if extended == 0 || released == nil || released.Token != "owner" {
+		t.Fatalf("expected the lock extended while held and released by its owner, got extended=%d released=%+v", extended, released)
+	}
+}
+
+func TestBookingService_CreateBooking_SeatReservationRollsBack(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = 1
+			return booking, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			t.Fatalf("did not expect a compensating status update inside a transaction")
+			return nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{
+				ID:             id,
+				AvailableSeats: 10,
+				TotalSeats:     10,
+				Price:          100,
+				FlightStatus:   models.FlightStatusScheduled,
+				Version:        1,
+			}, nil
+		},
+		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
+			return errors.New("optimistic lock failed or insufficient seats")
+		},
+	}
+	txManager := &mockTxManager{}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      txManager,
+	}
+
+	req := &models.BookingRequest{
+		FlightID:    1,
+		UserID:      123,
+		SeatsBooked: 1,
+		PassengerDetails: []models.PassengerDetails{
+			{Name: "John"},
+		},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.BookingID != 0 {
+		t.Fatalf("expected failed response without a booking id, got %+v", resp)
+	}
+
+	if txManager.calls != 1 {
+		t.Fatalf("expected booking to be created in one transaction, got %d", txManager.calls)
+	}
+}
+
+func TestBookingService_CreateBooking_AssignsSelectedSeats(t *testing.T) {
+	var assigned []string
+	var storedPassengers []models.PassengerDetails
+
+	bookingRepo := &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = 1
+			storedPassengers = booking.BookingMetadata
+			return booking, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		assignSeatsFn: func(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+			assigned = seatNumbers
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       seatRepo,
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	req := &models.BookingRequest{
+		FlightID:    1,
+		UserID:      123,
+		SeatsBooked: 2,
+		PassengerDetails: []models.PassengerDetails{
+			{Name: "John", SeatNumber: "12a"},
+			{Name: "Jane", SeatNumber: "12B"},
+		},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending {
+		t.Fatalf("expected pending status, got %s", resp.Status)
+	}
+
+	if len(assigned) != 2 || assigned[0] != "12A" || assigned[1] != "12B" {
+		t.Fatalf("expected seats 12A and 12B to be assigned, got %v", assigned)
+	}
+
+	if storedPassengers[0].SeatNumber != "12A" {
+		t.Fatalf("expected normalized seat number to be stored, got %q", storedPassengers[0].SeatNumber)
+	}
+}
+
+func TestBookingService_CreateBooking_SelectedSeatTaken(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = 1
+			return booking, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		assignSeatsFn: func(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+			return repositories.ErrSeatsUnavailable
+		},
+	}
+	gateway := &mockPaymentGateway{
+		authorizeFn: func(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect payment for a rolled back booking")
+			return nil, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       seatRepo,
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	req := &models.BookingRequest{
+		FlightID:    1,
+		UserID:      123,
+		SeatsBooked: 1,
+		PassengerDetails: []models.PassengerDetails{
+			{Name: "John", SeatNumber: "12A"},
+		},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.BookingID != 0 {
+		t.Fatalf("expected failed response without a booking id, got %+v", resp)
+	}
+}
+
+func TestBookingService_CreateBooking_DuplicateSelectedSeats(t *testing.T) {
+	svc := &BookingService{}
+
+	req := &models.BookingRequest{
+		FlightID:    1,
+		UserID:      123,
+		SeatsBooked: 2,
+		PassengerDetails: []models.PassengerDetails{
+			{Name: "John", SeatNumber: "12A"},
+			{Name: "Jane", SeatNumber: "12a"},
+		},
+	}
+
+	if _, err := svc.CreateBooking(context.Background(), req); err == nil {
+		t.Fatalf("expected error for duplicate seat selection, got nil")
+	}
+}
+
+func TestBookingService_CreateBooking_PricesByFareClass(t *testing.T) {
+	var stored *models.Booking
+	var reservedID int64
+	reservedVersion := 0
+	var assignedCabin models.SeatCabin
+
+	bookingRepo := &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = 1
+			stored = booking
+			return booking, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		getFn: func(ctx context.Context, flightID int64, code string) (*models.FareClass, error) {
+			if code != "J" {
+				t.Fatalf("expected normalized fare class code, got %q", code)
+			}
+			return &models.FareClass{ID: 5, FlightID: flightID, Code: code, Cabin: models.SeatCabinBusiness, Price: 400, TotalSeats: 4, AvailableSeats: 4, Version: 3}, nil
+		},
+		reserveFn: func(ctx context.Context, fareClassID int64, seatsToBook int, version int) error {
+			reservedID = fareClassID
+			reservedVersion = version
+			return nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		assignSeatsFn: func(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+			assignedCabin = cabin
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       seatRepo,
+		fareClassRepo:  fareClassRepo,
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	req := &models.BookingRequest{
+		FlightID:      1,
+		UserID:        123,
+		SeatsBooked:   2,
+		FareClassCode: "j",
+		PassengerDetails: []models.PassengerDetails{
+			{Name: "John", SeatNumber: "1A"},
+			{Name: "Jane"},
+		},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending {
+		t.Fatalf("expected pending status, got %s (%s)", resp.Status, resp.Message)
+	}
+
+	if stored.BookingPrice != 800 || stored.FareClassCode != "J" {
+		t.Fatalf("expected booking priced at the fare class, got price=%v class=%q", stored.BookingPrice, stored.FareClassCode)
+	}
+
+	if reservedID != 5 || reservedVersion != 3 {
+		t.Fatalf("expected fare class 5 to be reserved at version 3, got %d at version %d", reservedID, reservedVersion)
+	}
+
+	if assignedCabin != models.SeatCabinBusiness {
+		t.Fatalf("expected seats to be restricted to the business cabin, got %q", assignedCabin)
+	}
+}
+
+func TestBookingService_CreateBooking_ChargesQuotedPrice(t *testing.T) {
+	var stored *models.Booking
+
+	bookingRepo := &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = 1
+			stored = booking
+			return booking, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+		},
+	}
+	pricing := &mockPriceQuoter{
+		quoteFn: func(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+			return 137.5
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        pricing,
+		txManager:      &mockTxManager{},
+	}
+
+	req := &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      2,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	}
+
+	if _, err := svc.CreateBooking(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stored.BookingPrice != 275 {
+		t.Fatalf("expected booking priced at the quoted fare, got %v", stored.BookingPrice)
+	}
+}
+
+// newItineraryTestService builds a booking service selling the legs of an
+// itinerary, with up to 10 seats left on each
+func newItineraryTestService(legs []models.Flight, reserved *[]int64, cache *mockFlightCacheBooking) (*BookingService, *mockTxManager) {
+	txManager := &mockTxManager{}
+	return &BookingService{
+		orderRepo: &mockOrderRepo{},
+		bookingRepo: &mockBookingRepo{
+			createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+				booking.ID = int64(len(*reserved) + 1)
+				return booking, nil
+			},
+		},
+		flightRepo: &mockFlightRepoBooking{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+				for _, leg := range legs {
+					if leg.ID == id {
+						flight := leg
+						return &flight, nil
+					}
+				}
+				return nil, errors.New("flight not found")
+			},
+			updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
+				if flightID == 3 {
+					return errors.New("optimistic lock failed or insufficient seats")
+				}
+				*reserved = append(*reserved, flightID)
+				return nil
+			},
+		},
+		cacheService:   cache,
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      txManager,
+		config:         itineraryConfig,
+	}, txManager
+}
+
+func newItineraryBookingRequest(flightIDs ...int64) *models.ItineraryBookingRequest {
+	req := &models.ItineraryBookingRequest{
+		UserID:           123,
+		SeatsBooked:      2,
+		PassengerDetails: []models.PassengerDetails{{Name: "John Doe"}, {Name: "Jane Doe"}},
+	}
+	for _, flightID := range flightIDs {
+		req.Legs = append(req.Legs, models.ItineraryLeg{FlightID: flightID})
+	}
+	return req
+}
+
+func TestBookingService_CreateItineraryBooking_ReservesEveryLeg(t *testing.T) {
+	legs := []models.Flight{
+		testLeg(2, "Delhi", "Mumbai", 8),
+		testLeg(1, "Mumbai", "Chennai", 12),
+	}
+	var reserved []int64
+	var locked, released []int64
+	cache := &mockFlightCacheBooking{
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			locked = append(locked, flightID)
+			return &models.FlightLock{FlightID: flightID, Fence: int64(len(locked))}, nil
+		},
+		releaseFn: func(ctx context.Context, lock *models.FlightLock) error {
+			released = append(released, lock.FlightID)
+			return nil
+		},
+	}
+	svc, txManager := newItineraryTestService(legs, &reserved, cache)
+
+	var bookings []models.Booking
+	svc.bookingRepo = &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = int64(len(bookings) + 1)
+			bookings = append(bookings, *booking)
+			return booking, nil
+		},
+	}
+
+	resp, err := svc.CreateItineraryBooking(context.Background(), newItineraryBookingRequest(2, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending || len(resp.BookingIDs) != 2 || resp.TotalPrice != 400 || resp.OrderID != 7 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// The legs are paid together as the segments of one order
+	for _, booking := range bookings {
+		if booking.OrderID != 7 || booking.PaymentReferenceID != resp.PaymentReferenceID {
+			t.Fatalf("expected every leg to carry order 7 and its payment reference, got %+v", booking)
+		}
+	}
+
+	if len(reserved) != 2 || reserved[0] != 2 || reserved[1] != 1 || txManager.calls != 1 {
+		t.Fatalf("expected both legs reserved in travel order in one transaction, got %v in %d", reserved, txManager.calls)
+	}
+
+	if len(locked) != 2 || locked[0] != 1 || len(released) != 2 {
+		t.Fatalf("expected both flights locked in flight ID order and released, got locked=%v released=%v", locked, released)
+	}
+}
+
+func TestBookingService_CreateItineraryBooking_Failures(t *testing.T) {
+	legs := []models.Flight{
+		testLeg(1, "Delhi", "Mumbai", 8),
+		testLeg(2, "Mumbai", "Chennai", 10.5),
+		testLeg(3, "Mumbai", "Chennai", 12),
+		testLeg(4, "Mumbai", "Pune", 12),
+	}
+
+	tests := []struct {
+		name      string
+		flightIDs []int64
+		message   string
+	}{
+		{name: "layover too short", flightIDs: []int64{1, 2}, message: "Flights do not form a valid connection"},
+		{name: "legs do not connect", flightIDs: []int64{4, 3}, message: "Flights do not form a valid connection"},
+		{name: "second leg cannot be reserved", flightIDs: []int64{1, 3}, message: "Failed to reserve seats"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reserved []int64
+			svc, _ := newItineraryTestService(legs, &reserved, &mockFlightCacheBooking{})
+
+			resp, err := svc.CreateItineraryBooking(context.Background(), newItineraryBookingRequest(tt.flightIDs...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Status != models.BookingStatusFailed || resp.Message != tt.message || len(resp.BookingIDs) != 0 {
+				t.Fatalf("expected failure %q, got %+v", tt.message, resp)
+			}
+		})
+	}
+}
+
+func TestBookingService_CreateItineraryBooking_ReleasesLocksWhenBusy(t *testing.T) {
+	legs := []models.Flight{
+		testLeg(1, "Delhi", "Mumbai", 8),
+		testLeg(2, "Mumbai", "Chennai", 12),
+	}
+	busy := int64(2)
+	var released []int64
+	cache := &mockFlightCacheBooking{
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			if flightID == busy {
+				return nil, nil
+			}
+			return &models.FlightLock{FlightID: flightID}, nil
+		},
+		releaseFn: func(ctx context.Context, lock *models.FlightLock) error {
+			released = append(released, lock.FlightID)
+			return nil
+		},
+	}
+	var reserved []int64
+	svc, _ := newItineraryTestService(legs, &reserved, cache)
+
+	resp, err := svc.CreateItineraryBooking(context.Background(), newItineraryBookingRequest(1, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || len(reserved) != 0 {
+		t.Fatalf("expected booking to fail without reserving seats, got %+v", resp)
+	}
+
+	if len(released) != 1 || released[0] == busy {
+		t.Fatalf("expected only the acquired lock to be released, got %v", released)
+	}
+}
+
+// newQuoteTestService builds a booking service for flight 1 with 10 free
+// seats, quoting pricePerSeat
+func newQuoteTestService(pricePerSeat float64, created *bool) *BookingService {
+	return &BookingService{
+		bookingRepo: &mockBookingRepo{
+			createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+				*created = true
+				booking.ID = 1
+				return booking, nil
+			},
+		},
+		flightRepo: &mockFlightRepoBooking{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+				return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+			},
+		},
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing: &mockPriceQuoter{
+			quoteFn: func(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+				return pricePerSeat
+			},
+		},
+		txManager: &mockTxManager{},
+		config:    &config.AppConfig{QuoteSigningSecret: "secret", QuoteTTL: time.Minute},
+	}
+}
+
+func TestBookingService_CreateQuote_SignsQuotedPrice(t *testing.T) {
+	created := false
+	svc := newQuoteTestService(120, &created)
+
+	resp, err := svc.CreateQuote(context.Background(), &models.QuoteRequest{UserID: 123, FlightID: 1, Seats: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.PricePerSeat != 120 || resp.TotalPrice != 360 || resp.QuoteToken == "" {
+		t.Fatalf("unexpected quote: %+v", resp)
+	}
+
+	quote, err := svc.verifyQuoteToken(resp.QuoteToken)
+	if err != nil || !quote.Matches(1, "", 3) || !quote.IssuedTo(123) || quote.IssuedTo(456) {
+		t.Fatalf("expected token to carry the quote, got %+v (%v)", quote, err)
+	}
+}
+
+func TestBookingService_CreateQuote_Errors(t *testing.T) {
+	created := false
+	svc := newQuoteTestService(120, &created)
+
+	if _, err := svc.CreateQuote(context.Background(), &models.QuoteRequest{UserID: 123, FlightID: 1, Seats: 11}); !errors.Is(err, models.ErrNotQuotable) {
+		t.Fatalf("expected ErrNotQuotable, got %v", err)
+	}
+
+	if _, err := svc.CreateQuote(context.Background(), &models.QuoteRequest{FlightID: 1, Seats: 1}); err == nil || errors.Is(err, models.ErrNotQuotable) {
+		t.Fatalf("expected a quote without a user to be invalid, got %v", err)
+	}
+}
+
+func TestBookingService_CreateBooking_QuoteToken(t *testing.T) {
+	quoted := newQuoteTestService(120, new(bool))
+	quote, err := quoted.CreateQuote(context.Background(), &models.QuoteRequest{UserID: 123, FlightID: 1, Seats: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		currentPrice float64
+		baseFare     float64
+		token        string
+		seats        int
+		status       models.BookingStatus
+		currentShown float64
+		charged      float64
+	}{
+		{name: "price unchanged", currentPrice: 120, baseFare: 100, token: quote.QuoteToken, seats: 2, status: models.BookingStatusPending, charged: 240},
+		{name: "demand moved the price", currentPrice: 150, baseFare: 100, token: quote.QuoteToken, seats: 2, status: models.BookingStatusPending, charged: 240},
+		{name: "base fare changed", currentPrice: 150, baseFare: 125, token: quote.QuoteToken, seats: 2, status: models.BookingStatusFailed, currentShown: 150},
+		{name: "seats differ from quote", currentPrice: 120, baseFare: 100, token: quote.QuoteToken, seats: 3, status: models.BookingStatusFailed},
+		{name: "forged token", currentPrice: 120, baseFare: 100, token: "e30.c2ln", seats: 2, status: models.BookingStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			svc := newQuoteTestService(tt.currentPrice, &created)
+			svc.flightRepo = &mockFlightRepoBooking{
+				getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+					return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: tt.baseFare, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+				},
+			}
+			var charged float64
+			svc.bookingRepo = &mockBookingRepo{
+				createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+					created, charged = true, booking.BookingPrice
+					booking.ID = 1
+					return booking, nil
+				},
+			}
+
+			passengers := make([]models.PassengerDetails, tt.seats)
+			req := &models.BookingRequest{
+				FlightID:         1,
+				UserID:           123,
+				SeatsBooked:      tt.seats,
+				QuoteToken:       tt.token,
+				PassengerDetails: passengers,
+			}
+
+			resp, err := svc.CreateBooking(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Status != tt.status || resp.CurrentPrice != tt.currentShown {
+				t.Fatalf("expected %s with current price %v, got %s with %v (%s)", tt.status, tt.currentShown, resp.Status, resp.CurrentPrice, resp.Message)
+			}
+
+			if created != (tt.status == models.BookingStatusPending) {
+				t.Fatalf("expected booking created=%v, got %v", tt.status == models.BookingStatusPending, created)
+			}
+
+			if charged != tt.charged {
+				t.Fatalf("expected %v charged, got %v", tt.charged, charged)
+			}
+		})
+	}
+}
+
+func TestBookingService_CreateBooking_QuoteTokenIsSingleUse(t *testing.T) {
+	quoted := newQuoteTestService(120, new(bool))
+	quote, err := quoted.CreateQuote(context.Background(), &models.QuoteRequest{UserID: 123, FlightID: 1, Seats: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A quote issued with search results is for any user and seat count
+	searchQuote := &models.PriceQuote{FlightID: 1, BaseFare: 100, PricePerSeat: 120}
+	searchToken, err := issueQuote([]byte("secret"), time.Minute, searchQuote, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		userID  int64
+		claimed bool
+		created bool
+		status  models.BookingStatus
+	}{
+		{name: "quoted user", token: quote.QuoteToken, userID: 123, created: true, status: models.BookingStatusPending},
+		{name: "another user", token: quote.QuoteToken, userID: 456, status: models.BookingStatusFailed},
+		{name: "already used", token: quote.QuoteToken, userID: 123, claimed: true, status: models.BookingStatusFailed},
+		{name: "search quote", token: searchToken, userID: 456, created: true, status: models.BookingStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			svc := newQuoteTestService(150, &created)
+			var claimedID string
+			svc.cacheService = &mockFlightCacheBooking{
+				claimQuoteFn: func(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error) {
+					claimedID = quoteID
+					return !tt.claimed, nil
+				},
+			}
+
+			resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+				FlightID:         1,
+				UserID:           tt.userID,
+				SeatsBooked:      2,
+				QuoteToken:       tt.token,
+				PassengerDetails: make([]models.PassengerDetails, 2),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Status != tt.status || created != tt.created {
+				t.Fatalf("expected %s with booking created=%v, got %s with %v (%s)", tt.status, tt.created, resp.Status, created, resp.Message)
+			}
+			if tt.userID == 123 && claimedID == "" {
+				t.Fatal("expected the quote to be claimed")
+			}
+		})
+	}
+}
+
+func TestBookingService_CreateBooking_FailedBookingReleasesQuote(t *testing.T) {
+	created := false
+	svc := newQuoteTestService(120, &created)
+	quote, err := svc.CreateQuote(context.Background(), &models.QuoteRequest{UserID: 123, FlightID: 1, Seats: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var claimed, released string
+	svc.cacheService = &mockFlightCacheBooking{
+		claimQuoteFn: func(ctx context.Context, quoteID string, expiresAt time.Time) (bool, error) {
+			claimed = quoteID
+			return true, nil
+		},
+		releaseQuoteFn: func(ctx context.Context, quoteID string) error {
+			released = quoteID
+			return nil
+		},
+	}
+	svc.flightRepo = &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 1, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+		},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      2,
+		QuoteToken:       quote.QuoteToken,
+		PassengerDetails: make([]models.PassengerDetails, 2),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || claimed == "" || released != claimed {
+		t.Fatalf("expected the failed booking to release its quote, got %s (claimed %q, released %q)", resp.Status, claimed, released)
+	}
+}
+
+func TestBookingService_CreateBooking_FareClassFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		fareClassCode string
+		fareClassRepo *mockFareClassRepo
+		message       string
+	}{
+		{
+			name: "class required on flights sold by class",
+			fareClassRepo: &mockFareClassRepo{
+				getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+					return []models.FareClass{{FlightID: 1, Code: "Y"}}, nil
+				},
+			},
+			message: "A fare class is required for this flight",
+		},
+		{
+			name:          "unknown class",
+			fareClassCode: "Q",
+			fareClassRepo: &mockFareClassRepo{},
+			message:       "Fare class is not sold on this flight",
+		},
+		{
+			name:          "class sold out",
+			fareClassCode: "Y",
+			fareClassRepo: &mockFareClassRepo{
+				getFn: func(ctx context.Context, flightID int64, code string) (*models.FareClass, error) {
+					return &models.FareClass{ID: 1, Code: code, Cabin: models.SeatCabinEconomy, Price: 100, AvailableSeats: 1}, nil
+				},
+			},
+			message: "Insufficient seats available in fare class",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookingRepo := &mockBookingRepo{
+				createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+					t.Fatalf("did not expect a booking to be created")
+					return nil, nil
+				},
+			}
+			flightRepo := &mockFlightRepoBooking{
+				getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+					return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+				},
+			}
+
+			svc := &BookingService{
+				bookingRepo:   bookingRepo,
+				flightRepo:    flightRepo,
+				cacheService:  &mockFlightCacheBooking{},
+				fareClassRepo: tt.fareClassRepo,
+				pricing:       &mockPriceQuoter{},
+				txManager:     &mockTxManager{},
+			}
+
+			req := &models.BookingRequest{
+				FlightID:         1,
+				UserID:           123,
+				SeatsBooked:      2,
+				FareClassCode:    tt.fareClassCode,
+				PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+			}
+
+			resp, err := svc.CreateBooking(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Status != models.BookingStatusFailed || resp.Message != tt.message {
+				t.Fatalf("expected failed status with %q, got %s with %q", tt.message, resp.Status, resp.Message)
+			}
+		})
+	}
+}
+
+func TestBookingService_CancelBooking_ReleasesSeats(t *testing.T) {
+	var cancelledStatus models.BookingStatus
+	releasedSeats := 0
+	cacheDeleted := false
+	eventSent := false
+
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{
+				ID:                 id,
+				FlightID:           1,
+				UserID:             123,
+				Status:             models.BookingStatusCompleted,
+				PaymentReferenceID: "PAY-1",
+				SeatsBooked:        2,
+			}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			cancelledStatus = status
+			if paymentRefID == nil || *paymentRefID != "PAY-1" {
+				t.Fatalf("expected payment reference to be preserved, got %v", paymentRefID)
+			}
+			return nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, Source: "Delhi", Destination: "Mumbai", Timestamp: testDeparture, AvailableSeats: 8, TotalSeats: 10, Version: 4}, nil
+		},
+		releaseSeatsFn: func(ctx context.Context, flightID int64, seatsToRelease int) error {
+			releasedSeats = seatsToRelease
+			return nil
+		},
+	}
+	var invalidatedRoute string
+	cache := &mockFlightCacheBooking{
+		deleteFn: func(ctx context.Context, flightID int64) error {
+			cacheDeleted = true
+			return nil
+		},
+		invalidateRouteFn: func(ctx context.Context, source, destination string, departure time.Time) error {
+			invalidatedRoute = source + "#" + destination + "#" + departure.Format("2006-01-02")
+			return nil
+		},
+	}
+	producer := &mockProducer{
+		sendCancelFn: func(ctx context.Context, event *models.BookingCancellationEvent) error {
+			eventSent = event.SeatsReleased == 2 && event.FlightID == 1
+			return nil
+		},
+	}
+
+	refunded := false
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			refunded = paymentRefID == "PAY-1"
+			return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  producer,
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCancelled {
+		t.Fatalf("expected cancelled status, got %s", resp.Status)
+	}
+
+	if cancelledStatus != models.BookingStatusCancelled {
+		t.Fatalf("expected booking to be marked cancelled, got %s", cancelledStatus)
+	}
+
+	if releasedSeats != 2 {
+		t.Fatalf("expected 2 seats released, got %d", releasedSeats)
+	}
+
+	if !refunded || !cacheDeleted || !eventSent {
+		t.Fatalf("expected refunded=%v, cacheDeleted=%v, eventSent=%v to all be true", refunded, cacheDeleted, eventSent)
+	}
+
+	// Released seats can put the flight back into searches it had left
+	if invalidatedRoute != "Delhi#Mumbai#2025-01-20" {
+		t.Fatalf("expected cached searches of the flight's route and day dropped, got %q", invalidatedRoute)
+	}
+}
+
+func TestBookingService_CancelBooking_EventFailureRollsBack(t *testing.T) {
+	cacheDeleted := false
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 8, TotalSeats: 10, Version: 4}, nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		deleteFn: func(ctx context.Context, flightID int64) error {
+			cacheDeleted = true
+			return nil
+		},
+	}
+	producer := &mockProducer{
+		sendCancelFn: func(ctx context.Context, event *models.BookingCancellationEvent) error {
+			return errors.New("outbox unavailable")
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  producer,
+		paymentGateway: &mockPaymentGateway{},
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	if _, err := svc.CancelBooking(context.Background(), 7); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if cacheDeleted {
+		t.Fatalf("did not expect cache invalidation when the cancellation was rolled back")
+	}
+}
+
+func TestBookingService_CancelBooking_NotCancellable(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusCancelled, SeatsBooked: 2}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			t.Fatalf("did not expect status update for an already cancelled booking")
+			return nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		releaseSeatsFn: func(ctx context.Context, flightID int64, seatsToRelease int) error {
+			t.Fatalf("did not expect seats to be released")
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		flightRepo:    flightRepo,
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCancelled {
+		t.Fatalf("expected existing cancelled status, got %s", resp.Status)
+	}
+}
+
+func TestBookingService_CancelBooking_RefundDeclined(t *testing.T) {
+	compensationBackoff = time.Millisecond
+	defer func() { compensationBackoff = 500 * time.Millisecond }()
+
+	var events []string
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusCompleted, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			events = append(events, "cancel")
+			return nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 8, TotalSeats: 10, Version: 1}, nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			events = append(events, "refund")
+			return &models.PaymentResult{Status: models.PaymentStatusDeclined, Message: "refund declined"}, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The cancellation is committed first, then the refund is retried
+	want := []string{"cancel", "refund", "refund", "refund"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+
+	if resp.Status != models.BookingStatusCancelled || resp.Message != "Booking cancelled, but the refund could not be issued yet" {
+		t.Fatalf("expected a cancelled booking with a pending refund, got %+v", resp)
+	}
+}
+
+func TestBookingService_CancelBooking_PendingReversal(t *testing.T) {
+	compensationBackoff = time.Millisecond
+	defer func() { compensationBackoff = 500 * time.Millisecond }()
+
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+		message  string
+	}{
+		{name: "never authorized", err: fmt.Errorf("%w: PAY-1", ErrPaymentNotFound), attempts: 1, message: "Booking cancelled"},
+		{name: "gateway unavailable", err: errors.New("gateway timeout"), attempts: 3, message: "Booking cancelled, but the refund could not be issued yet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			voids := 0
+			gateway := &mockPaymentGateway{
+				voidFn: func(ctx context.Context, paymentRefID string) (*models.PaymentResult, error) {
+					voids++
+					return nil, tt.err
+				},
+				refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+					return nil, tt.err
+				},
+			}
+
+			svc := &BookingService{
+				bookingRepo: &mockBookingRepo{
+					getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+						return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+					},
+				},
+				flightRepo: &mockFlightRepoBooking{
+					getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+						return &models.Flight{ID: id, AvailableSeats: 8, TotalSeats: 10, Version: 1}, nil
+					},
+				},
+				cacheService:   &mockFlightCacheBooking{},
+				kafkaProducer:  &mockProducer{},
+				paymentGateway: gateway,
+				seatRepo:       &mockSeatRepo{},
+				fareClassRepo:  &mockFareClassRepo{},
+				pricing:        &mockPriceQuoter{},
+				txManager:      &mockTxManager{},
+			}
+
+			resp, err := svc.CancelBooking(context.Background(), 7)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if voids != tt.attempts || resp.Message != tt.message {
+				t.Fatalf("expected %d void attempts and %q, got %d and %q", tt.attempts, tt.message, voids, resp.Message)
+			}
+		})
+	}
+}
+
+func TestBookingService_CancelBooking_RolledBackCancellationKeepsPayment(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusCompleted, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 8, TotalSeats: 10, Version: 1}, nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect a refund when the cancellation rolled back")
+			return nil, nil
+		},
+	}
+	producer := &mockProducer{
+		sendCancelFn: func(ctx context.Context, event *models.BookingCancellationEvent) error {
+			return errors.New("outbox unavailable")
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  producer,
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	if _, err := svc.CancelBooking(context.Background(), 7); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestBookingService_CancelBooking_NonRefundableFare(t *testing.T) {
+	releasedClass := ""
+	releasedClassSeats := 0
+
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusCompleted, PaymentReferenceID: "PAY-1", SeatsBooked: 2, FareClassCode: "Y"}, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 8, TotalSeats: 10, Version: 1}, nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		getFn: func(ctx context.Context, flightID int64, code string) (*models.FareClass, error) {
+			return &models.FareClass{ID: 1, FlightID: flightID, Code: code, Refundable: false}, nil
+		},
+		releaseFn: func(ctx context.Context, flightID int64, code string, seatsToRelease int) error {
+			releasedClass = code
+			releasedClassSeats = seatsToRelease
+			return nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect a refund for a non-refundable fare")
+			return nil, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     flightRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  fareClassRepo,
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCancelled {
+		t.Fatalf("expected cancelled status, got %s", resp.Status)
+	}
+
+	if releasedClass != "Y" || releasedClassSeats != 2 {
+		t.Fatalf("expected 2 seats returned to fare class Y, got %d to %q", releasedClassSeats, releasedClass)
+	}
+}
+
+func TestBookingService_CancelBooking_RereadsBookingUnderLock(t *testing.T) {
+	reads := 0
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			reads++
+			status := models.BookingStatusPending
+			if reads > 1 {
+				// Expired while the cancellation waited for the lock
+				status = models.BookingStatusExpired
+			}
+			return &models.Booking{ID: id, FlightID: 1, Status: status, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			t.Fatalf("did not expect an expired booking to be cancelled")
+			return nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		voidFn: func(ctx context.Context, paymentRefID string) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect the payment of an expired booking to be voided again")
+			return nil, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		flightRepo:     &mockFlightRepoBooking{},
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusExpired || resp.Message != "Booking cannot be cancelled" {
+		t.Fatalf("expected the expired booking left alone, got %+v", resp)
+	}
+}
+
+func TestBookingService_SettleBookingPayment_RefundsUnclaimedPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  models.BookingStatus
+		refunded bool
+	}{
+		{name: "cancelled while capturing", current: models.BookingStatusCancelled, refunded: true},
+		{name: "expired while capturing", current: models.BookingStatusExpired, refunded: true},
+		{name: "completed by the webhook", current: models.BookingStatusCompleted, refunded: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookingRepo := &mockBookingRepo{
+				getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+					return &models.Booking{ID: id, Status: tt.current}, nil
+				},
+				updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+					if from != models.BookingStatusPending {
+						t.Fatalf("expected only a pending booking to be completed, got from=%s", from)
+					}
+					return repositories.ErrBookingStatusConflict
+				},
+			}
+			refunded := false
+			gateway := &mockPaymentGateway{
+				refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+					refunded = paymentRefID == "PAY-1" && amount == 250
+					return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+				},
+			}
+			producer := &mockProducer{
+				sendPaymentFn: func(ctx context.Context, event *models.PaymentEvent) error {
+					t.Fatalf("did not expect a payment event for a booking that was not completed")
+					return nil
+				},
+			}
+
+			svc := &BookingService{
+				bookingRepo:    bookingRepo,
+				cacheService:   &mockFlightCacheBooking{},
+				kafkaProducer:  producer,
+				paymentGateway: gateway,
+				seatRepo:       &mockSeatRepo{},
+				txManager:      &mockTxManager{},
+			}
+
+			booking := &models.Booking{ID: 1, FlightID: 1, PaymentReferenceID: "PAY-1", BookingPrice: 250, SeatsBooked: 2}
+			status, err := svc.settleBookingPayment(context.Background(), booking, "PAY-1", true)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if status != tt.current || refunded != tt.refunded {
+				t.Fatalf("expected status=%s refunded=%v, got status=%s refunded=%v", tt.current, tt.refunded, status, refunded)
+			}
+		})
+	}
+}
+
+func TestBookingService_ChargePayment_Outcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       PaymentOperation
+		outcome  FakePaymentOutcome
+		expected bool
+	}{
+		{"approved", PaymentOperationAuthorize, FakePaymentOutcomeApprove, true},
+		{"authorization declined", PaymentOperationAuthorize, FakePaymentOutcomeDecline, false},
+		{"authorization error", PaymentOperationAuthorize, FakePaymentOutcomeError, false},
+		{"capture declined", PaymentOperationCapture, FakePaymentOutcomeDecline, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := NewFakePaymentGateway(FakePaymentOutcomeApprove, 0)
+			gateway.SetOutcome(tt.op, tt.outcome)
+
+			svc := &BookingService{paymentGateway: gateway}
+
+			req := &models.PaymentRequest{BookingID: 1, PaymentReferenceID: "PAY-1", Amount: 200}
+			if got := svc.chargePayment(context.Background(), req); got != tt.expected {
+				t.Fatalf("expected chargePayment=%v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBookingService_ReleaseSeatsForFailedPayment_RetriesUntilSuccess(t *testing.T) {
+	compensationBackoff = time.Millisecond
+	defer func() { compensationBackoff = 500 * time.Millisecond }()
+
+	attempts := 0
+	cacheDeleted := false
+	bookingRepo := &mockBookingRepo{
+		failAndReleaseFn: func(ctx context.Context, bookingID int64, paymentRefID *string) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("db unavailable")
+			}
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		deleteFn: func(ctx context.Context, flightID int64) error {
+			cacheDeleted = true
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  cache,
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	booking := &models.Booking{ID: 1, FlightID: 1, SeatsBooked: 2}
+	if err := svc.releaseSeatsForFailedPayment(context.Background(), booking, "PAY-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attempts != 2 || !cacheDeleted {
+		t.Fatalf("expected 2 attempts and cache invalidation, got attempts=%d cacheDeleted=%v", attempts, cacheDeleted)
+	}
+}
+
+func TestBookingService_ReleaseSeatsForFailedPayment_GivesUp(t *testing.T) {
+	compensationBackoff = time.Millisecond
+	defer func() { compensationBackoff = 500 * time.Millisecond }()
+
+	attempts := 0
+	bookingRepo := &mockBookingRepo{
+		failAndReleaseFn: func(ctx context.Context, bookingID int64, paymentRefID *string) error {
+			attempts++
+			return errors.New("db unavailable")
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	booking := &models.Booking{ID: 1, FlightID: 1, SeatsBooked: 2}
+	if err := svc.releaseSeatsForFailedPayment(context.Background(), booking, "PAY-1"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if attempts != compensationMaxAttempts {
+		t.Fatalf("expected %d attempts, got %d", compensationMaxAttempts, attempts)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_Succeeded(t *testing.T) {
+	var updatedStatus models.BookingStatus
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID, SeatsBooked: 2}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			updatedStatus = status
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1"}
+	resp, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCompleted || updatedStatus != models.BookingStatusCompleted {
+		t.Fatalf("expected booking to be completed, got response=%s updated=%s", resp.Status, updatedStatus)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_FailedReleasesSeats(t *testing.T) {
+	released := false
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID, SeatsBooked: 2}, nil
+		},
+		failAndReleaseFn: func(ctx context.Context, bookingID int64, paymentRefID *string) error {
+			released = true
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventFailed, PaymentReferenceID: "PAY-1"}
+	resp, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || !released {
+		t.Fatalf("expected failed booking with released seats, got status=%s released=%v", resp.Status, released)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_DuplicateDelivery(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			t.Fatalf("did not expect a duplicate delivery to update the booking")
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		markFn: func(ctx context.Context, eventID string) (bool, error) {
+			return false, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  cache,
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1"}
+	resp, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending {
+		t.Fatalf("expected unchanged pending status, got %s", resp.Status)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_UnknownPaymentReference(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return nil, repositories.ErrBookingNotFound
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:  bookingRepo,
+		cacheService: &mockFlightCacheBooking{},
+		txManager:    &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-404"}
+	_, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if !errors.Is(err, models.ErrPaymentReferenceNotFound) {
+		t.Fatalf("expected ErrPaymentReferenceNotFound, got %v", err)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_AmountMismatch(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID, BookingPrice: 5000}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			t.Fatalf("did not expect an event with the wrong amount to settle the booking")
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		markFn: func(ctx context.Context, eventID string) (bool, error) {
+			t.Fatalf("did not expect an event with the wrong amount to be recorded")
+			return true, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:  bookingRepo,
+		cacheService: cache,
+		txManager:    &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1", Amount: 50}
+	_, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if !errors.Is(err, models.ErrPaymentAmountMismatch) {
+		t.Fatalf("expected ErrPaymentAmountMismatch, got %v", err)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_SettledByAsyncPayment(t *testing.T) {
+	bookingRepo := &mockBookingRepo{
+		// Still pending when the webhook reads it
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID, BookingPrice: 5000}, nil
+		},
+		// ...but completed by the asynchronous payment before it settles
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			return repositories.ErrBookingStatusConflict
+		},
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, Status: models.BookingStatusCompleted}, nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect the payment of a completed booking to be refunded")
+			return nil, nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:    bookingRepo,
+		cacheService:   &mockFlightCacheBooking{},
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       &mockSeatRepo{},
+		txManager:      &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1", Amount: 5000}
+	resp, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCompleted {
+		t.Fatalf("expected completed status, got %s", resp.Status)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_ErrorClearsEvent(t *testing.T) {
+	cleared := false
+	bookingRepo := &mockBookingRepo{
+		getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+			return &models.Booking{ID: 1, FlightID: 1, Status: models.BookingStatusPending, PaymentReferenceID: paymentRefID}, nil
+		},
+		updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+			return errors.New("db unavailable")
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		clearFn: func(ctx context.Context, eventID string) error {
+			cleared = eventID == "evt-1"
+			return nil
+		},
+	}
+
+	svc := &BookingService{
+		bookingRepo:   bookingRepo,
+		cacheService:  cache,
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		fareClassRepo: &mockFareClassRepo{},
+		pricing:       &mockPriceQuoter{},
+		txManager:     &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1"}
+	if _, err := svc.HandlePaymentWebhook(context.Background(), event); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if !cleared {
+		t.Fatalf("expected webhook event to be cleared for redelivery")
 	}
 }
 
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+
+	"go.opentelemetry.io/otel"
+)
+
+// cacheWarmerJob is the leadership key shared by all cache warmer instances
+const cacheWarmerJob = "cache-warmer"
+
+// SearchWarmer defines the search operation used by CacheWarmer.
+type SearchWarmer interface {
+	WarmSearch(ctx context.Context, req *models.FlightSearchRequest) error
+}
+
+// RoutePopularityRepositoryWarmer defines persistence operations used by CacheWarmer.
+type RoutePopularityRepositoryWarmer interface {
+	SaveRoutePopularity(ctx context.Context, routes []models.RoutePopularity) error
+	GetTopRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error)
+}
+
+// FlightCacheWarmer defines cache operations used by CacheWarmer.
+type FlightCacheWarmer interface {
+	AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	ResignLeadership(ctx context.Context, job string, instanceID string) error
+	TopSearchedRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error)
+	SeedRouteSearches(ctx context.Context, routes []models.RoutePopularity) error
+	IsCached(ctx context.Context, cacheKey string) (bool, error)
+}
+
+// CacheWarmer pre-populates the search cache for the most searched routes
+// over the coming days, on startup and then every CacheWarmupInterval, so a
+// deploy or Redis flush does not send every search to the database at once.
+// Only the replica holding leadership in Redis warms on each tick.
+type CacheWarmer struct {
+	searchWarmer   SearchWarmer
+	popularityRepo RoutePopularityRepositoryWarmer
+	cacheService   FlightCacheWarmer
+	config         *config.AppConfig
+	instanceID     string
+	tracerName     string
+	now            func() time.Time
+}
+
+// NewCacheWarmer creates a new cache warmer
+func NewCacheWarmer(
+	flightService *FlightService,
+	popularityRepo *repositories.RoutePopularityRepository,
+	cacheService *cache.FlightCacheService,
+	config *config.AppConfig,
+) *CacheWarmer {
+	return &CacheWarmer{
+		searchWarmer:   flightService,
+		popularityRepo: popularityRepo,
+		cacheService:   cacheService,
+		config:         config,
+		instanceID:     generateInstanceID(),
+		tracerName:     "airline-booking-system/cache-warmer",
+		now:            time.Now,
+	}
+}
+
+// Run warms the cache every CacheWarmupInterval until ctx is cancelled
+func (w *CacheWarmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.CacheWarmupInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		// Hand leadership over promptly instead of waiting for the lease to lapse
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := w.cacheService.ResignLeadership(resignCtx, cacheWarmerJob, w.instanceID); err != nil {
+			log.Printf("Failed to resign cache warmer leadership: %v", err)
+		}
+	}()
+
+	for {
+		w.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs one warm-up pass if this instance is the leader
+func (w *CacheWarmer) tick(ctx context.Context) {
+	// The lease outlives one interval so a healthy leader keeps it between ticks
+	leader, err := w.cacheService.AcquireLeadership(ctx, cacheWarmerJob, w.instanceID, 2*w.config.CacheWarmupInterval)
+	if err != nil {
+		log.Printf("Failed to acquire cache warmer leadership: %v", err)
+		return
+	}
+
+	if !leader {
+		return
+	}
+
+	warmed, err := w.WarmPopularRoutes(ctx)
+	if err != nil {
+		log.Printf("Failed to warm search cache: %v", err)
+		return
+	}
+
+	if warmed > 0 {
+		log.Printf("Warmed %d cached searches", warmed)
+	}
+}
+
+// WarmPopularRoutes caches the plain search of each of the top
+// CacheWarmupRoutes routes for today and the following CacheWarmupDays-1
+// days, skipping searches already cached. At most CacheWarmupConcurrency
+// searches query the database at once. It returns the number of searches
+// cached.
+func (w *CacheWarmer) WarmPopularRoutes(ctx context.Context) (int, error) {
+	tr := otel.Tracer(w.tracerName)
+	ctx, span := tr.Start(ctx, "CacheWarmer.WarmPopularRoutes")
+	defer span.End()
+
+	routes, err := w.popularRoutes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	today := w.now().UTC().Truncate(24 * time.Hour)
+	limit := make(chan struct{}, max(w.config.CacheWarmupConcurrency, 1))
+	var warmed int64
+	var wg sync.WaitGroup
+
+	for _, route := range routes {
+		for day := 0; day < w.config.CacheWarmupDays; day++ {
+			req := &models.FlightSearchRequest{
+				Source:      route.Source,
+				Destination: route.Destination,
+				Date:        today.AddDate(0, 0, day),
+			}
+
+			if cached, err := w.cacheService.IsCached(ctx, req.GetCacheKey()); err == nil && cached {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return int(warmed), ctx.Err()
+			case limit <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-limit }()
+
+				if err := w.searchWarmer.WarmSearch(ctx, req); err != nil {
+					log.Printf("Failed to warm search %s: %v", req.GetCacheKey(), err)
+					return
+				}
+				atomic.AddInt64(&warmed, 1)
+			}()
+		}
+	}
+
+	wg.Wait()
+	return int(warmed), nil
+}
+
+// popularRoutes gets the most searched routes recorded in Redis and stores
+// them as a snapshot. If Redis has none, as after a flush, the stored
+// snapshot is used and restored into Redis so counting carries on from it.
+func (w *CacheWarmer) popularRoutes(ctx context.Context) ([]models.RoutePopularity, error) {
+	routes, err := w.cacheService.TopSearchedRoutes(ctx, w.config.CacheWarmupRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(routes) > 0 {
+		if err := w.popularityRepo.SaveRoutePopularity(ctx, routes); err != nil {
+			log.Printf("Failed to save route popularity: %v", err)
+		}
+		return routes, nil
+	}
+
+	routes, err = w.popularityRepo.GetTopRoutes(ctx, w.config.CacheWarmupRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.cacheService.SeedRouteSearches(ctx, routes); err != nil {
+		log.Printf("Failed to restore route popularity: %v", err)
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// mockSearchWarmer implements SearchWarmer for testing.
+type mockSearchWarmer struct {
+	warmFn func(ctx context.Context, req *models.FlightSearchRequest) error
+}
+
+func (m *mockSearchWarmer) WarmSearch(ctx context.Context, req *models.FlightSearchRequest) error {
+	if m.warmFn != nil {
+		return m.warmFn(ctx, req)
+	}
+	return nil
+}
+
+// mockRoutePopularityRepo implements RoutePopularityRepositoryWarmer for testing.
+type mockRoutePopularityRepo struct {
+	saveFn   func(ctx context.Context, routes []models.RoutePopularity) error
+	getTopFn func(ctx context.Context, limit int) ([]models.RoutePopularity, error)
+}
+
+func (m *mockRoutePopularityRepo) SaveRoutePopularity(ctx context.Context, routes []models.RoutePopularity) error {
+	if m.saveFn != nil {
+		return m.saveFn(ctx, routes)
+	}
+	return nil
+}
+
+func (m *mockRoutePopularityRepo) GetTopRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+	if m.getTopFn != nil {
+		return m.getTopFn(ctx, limit)
+	}
+	return nil, nil
+}
+
+// mockWarmerCache implements FlightCacheWarmer for testing.
+type mockWarmerCache struct {
+	acquireFn   func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	topRoutesFn func(ctx context.Context, limit int) ([]models.RoutePopularity, error)
+	seedFn      func(ctx context.Context, routes []models.RoutePopularity) error
+	isCachedFn  func(ctx context.Context, cacheKey string) (bool, error)
+}
+
+func (m *mockWarmerCache) AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+	if m.acquireFn != nil {
+		return m.acquireFn(ctx, job, instanceID, ttl)
+	}
+	return true, nil
+}
+
+func (m *mockWarmerCache) ResignLeadership(ctx context.Context, job string, instanceID string) error {
+	return nil
+}
+
+func (m *mockWarmerCache) TopSearchedRoutes(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+	if m.topRoutesFn != nil {
+		return m.topRoutesFn(ctx, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockWarmerCache) SeedRouteSearches(ctx context.Context, routes []models.RoutePopularity) error {
+	if m.seedFn != nil {
+		return m.seedFn(ctx, routes)
+	}
+	return nil
+}
+
+func (m *mockWarmerCache) IsCached(ctx context.Context, cacheKey string) (bool, error) {
+	if m.isCachedFn != nil {
+		return m.isCachedFn(ctx, cacheKey)
+	}
+	return false, nil
+}
+
+func newTestCacheWarmer(searchWarmer SearchWarmer, repo RoutePopularityRepositoryWarmer, cache FlightCacheWarmer) *CacheWarmer {
+	return &CacheWarmer{
+		searchWarmer:   searchWarmer,
+		popularityRepo: repo,
+		cacheService:   cache,
+		config: &config.AppConfig{
+			CacheWarmupInterval:    time.Minute,
+			CacheWarmupRoutes:      2,
+			CacheWarmupDays:        3,
+			CacheWarmupConcurrency: 2,
+		},
+		instanceID: "test-instance",
+		now:        func() time.Time { return time.Date(2025, 1, 20, 15, 30, 0, 0, time.UTC) },
+	}
+}
+
+var testPopularRoutes = []models.RoutePopularity{
+	{Source: "Delhi", Destination: "Mumbai", Searches: 120},
+	{Source: "Mumbai", Destination: "Chennai", Searches: 80},
+}
+
+func TestCacheWarmer_WarmPopularRoutes_WarmsUncachedDays(t *testing.T) {
+	var mu sync.Mutex
+	warmedKeys := make(map[string]bool)
+	var saved []models.RoutePopularity
+
+	searchWarmer := &mockSearchWarmer{
+		warmFn: func(ctx context.Context, req *models.FlightSearchRequest) error {
+			mu.Lock()
+			defer mu.Unlock()
+			warmedKeys[req.GetCacheKey()] = true
+			return nil
+		},
+	}
+	repo := &mockRoutePopularityRepo{
+		saveFn: func(ctx context.Context, routes []models.RoutePopularity) error {
+			saved = routes
+			return nil
+		},
+		getTopFn: func(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+			t.Fatalf("did not expect the stored snapshot to be read while Redis has routes")
+			return nil, nil
+		},
+	}
+	cache := &mockWarmerCache{
+		topRoutesFn: func(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+			if limit != 2 {
+				t.Fatalf("expected the top 2 routes to be requested, got %d", limit)
+			}
+			return testPopularRoutes, nil
+		},
+		isCachedFn: func(ctx context.Context, cacheKey string) (bool, error) {
+			return cacheKey == "Delhi#Mumbai#2025-01-21", nil
+		},
+	}
+
+	warmer := newTestCacheWarmer(searchWarmer, repo, cache)
+
+	warmed, err := warmer.WarmPopularRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 2 routes x 3 days, one of which is already cached
+	if warmed != 5 || len(warmedKeys) != 5 {
+		t.Fatalf("expected 5 searches warmed, got %d: %v", warmed, warmedKeys)
+	}
+
+	for _, key := range []string{"Delhi#Mumbai#2025-01-20", "Delhi#Mumbai#2025-01-22", "Mumbai#Chennai#2025-01-20", "Mumbai#Chennai#2025-01-22"} {
+		if !warmedKeys[key] {
+			t.Fatalf("expected %s to be warmed, got %v", key, warmedKeys)
+		}
+	}
+
+	if warmedKeys["Delhi#Mumbai#2025-01-21"] {
+		t.Fatalf("did not expect an already cached search to be warmed again")
+	}
+
+	if len(saved) != 2 {
+		t.Fatalf("expected the route popularity snapshot to be saved, got %+v", saved)
+	}
+}
+
+func TestCacheWarmer_WarmPopularRoutes_RestoresSnapshotAfterFlush(t *testing.T) {
+	var seeded []models.RoutePopularity
+
+	searchWarmer := &mockSearchWarmer{
+		warmFn: func(ctx context.Context, req *models.FlightSearchRequest) error {
+			if req.Source != "Delhi" {
+				t.Fatalf("expected only the stored route to be warmed, got %+v", req)
+			}
+			return nil
+		},
+	}
+	repo := &mockRoutePopularityRepo{
+		saveFn: func(ctx context.Context, routes []models.RoutePopularity) error {
+			t.Fatalf("did not expect an empty Redis ranking to overwrite the snapshot")
+			return nil
+		},
+		getTopFn: func(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+			return testPopularRoutes[:1], nil
+		},
+	}
+	cache := &mockWarmerCache{
+		seedFn: func(ctx context.Context, routes []models.RoutePopularity) error {
+			seeded = routes
+			return nil
+		},
+	}
+
+	warmer := newTestCacheWarmer(searchWarmer, repo, cache)
+
+	warmed, err := warmer.WarmPopularRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if warmed != 3 {
+		t.Fatalf("expected 3 days of the stored route warmed, got %d", warmed)
+	}
+
+	if len(seeded) != 1 || seeded[0].Searches != 120 {
+		t.Fatalf("expected the snapshot restored into Redis, got %+v", seeded)
+	}
+}
+
+func TestCacheWarmer_WarmPopularRoutes_LimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	searchWarmer := &mockSearchWarmer{
+		warmFn: func(ctx context.Context, req *models.FlightSearchRequest) error {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+
+			if req.Source == "Mumbai" {
+				return errors.New("db unavailable")
+			}
+			return nil
+		},
+	}
+	cache := &mockWarmerCache{
+		topRoutesFn: func(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+			return testPopularRoutes, nil
+		},
+	}
+
+	warmer := newTestCacheWarmer(searchWarmer, &mockRoutePopularityRepo{}, cache)
+
+	warmed, err := warmer.WarmPopularRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if maxInFlight > 2 {
+		t.Fatalf("expected at most 2 searches at once, got %d", maxInFlight)
+	}
+
+	// Failed searches are logged and skipped
+	if warmed != 3 {
+		t.Fatalf("expected the 3 searches of the healthy route warmed, got %d", warmed)
+	}
+}
+
+func TestCacheWarmer_Tick_SkipsWhenNotLeader(t *testing.T) {
+	cache := &mockWarmerCache{
+		acquireFn: func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+			if job != cacheWarmerJob || ttl != 2*time.Minute {
+				t.Fatalf("unexpected leadership request job=%s ttl=%s", job, ttl)
+			}
+			return false, nil
+		},
+		topRoutesFn: func(ctx context.Context, limit int) ([]models.RoutePopularity, error) {
+			t.Fatalf("did not expect a follower to warm the cache")
+			return nil, nil
+		},
+	}
+
+	warmer := newTestCacheWarmer(&mockSearchWarmer{}, &mockRoutePopularityRepo{}, cache)
+	warmer.tick(context.Background())
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/kafka"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Page size limits for listing dead letters
+const (
+	defaultDeadLetterPageSize = 50
+	maxDeadLetterPageSize     = 500
+)
+
+// DeadLetterQueue defines the dead-letter operations used by DeadLetterService.
+type DeadLetterQueue interface {
+	List(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error)
+	Replay(ctx context.Context, offset int64) (*models.DeadLetter, error)
+}
+
+// DeadLetterService lists and replays dead-lettered Kafka messages
+type DeadLetterService struct {
+	deadLetters DeadLetterQueue
+	tracerName  string
+}
+
+// NewDeadLetterService creates a new dead-letter service
+func NewDeadLetterService(deadLetters *kafka.DeadLetterQueue) *DeadLetterService {
+	return &DeadLetterService{
+		deadLetters: deadLetters,
+		tracerName:  "airline-booking-system/dead-letter-service",
+	}
+}
+
+// ListDeadLetters returns a page of dead letters starting at offset
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, offset int64, limit int) (*models.DeadLetterListResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "DeadLetterService.ListDeadLetters")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultDeadLetterPageSize
+	}
+	if limit > maxDeadLetterPageSize {
+		limit = maxDeadLetterPageSize
+	}
+
+	deadLetters, err := s.deadLetters.List(ctx, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	nextOffset := offset
+	if len(deadLetters) > 0 {
+		nextOffset = deadLetters[len(deadLetters)-1].Offset + 1
+	}
+
+	return &models.DeadLetterListResponse{
+		DeadLetters: deadLetters,
+		Count:       len(deadLetters),
+		NextOffset:  nextOffset,
+	}, nil
+}
+
+// ReplayDeadLetter republishes a dead letter to its original topic
+func (s *DeadLetterService) ReplayDeadLetter(ctx context.Context, offset int64) (*models.DeadLetter, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "DeadLetterService.ReplayDeadLetter")
+	defer span.End()
+
+	deadLetter, err := s.deadLetters.Replay(ctx, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+
+	return deadLetter, nil
+}
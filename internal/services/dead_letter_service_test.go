@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"airline-booking-system/internal/models"
+)
+
+// mockDeadLetterQueue implements DeadLetterQueue for testing.
+type mockDeadLetterQueue struct {
+	listFn   func(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error)
+	replayFn func(ctx context.Context, offset int64) (*models.DeadLetter, error)
+}
+
+func (m *mockDeadLetterQueue) List(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, offset, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockDeadLetterQueue) Replay(ctx context.Context, offset int64) (*models.DeadLetter, error) {
+	if m.replayFn != nil {
+		return m.replayFn(ctx, offset)
+	}
+	return nil, nil
+}
+
+func TestDeadLetterService_ListDeadLetters_PagesFromOffset(t *testing.T) {
+	limitUsed := 0
+	queue := &mockDeadLetterQueue{
+		listFn: func(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error) {
+			limitUsed = limit
+			return []models.DeadLetter{{Offset: offset}, {Offset: offset + 1}}, nil
+		},
+	}
+
+	svc := &DeadLetterService{deadLetters: queue}
+
+	resp, err := svc.ListDeadLetters(context.Background(), 5, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if limitUsed != defaultDeadLetterPageSize {
+		t.Fatalf("expected default page size %d, got %d", defaultDeadLetterPageSize, limitUsed)
+	}
+
+	if resp.Count != 2 || resp.NextOffset != 7 {
+		t.Fatalf("expected 2 dead letters and next offset 7, got count=%d next=%d", resp.Count, resp.NextOffset)
+	}
+}
+
+func TestDeadLetterService_ListDeadLetters_CapsPageSize(t *testing.T) {
+	limitUsed := 0
+	queue := &mockDeadLetterQueue{
+		listFn: func(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error) {
+			limitUsed = limit
+			return []models.DeadLetter{}, nil
+		},
+	}
+
+	svc := &DeadLetterService{deadLetters: queue}
+
+	resp, err := svc.ListDeadLetters(context.Background(), 5, 10000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if limitUsed != maxDeadLetterPageSize {
+		t.Fatalf("expected page size capped at %d, got %d", maxDeadLetterPageSize, limitUsed)
+	}
+
+	if resp.NextOffset != 5 {
+		t.Fatalf("expected next offset to stay at 5 for an empty page, got %d", resp.NextOffset)
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"airline-booking-system/internal/models"
+)
+
+// FakePaymentOutcome represents the scripted result of a fake gateway operation
+type FakePaymentOutcome string
+
+const (
+	FakePaymentOutcomeApprove FakePaymentOutcome = "approve"
+	FakePaymentOutcomeDecline FakePaymentOutcome = "decline"
+	FakePaymentOutcomeError   FakePaymentOutcome = "error"
+)
+
+// IsValid checks if the outcome is a known fake payment outcome
+func (o FakePaymentOutcome) IsValid() bool {
+	return o == FakePaymentOutcomeApprove || o == FakePaymentOutcomeDecline || o == FakePaymentOutcomeError
+}
+
+// PaymentOperation names a payment gateway operation
+type PaymentOperation string
+
+const (
+	PaymentOperationAuthorize PaymentOperation = "authorize"
+	PaymentOperationCapture   PaymentOperation = "capture"
+	PaymentOperationRefund    PaymentOperation = "refund"
+	PaymentOperationVoid      PaymentOperation = "void"
+)
+
+// fakePaymentState tracks the lifecycle of a payment in the fake gateway
+type fakePaymentState string
+
+const (
+	fakePaymentAuthorized fakePaymentState = "authorized"
+	fakePaymentCaptured   fakePaymentState = "captured"
+	fakePaymentRefunded   fakePaymentState = "refunded"
+	fakePaymentVoided     fakePaymentState = "voided"
+)
+
+// fakeCapturedRetention is how long a captured payment can still be refunded
+// before the fake gateway forgets it
+const fakeCapturedRetention = 24 * time.Hour
+
+type fakePayment struct {
+	transactionID string
+	amount        float64
+	state         fakePaymentState
+	capturedAt    time.Time
+}
+
+// FakePaymentGateway is a deterministic in-process PaymentGateway for local
+// development and tests. Every operation returns its configured outcome after
+// the configured latency. Refunded and voided payments are forgotten at once,
+// and captured ones after fakeCapturedRetention.
+type FakePaymentGateway struct {
+	mu             sync.Mutex
+	defaultOutcome FakePaymentOutcome
+	outcomes       map[PaymentOperation]FakePaymentOutcome
+	latency        time.Duration
+	payments       map[string]*fakePayment
+	nextTxnID      int64
+}
+
+// NewFakePaymentGateway creates a new fake payment gateway
+func NewFakePaymentGateway(outcome FakePaymentOutcome, latency time.Duration) *FakePaymentGateway {
+	return &FakePaymentGateway{
+		defaultOutcome: outcome,
+		outcomes:       make(map[PaymentOperation]FakePaymentOutcome),
+		latency:        latency,
+		payments:       make(map[string]*fakePayment),
+	}
+}
+
+// SetOutcome overrides the outcome for a single operation
+func (g *FakePaymentGateway) SetOutcome(op PaymentOperation, outcome FakePaymentOutcome) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.outcomes[op] = outcome
+}
+
+// Authorize places a hold for the requested amount
+func (g *FakePaymentGateway) Authorize(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.evictCaptured(time.Now())
+
+	if existing, exists := g.payments[req.PaymentReferenceID]; exists {
+		return nil, fmt.Errorf("payment %s already %s", req.PaymentReferenceID, existing.state)
+	}
+
+	result, err := g.result(PaymentOperationAuthorize, req.PaymentReferenceID, "")
+	if err != nil || !result.IsApproved() {
+		return result, err
+	}
+
+	g.nextTxnID++
+	payment := &fakePayment{
+		transactionID: fmt.Sprintf("FAKE-TXN-%d", g.nextTxnID),
+		amount:        req.Amount,
+		state:         fakePaymentAuthorized,
+	}
+	g.payments[req.PaymentReferenceID] = payment
+	result.TransactionID = payment.transactionID
+
+	return result, nil
+}
+
+// Capture settles a previously authorized payment
+func (g *FakePaymentGateway) Capture(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+	return g.transition(ctx, PaymentOperationCapture, paymentRefID, amount, fakePaymentAuthorized, fakePaymentCaptured)
+}
+
+// Refund returns a captured payment to the customer
+func (g *FakePaymentGateway) Refund(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+	return g.transition(ctx, PaymentOperationRefund, paymentRefID, amount, fakePaymentCaptured, fakePaymentRefunded)
+}
+
+// Void releases an authorization that was never captured
+func (g *FakePaymentGateway) Void(ctx context.Context, paymentRefID string) (*models.PaymentResult, error) {
+	return g.transition(ctx, PaymentOperationVoid, paymentRefID, 0, fakePaymentAuthorized, fakePaymentVoided)
+}
+
+// transition moves a payment between states if the operation is approved
+func (g *FakePaymentGateway) transition(ctx context.Context, op PaymentOperation, paymentRefID string, amount float64, from, to fakePaymentState) (*models.PaymentResult, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, exists := g.payments[paymentRefID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentRefID)
+	}
+
+	if payment.state != from {
+		return nil, fmt.Errorf("cannot %s payment %s in state %s", op, paymentRefID, payment.state)
+	}
+
+	if amount > payment.amount {
+		return nil, fmt.Errorf("cannot %s %.2f, payment %s is for %.2f", op, amount, paymentRefID, payment.amount)
+	}
+
+	result, err := g.result(op, paymentRefID, payment.transactionID)
+	if err != nil || !result.IsApproved() {
+		return result, err
+	}
+
+	switch to {
+	case fakePaymentCaptured:
+		payment.state = to
+		payment.capturedAt = time.Now()
+	case fakePaymentRefunded, fakePaymentVoided:
+		// Nothing can happen to the payment anymore
+		delete(g.payments, paymentRefID)
+	default:
+		payment.state = to
+	}
+	return result, nil
+}
+
+// evictCaptured forgets captured payments past fakeCapturedRetention
+func (g *FakePaymentGateway) evictCaptured(now time.Time) {
+	for refID, payment := range g.payments {
+		if payment.state == fakePaymentCaptured && now.Sub(payment.capturedAt) > fakeCapturedRetention {
+			delete(g.payments, refID)
+		}
+	}
+}
+
+// result builds the configured outcome for an operation
+func (g *FakePaymentGateway) result(op PaymentOperation, paymentRefID, transactionID string) (*models.PaymentResult, error) {
+	outcome, exists := g.outcomes[op]
+	if !exists {
+		outcome = g.defaultOutcome
+	}
+
+	switch outcome {
+	case FakePaymentOutcomeDecline:
+		return &models.PaymentResult{
+			PaymentReferenceID: paymentRefID,
+			TransactionID:      transactionID,
+			Status:             models.PaymentStatusDeclined,
+			Message:            fmt.Sprintf("%s declined", op),
+		}, nil
+	case FakePaymentOutcomeError:
+		return nil, fmt.Errorf("fake gateway error during %s", op)
+	default:
+		return &models.PaymentResult{
+			PaymentReferenceID: paymentRefID,
+			TransactionID:      transactionID,
+			Status:             models.PaymentStatusApproved,
+			Message:            fmt.Sprintf("%s approved", op),
+		}, nil
+	}
+}
+
+// wait simulates gateway latency
+func (g *FakePaymentGateway) wait(ctx context.Context) error {
+	if g.latency <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(g.latency):
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+func TestNewPaymentGateway_SelectsFakeProvider(t *testing.T) {
+	gateway, err := NewPaymentGateway(&config.AppConfig{
+		PaymentProvider:    "fake",
+		FakePaymentOutcome: "decline",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := gateway.(*FakePaymentGateway); !ok {
+		t.Fatalf("expected *FakePaymentGateway, got %T", gateway)
+	}
+}
+
+func TestNewPaymentGateway_InvalidConfig(t *testing.T) {
+	cases := []*config.AppConfig{
+		{PaymentProvider: "unknown", FakePaymentOutcome: "approve"},
+		{PaymentProvider: "fake", FakePaymentOutcome: "sometimes"},
+	}
+
+	for _, cfg := range cases {
+		if _, err := NewPaymentGateway(cfg); err == nil {
+			t.Fatalf("expected error for provider=%q outcome=%q, got nil", cfg.PaymentProvider, cfg.FakePaymentOutcome)
+		}
+	}
+}
+
+func TestFakePaymentGateway_Lifecycle(t *testing.T) {
+	gateway := NewFakePaymentGateway(FakePaymentOutcomeApprove, 0)
+	ctx := context.Background()
+
+	auth, err := gateway.Authorize(ctx, &models.PaymentRequest{BookingID: 1, PaymentReferenceID: "PAY-1", Amount: 200})
+	if err != nil || !auth.IsApproved() {
+		t.Fatalf("expected approved authorization, got %+v, %v", auth, err)
+	}
+
+	if auth.TransactionID != "FAKE-TXN-1" {
+		t.Fatalf("expected deterministic transaction id FAKE-TXN-1, got %s", auth.TransactionID)
+	}
+
+	if _, err := gateway.Refund(ctx, "PAY-1", 200); err == nil {
+		t.Fatalf("expected refund of uncaptured payment to fail")
+	}
+
+	if result, err := gateway.Capture(ctx, "PAY-1", 200); err != nil || !result.IsApproved() {
+		t.Fatalf("expected approved capture, got %+v, %v", result, err)
+	}
+
+	if _, err := gateway.Void(ctx, "PAY-1"); err == nil {
+		t.Fatalf("expected void of captured payment to fail")
+	}
+
+	if _, err := gateway.Refund(ctx, "PAY-1", 500); err == nil {
+		t.Fatalf("expected refund above captured amount to fail")
+	}
+
+	if result, err := gateway.Refund(ctx, "PAY-1", 200); err != nil || !result.IsApproved() {
+		t.Fatalf("expected approved refund, got %+v, %v", result, err)
+	}
+
+	if _, err := gateway.Refund(ctx, "PAY-1", 200); !errors.Is(err, ErrPaymentNotFound) {
+		t.Fatalf("expected refunded payment to be evicted, got %v", err)
+	}
+}
+
+func TestFakePaymentGateway_EvictsSettledPayments(t *testing.T) {
+	gateway := NewFakePaymentGateway(FakePaymentOutcomeApprove, 0)
+	ctx := context.Background()
+
+	for _, ref := range []string{"PAY-1", "PAY-2", "PAY-3"} {
+		if _, err := gateway.Authorize(ctx, &models.PaymentRequest{PaymentReferenceID: ref, Amount: 100}); err != nil {
+			t.Fatalf("failed to authorize %s: %v", ref, err)
+		}
+	}
+
+	if _, err := gateway.Void(ctx, "PAY-1"); err != nil {
+		t.Fatalf("failed to void: %v", err)
+	}
+	if _, err := gateway.Capture(ctx, "PAY-2", 100); err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+	if _, err := gateway.Capture(ctx, "PAY-3", 100); err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+
+	// PAY-2 was captured long enough ago that it can no longer be refunded
+	gateway.payments["PAY-2"].capturedAt = time.Now().Add(-fakeCapturedRetention - time.Minute)
+
+	if _, err := gateway.Authorize(ctx, &models.PaymentRequest{PaymentReferenceID: "PAY-4", Amount: 100}); err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+
+	for ref, expected := range map[string]bool{"PAY-1": false, "PAY-2": false, "PAY-3": true, "PAY-4": true} {
+		if _, exists := gateway.payments[ref]; exists != expected {
+			t.Fatalf("expected %s kept=%v, got %v", ref, expected, exists)
+		}
+	}
+}
+
+func TestFakePaymentGateway_DeclineLeavesNoPayment(t *testing.T) {
+	gateway := NewFakePaymentGateway(FakePaymentOutcomeDecline, 0)
+	ctx := context.Background()
+
+	auth, err := gateway.Authorize(ctx, &models.PaymentRequest{PaymentReferenceID: "PAY-1", Amount: 200})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if auth.IsApproved() {
+		t.Fatalf("expected declined authorization")
+	}
+
+	if _, err := gateway.Capture(ctx, "PAY-1", 200); err == nil {
+		t.Fatalf("expected capture of declined payment to fail")
+	}
+}
+
+func TestFakePaymentGateway_LatencyRespectsContext(t *testing.T) {
+	gateway := NewFakePaymentGateway(FakePaymentOutcomeApprove, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := gateway.Authorize(ctx, &models.PaymentRequest{PaymentReferenceID: "PAY-1", Amount: 200}); err == nil {
+		t.Fatalf("expected context deadline error, got nil")
+	}
+}
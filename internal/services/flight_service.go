@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"time"
 
 	"airline-booking-system/internal/cache"
 	"airline-booking-system/internal/config"
 	"airline-booking-system/internal/models"
 	"airline-booking-system/internal/repositories"
+	"airline-booking-system/pkg/database"
 
 	"go.opentelemetry.io/otel"
 )
@@ -16,32 +19,67 @@ import (
 // FlightRepository defines the persistence operations used by FlightService.
 type FlightRepository interface {
 	SearchFlights(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error)
+	SearchFlightsDepartingBetween(ctx context.Context, from, to time.Time, minSeats int) ([]models.Flight, error)
+	SearchRouteFlightsDepartingBetween(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error)
 	GetFlightByID(ctx context.Context, id int64) (*models.Flight, error)
 	CreateFlight(ctx context.Context, flight *models.Flight) (*models.Flight, error)
 	UpdateFlight(ctx context.Context, flight *models.Flight) error
 }
 
+// SeatRepositoryFlight defines seat operations used by FlightService.
+type SeatRepositoryFlight interface {
+	CreateSeats(ctx context.Context, flightID int64, seats []models.Seat) error
+	GetSeatsByFlightID(ctx context.Context, flightID int64) ([]models.Seat, error)
+}
+
+// FareClassRepositoryFlight defines fare class operations used by FlightService.
+type FareClassRepositoryFlight interface {
+	CreateFareClasses(ctx context.Context, flightID int64, fareClasses []models.FareClass) ([]models.FareClass, error)
+	GetFareClassesByFlightIDs(ctx context.Context, flightIDs []int64) ([]models.FareClass, error)
+}
+
 // FlightCache defines the caching operations used by FlightService.
 type FlightCache interface {
 	GetCachedFlights(ctx context.Context, key string) ([]models.Flight, error)
-	SetCachedFlights(ctx context.Context, key string, flights []models.Flight) error
+	SetCachedFlights(ctx context.Context, req *models.FlightSearchRequest, flights []models.Flight) error
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+	InvalidateRouteSearches(ctx context.Context, source, destination string, departure time.Time) error
+	RecordRouteSearch(ctx context.Context, source, destination string) error
+	GetCachedFareCalendar(ctx context.Context, key string) (*models.FareCalendarResponse, error)
+	SetCachedFareCalendar(ctx context.Context, req *models.FareCalendarRequest, calendar *models.FareCalendarResponse, flightIDs []int64) error
 }
 
 // FlightService handles flight business logic
 type FlightService struct {
-	flightRepo   FlightRepository
-	cacheService FlightCache
-	config       *config.AppConfig
-	tracerName   string
+	flightRepo    FlightRepository
+	seatRepo      SeatRepositoryFlight
+	fareClassRepo FareClassRepositoryFlight
+	cacheService  FlightCache
+	pricing       PriceQuoter
+	txManager     TxManager
+	config        *config.AppConfig
+	tracerName    string
 }
 
 // NewFlightService creates a new flight service
-func NewFlightService(flightRepo *repositories.FlightRepository, cacheService *cache.FlightCacheService, config *config.AppConfig) *FlightService {
+func NewFlightService(
+	flightRepo *repositories.FlightRepository,
+	seatRepo *repositories.SeatRepository,
+	fareClassRepo *repositories.FareClassRepository,
+	cacheService *cache.FlightCacheService,
+	pricing *PricingService,
+	db *database.DB,
+	config *config.AppConfig,
+) *FlightService {
 	return &FlightService{
-		flightRepo:   flightRepo,
-		cacheService: cacheService,
-		config:       config,
-		tracerName:   "airline-booking-system/flight-service",
+		flightRepo:    flightRepo,
+		seatRepo:      seatRepo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  cacheService,
+		pricing:       pricing,
+		txManager:     db,
+		config:        config,
+		tracerName:    "airline-booking-system/flight-service",
 	}
 }
 
@@ -55,33 +93,261 @@ func (s *FlightService) SearchFlights(ctx context.Context, req *models.FlightSea
 		return nil, fmt.Errorf("invalid search request")
 	}
 
+	// Popular routes are warmed up ahead of searches
+	if err := s.cacheService.RecordRouteSearch(ctx, req.Source, req.Destination); err != nil {
+		log.Printf("Failed to record search of route %s-%s: %v", req.Source, req.Destination, err)
+	}
+
 	cacheKey := req.GetCacheKey()
 
 	// Try to get from cache first
 	if flights, err := s.cacheService.GetCachedFlights(ctx, cacheKey); err == nil {
 		log.Printf("Cache hit for search: %s", cacheKey)
-		return &models.FlightSearchResponse{
-			Flights: flights,
-			Count:   len(flights),
-		}, nil
+		return s.pricedSearchPage(ctx, req, flights)
 	}
 
 	// Cache miss - query database
 	log.Printf("Cache miss for search: %s, querying database", cacheKey)
+	flights, err := s.loadSearch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quote prices after caching; they change with every booking
+	return s.pricedSearchPage(ctx, req, flights)
+}
+
+// pricedSearchPage quotes the flights of a search and returns the requested
+// page. Searches by price come unpaged from the database and are filtered,
+// sorted and paged here once priced; other searches come paged and only
+// their page is priced.
+func (s *FlightService) pricedSearchPage(ctx context.Context, req *models.FlightSearchRequest, flights []models.Flight) (*models.FlightSearchResponse, error) {
+	if !req.FiltersByPrice() {
+		resp := searchPage(req, flights)
+		s.quotePrices(ctx, resp.Flights)
+		if err := s.holdPrices(resp.Flights); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	var after *models.FlightSearchCursor
+	if req.Cursor != "" {
+		cursor, err := req.DecodeCursor()
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+
+	s.quotePrices(ctx, flights)
+
+	matched := make([]models.Flight, 0, len(flights))
+	for _, flight := range flights {
+		if !req.MatchesPrice(flight) {
+			continue
+		}
+		if after != nil && req.Position(flight).Compare(*after) <= 0 {
+			continue
+		}
+		matched = append(matched, flight)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return req.Position(matched[i]).Compare(req.Position(matched[j])) < 0
+	})
+
+	resp := searchPage(req, matched)
+	if err := s.holdPrices(resp.Flights); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// WarmSearch runs a search against the database and caches its results
+// ahead of the first request for it
+func (s *FlightService) WarmSearch(ctx context.Context, req *models.FlightSearchRequest) error {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "FlightService.WarmSearch")
+	defer span.End()
+
+	if !req.IsValid() {
+		return fmt.Errorf("invalid search request")
+	}
+
+	_, err := s.loadSearch(ctx, req)
+	return err
+}
+
+// loadSearch queries the flights of a search with their fare classes and
+// caches them without prices
+func (s *FlightService) loadSearch(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error) {
 	flights, err := s.flightRepo.SearchFlights(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search flights: %w", err)
 	}
 
+	// Report availability per fare class alongside the flight totals
+	if err := s.attachFareClasses(ctx, flights); err != nil {
+		return nil, err
+	}
+
 	// Cache the results
-	if err := s.cacheService.SetCachedFlights(ctx, cacheKey, flights); err != nil {
+	if err := s.cacheService.SetCachedFlights(ctx, req, flights); err != nil {
 		log.Printf("Failed to cache search results: %v", err)
 		// Don't fail the request if caching fails
 	}
 
-	return &models.FlightSearchResponse{
-		Flights: flights,
-		Count:   len(flights),
+	return flights, nil
+}
+
+// searchPage trims search results to the requested page. The repository
+// returns one flight past the page when more follow; the page then carries
+// the cursor after its last flight.
+func searchPage(req *models.FlightSearchRequest, flights []models.Flight) *models.FlightSearchResponse {
+	resp := &models.FlightSearchResponse{Flights: flights}
+	if len(flights) > req.PageSize() {
+		resp.Flights = flights[:req.PageSize()]
+		resp.NextCursor = req.CursorAfter(resp.Flights[len(resp.Flights)-1])
+	}
+	resp.Count = len(resp.Flights)
+	return resp
+}
+
+// GetFareCalendar returns the lowest quoted price per day on a route over a
+// month, cached per route, month and seat count until a booking or flight
+// change on the route invalidates it
+func (s *FlightService) GetFareCalendar(ctx context.Context, req *models.FareCalendarRequest) (*models.FareCalendarResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "FlightService.GetFareCalendar")
+	defer span.End()
+
+	if !req.IsValid() {
+		return nil, fmt.Errorf("invalid fare calendar request")
+	}
+
+	cacheKey := req.GetCacheKey()
+
+	// Try to get from cache first
+	if calendar, err := s.cacheService.GetCachedFareCalendar(ctx, cacheKey); err == nil {
+		log.Printf("Cache hit for fare calendar: %s", cacheKey)
+		return calendar, nil
+	}
+
+	// Cache miss - price the route's flights for the month
+	log.Printf("Cache miss for fare calendar: %s, querying database", cacheKey)
+	from, to := req.MonthRange()
+	flights, err := s.flightRepo.SearchRouteFlightsDepartingBetween(ctx, req.Source, req.Destination, from, to, req.Seats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fare calendar: %w", err)
+	}
+
+	if err := s.attachFareClasses(ctx, flights); err != nil {
+		return nil, err
+	}
+	s.quotePrices(ctx, flights)
+
+	calendar := &models.FareCalendarResponse{
+		Source:      req.Source,
+		Destination: req.Destination,
+		Month:       from.Format("2006-01"),
+		Days:        []models.FareCalendarDay{},
+	}
+
+	flightIDs := make([]int64, 0, len(flights))
+	for i := range flights {
+		price, ok := flights[i].LowestQuotedPrice(req.Seats)
+		if !ok {
+			continue
+		}
+		flightIDs = append(flightIDs, flights[i].ID)
+
+		// Flights come in departure order, so each day is appended once
+		date := flights[i].Timestamp.UTC().Format("2006-01-02")
+		last := len(calendar.Days) - 1
+		if last < 0 || calendar.Days[last].Date != date {
+			calendar.Days = append(calendar.Days, models.FareCalendarDay{Date: date, LowestPrice: price})
+			last++
+		}
+		calendar.Days[last].Flights++
+		if price < calendar.Days[last].LowestPrice {
+			calendar.Days[last].LowestPrice = price
+		}
+	}
+
+	// Cache the results
+	if err := s.cacheService.SetCachedFareCalendar(ctx, req, calendar, flightIDs); err != nil {
+		log.Printf("Failed to cache fare calendar: %v", err)
+		// Don't fail the request if caching fails
+	}
+
+	return calendar, nil
+}
+
+// SearchItineraries searches for direct and connecting journeys departing on
+// the requested date
+func (s *FlightService) SearchItineraries(ctx context.Context, req *models.ItinerarySearchRequest) (*models.ItinerarySearchResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "FlightService.SearchItineraries")
+	defer span.End()
+
+	if !req.IsValid() {
+		return nil, fmt.Errorf("invalid itinerary search request")
+	}
+
+	// Later legs may depart after the search date, but never later than
+	// the longest itinerary allows
+	from := req.Date
+	to := from.AddDate(0, 0, 1).Add(s.config.MaxItineraryDuration)
+	flights, err := s.flightRepo.SearchFlightsDepartingBetween(ctx, from, to, req.Seats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search flights: %w", err)
+	}
+
+	paths := composeItineraries(flights, req, s.config)
+
+	// Price each flight used by an itinerary once
+	legIndex := make(map[int64]int)
+	var legs []models.Flight
+	for _, path := range paths {
+		for _, leg := range path {
+			if _, ok := legIndex[leg.ID]; !ok {
+				legIndex[leg.ID] = len(legs)
+				legs = append(legs, leg)
+			}
+		}
+	}
+
+	if err := s.attachFareClasses(ctx, legs); err != nil {
+		return nil, err
+	}
+	s.quotePrices(ctx, legs)
+
+	itineraries := make([]models.Itinerary, 0, len(paths))
+	for _, path := range paths {
+		priced := make([]models.Flight, len(path))
+		for i, leg := range path {
+			priced[i] = legs[legIndex[leg.ID]]
+		}
+		if itinerary, ok := newItinerary(priced, req.Seats); ok {
+			itineraries = append(itineraries, itinerary)
+		}
+	}
+
+	sort.SliceStable(itineraries, func(i, j int) bool {
+		a, b := itineraries[i], itineraries[j]
+		if !a.DepartureTime.Equal(b.DepartureTime) {
+			return a.DepartureTime.Before(b.DepartureTime)
+		}
+		if a.DurationMinutes != b.DurationMinutes {
+			return a.DurationMinutes < b.DurationMinutes
+		}
+		return a.TotalPrice < b.TotalPrice
+	})
+
+	return &models.ItinerarySearchResponse{
+		Itineraries: itineraries,
+		Count:       len(itineraries),
 	}, nil
 }
 
@@ -91,7 +357,87 @@ func (s *FlightService) GetFlightByID(ctx context.Context, id int64) (*models.Fl
 	ctx, span := tr.Start(ctx, "FlightService.GetFlightByID")
 	defer span.End()
 
-	return s.flightRepo.GetFlightByID(ctx, id)
+	flight, err := s.flightRepo.GetFlightByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	flights := []models.Flight{*flight}
+	if err := s.attachFareClasses(ctx, flights); err != nil {
+		return nil, err
+	}
+
+	s.quotePrices(ctx, flights)
+	if err := s.holdPrices(flights); err != nil {
+		return nil, err
+	}
+
+	return &flights[0], nil
+}
+
+// quotePrices sets the current quoted price on each flight and fare class
+func (s *FlightService) quotePrices(ctx context.Context, flights []models.Flight) {
+	for i := range flights {
+		flight := &flights[i]
+		flight.QuotedPrice = s.pricing.QuotePrice(ctx, flight, nil)
+		for j := range flight.FareClasses {
+			flight.FareClasses[j].QuotedPrice = s.pricing.QuotePrice(ctx, flight, &flight.FareClasses[j])
+		}
+	}
+}
+
+// holdPrices sets a quote token holding the quoted price on each flight, or
+// on each fare class of flights sold by class, so booking from the results
+// charges the price they show
+func (s *FlightService) holdPrices(flights []models.Flight) error {
+	for i := range flights {
+		flight := &flights[i]
+		if len(flight.FareClasses) == 0 {
+			token, err := s.pricing.HoldPrice(flight, nil, flight.QuotedPrice)
+			if err != nil {
+				return err
+			}
+			flight.QuoteToken = token
+			continue
+		}
+		for j := range flight.FareClasses {
+			fareClass := &flight.FareClasses[j]
+			token, err := s.pricing.HoldPrice(flight, fareClass, fareClass.QuotedPrice)
+			if err != nil {
+				return err
+			}
+			fareClass.QuoteToken = token
+		}
+	}
+	return nil
+}
+
+// attachFareClasses loads the fare classes of the given flights in one query
+// and sets them on each flight
+func (s *FlightService) attachFareClasses(ctx context.Context, flights []models.Flight) error {
+	if len(flights) == 0 {
+		return nil
+	}
+
+	flightIDs := make([]int64, len(flights))
+	for i, flight := range flights {
+		flightIDs[i] = flight.ID
+	}
+
+	fareClasses, err := s.fareClassRepo.GetFareClassesByFlightIDs(ctx, flightIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get fare classes: %w", err)
+	}
+
+	byFlight := make(map[int64][]models.FareClass, len(flights))
+	for _, fareClass := range fareClasses {
+		byFlight[fareClass.FlightID] = append(byFlight[fareClass.FlightID], fareClass)
+	}
+	for i := range flights {
+		flights[i].FareClasses = byFlight[flights[i].ID]
+	}
+
+	return nil
 }
 
 // CreateFlight creates a new flight
@@ -109,10 +455,18 @@ func (s *FlightService) CreateFlight(ctx context.Context, flight *models.Flight)
 		return nil, fmt.Errorf("available seats cannot exceed total seats")
 	}
 
+	if !flight.ArrivalTimestamp.After(flight.Timestamp) {
+		return nil, fmt.Errorf("arrival must be after departure")
+	}
+
 	if flight.Source == flight.Destination {
 		return nil, fmt.Errorf("source and destination cannot be the same")
 	}
 
+	if err := validateFareClasses(flight); err != nil {
+		return nil, err
+	}
+
 	// Set default status if not provided
 	if flight.FlightStatus == "" {
 		flight.FlightStatus = models.FlightStatusScheduled
@@ -120,7 +474,116 @@ func (s *FlightService) CreateFlight(ctx context.Context, flight *models.Flight)
 
 	flight.Version = 1
 
-	return s.flightRepo.CreateFlight(ctx, flight)
+	// Create the flight together with its fare classes and seat map
+	fareClasses := flight.FareClasses
+	var createdFlight *models.Flight
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdFlight, err = s.flightRepo.CreateFlight(ctx, flight)
+		if err != nil {
+			return err
+		}
+
+		if len(fareClasses) == 0 {
+			return s.seatRepo.CreateSeats(ctx, createdFlight.ID, models.GenerateSeatMap(createdFlight.ID, createdFlight.TotalSeats))
+		}
+
+		createdFlight.FareClasses, err = s.fareClassRepo.CreateFareClasses(ctx, createdFlight.ID, fareClasses)
+		if err != nil {
+			return err
+		}
+
+		return s.seatRepo.CreateSeats(ctx, createdFlight.ID, models.GenerateFareClassSeatMap(createdFlight.ID, createdFlight.TotalSeats, fareClasses))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached searches of the route and day do not list the new flight yet
+	if err := s.cacheService.InvalidateRouteSearches(ctx, createdFlight.Source, createdFlight.Destination, createdFlight.Timestamp); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", createdFlight.ID, err)
+	}
+
+	return createdFlight, nil
+}
+
+// checkSeatCountsEditable returns an error if a flight has a seat map or fare
+// classes, whose seats its seat counts must keep matching
+func (s *FlightService) checkSeatCountsEditable(ctx context.Context, flightID int64) error {
+	fareClasses, err := s.fareClassRepo.GetFareClassesByFlightIDs(ctx, []int64{flightID})
+	if err != nil {
+		return err
+	}
+	if len(fareClasses) > 0 {
+		return fmt.Errorf("seat counts cannot be changed on a flight sold by fare class")
+	}
+
+	seats, err := s.seatRepo.GetSeatsByFlightID(ctx, flightID)
+	if err != nil {
+		return err
+	}
+	if len(seats) > 0 {
+		return fmt.Errorf("seat counts cannot be changed on a flight with a seat map")
+	}
+	return nil
+}
+
+// validateFareClasses normalizes the fare class codes of a new flight and
+// checks that the classes are well formed, distinct and allocate exactly the
+// flight's seats
+func validateFareClasses(flight *models.Flight) error {
+	if len(flight.FareClasses) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(flight.FareClasses))
+	allocated := 0
+	for i := range flight.FareClasses {
+		fareClass := &flight.FareClasses[i]
+		fareClass.Code = models.NormalizeFareClassCode(fareClass.Code)
+		if !fareClass.IsValid() {
+			return fmt.Errorf("invalid fare class %q", fareClass.Code)
+		}
+		if seen[fareClass.Code] {
+			return fmt.Errorf("duplicate fare class %q", fareClass.Code)
+		}
+		seen[fareClass.Code] = true
+		allocated += fareClass.TotalSeats
+	}
+
+	if allocated != flight.TotalSeats {
+		return fmt.Errorf("fare class seats must add up to total seats")
+	}
+
+	return nil
+}
+
+// GetSeatMap gets the seat layout and availability of a flight
+func (s *FlightService) GetSeatMap(ctx context.Context, flightID int64) (*models.SeatMap, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "FlightService.GetSeatMap")
+	defer span.End()
+
+	if _, err := s.flightRepo.GetFlightByID(ctx, flightID); err != nil {
+		return nil, err
+	}
+
+	seats, err := s.seatRepo.GetSeatsByFlightID(ctx, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seat map: %w", err)
+	}
+
+	seatMap := &models.SeatMap{
+		FlightID: flightID,
+		Seats:    seats,
+	}
+	for _, seat := range seats {
+		if seat.Available {
+			seatMap.AvailableCount++
+		}
+	}
+
+	return seatMap, nil
 }
 
 // UpdateFlight updates an existing flight
@@ -134,9 +597,47 @@ func (s *FlightService) UpdateFlight(ctx context.Context, flight *models.Flight)
 		return fmt.Errorf("available seats cannot exceed total seats")
 	}
 
+	if !flight.ArrivalTimestamp.After(flight.Timestamp) {
+		return fmt.Errorf("arrival must be after departure")
+	}
+
 	if flight.Source == flight.Destination {
 		return fmt.Errorf("source and destination cannot be the same")
 	}
 
-	return s.flightRepo.UpdateFlight(ctx, flight)
+	// Bookings keep the seat counts in step with the seat map and the fare
+	// class allocations, so they are only edited on flights with neither.
+	// The version check of the update guarantees nothing changed since the
+	// counts were compared.
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := s.flightRepo.GetFlightByID(ctx, flight.ID)
+		if err != nil {
+			return err
+		}
+		if current.Version != flight.Version {
+			return fmt.Errorf("flight not found or version conflict")
+		}
+
+		if current.TotalSeats != flight.TotalSeats || current.AvailableSeats != flight.AvailableSeats {
+			if err := s.checkSeatCountsEditable(ctx, flight.ID); err != nil {
+				return err
+			}
+		}
+
+		return s.flightRepo.UpdateFlight(ctx, flight)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drop the searches that list the flight as it was, and those of its
+	// route and day now, which it may newly match
+	if err := s.cacheService.InvalidateFlightSearches(ctx, flight.ID); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flight.ID, err)
+	}
+	if err := s.cacheService.InvalidateRouteSearches(ctx, flight.Source, flight.Destination, flight.Timestamp); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flight.ID, err)
+	}
+
+	return nil
 }
@@ -3,15 +3,22 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
 )
 
+// testDeparture is the departure time of flights created in tests.
+var testDeparture = time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)
+
 // mockFlightRepo implements FlightRepository for testing.
 type mockFlightRepo struct {
 	searchFlightsFn      func(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error)
+	searchDepartingFn    func(ctx context.Context, from, to time.Time, minSeats int) ([]models.Flight, error)
+	searchRouteFn        func(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error)
 	getFlightByIDFn      func(ctx context.Context, id int64) (*models.Flight, error)
 	createFlightFn       func(ctx context.Context, flight *models.Flight) (*models.Flight, error)
 	updateFlightFn       func(ctx context.Context, flight *models.Flight) error
@@ -25,6 +32,20 @@ func (m *mockFlightRepo) SearchFlights(ctx context.Context, req *models.FlightSe
 	return nil, nil
 }
 
+func (m *mockFlightRepo) SearchFlightsDepartingBetween(ctx context.Context, from, to time.Time, minSeats int) ([]models.Flight, error) {
+	if m.searchDepartingFn != nil {
+		return m.searchDepartingFn(ctx, from, to, minSeats)
+	}
+	return nil, nil
+}
+
+func (m *mockFlightRepo) SearchRouteFlightsDepartingBetween(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error) {
+	if m.searchRouteFn != nil {
+		return m.searchRouteFn(ctx, source, destination, from, to, minSeats)
+	}
+	return nil, nil
+}
+
 func (m *mockFlightRepo) GetFlightByID(ctx context.Context, id int64) (*models.Flight, error) {
 	if m.getFlightByIDFn != nil {
 		return m.getFlightByIDFn(ctx, id)
@@ -53,10 +74,102 @@ func (m *mockFlightRepo) UpdateAvailableSeats(ctx context.Context, flightID int6
 	return nil
 }
 
+// mockSeatRepo implements the seat repository interfaces for testing.
+type mockSeatRepo struct {
+	createSeatsFn  func(ctx context.Context, flightID int64, seats []models.Seat) error
+	getByFlightFn  func(ctx context.Context, flightID int64) ([]models.Seat, error)
+	assignSeatsFn  func(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error
+	releaseSeatsFn func(ctx context.Context, bookingID int64) error
+}
+
+func (m *mockSeatRepo) CreateSeats(ctx context.Context, flightID int64, seats []models.Seat) error {
+	if m.createSeatsFn != nil {
+		return m.createSeatsFn(ctx, flightID, seats)
+	}
+	return nil
+}
+
+func (m *mockSeatRepo) GetSeatsByFlightID(ctx context.Context, flightID int64) ([]models.Seat, error) {
+	if m.getByFlightFn != nil {
+		return m.getByFlightFn(ctx, flightID)
+	}
+	return nil, nil
+}
+
+func (m *mockSeatRepo) AssignSeats(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+	if m.assignSeatsFn != nil {
+		return m.assignSeatsFn(ctx, flightID, bookingID, seatNumbers, cabin)
+	}
+	return nil
+}
+
+func (m *mockSeatRepo) ReleaseBookingSeats(ctx context.Context, bookingID int64) error {
+	if m.releaseSeatsFn != nil {
+		return m.releaseSeatsFn(ctx, bookingID)
+	}
+	return nil
+}
+
+// mockFareClassRepo implements the fare class repository interfaces for testing.
+type mockFareClassRepo struct {
+	createFn       func(ctx context.Context, flightID int64, fareClasses []models.FareClass) ([]models.FareClass, error)
+	getByFlightsFn func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error)
+	getFn          func(ctx context.Context, flightID int64, code string) (*models.FareClass, error)
+	reserveFn      func(ctx context.Context, fareClassID int64, seatsToBook int, version int) error
+	releaseFn      func(ctx context.Context, flightID int64, code string, seatsToRelease int) error
+}
+
+func (m *mockFareClassRepo) CreateFareClasses(ctx context.Context, flightID int64, fareClasses []models.FareClass) ([]models.FareClass, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, flightID, fareClasses)
+	}
+	return fareClasses, nil
+}
+
+func (m *mockFareClassRepo) GetFareClassesByFlightIDs(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+	if m.getByFlightsFn != nil {
+		return m.getByFlightsFn(ctx, flightIDs)
+	}
+	return nil, nil
+}
+
+func (m *mockFareClassRepo) GetFareClass(ctx context.Context, flightID int64, code string) (*models.FareClass, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, flightID, code)
+	}
+	return nil, repositories.ErrFareClassNotFound
+}
+
+func (m *mockFareClassRepo) ReserveFareClassSeats(ctx context.Context, fareClassID int64, seatsToBook int, version int) error {
+	if m.reserveFn != nil {
+		return m.reserveFn(ctx, fareClassID, seatsToBook, version)
+	}
+	return nil
+}
+
+func (m *mockFareClassRepo) ReleaseFareClassSeats(ctx context.Context, flightID int64, code string, seatsToRelease int) error {
+	if m.releaseFn != nil {
+		return m.releaseFn(ctx, flightID, code, seatsToRelease)
+	}
+	return nil
+}
+
 // mockFlightCache implements FlightCache for testing.
 type mockFlightCache struct {
-	getFn func(ctx context.Context, key string) ([]models.Flight, error)
-	setFn func(ctx context.Context, key string, flights []models.Flight) error
+	getFn             func(ctx context.Context, key string) ([]models.Flight, error)
+	setFn             func(ctx context.Context, key string, flights []models.Flight) error
+	getCalendarFn     func(ctx context.Context, key string) (*models.FareCalendarResponse, error)
+	setCalendarFn     func(ctx context.Context, key string, calendar *models.FareCalendarResponse, flightIDs []int64) error
+	invalidateFn      func(ctx context.Context, flightID int64) error
+	invalidateRouteFn func(ctx context.Context, source, destination string, departure time.Time) error
+	recordRouteFn     func(ctx context.Context, source, destination string) error
+}
+
+func (m *mockFlightCache) RecordRouteSearch(ctx context.Context, source, destination string) error {
+	if m.recordRouteFn != nil {
+		return m.recordRouteFn(ctx, source, destination)
+	}
+	return nil
 }
 
 func (m *mockFlightCache) GetCachedFlights(ctx context.Context, key string) ([]models.Flight, error) {
@@ -66,9 +179,37 @@ func (m *mockFlightCache) GetCachedFlights(ctx context.Context, key string) ([]m
 	return nil, errors.New("cache miss")
 }
 
-func (m *mockFlightCache) SetCachedFlights(ctx context.Context, key string, flights []models.Flight) error {
+func (m *mockFlightCache) SetCachedFlights(ctx context.Context, req *models.FlightSearchRequest, flights []models.Flight) error {
 	if m.setFn != nil {
-		return m.setFn(ctx, key, flights)
+		return m.setFn(ctx, req.GetCacheKey(), flights)
+	}
+	return nil
+}
+
+func (m *mockFlightCache) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	if m.invalidateFn != nil {
+		return m.invalidateFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockFlightCache) InvalidateRouteSearches(ctx context.Context, source, destination string, departure time.Time) error {
+	if m.invalidateRouteFn != nil {
+		return m.invalidateRouteFn(ctx, source, destination, departure)
+	}
+	return nil
+}
+
+func (m *mockFlightCache) GetCachedFareCalendar(ctx context.Context, key string) (*models.FareCalendarResponse, error) {
+	if m.getCalendarFn != nil {
+		return m.getCalendarFn(ctx, key)
+	}
+	return nil, errors.New("cache miss")
+}
+
+func (m *mockFlightCache) SetCachedFareCalendar(ctx context.Context, req *models.FareCalendarRequest, calendar *models.FareCalendarResponse, flightIDs []int64) error {
+	if m.setCalendarFn != nil {
+		return m.setCalendarFn(ctx, req.GetCacheKey(), calendar, flightIDs)
 	}
 	return nil
 }
@@ -76,7 +217,7 @@ func (m *mockFlightCache) SetCachedFlights(ctx context.Context, key string, flig
 func TestFlightService_SearchFlights_InvalidRequest(t *testing.T) {
 	repo := &mockFlightRepo{}
 	cache := &mockFlightCache{}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
 
 	// Invalid because Date is zero
 	req := &models.FlightSearchRequest{
@@ -100,7 +241,7 @@ func TestFlightService_SearchFlights_CacheHit(t *testing.T) {
 			return expected, nil
 		},
 	}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
 
 	req := &models.FlightSearchRequest{
 		Source:      "Delhi",
@@ -134,7 +275,7 @@ func TestFlightService_SearchFlights_CacheMiss_DBHit(t *testing.T) {
 		},
 	}
 
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
 
 	req := &models.FlightSearchRequest{
 		Source:      "Delhi",
@@ -152,10 +293,459 @@ func TestFlightService_SearchFlights_CacheMiss_DBHit(t *testing.T) {
 	}
 }
 
+func TestFlightService_SearchFlights_FlexibleDatesCachedSeparately(t *testing.T) {
+	var keys []string
+	cache := &mockFlightCache{
+		setFn: func(ctx context.Context, key string, flights []models.Flight) error {
+			keys = append(keys, key)
+			return nil
+		},
+	}
+	svc := &FlightService{flightRepo: &mockFlightRepo{}, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
+
+	for _, flexDays := range []int{0, 2} {
+		req := &models.FlightSearchRequest{
+			Source:      "Delhi",
+			Destination: "Mumbai",
+			Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+			FlexDays:    flexDays,
+		}
+		if _, err := svc.SearchFlights(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(keys) != 2 || keys[0] != "Delhi#Mumbai#2025-01-20" || keys[1] != "Delhi#Mumbai#2025-01-20#flex2" {
+		t.Fatalf("expected exact and flexible searches cached under different keys, got %q", keys)
+	}
+
+	tooWide := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		FlexDays:    models.MaxFlexDays + 1,
+	}
+	if _, err := svc.SearchFlights(context.Background(), tooWide); err == nil {
+		t.Fatalf("expected error for a flexible window wider than %d days", models.MaxFlexDays)
+	}
+}
+
+func TestFlightService_SearchFlights_PagesResults(t *testing.T) {
+	var cached []models.Flight
+	repo := &mockFlightRepo{
+		searchFlightsFn: func(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error) {
+			// the repository returns one flight past the page
+			return []models.Flight{
+				{ID: 3, Timestamp: testDeparture, ArrivalTimestamp: testDeparture.Add(time.Hour)},
+				{ID: 1, Timestamp: testDeparture, ArrivalTimestamp: testDeparture.Add(2 * time.Hour)},
+				{ID: 2, Timestamp: testDeparture, ArrivalTimestamp: testDeparture.Add(3 * time.Hour)},
+			}, nil
+		},
+	}
+	cache := &mockFlightCache{
+		setFn: func(ctx context.Context, key string, flights []models.Flight) error {
+			cached = flights
+			return nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
+
+	req := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		SortBy:      models.FlightSortDuration,
+		Limit:       2,
+	}
+	resp, err := svc.SearchFlights(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Count != 2 || resp.Flights[1].ID != 1 || resp.NextCursor == "" {
+		t.Fatalf("expected a page of 2 flights with a next cursor, got %+v", resp)
+	}
+
+	if len(cached) != 3 {
+		t.Fatalf("expected the extra flight cached so cache hits can page too, got %d", len(cached))
+	}
+
+	next := *req
+	next.Cursor = resp.NextCursor
+	cursor, err := next.DecodeCursor()
+	if err != nil {
+		t.Fatalf("unexpected cursor error: %v", err)
+	}
+
+	if cursor.ID != 1 || cursor.Duration != 7200 {
+		t.Fatalf("expected the cursor after flight 1 at two hours, got %+v", cursor)
+	}
+
+	next.SortBy = models.FlightSortDeparture
+	if _, err := svc.SearchFlights(context.Background(), &next); err == nil {
+		t.Fatalf("expected a duration cursor to be rejected for a departure-sorted search")
+	}
+}
+
+func TestFlightService_SearchFlights_FiltersAndSortsByQuotedPrice(t *testing.T) {
+	var cacheKey string
+	var cached []models.Flight
+	repo := &mockFlightRepo{
+		searchFlightsFn: func(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error) {
+			return []models.Flight{
+				{ID: 1, Price: 150, AvailableSeats: 2, Timestamp: testDeparture},
+				{ID: 2, Price: 180, AvailableSeats: 90, Timestamp: testDeparture.Add(time.Hour)},
+				{ID: 3, Price: 250, AvailableSeats: 90, Timestamp: testDeparture.Add(2 * time.Hour)},
+				{ID: 4, Price: 120, AvailableSeats: 90, Timestamp: testDeparture.Add(3 * time.Hour)},
+				{ID: 5, Price: 400, AvailableSeats: 90, Timestamp: testDeparture.Add(4 * time.Hour)},
+			}, nil
+		},
+	}
+	cache := &mockFlightCache{
+		setFn: func(ctx context.Context, key string, flights []models.Flight) error {
+			cacheKey, cached = key, flights
+			return nil
+		},
+	}
+	cache.getFn = func(ctx context.Context, key string) ([]models.Flight, error) {
+		if key != cacheKey {
+			return nil, errors.New("cache miss")
+		}
+		return append([]models.Flight(nil), cached...), nil
+	}
+	// Nearly full flights are quoted at twice their base fare
+	pricing := &mockPriceQuoter{
+		quoteFn: func(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+			if flight.AvailableSeats < 10 {
+				return flight.Price * 2
+			}
+			return flight.Price
+		},
+	}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: pricing}
+
+	req := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		MinPrice:    150,
+		MaxPrice:    300,
+		SortBy:      models.FlightSortPrice,
+		Limit:       2,
+	}
+	resp, err := svc.SearchFlights(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flight 1 has a base fare in range but is quoted at 300; flight 4 is
+	// quoted below the range and flight 5 above it
+	if resp.Count != 2 || resp.Flights[0].ID != 2 || resp.Flights[1].ID != 3 || resp.NextCursor == "" {
+		t.Fatalf("expected flights 2 and 3 by quoted price with a next cursor, got %+v", resp)
+	}
+
+	if len(cached) != 5 {
+		t.Fatalf("expected every flight cached unpriced for later pages, got %d", len(cached))
+	}
+
+	next := *req
+	next.Cursor = resp.NextCursor
+	resp, err = svc.SearchFlights(context.Background(), &next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Count != 1 || resp.Flights[0].ID != 1 || resp.Flights[0].QuotedPrice != 300 || resp.NextCursor != "" {
+		t.Fatalf("expected the last page to hold flight 1 at 300, got %+v", resp)
+	}
+}
+
+func TestFlightSearchRequest_GetCacheKey_IncludesFilters(t *testing.T) {
+	base := models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+	}
+
+	filtered := base
+	filtered.DepartureAfter = "06:00"
+	filtered.DepartureBefore = "12:00"
+	filtered.MinSeats = 2
+	filtered.Status = models.FlightStatusOnTime
+	filtered.SortBy = models.FlightSortDuration
+	filtered.Limit = 50
+
+	if key := base.GetCacheKey(); key != "Delhi#Mumbai#2025-01-20" {
+		t.Fatalf("expected the plain route and day key, got %q", key)
+	}
+
+	expected := "Delhi#Mumbai#2025-01-20#dep06:00-12:00#seats2#statuson_time#sortduration#limit50"
+	if key := filtered.GetCacheKey(); key != expected {
+		t.Fatalf("expected %q, got %q", expected, key)
+	}
+
+	// Searches by price share one unpaged key whatever their range and page
+	priced := filtered
+	priced.MinPrice = 2000
+	priced.MaxPrice = 5000
+	priced.Cursor = priced.CursorAfter(models.Flight{ID: 1})
+	expected = "Delhi#Mumbai#2025-01-20#dep06:00-12:00#seats2#statuson_time#priced"
+	if key := priced.GetCacheKey(); key != expected {
+		t.Fatalf("expected %q, got %q", expected, key)
+	}
+}
+
+func TestFlightService_GetFareCalendar_QueriesMonthAndCaches(t *testing.T) {
+	var from, to time.Time
+	repo := &mockFlightRepo{
+		searchRouteFn: func(ctx context.Context, source, destination string, f, t time.Time, minSeats int) ([]models.Flight, error) {
+			from, to = f, t
+			return []models.Flight{
+				{ID: 1, Timestamp: time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC), Price: 2500},
+				{ID: 2, Timestamp: time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC), Price: 2200},
+				{ID: 3, Timestamp: time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC), Price: 3000},
+			}, nil
+		},
+	}
+	var cachedKey string
+	var cachedFlights []int64
+	cache := &mockFlightCache{
+		setCalendarFn: func(ctx context.Context, key string, calendar *models.FareCalendarResponse, flightIDs []int64) error {
+			cachedKey, cachedFlights = key, flightIDs
+			return nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
+
+	req := &models.FareCalendarRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Month:       time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+		Seats:       2,
+	}
+
+	calendar, err := svc.GetFareCalendar(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []models.FareCalendarDay{
+		{Date: "2025-01-15", LowestPrice: 2200, Flights: 2},
+		{Date: "2025-01-16", LowestPrice: 3000, Flights: 1},
+	}
+	if calendar.Month != "2025-01" || len(calendar.Days) != len(expected) {
+		t.Fatalf("unexpected fare calendar: %+v", calendar)
+	}
+	for i, day := range expected {
+		if calendar.Days[i] != day {
+			t.Fatalf("expected day %d to be %+v, got %+v", i, day, calendar.Days[i])
+		}
+	}
+
+	if !from.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the whole of January to be queried, got [%v, %v)", from, to)
+	}
+
+	if cachedKey != "fare_calendar:Delhi#Mumbai#2025-01#2" {
+		t.Fatalf("expected calendar cached per route and month, got %q", cachedKey)
+	}
+	if len(cachedFlights) != 3 {
+		t.Fatalf("expected the calendar to be indexed under every flight it was priced from, got %v", cachedFlights)
+	}
+}
+
+func TestFlightService_GetFareCalendar_UsesQuotedPrices(t *testing.T) {
+	day := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	repo := &mockFlightRepo{
+		searchRouteFn: func(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error) {
+			return []models.Flight{
+				{ID: 1, Timestamp: day, Price: 2000},
+				{ID: 2, Timestamp: day.Add(time.Hour), Price: 1500},
+				{ID: 3, Timestamp: day.Add(2 * time.Hour), Price: 1000},
+			}, nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+			return []models.FareClass{
+				{FlightID: 2, Code: "Y", Price: 1500, AvailableSeats: 1},
+				{FlightID: 2, Code: "J", Price: 4000, AvailableSeats: 5},
+				{FlightID: 3, Code: "Y", Price: 1000, AvailableSeats: 1},
+			}, nil
+		},
+	}
+	// Demand doubles every quote
+	pricing := &mockPriceQuoter{
+		quoteFn: func(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+			if fareClass != nil {
+				return fareClass.Price * 2
+			}
+			return flight.Price * 2
+		},
+	}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: fareClassRepo, cacheService: &mockFlightCache{}, pricing: pricing}
+
+	req := &models.FareCalendarRequest{Source: "Delhi", Destination: "Mumbai", Month: day, Seats: 2}
+	calendar, err := svc.GetFareCalendar(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flight 3 has no class with two seats; flight 2 is priced at class J
+	expected := models.FareCalendarDay{Date: "2025-01-15", LowestPrice: 4000, Flights: 2}
+	if len(calendar.Days) != 1 || calendar.Days[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, calendar.Days)
+	}
+}
+
+func TestFlightService_GetFareCalendar_CacheHit(t *testing.T) {
+	repo := &mockFlightRepo{
+		searchRouteFn: func(ctx context.Context, source, destination string, from, to time.Time, minSeats int) ([]models.Flight, error) {
+			t.Fatalf("did not expect a cached calendar to query the database")
+			return nil, nil
+		},
+	}
+	cache := &mockFlightCache{
+		getCalendarFn: func(ctx context.Context, key string) (*models.FareCalendarResponse, error) {
+			return &models.FareCalendarResponse{Month: "2025-01"}, nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, cacheService: cache}
+
+	req := &models.FareCalendarRequest{Source: "Delhi", Destination: "Mumbai", Month: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Seats: 1}
+	if _, err := svc.GetFareCalendar(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFlightService_SearchFlights_AttachesFareClasses(t *testing.T) {
+	repo := &mockFlightRepo{
+		searchFlightsFn: func(ctx context.Context, req *models.FlightSearchRequest) ([]models.Flight, error) {
+			return []models.Flight{{ID: 1}, {ID: 2}}, nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+			if len(flightIDs) != 2 {
+				t.Fatalf("expected fare classes of both flights to be loaded at once, got %v", flightIDs)
+			}
+			return []models.FareClass{
+				{FlightID: 1, Code: "Y", Cabin: models.SeatCabinEconomy, AvailableSeats: 5},
+				{FlightID: 1, Code: "J", Cabin: models.SeatCabinBusiness, Price: 400, AvailableSeats: 0},
+			}, nil
+		},
+	}
+	var cached []models.Flight
+	cache := &mockFlightCache{
+		getFn: func(ctx context.Context, key string) ([]models.Flight, error) {
+			return nil, errors.New("cache miss")
+		},
+		setFn: func(ctx context.Context, key string, flights []models.Flight) error {
+			cached = flights
+			return nil
+		},
+	}
+
+	pricing := &mockPriceQuoter{
+		holdFn: func(flight *models.Flight, fareClass *models.FareClass, price float64) (string, error) {
+			if fareClass != nil {
+				return fmt.Sprintf("%d-%s", flight.ID, fareClass.Code), nil
+			}
+			return fmt.Sprintf("%d", flight.ID), nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: fareClassRepo, cacheService: cache, pricing: pricing}
+
+	req := &models.FlightSearchRequest{
+		Source:      "Delhi",
+		Destination: "Mumbai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+	}
+
+	resp, err := svc.SearchFlights(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.Flights[0].FareClasses) != 2 || len(resp.Flights[1].FareClasses) != 0 {
+		t.Fatalf("expected fare classes on the first flight only, got %+v", resp.Flights)
+	}
+
+	if len(cached) != 2 || len(cached[0].FareClasses) != 2 {
+		t.Fatalf("expected fare class availability to be cached with the flights")
+	}
+
+	if resp.Flights[0].FareClasses[1].QuotedPrice != resp.Flights[0].FareClasses[1].Price {
+		t.Fatalf("expected fare classes to carry a quoted price")
+	}
+	// Flights sold by class hold prices per class, others per flight
+	if resp.Flights[0].QuoteToken != "" || resp.Flights[0].FareClasses[1].QuoteToken != "1-J" || resp.Flights[1].QuoteToken != "2" {
+		t.Fatalf("expected the quoted prices to be held, got %+v", resp.Flights)
+	}
+}
+
+func TestFlightService_SearchItineraries_PricesAndSorts(t *testing.T) {
+	date := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	var window [2]time.Time
+	var minSeats int
+
+	repo := &mockFlightRepo{
+		searchDepartingFn: func(ctx context.Context, from, to time.Time, seats int) ([]models.Flight, error) {
+			window = [2]time.Time{from, to}
+			minSeats = seats
+			return []models.Flight{
+				testLeg(1, "Delhi", "Mumbai", 6),
+				testLeg(2, "Delhi", "Chennai", 7),
+				testLeg(3, "Mumbai", "Chennai", 9),
+			}, nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+			return []models.FareClass{{FlightID: 3, Code: "Y", Price: 50, AvailableSeats: 9}}, nil
+		},
+	}
+	svc := &FlightService{
+		flightRepo:    repo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  &mockFlightCache{},
+		pricing:       &mockPriceQuoter{},
+		config:        itineraryConfig,
+	}
+
+	resp, err := svc.SearchItineraries(context.Background(), &models.ItinerarySearchRequest{
+		Source:      "Delhi",
+		Destination: "Chennai",
+		Date:        date,
+		Seats:       2,
+		MaxStops:    1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !window[0].Equal(date) || !window[1].Equal(date.Add(48*time.Hour)) || minSeats != 2 {
+		t.Fatalf("unexpected search window %v for %d seats", window, minSeats)
+	}
+
+	if resp.Count != 2 {
+		t.Fatalf("expected 2 itineraries, got %d", resp.Count)
+	}
+
+	connecting, direct := resp.Itineraries[0], resp.Itineraries[1]
+	if connecting.Stops != 1 || connecting.TotalPrice != 300 || connecting.DurationMinutes != 300 {
+		t.Fatalf("expected the earlier connection priced with its fare class first, got %+v", connecting)
+	}
+
+	if direct.Stops != 0 || direct.TotalPrice != 200 {
+		t.Fatalf("unexpected direct itinerary: %+v", direct)
+	}
+}
+
 func TestFlightService_CreateFlight_ValidationErrors(t *testing.T) {
 	repo := &mockFlightRepo{}
 	cache := &mockFlightCache{}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
 
 	tests := []struct {
 		name   string
@@ -164,31 +754,49 @@ func TestFlightService_CreateFlight_ValidationErrors(t *testing.T) {
 		{
 			name: "missing fields",
 			flight: &models.Flight{
-				Source:         "",
-				Destination:    "Mumbai",
-				AvailableSeats: 10,
-				TotalSeats:     20,
-				Price:          100,
+				Source:           "",
+				Destination:      "Mumbai",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+				AvailableSeats:   10,
+				TotalSeats:       20,
+				Price:            100,
 			},
 		},
 		{
 			name: "available > total",
 			flight: &models.Flight{
-				Source:         "Delhi",
-				Destination:    "Mumbai",
-				AvailableSeats: 30,
-				TotalSeats:     20,
-				Price:          100,
+				Source:           "Delhi",
+				Destination:      "Mumbai",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+				AvailableSeats:   30,
+				TotalSeats:       20,
+				Price:            100,
+			},
+		},
+		{
+			name: "arrival before departure",
+			flight: &models.Flight{
+				Source:           "Delhi",
+				Destination:      "Mumbai",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(-time.Hour),
+				AvailableSeats:   10,
+				TotalSeats:       20,
+				Price:            100,
 			},
 		},
 		{
 			name: "same source and destination",
 			flight: &models.Flight{
-				Source:         "Delhi",
-				Destination:    "Delhi",
-				AvailableSeats: 10,
-				TotalSeats:     20,
-				Price:          100,
+				Source:           "Delhi",
+				Destination:      "Delhi",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+				AvailableSeats:   10,
+				TotalSeats:       20,
+				Price:            100,
 			},
 		},
 	}
@@ -205,21 +813,39 @@ func TestFlightService_CreateFlight_ValidationErrors(t *testing.T) {
 func TestFlightService_CreateFlight_SetsDefaultsAndCallsRepo(t *testing.T) {
 	called := false
 
+	seatsCreated := 0
+
 	repo := &mockFlightRepo{
 		createFlightFn: func(ctx context.Context, f *models.Flight) (*models.Flight, error) {
 			called = true
+			f.ID = 1
 			return f, nil
 		},
 	}
-	cache := &mockFlightCache{}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	seatRepo := &mockSeatRepo{
+		createSeatsFn: func(ctx context.Context, flightID int64, seats []models.Seat) error {
+			seatsCreated = len(seats)
+			return nil
+		},
+	}
+	var invalidatedRoute string
+	cache := &mockFlightCache{
+		invalidateRouteFn: func(ctx context.Context, source, destination string, departure time.Time) error {
+			invalidatedRoute = source + "#" + destination + "#" + departure.Format("2006-01-02")
+			return nil
+		},
+	}
+	txManager := &mockTxManager{}
+	svc := &FlightService{flightRepo: repo, seatRepo: seatRepo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, txManager: txManager}
 
 	flight := &models.Flight{
-		Source:         "Delhi",
-		Destination:    "Mumbai",
-		AvailableSeats: 10,
-		TotalSeats:     20,
-		Price:          100,
+		Source:           "Delhi",
+		Destination:      "Mumbai",
+		Timestamp:        testDeparture,
+		ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+		AvailableSeats:   10,
+		TotalSeats:       20,
+		Price:            100,
 	}
 
 	created, err := svc.CreateFlight(context.Background(), flight)
@@ -238,12 +864,178 @@ func TestFlightService_CreateFlight_SetsDefaultsAndCallsRepo(t *testing.T) {
 	if created.Version != 1 {
 		t.Fatalf("expected version 1, got %d", created.Version)
 	}
+
+	if seatsCreated != 20 || txManager.calls != 1 {
+		t.Fatalf("expected a 20 seat map created in one transaction, got seats=%d tx=%d", seatsCreated, txManager.calls)
+	}
+
+	if invalidatedRoute != "Delhi#Mumbai#2025-01-20" {
+		t.Fatalf("expected cached searches of the new flight's route and day dropped, got %q", invalidatedRoute)
+	}
+}
+
+func TestFlightService_CreateFlight_WithFareClasses(t *testing.T) {
+	cabins := make(map[models.SeatCabin]int)
+	var createdClasses []models.FareClass
+
+	repo := &mockFlightRepo{
+		createFlightFn: func(ctx context.Context, f *models.Flight) (*models.Flight, error) {
+			f.ID = 1
+			return f, nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		createSeatsFn: func(ctx context.Context, flightID int64, seats []models.Seat) error {
+			for _, seat := range seats {
+				cabins[seat.Cabin]++
+			}
+			return nil
+		},
+	}
+	fareClassRepo := &mockFareClassRepo{
+		createFn: func(ctx context.Context, flightID int64, fareClasses []models.FareClass) ([]models.FareClass, error) {
+			createdClasses = fareClasses
+			return fareClasses, nil
+		},
+	}
+	txManager := &mockTxManager{}
+	svc := &FlightService{flightRepo: repo, seatRepo: seatRepo, fareClassRepo: fareClassRepo, cacheService: &mockFlightCache{}, txManager: txManager}
+
+	flight := &models.Flight{
+		Source:           "Delhi",
+		Destination:      "Mumbai",
+		Timestamp:        testDeparture,
+		ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+		AvailableSeats:   60,
+		TotalSeats:       60,
+		Price:            100,
+		FareClasses: []models.FareClass{
+			{Code: "y", Cabin: models.SeatCabinEconomy, Price: 100, TotalSeats: 36},
+			{Code: "W", Cabin: models.SeatCabinPremium, Price: 180, TotalSeats: 12},
+			{Code: "J", Cabin: models.SeatCabinBusiness, Price: 400, TotalSeats: 12, Refundable: true},
+		},
+	}
+
+	created, err := svc.CreateFlight(context.Background(), flight)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(createdClasses) != 3 || createdClasses[0].Code != "Y" {
+		t.Fatalf("expected normalized fare classes to be created, got %+v", createdClasses)
+	}
+
+	if len(created.FareClasses) != 3 {
+		t.Fatalf("expected created flight to carry its fare classes")
+	}
+
+	if cabins[models.SeatCabinEconomy] != 36 || cabins[models.SeatCabinPremium] != 12 || cabins[models.SeatCabinBusiness] != 12 {
+		t.Fatalf("expected seat map cabins to follow fare class allocation, got %v", cabins)
+	}
+
+	if txManager.calls != 1 {
+		t.Fatalf("expected flight, fare classes and seats to be created in one transaction")
+	}
+}
+
+func TestFlightService_CreateFlight_InvalidFareClasses(t *testing.T) {
+	svc := &FlightService{flightRepo: &mockFlightRepo{}, fareClassRepo: &mockFareClassRepo{}, txManager: &mockTxManager{}}
+
+	tests := []struct {
+		name        string
+		fareClasses []models.FareClass
+	}{
+		{
+			name: "seats do not add up to total",
+			fareClasses: []models.FareClass{
+				{Code: "Y", Cabin: models.SeatCabinEconomy, Price: 100, TotalSeats: 10},
+			},
+		},
+		{
+			name: "duplicate code",
+			fareClasses: []models.FareClass{
+				{Code: "Y", Cabin: models.SeatCabinEconomy, Price: 100, TotalSeats: 10},
+				{Code: "y", Cabin: models.SeatCabinEconomy, Price: 120, TotalSeats: 10},
+			},
+		},
+		{
+			name: "unknown cabin",
+			fareClasses: []models.FareClass{
+				{Code: "F", Cabin: "first", Price: 900, TotalSeats: 20},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flight := &models.Flight{
+				Source:           "Delhi",
+				Destination:      "Mumbai",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+				AvailableSeats:   20,
+				TotalSeats:       20,
+				Price:            100,
+				FareClasses:      tt.fareClasses,
+			}
+			if _, err := svc.CreateFlight(context.Background(), flight); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFlightService_GetSeatMap_CountsAvailableSeats(t *testing.T) {
+	bookingID := int64(9)
+	repo := &mockFlightRepo{
+		getFlightByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id}, nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		getByFlightFn: func(ctx context.Context, flightID int64) ([]models.Seat, error) {
+			return []models.Seat{
+				{SeatNumber: "1A", Available: true},
+				{SeatNumber: "1B", BookingID: &bookingID},
+				{SeatNumber: "1C", Available: true},
+			}, nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, seatRepo: seatRepo}
+
+	seatMap, err := svc.GetSeatMap(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seatMap.Seats) != 3 || seatMap.AvailableCount != 2 {
+		t.Fatalf("expected 3 seats with 2 available, got %d seats and %d available", len(seatMap.Seats), seatMap.AvailableCount)
+	}
+}
+
+func TestFlightService_GetSeatMap_FlightNotFound(t *testing.T) {
+	repo := &mockFlightRepo{
+		getFlightByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return nil, errors.New("flight not found")
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		getByFlightFn: func(ctx context.Context, flightID int64) ([]models.Seat, error) {
+			t.Fatalf("did not expect seats to be loaded for a missing flight")
+			return nil, nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, seatRepo: seatRepo}
+
+	if _, err := svc.GetSeatMap(context.Background(), 1); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
 }
 
 func TestFlightService_UpdateFlight_ValidationErrors(t *testing.T) {
 	repo := &mockFlightRepo{}
 	cache := &mockFlightCache{}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	svc := &FlightService{flightRepo: repo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}}
 
 	flight := &models.Flight{
 		Source:      "",
@@ -259,21 +1051,45 @@ func TestFlightService_UpdateFlight_ValidationErrors(t *testing.T) {
 
 func TestFlightService_UpdateFlight_Success(t *testing.T) {
 	called := false
+	var invalidatedFlight int64
+	var invalidatedRoute string
 	repo := &mockFlightRepo{
+		getFlightByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 20, Price: 80, Version: 2}, nil
+		},
 		updateFlightFn: func(ctx context.Context, f *models.Flight) error {
 			called = true
 			return nil
 		},
 	}
-	cache := &mockFlightCache{}
-	svc := &FlightService{flightRepo: repo, cacheService: cache}
+	cache := &mockFlightCache{
+		invalidateFn: func(ctx context.Context, flightID int64) error {
+			invalidatedFlight = flightID
+			return nil
+		},
+		invalidateRouteFn: func(ctx context.Context, source, destination string, departure time.Time) error {
+			invalidatedRoute = source + "#" + destination + "#" + departure.Format("2006-01-02")
+			return nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		getByFlightFn: func(ctx context.Context, flightID int64) ([]models.Seat, error) {
+			t.Fatalf("did not expect the seat map to be checked when seat counts are unchanged")
+			return nil, nil
+		},
+	}
+	svc := &FlightService{flightRepo: repo, seatRepo: seatRepo, fareClassRepo: &mockFareClassRepo{}, cacheService: cache, pricing: &mockPriceQuoter{}, txManager: &mockTxManager{}}
 
 	flight := &models.Flight{
-		Source:         "Delhi",
-		Destination:    "Mumbai",
-		AvailableSeats: 10,
-		TotalSeats:     20,
-		Price:          100,
+		ID:               3,
+		Source:           "Delhi",
+		Destination:      "Mumbai",
+		Timestamp:        testDeparture,
+		ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+		AvailableSeats:   10,
+		TotalSeats:       20,
+		Price:            100,
+		Version:          2,
 	}
 
 	if err := svc.UpdateFlight(context.Background(), flight); err != nil {
@@ -283,6 +1099,69 @@ func TestFlightService_UpdateFlight_Success(t *testing.T) {
 	if !called {
 		t.Fatalf("expected repo.UpdateFlight to be called")
 	}
+
+	if invalidatedFlight != 3 || invalidatedRoute != "Delhi#Mumbai#2025-01-20" {
+		t.Fatalf("expected searches listing flight 3 and covering its route and day dropped, got flight %d route %q", invalidatedFlight, invalidatedRoute)
+	}
 }
 
+func TestFlightService_UpdateFlight_SeatCounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		seats       []models.Seat
+		fareClasses []models.FareClass
+		totalSeats  int
+		version     int
+		wantErr     bool
+	}{
+		{name: "resized without a seat map", totalSeats: 30, version: 2},
+		{name: "resized with a seat map", seats: []models.Seat{{SeatNumber: "1A"}}, totalSeats: 30, version: 2, wantErr: true},
+		{name: "resized with fare classes", fareClasses: []models.FareClass{{FlightID: 3, Code: "Y", TotalSeats: 20}}, totalSeats: 30, version: 2, wantErr: true},
+		{name: "stale version", totalSeats: 20, version: 1, wantErr: true},
+	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			repo := &mockFlightRepo{
+				getFlightByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+					return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 20, Price: 100, Version: 2}, nil
+				},
+				updateFlightFn: func(ctx context.Context, f *models.Flight) error {
+					updated = true
+					return nil
+				},
+			}
+			seatRepo := &mockSeatRepo{
+				getByFlightFn: func(ctx context.Context, flightID int64) ([]models.Seat, error) {
+					return tt.seats, nil
+				},
+			}
+			fareClassRepo := &mockFareClassRepo{
+				getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+					return tt.fareClasses, nil
+				},
+			}
+			svc := &FlightService{flightRepo: repo, seatRepo: seatRepo, fareClassRepo: fareClassRepo, cacheService: &mockFlightCache{}, pricing: &mockPriceQuoter{}, txManager: &mockTxManager{}}
+
+			err := svc.UpdateFlight(context.Background(), &models.Flight{
+				ID:               3,
+				Source:           "Delhi",
+				Destination:      "Mumbai",
+				Timestamp:        testDeparture,
+				ArrivalTimestamp: testDeparture.Add(2 * time.Hour),
+				AvailableSeats:   10,
+				TotalSeats:       tt.totalSeats,
+				Price:            100,
+				Version:          tt.version,
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if updated == tt.wantErr {
+				t.Fatalf("expected flight updated=%v", !tt.wantErr)
+			}
+		})
+	}
+}
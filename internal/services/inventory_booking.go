@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"airline-booking-system/internal/models"
+
+	"go.opentelemetry.io/otel"
+)
+
+// createInventoryBooking books seats already taken from a flight's seat
+// inventory, without the flight lock. The booking records a pending seat
+// deduction instead of updating the flight, which the seat inventory sync
+// applies later. If the booking cannot be made its seats go back to the
+// inventory.
+func (s *BookingService) createInventoryBooking(ctx context.Context, req *models.BookingRequest, flight *models.Flight, quote *models.PriceQuote) (*models.BookingResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.createInventoryBooking")
+	defer span.End()
+
+	response, err := s.insertInventoryBooking(ctx, req, flight, quote)
+	if err != nil || response.Status == models.BookingStatusFailed {
+		s.returnInventorySeats(ctx, req.FlightID, req.SeatsBooked)
+	}
+	return response, err
+}
+
+// insertInventoryBooking prices a booking of seats taken from a seat
+// inventory and inserts it with its pending seat deduction
+func (s *BookingService) insertInventoryBooking(ctx context.Context, req *models.BookingRequest, flight *models.Flight, quote *models.PriceQuote) (*models.BookingResponse, error) {
+	// Flights sold by fare class never have a seat inventory, so this only
+	// turns away requests for a fare class
+	fareClass, failure, err := s.resolveFareClass(ctx, req.FlightID, req.FareClassCode, req.SeatsBooked)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.BookingResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	pricePerSeat, held := s.chargedPrice(ctx, flight, fareClass, quote)
+	if !held {
+		return &models.BookingResponse{
+			Status:       models.BookingStatusFailed,
+			Message:      "Fare changed since the quote was issued; request a new quote to confirm it",
+			CurrentPrice: pricePerSeat,
+		}, nil
+	}
+
+	booking := newPendingBooking(req, pricePerSeat*float64(req.SeatsBooked), "")
+
+	var createdBooking *models.Booking
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdBooking, err = s.bookingRepo.CreateBooking(ctx, booking)
+		if err != nil {
+			return fmt.Errorf("failed to create booking: %w", err)
+		}
+
+		if err := s.flightRepo.AddPendingSeatDeduction(ctx, createdBooking.ID, flight.ID, booking.SeatsBooked); err != nil {
+			return err
+		}
+
+		return s.assignSelectedSeats(ctx, flight.ID, createdBooking.ID, req.SelectedSeats(), "")
+	})
+	if err != nil {
+		if failure := reservationFailure(err); failure != "" {
+			return &models.BookingResponse{
+				Status:  models.BookingStatusFailed,
+				Message: failure,
+			}, nil
+		}
+		return nil, err
+	}
+
+	return s.startBookingPayment(ctx, createdBooking, booking.PaymentReferenceID), nil
+}
+
+// settleSeatInventory readies a flight whose lock the caller holds for a
+// booking under the lock. It returns false if the flight is sold from a seat
+// inventory, which bookings under the lock must leave alone, and otherwise
+// deducts any seats still pending from an inventory disabled since.
+func (s *BookingService) settleSeatInventory(ctx context.Context, flightID int64) (bool, error) {
+	tracked, err := s.cacheService.IsInventoryTracked(ctx, flightID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check seat inventory: %w", err)
+	}
+	if tracked {
+		return false, nil
+	}
+
+	if _, err := s.flightRepo.ApplyPendingSeatDeductions(ctx, flightID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// returnInventorySeats gives the seats of a released booking back to its
+// flight's seat inventory, if the flight has one. Failures are logged and
+// left for the seat inventory sync to correct.
+func (s *BookingService) returnInventorySeats(ctx context.Context, flightID int64, seats int) {
+	if err := s.cacheService.ReturnInventorySeats(ctx, flightID, seats); err != nil {
+		log.Printf("Failed to return %d seats to the seat inventory of flight %d: %v", seats, flightID, err)
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+)
+
+func newInventoryBookingService(flightRepo *mockFlightRepoBooking, cache *mockFlightCacheBooking, seatRepo *mockSeatRepo, gateway *mockPaymentGateway) *BookingService {
+	return &BookingService{
+		bookingRepo: &mockBookingRepo{
+			createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+				booking.ID = 9
+				return booking, nil
+			},
+		},
+		flightRepo:     flightRepo,
+		cacheService:   cache,
+		kafkaProducer:  &mockProducer{},
+		paymentGateway: gateway,
+		seatRepo:       seatRepo,
+		fareClassRepo:  &mockFareClassRepo{},
+		pricing:        &mockPriceQuoter{},
+		txManager:      &mockTxManager{},
+	}
+}
+
+func getInventoryFlight(ctx context.Context, id int64) (*models.Flight, error) {
+	return &models.Flight{ID: id, AvailableSeats: 10, TotalSeats: 10, Price: 100, FlightStatus: models.FlightStatusScheduled, Version: 1}, nil
+}
+
+func TestBookingService_CreateBooking_ReservesFromSeatInventory(t *testing.T) {
+	var pendingBooking, pendingFlight int64
+	pendingSeats := 0
+
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: getInventoryFlight,
+		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
+			t.Fatalf("did not expect the flight to be updated when booking from a seat inventory")
+			return nil
+		},
+		addPendingFn: func(ctx context.Context, bookingID int64, flightID int64, seats int) error {
+			pendingBooking, pendingFlight, pendingSeats = bookingID, flightID, seats
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		reserveInventoryFn: func(ctx context.Context, flightID int64, seats int) (bool, error) {
+			if flightID != 1 || seats != 2 {
+				t.Fatalf("unexpected seat inventory reservation flight=%d seats=%d", flightID, seats)
+			}
+			return true, nil
+		},
+		acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			t.Fatalf("did not expect the flight lock to be taken")
+			return nil, nil
+		},
+		joinQueueFn: func(ctx context.Context, flightID int64) (string, int64, error) {
+			t.Fatalf("did not expect the booking queue to be joined")
+			return "", 0, nil
+		},
+		returnInventoryFn: func(ctx context.Context, flightID int64, seats int) error {
+			t.Fatalf("did not expect seats returned for a booking that was made")
+			return nil
+		},
+	}
+
+	svc := newInventoryBookingService(flightRepo, cache, &mockSeatRepo{}, &mockPaymentGateway{})
+
+	req := &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      2,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}, {Name: "Jane"}},
+	}
+
+	resp, err := svc.CreateBooking(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending || resp.BookingID != 9 || resp.PaymentReferenceID == "" {
+		t.Fatalf("expected a pending booking, got %+v", resp)
+	}
+
+	if pendingBooking != 9 || pendingFlight != 1 || pendingSeats != 2 {
+		t.Fatalf("expected 2 pending seats of booking 9 on flight 1, got booking=%d flight=%d seats=%d", pendingBooking, pendingFlight, pendingSeats)
+	}
+}
+
+func TestBookingService_CreateBooking_SeatInventorySoldOut(t *testing.T) {
+	flightRepo := &mockFlightRepoBooking{getByIDFn: getInventoryFlight}
+	cache := &mockFlightCacheBooking{
+		reserveInventoryFn: func(ctx context.Context, flightID int64, seats int) (bool, error) {
+			return false, nil
+		},
+		joinQueueFn: func(ctx context.Context, flightID int64) (string, int64, error) {
+			t.Fatalf("did not expect a sold out flight to fall back to the flight lock")
+			return "", 0, nil
+		},
+	}
+
+	svc := newInventoryBookingService(flightRepo, cache, &mockSeatRepo{}, &mockPaymentGateway{})
+
+	resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.Message != "Insufficient seats available" {
+		t.Fatalf("expected insufficient seats, got %+v", resp)
+	}
+}
+
+func TestBookingService_CreateBooking_SeatInventoryReturnsSeatsOnFailure(t *testing.T) {
+	var mu sync.Mutex
+	returned := 0
+
+	flightRepo := &mockFlightRepoBooking{getByIDFn: getInventoryFlight}
+	cache := &mockFlightCacheBooking{
+		reserveInventoryFn: func(ctx context.Context, flightID int64, seats int) (bool, error) {
+			return true, nil
+		},
+		returnInventoryFn: func(ctx context.Context, flightID int64, seats int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			returned += seats
+			return nil
+		},
+	}
+	seatRepo := &mockSeatRepo{
+		assignSeatsFn: func(ctx context.Context, flightID int64, bookingID int64, seatNumbers []string, cabin models.SeatCabin) error {
+			return repositories.ErrSeatsUnavailable
+		},
+	}
+	gateway := &mockPaymentGateway{
+		authorizeFn: func(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error) {
+			t.Fatalf("did not expect payment for a rolled back booking")
+			return nil, nil
+		},
+	}
+
+	svc := newInventoryBookingService(flightRepo, cache, seatRepo, gateway)
+
+	resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John", SeatNumber: "12A"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.Message != "One or more selected seats are not available" {
+		t.Fatalf("expected the selected seat to be unavailable, got %+v", resp)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if returned != 1 {
+		t.Fatalf("expected the reserved seat returned to the inventory, got %d", returned)
+	}
+}
+
+func TestBookingService_CreateBooking_InventoryEnabledWhileWaiting(t *testing.T) {
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: getInventoryFlight,
+		updateAvailableFn: func(ctx context.Context, flightID int64, seatsToBook int, version int, fence int64) error {
+			t.Fatalf("did not expect seats deducted under the lock from a flight sold from a seat inventory")
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		inventoryTrackedFn: func(ctx context.Context, flightID int64) (bool, error) {
+			return true, nil
+		},
+	}
+
+	svc := newInventoryBookingService(flightRepo, cache, &mockSeatRepo{}, &mockPaymentGateway{})
+	svc.config = queueConfig
+
+	resp, err := svc.CreateBooking(context.Background(), &models.BookingRequest{
+		FlightID:         1,
+		UserID:           123,
+		SeatsBooked:      1,
+		PassengerDetails: []models.PassengerDetails{{Name: "John"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusFailed || resp.Message != "Flight is busy, please try again" {
+		t.Fatalf("expected the request to be turned away, got %+v", resp)
+	}
+}
+
+func TestBookingService_CancelBooking_DropsUndeductedSeats(t *testing.T) {
+	returned := 0
+
+	bookingRepo := &mockBookingRepo{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+			return &models.Booking{ID: id, FlightID: 1, Status: models.BookingStatusCompleted, PaymentReferenceID: "PAY-1", SeatsBooked: 2}, nil
+		},
+	}
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: getInventoryFlight,
+		dropPendingFn: func(ctx context.Context, bookingID int64) (bool, error) {
+			return bookingID == 7, nil
+		},
+		releaseSeatsFn: func(ctx context.Context, flightID int64, seatsToRelease int) error {
+			t.Fatalf("did not expect seats that were never deducted to be released")
+			return nil
+		},
+	}
+	cache := &mockFlightCacheBooking{
+		returnInventoryFn: func(ctx context.Context, flightID int64, seats int) error {
+			returned += seats
+			return nil
+		},
+	}
+	gateway := &mockPaymentGateway{
+		refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+			return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+		},
+	}
+
+	svc := newInventoryBookingService(flightRepo, cache, &mockSeatRepo{}, gateway)
+	svc.bookingRepo = bookingRepo
+
+	resp, err := svc.CancelBooking(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCancelled {
+		t.Fatalf("expected cancelled status, got %s", resp.Status)
+	}
+
+	if returned != 2 {
+		t.Fatalf("expected 2 seats returned to the inventory, got %d", returned)
+	}
+}
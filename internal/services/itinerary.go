@@ -0,0 +1,124 @@
+package services
+
+import (
+	"math"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// connects reports whether next can be flown after prev: it leaves from the
+// airport prev lands at, after a layover within the configured bounds
+func connects(prev, next *models.Flight, cfg *config.AppConfig) bool {
+	if next.Source != prev.Destination {
+		return false
+	}
+
+	layover := next.Timestamp.Sub(prev.ArrivalTimestamp)
+	return layover >= cfg.MinLayover && layover <= cfg.MaxLayover
+}
+
+// validItinerary reports whether legs form one journey: each leg connects to
+// the next, no airport is visited twice and the whole trip takes no longer
+// than MaxItineraryDuration
+func validItinerary(legs []models.Flight, cfg *config.AppConfig) bool {
+	if len(legs) == 0 || len(legs) > models.MaxItineraryStops+1 {
+		return false
+	}
+
+	visited := map[string]bool{legs[0].Source: true}
+	for i := range legs {
+		if i > 0 && !connects(&legs[i-1], &legs[i], cfg) {
+			return false
+		}
+		if visited[legs[i].Destination] {
+			return false
+		}
+		visited[legs[i].Destination] = true
+	}
+
+	return legs[len(legs)-1].ArrivalTimestamp.Sub(legs[0].Timestamp) <= cfg.MaxItineraryDuration
+}
+
+// composeItineraries finds every journey from req.Source to req.Destination
+// with at most req.MaxStops connections whose first leg departs on req.Date.
+// Flights that are not part of any journey are ignored.
+func composeItineraries(flights []models.Flight, req *models.ItinerarySearchRequest, cfg *config.AppConfig) [][]models.Flight {
+	bySource := make(map[string][]models.Flight)
+	for _, flight := range flights {
+		bySource[flight.Source] = append(bySource[flight.Source], flight)
+	}
+
+	var itineraries [][]models.Flight
+	var extend func(legs []models.Flight)
+	extend = func(legs []models.Flight) {
+		if !validItinerary(legs, cfg) {
+			return
+		}
+		if legs[len(legs)-1].Destination == req.Destination {
+			itineraries = append(itineraries, legs)
+			return
+		}
+		if len(legs) > req.MaxStops {
+			return
+		}
+		for _, next := range bySource[legs[len(legs)-1].Destination] {
+			// Copy on extend so sibling itineraries never share legs
+			extend(append(legs[:len(legs):len(legs)], next))
+		}
+	}
+
+	dayEnd := req.Date.AddDate(0, 0, 1)
+	for _, first := range bySource[req.Source] {
+		if first.Timestamp.Before(req.Date) || !first.Timestamp.Before(dayEnd) {
+			continue
+		}
+		extend([]models.Flight{first})
+	}
+
+	return itineraries
+}
+
+// newItinerary summarizes legs whose prices have been quoted. A leg sold by
+// fare class is priced at its cheapest class with enough seats left; ok is
+// false when a leg has no such class.
+func newItinerary(legs []models.Flight, seats int) (models.Itinerary, bool) {
+	pricePerSeat := 0.0
+	for i := range legs {
+		fare, ok := lowestAvailableFare(&legs[i], seats)
+		if !ok {
+			return models.Itinerary{}, false
+		}
+		pricePerSeat += fare
+	}
+
+	departure := legs[0].Timestamp
+	arrival := legs[len(legs)-1].ArrivalTimestamp
+	return models.Itinerary{
+		Legs:            legs,
+		Stops:           len(legs) - 1,
+		DepartureTime:   departure,
+		ArrivalTime:     arrival,
+		DurationMinutes: int(arrival.Sub(departure).Minutes()),
+		TotalPrice:      math.Round(pricePerSeat*float64(seats)*100) / 100,
+	}, true
+}
+
+// lowestAvailableFare returns the quoted price per seat of the cheapest way
+// to book seats on a flight
+func lowestAvailableFare(flight *models.Flight, seats int) (float64, bool) {
+	if len(flight.FareClasses) == 0 {
+		return flight.QuotedPrice, true
+	}
+
+	lowest, found := 0.0, false
+	for _, fareClass := range flight.FareClasses {
+		if fareClass.AvailableSeats < seats {
+			continue
+		}
+		if !found || fareClass.QuotedPrice < lowest {
+			lowest, found = fareClass.QuotedPrice, true
+		}
+	}
+	return lowest, found
+}
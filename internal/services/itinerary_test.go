@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// itineraryConfig allows layovers of 45 minutes to 6 hours and trips of up to a day.
+var itineraryConfig = &config.AppConfig{
+	MinLayover:           45 * time.Minute,
+	MaxLayover:           6 * time.Hour,
+	MaxItineraryDuration: 24 * time.Hour,
+}
+
+// testLeg returns a flight departing hours after the start of 2025-01-20 and
+// landing two hours later.
+func testLeg(id int64, source, destination string, hours float64) models.Flight {
+	departure := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC).Add(time.Duration(hours * float64(time.Hour)))
+	return models.Flight{
+		ID:               id,
+		Source:           source,
+		Destination:      destination,
+		Timestamp:        departure,
+		ArrivalTimestamp: departure.Add(2 * time.Hour),
+		AvailableSeats:   10,
+		TotalSeats:       10,
+		Price:            100,
+		QuotedPrice:      100,
+	}
+}
+
+func legIDs(legs []models.Flight) []int64 {
+	ids := make([]int64, len(legs))
+	for i, leg := range legs {
+		ids[i] = leg.ID
+	}
+	return ids
+}
+
+func TestComposeItineraries(t *testing.T) {
+	flights := []models.Flight{
+		testLeg(1, "Delhi", "Chennai", 6),     // direct
+		testLeg(2, "Delhi", "Mumbai", 8),      // lands 10:00
+		testLeg(3, "Mumbai", "Chennai", 10.5), // 30 minute layover: too short
+		testLeg(4, "Mumbai", "Chennai", 12),   // 2 hour layover
+		testLeg(5, "Mumbai", "Chennai", 17),   // 7 hour layover: too long
+		testLeg(6, "Mumbai", "Pune", 11),      // lands 13:00
+		testLeg(7, "Pune", "Chennai", 14),     // two stops via Mumbai and Pune
+		testLeg(8, "Mumbai", "Delhi", 11),     // back to the source
+		testLeg(9, "Delhi", "Chennai", 25),    // departs the next day
+	}
+
+	req := &models.ItinerarySearchRequest{
+		Source:      "Delhi",
+		Destination: "Chennai",
+		Date:        time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		Seats:       1,
+		MaxStops:    models.MaxItineraryStops,
+	}
+
+	itineraries := composeItineraries(flights, req, itineraryConfig)
+
+	var got [][]int64
+	for _, legs := range itineraries {
+		got = append(got, legIDs(legs))
+	}
+
+	want := [][]int64{{1}, {2, 4}, {2, 6, 7}}
+	if len(got) != len(want) {
+		t.Fatalf("expected itineraries %v, got %v", want, got)
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("expected itineraries %v, got %v", want, got)
+		}
+		for j := range want[i] {
+			if got[i][j] != want[i][j] {
+				t.Fatalf("expected itineraries %v, got %v", want, got)
+			}
+		}
+	}
+
+	req.MaxStops = 0
+	if direct := composeItineraries(flights, req, itineraryConfig); len(direct) != 1 || direct[0][0].ID != 1 {
+		t.Fatalf("expected only the direct flight without stops, got %v", direct)
+	}
+}
+
+func TestValidItinerary_MaxDuration(t *testing.T) {
+	legs := []models.Flight{
+		testLeg(1, "Delhi", "Mumbai", 8),
+		testLeg(2, "Mumbai", "Chennai", 14),
+	}
+
+	if !validItinerary(legs, itineraryConfig) {
+		t.Fatalf("expected connection to be valid")
+	}
+
+	short := *itineraryConfig
+	short.MaxItineraryDuration = 6 * time.Hour
+	if validItinerary(legs, &short) {
+		t.Fatalf("expected an 8 hour trip to exceed a 6 hour limit")
+	}
+}
+
+func TestNewItinerary_PricesCheapestAvailableFare(t *testing.T) {
+	classLeg := testLeg(2, "Mumbai", "Chennai", 12)
+	classLeg.FareClasses = []models.FareClass{
+		{Code: "Y", QuotedPrice: 80, AvailableSeats: 1},
+		{Code: "M", QuotedPrice: 120, AvailableSeats: 5},
+		{Code: "J", QuotedPrice: 300, AvailableSeats: 5},
+	}
+	legs := []models.Flight{testLeg(1, "Delhi", "Mumbai", 8), classLeg}
+
+	itinerary, ok := newItinerary(legs, 2)
+	if !ok {
+		t.Fatalf("expected itinerary to be bookable")
+	}
+
+	if itinerary.TotalPrice != 440 || itinerary.Stops != 1 || itinerary.DurationMinutes != 360 {
+		t.Fatalf("unexpected itinerary: %+v", itinerary)
+	}
+
+	if _, ok := newItinerary(legs, 6); ok {
+		t.Fatalf("expected itinerary without a fare class for 6 seats to be dropped")
+	}
+}
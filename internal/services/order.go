@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+
+	"go.opentelemetry.io/otel"
+)
+
+// inTravelOrder reports whether each flight departs after the previous one
+// lands. Unlike itinerary legs, order segments need not connect: a round trip
+// returns days later and a multi-city trip may continue from another airport.
+func inTravelOrder(flights []models.Flight) bool {
+	for i := 1; i < len(flights); i++ {
+		if !flights[i].Timestamp.After(flights[i-1].ArrivalTimestamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateOrder books the same passengers on every segment of a round-trip or
+// multi-city trip. All segments are reserved in one transaction, so either
+// every segment is booked or none is, and the whole order is paid at once.
+func (s *BookingService) CreateOrder(ctx context.Context, req *models.OrderRequest) (*models.OrderResponse, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.CreateOrder")
+	defer span.End()
+
+	if !req.IsValid() {
+		return nil, fmt.Errorf("invalid order request")
+	}
+
+	flights, err := s.getItineraryFlights(ctx, req.Segments)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inTravelOrder(flights) {
+		return &models.OrderResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Segments must be listed in travel order",
+		}, nil
+	}
+
+	// Acquire the lock of every segment, in flight ID order
+	locks, err := s.acquireFlightLocks(ctx, itineraryLockOrder(req.Segments))
+	if err != nil {
+		return nil, err
+	}
+
+	if locks == nil {
+		return &models.OrderResponse{
+			Status:  models.BookingStatusFailed,
+			Message: "Flight is currently being booked by another user",
+		}, nil
+	}
+
+	// Ensure locks are released
+	defer s.releaseFlightLocks(ctx, locks)
+
+	// Double-check every segment after acquiring the locks and price it
+	legs, totalPrice, failure, err := s.priceItineraryLegs(ctx, req.Segments, req.SeatsBooked)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.OrderResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	order, failure, err := s.reserveOrder(ctx, req.UserID, req.SeatsBooked, req.PassengerDetails, legs, totalPrice, locks)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &models.OrderResponse{
+			Status:  models.BookingStatusFailed,
+			Message: failure,
+		}, nil
+	}
+
+	s.startOrderPayment(ctx, order)
+
+	return &models.OrderResponse{
+		OrderID:            order.ID,
+		Status:             models.BookingStatusPending,
+		PaymentReferenceID: order.PaymentReferenceID,
+		TotalPrice:         order.TotalPrice,
+		Message:            "Order created, processing payment",
+	}, nil
+}
+
+// reserveOrder creates an order for priced legs held under their flight
+// locks and reserves every leg as one of its segments in one transaction; a
+// leg that cannot be had rolls back the whole order. It returns a failure
+// message when the seats could not be reserved.
+func (s *BookingService) reserveOrder(ctx context.Context, userID int64, seatsBooked int, passengers []models.PassengerDetails, legs []itineraryLeg, totalPrice float64, locks *flightLocks) (*models.Order, string, error) {
+	// Every segment carries the order's payment reference so gateway
+	// callbacks for the single payment can find the order
+	order := &models.Order{
+		UserID:             userID,
+		Status:             models.BookingStatusPending,
+		PaymentReferenceID: generatePaymentReferenceID(),
+		TotalPrice:         math.Round(totalPrice*100) / 100,
+		SeatsBooked:        seatsBooked,
+	}
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.orderRepo.CreateOrder(ctx, order); err != nil {
+			return err
+		}
+
+		for _, leg := range legs {
+			booking := &models.Booking{
+				FlightID:           leg.flight.ID,
+				UserID:             userID,
+				Status:             models.BookingStatusPending,
+				PaymentReferenceID: order.PaymentReferenceID,
+				BookingPrice:       leg.price,
+				SeatsBooked:        seatsBooked,
+				OrderID:            order.ID,
+				BookingMetadata:    passengers,
+			}
+			if leg.fareClass != nil {
+				booking.FareClassCode = leg.fareClass.Code
+			}
+
+			createdBooking, err := s.reserveBooking(ctx, booking, leg.flight, locks.fence(leg.flight.ID), leg.fareClass, nil)
+			if err != nil {
+				return err
+			}
+			order.Segments = append(order.Segments, *createdBooking)
+		}
+		return nil
+	})
+	if err != nil {
+		if failure := reservationFailure(err); failure != "" {
+			return nil, failure, nil
+		}
+		return nil, "", err
+	}
+
+	return order, "", nil
+}
+
+// startOrderPayment invalidates the cached seats of every segment of a newly
+// reserved order and starts charging its single payment
+func (s *BookingService) startOrderPayment(ctx context.Context, order *models.Order) {
+	for _, segment := range order.Segments {
+		s.invalidateFlightCache(ctx, segment.FlightID)
+	}
+
+	go s.processOrderPaymentAsync(context.WithoutCancel(ctx), order)
+}
+
+// GetOrderByID gets an order with all of its segments
+func (s *BookingService) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	return s.getOrder(ctx, id)
+}
+
+// getOrder loads an order and its segment bookings
+func (s *BookingService) getOrder(ctx context.Context, id int64) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := s.bookingRepo.GetBookingsByOrderID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	order.Segments = segments
+
+	return order, nil
+}
+
+// processOrderPaymentAsync charges the order total in one payment and settles
+// every segment with the outcome
+func (s *BookingService) processOrderPaymentAsync(ctx context.Context, order *models.Order) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "BookingService.processOrderPaymentAsync")
+	defer span.End()
+
+	paymentSuccessful := s.chargePayment(ctx, &models.PaymentRequest{
+		OrderID:            order.ID,
+		PaymentReferenceID: order.PaymentReferenceID,
+		Amount:             order.TotalPrice,
+	})
+
+	newStatus, err := s.settleOrderPayment(ctx, order, paymentSuccessful)
+	if err != nil {
+		log.Printf("Failed to settle payment for order %d: %v", order.ID, err)
+		return
+	}
+
+	log.Printf("Order %d payment processing completed: %s", order.ID, newStatus)
+}
+
+// settleOrderPayment moves a pending order and all of its segments to
+// completed or failed based on the payment outcome, in one transaction
+//
+// An order expired while its payment was being taken is left as it is, and a
+// payment captured for it is refunded.
+func (s *BookingService) settleOrderPayment(ctx context.Context, order *models.Order, paymentSuccessful bool) (models.BookingStatus, error) {
+	if !paymentSuccessful {
+		// Fail every segment and give the seats back to their flights
+		err := s.releaseSeatsForFailedOrder(ctx, order)
+		if errors.Is(err, repositories.ErrOrderStatusConflict) {
+			return s.currentOrderStatus(ctx, order.ID)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to release seats: %w", err)
+		}
+		return models.BookingStatusFailed, nil
+	}
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, models.BookingStatusCompleted); err != nil {
+			return err
+		}
+
+		for i := range order.Segments {
+			if err := s.completeBooking(ctx, &order.Segments[i], order.PaymentReferenceID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, repositories.ErrOrderStatusConflict) {
+		return s.refundUnclaimedOrderPayment(ctx, order)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return models.BookingStatusCompleted, nil
+}
+
+// refundUnclaimedOrderPayment refunds a payment captured for an order that
+// was settled without it, as when the order expired while the payment was
+// being taken. An order completed by another delivery of the same payment
+// keeps it. It returns the order's current status.
+func (s *BookingService) refundUnclaimedOrderPayment(ctx context.Context, order *models.Order) (models.BookingStatus, error) {
+	status, err := s.currentOrderStatus(ctx, order.ID)
+	if err != nil || status == models.BookingStatusCompleted {
+		return status, err
+	}
+
+	log.Printf("Order %d was %s before its payment %s was captured; refunding it", order.ID, status, order.PaymentReferenceID)
+	result, err := s.paymentGateway.Refund(ctx, order.PaymentReferenceID, order.TotalPrice)
+	if err != nil {
+		return status, fmt.Errorf("failed to refund payment %s: %w", order.PaymentReferenceID, err)
+	}
+	if !result.IsApproved() {
+		return status, fmt.Errorf("refund of payment %s declined: %s", order.PaymentReferenceID, result.Message)
+	}
+
+	return status, nil
+}
+
+// currentOrderStatus reads an order's status as it is now
+func (s *BookingService) currentOrderStatus(ctx context.Context, orderID int64) (models.BookingStatus, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get order: %w", err)
+	}
+	return order.Status, nil
+}
+
+// releaseSeatsForFailedOrder marks an order and its segments failed and
+// restores their seats, retrying with a linear backoff before giving up
+func (s *BookingService) releaseSeatsForFailedOrder(ctx context.Context, order *models.Order) error {
+	err := s.compensate(ctx, fmt.Sprintf("order %d", order.ID), func(ctx context.Context) error {
+		if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, models.BookingStatusFailed); err != nil {
+			return err
+		}
+
+		for i := range order.Segments {
+			if err := s.failBooking(ctx, &order.Segments[i], order.PaymentReferenceID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range order.Segments {
+		s.invalidateFlightCache(ctx, segment.FlightID)
+		s.returnInventorySeats(ctx, segment.FlightID, segment.SeatsBooked)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+)
+
+// mockOrderRepo implements OrderRepository for testing.
+type mockOrderRepo struct {
+	createFn       func(ctx context.Context, order *models.Order) (*models.Order, error)
+	getByIDFn      func(ctx context.Context, id int64) (*models.Order, error)
+	updateStatusFn func(ctx context.Context, orderID int64, status models.BookingStatus) error
+}
+
+func (m *mockOrderRepo) CreateOrder(ctx context.Context, order *models.Order) (*models.Order, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, order)
+	}
+	order.ID = 7
+	return order, nil
+}
+
+func (m *mockOrderRepo) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id)
+	}
+	return nil, errors.New("order not found")
+}
+
+func (m *mockOrderRepo) UpdateOrderStatus(ctx context.Context, orderID int64, status models.BookingStatus) error {
+	if m.updateStatusFn != nil {
+		return m.updateStatusFn(ctx, orderID, status)
+	}
+	return nil
+}
+
+func newOrderRequest(flightIDs ...int64) *models.OrderRequest {
+	req := &models.OrderRequest{
+		UserID:           123,
+		SeatsBooked:      2,
+		PassengerDetails: []models.PassengerDetails{{Name: "John Doe"}, {Name: "Jane Doe"}},
+	}
+	for _, flightID := range flightIDs {
+		req.Segments = append(req.Segments, models.ItineraryLeg{FlightID: flightID})
+	}
+	return req
+}
+
+// roundTrip is an outbound flight and a return flight two days later
+var roundTrip = []models.Flight{
+	testLeg(1, "Delhi", "Mumbai", 8),
+	testLeg(2, "Mumbai", "Delhi", 56),
+	testLeg(3, "Mumbai", "Delhi", 80),
+}
+
+func TestBookingService_CreateOrder_ReservesEverySegmentUnderOnePayment(t *testing.T) {
+	var reserved []int64
+	svc, _ := newItineraryTestService(roundTrip, &reserved, &mockFlightCacheBooking{})
+
+	var segments []models.Booking
+	svc.bookingRepo = &mockBookingRepo{
+		createFn: func(ctx context.Context, booking *models.Booking) (*models.Booking, error) {
+			booking.ID = int64(len(segments) + 1)
+			segments = append(segments, *booking)
+			return booking, nil
+		},
+	}
+	svc.orderRepo = &mockOrderRepo{}
+
+	resp, err := svc.CreateOrder(context.Background(), newOrderRequest(1, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusPending || resp.OrderID != 7 || resp.TotalPrice != 400 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if len(segments) != 2 || segments[0].FlightID != 1 || segments[1].FlightID != 2 {
+		t.Fatalf("expected both segments booked in travel order, got %+v", segments)
+	}
+
+	for _, segment := range segments {
+		if segment.OrderID != 7 || segment.PaymentReferenceID != resp.PaymentReferenceID {
+			t.Fatalf("expected segment to carry order 7 and its payment reference, got %+v", segment)
+		}
+	}
+}
+
+func TestBookingService_CreateOrder_Failures(t *testing.T) {
+	tests := []struct {
+		name      string
+		flightIDs []int64
+		message   string
+	}{
+		{name: "segments out of travel order", flightIDs: []int64{2, 1}, message: "Segments must be listed in travel order"},
+		{name: "return segment cannot be reserved", flightIDs: []int64{1, 3}, message: "Failed to reserve seats"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reserved []int64
+			svc, _ := newItineraryTestService(roundTrip, &reserved, &mockFlightCacheBooking{})
+			svc.orderRepo = &mockOrderRepo{}
+
+			resp, err := svc.CreateOrder(context.Background(), newOrderRequest(tt.flightIDs...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Status != models.BookingStatusFailed || resp.Message != tt.message || resp.OrderID != 0 {
+				t.Fatalf("expected failure %q, got %+v", tt.message, resp)
+			}
+		})
+	}
+}
+
+func TestBookingService_SettleOrderPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		paid     bool
+		expected models.BookingStatus
+	}{
+		{name: "paid", paid: true, expected: models.BookingStatusCompleted},
+		{name: "declined", paid: false, expected: models.BookingStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orderStatus models.BookingStatus
+			var completed, failed []int64
+			var events []*models.PaymentEvent
+
+			svc := &BookingService{
+				bookingRepo: &mockBookingRepo{
+					updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+						completed = append(completed, bookingID)
+						return nil
+					},
+					failAndReleaseFn: func(ctx context.Context, bookingID int64, paymentRefID *string) error {
+						failed = append(failed, bookingID)
+						return nil
+					},
+				},
+				orderRepo: &mockOrderRepo{
+					updateStatusFn: func(ctx context.Context, orderID int64, status models.BookingStatus) error {
+						orderStatus = status
+						return nil
+					},
+				},
+				cacheService: &mockFlightCacheBooking{},
+				kafkaProducer: &mockProducer{
+					sendPaymentFn: func(ctx context.Context, event *models.PaymentEvent) error {
+						events = append(events, event)
+						return nil
+					},
+				},
+				seatRepo:  &mockSeatRepo{},
+				txManager: &mockTxManager{},
+			}
+
+			order := &models.Order{
+				ID:                 7,
+				Status:             models.BookingStatusPending,
+				PaymentReferenceID: "PAY-1",
+				Segments: []models.Booking{
+					{ID: 1, FlightID: 1, OrderID: 7, SeatsBooked: 2},
+					{ID: 2, FlightID: 2, OrderID: 7, SeatsBooked: 2},
+				},
+			}
+
+			status, err := svc.settleOrderPayment(context.Background(), order, tt.paid)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			settled := failed
+			if tt.paid {
+				settled = completed
+			}
+
+			if status != tt.expected || orderStatus != tt.expected || len(settled) != 2 {
+				t.Fatalf("expected order and both segments %s, got status=%s order=%s segments=%v", tt.expected, status, orderStatus, settled)
+			}
+
+			if len(events) != 2 || events[0].OrderID != 7 || events[0].PaymentReferenceID != "PAY-1" {
+				t.Fatalf("expected one payment event per segment tagged with the order, got %+v", events)
+			}
+		})
+	}
+}
+
+func TestBookingService_SettleOrderPayment_RefundsExpiredOrder(t *testing.T) {
+	var refundedAmount float64
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+				t.Fatalf("did not expect segments of an expired order to be completed")
+				return nil
+			},
+		},
+		orderRepo: &mockOrderRepo{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Order, error) {
+				return &models.Order{ID: id, Status: models.BookingStatusExpired}, nil
+			},
+			updateStatusFn: func(ctx context.Context, orderID int64, status models.BookingStatus) error {
+				return repositories.ErrOrderStatusConflict
+			},
+		},
+		paymentGateway: &mockPaymentGateway{
+			refundFn: func(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error) {
+				refundedAmount = amount
+				return &models.PaymentResult{Status: models.PaymentStatusApproved}, nil
+			},
+		},
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		txManager:     &mockTxManager{},
+	}
+
+	order := &models.Order{ID: 7, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1", TotalPrice: 900}
+	status, err := svc.settleOrderPayment(context.Background(), order, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status != models.BookingStatusExpired || refundedAmount != 900 {
+		t.Fatalf("expected the expired order's payment refunded, got status=%s refunded=%v", status, refundedAmount)
+	}
+}
+
+func TestBookingService_HandlePaymentWebhook_SettlesOrder(t *testing.T) {
+	var orderStatus models.BookingStatus
+	var completed []int64
+	segments := []models.Booking{
+		{ID: 1, FlightID: 1, OrderID: 7, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1"},
+		{ID: 2, FlightID: 2, OrderID: 7, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1"},
+	}
+
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			getByPaymentRefFn: func(ctx context.Context, paymentRefID string) (*models.Booking, error) {
+				segment := segments[0]
+				return &segment, nil
+			},
+			getByOrderFn: func(ctx context.Context, orderID int64) ([]models.Booking, error) {
+				return segments, nil
+			},
+			updateStatusFn: func(ctx context.Context, bookingID int64, from, status models.BookingStatus, paymentRefID *string) error {
+				completed = append(completed, bookingID)
+				return nil
+			},
+		},
+		orderRepo: &mockOrderRepo{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Order, error) {
+				return &models.Order{ID: id, Status: models.BookingStatusPending, PaymentReferenceID: "PAY-1"}, nil
+			},
+			updateStatusFn: func(ctx context.Context, orderID int64, status models.BookingStatus) error {
+				orderStatus = status
+				return nil
+			},
+		},
+		cacheService:  &mockFlightCacheBooking{},
+		kafkaProducer: &mockProducer{},
+		seatRepo:      &mockSeatRepo{},
+		txManager:     &mockTxManager{},
+	}
+
+	event := &models.PaymentWebhookEvent{EventID: "evt-1", Type: models.PaymentWebhookEventSucceeded, PaymentReferenceID: "PAY-1"}
+	resp, err := svc.HandlePaymentWebhook(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCompleted || orderStatus != models.BookingStatusCompleted || len(completed) != 2 {
+		t.Fatalf("expected the whole order completed, got response=%s order=%s segments=%v", resp.Status, orderStatus, completed)
+	}
+}
+
+func TestBookingService_CancelBooking_RefusesOrderSegment(t *testing.T) {
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Booking, error) {
+				return &models.Booking{ID: id, FlightID: 1, OrderID: 7, Status: models.BookingStatusCompleted}, nil
+			},
+		},
+		cacheService: &mockFlightCacheBooking{
+			acquireFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+				t.Fatalf("did not expect an order segment to be cancelled on its own")
+				return nil, nil
+			},
+		},
+	}
+
+	resp, err := svc.CancelBooking(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Status != models.BookingStatusCompleted {
+		t.Fatalf("expected booking to stay completed, got %+v", resp)
+	}
+}
+
+func TestBookingService_GetOrderByID_IncludesSegments(t *testing.T) {
+	svc := &BookingService{
+		bookingRepo: &mockBookingRepo{
+			getByOrderFn: func(ctx context.Context, orderID int64) ([]models.Booking, error) {
+				return []models.Booking{{ID: 1, OrderID: orderID}, {ID: 2, OrderID: orderID}}, nil
+			},
+		},
+		orderRepo: &mockOrderRepo{
+			getByIDFn: func(ctx context.Context, id int64) (*models.Order, error) {
+				return &models.Order{ID: id}, nil
+			},
+		},
+	}
+
+	order, err := svc.GetOrderByID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if order.ID != 7 || len(order.Segments) != 2 {
+		t.Fatalf("expected order 7 with 2 segments, got %+v", order)
+	}
+}
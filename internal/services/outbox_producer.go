@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+	"airline-booking-system/pkg/kafka"
+)
+
+// OutboxRepository defines the outbox operations used by OutboxProducer and OutboxRelay.
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, topic string, key string, event interface{}) error
+	ClaimUnsent(ctx context.Context, limit int, claimTTL time.Duration) ([]models.OutboxMessage, error)
+	MarkSent(ctx context.Context, ids []int64) error
+	RecordFailure(ctx context.Context, ids []int64, publishErr string, retryAt time.Time) error
+}
+
+// OutboxProducer records events in the outbox instead of writing them to Kafka.
+// Events sent with a transactional context commit together with that
+// transaction and are published later by OutboxRelay.
+type OutboxProducer struct {
+	outboxRepo OutboxRepository
+}
+
+// NewOutboxProducer creates a new outbox producer
+func NewOutboxProducer(outboxRepo *repositories.OutboxRepository) *OutboxProducer {
+	return &OutboxProducer{outboxRepo: outboxRepo}
+}
+
+// SendSeatUpdateEvent records a seat update event
+func (p *OutboxProducer) SendSeatUpdateEvent(ctx context.Context, event *models.SeatUpdateEvent) error {
+	return p.outboxRepo.Enqueue(ctx, kafka.TopicFlightBookings, fmt.Sprintf("%d", event.FlightID), event)
+}
+
+// SendPaymentEvent records a payment event
+func (p *OutboxProducer) SendPaymentEvent(ctx context.Context, event *models.PaymentEvent) error {
+	return p.outboxRepo.Enqueue(ctx, kafka.TopicPaymentEvents, fmt.Sprintf("%d", event.BookingID), event)
+}
+
+// SendBookingCancellationEvent records a booking cancellation event
+func (p *OutboxProducer) SendBookingCancellationEvent(ctx context.Context, event *models.BookingCancellationEvent) error {
+	return p.outboxRepo.Enqueue(ctx, kafka.TopicBookingCancellations, fmt.Sprintf("%d", event.FlightID), event)
+}
+
+// SendBookingExpiredEvent records a booking expiry event
+func (p *OutboxProducer) SendBookingExpiredEvent(ctx context.Context, event *models.BookingExpiredEvent) error {
+	return p.outboxRepo.Enqueue(ctx, kafka.TopicBookingExpirations, fmt.Sprintf("%d", event.FlightID), event)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/kafka"
+)
+
+// mockOutboxRepo implements OutboxRepository for testing.
+type mockOutboxRepo struct {
+	enqueueFn       func(ctx context.Context, topic string, key string, event interface{}) error
+	claimFn         func(ctx context.Context, limit int, claimTTL time.Duration) ([]models.OutboxMessage, error)
+	markSentFn      func(ctx context.Context, ids []int64) error
+	recordFailureFn func(ctx context.Context, ids []int64, publishErr string, retryAt time.Time) error
+}
+
+func (m *mockOutboxRepo) Enqueue(ctx context.Context, topic string, key string, event interface{}) error {
+	if m.enqueueFn != nil {
+		return m.enqueueFn(ctx, topic, key, event)
+	}
+	return nil
+}
+
+func (m *mockOutboxRepo) ClaimUnsent(ctx context.Context, limit int, claimTTL time.Duration) ([]models.OutboxMessage, error) {
+	if m.claimFn != nil {
+		return m.claimFn(ctx, limit, claimTTL)
+	}
+	return nil, nil
+}
+
+func (m *mockOutboxRepo) MarkSent(ctx context.Context, ids []int64) error {
+	if m.markSentFn != nil {
+		return m.markSentFn(ctx, ids)
+	}
+	return nil
+}
+
+func (m *mockOutboxRepo) RecordFailure(ctx context.Context, ids []int64, publishErr string, retryAt time.Time) error {
+	if m.recordFailureFn != nil {
+		return m.recordFailureFn(ctx, ids, publishErr, retryAt)
+	}
+	return nil
+}
+
+func TestOutboxProducer_RoutesEventsToTopics(t *testing.T) {
+	type enqueued struct {
+		topic string
+		key   string
+	}
+	var got []enqueued
+
+	producer := &OutboxProducer{outboxRepo: &mockOutboxRepo{
+		enqueueFn: func(ctx context.Context, topic string, key string, event interface{}) error {
+			got = append(got, enqueued{topic: topic, key: key})
+			return nil
+		},
+	}}
+
+	ctx := context.Background()
+	producer.SendSeatUpdateEvent(ctx, &models.SeatUpdateEvent{FlightID: 1, BookingID: 10})
+	producer.SendPaymentEvent(ctx, &models.PaymentEvent{BookingID: 10})
+	producer.SendBookingCancellationEvent(ctx, &models.BookingCancellationEvent{FlightID: 2, BookingID: 11})
+	producer.SendBookingExpiredEvent(ctx, &models.BookingExpiredEvent{FlightID: 3, BookingID: 12})
+
+	expected := []enqueued{
+		{topic: kafka.TopicFlightBookings, key: "1"},
+		{topic: kafka.TopicPaymentEvents, key: "10"},
+		{topic: kafka.TopicBookingCancellations, key: "2"},
+		{topic: kafka.TopicBookingExpirations, key: "3"},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d enqueued events, got %d", len(expected), len(got))
+	}
+
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("event %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+	"airline-booking-system/pkg/database"
+	"airline-booking-system/pkg/kafka"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Publisher defines the Kafka operations used by OutboxRelay.
+type Publisher interface {
+	Write(ctx context.Context, messages ...kafka.OutboundMessage) error
+}
+
+// OutboxRelay publishes outbox messages to Kafka and marks them sent, giving
+// at-least-once delivery. Replicas can run relays concurrently: each relay
+// claims whole message keys for a while, so the messages of one key are
+// published by one relay at a time and in the order they were written.
+type OutboxRelay struct {
+	outboxRepo OutboxRepository
+	publisher  Publisher
+	txManager  TxManager
+	config     *config.AppConfig
+	tracerName string
+}
+
+// NewOutboxRelay creates a new outbox relay
+func NewOutboxRelay(
+	outboxRepo *repositories.OutboxRepository,
+	publisher *kafka.Producer,
+	db *database.DB,
+	config *config.AppConfig,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		txManager:  db,
+		config:     config,
+		tracerName: "airline-booking-system/outbox-relay",
+	}
+}
+
+// Run relays outbox messages every OutboxPollInterval until ctx is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.OutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the backlog before waiting for the next tick
+		for {
+			relayed, err := r.RelayBatch(ctx)
+			if err != nil {
+				log.Printf("Failed to relay outbox messages: %v", err)
+				break
+			}
+			if relayed < r.config.OutboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes one batch of unsent outbox messages and returns how
+// many were relayed. The batch is claimed in a short transaction and
+// published outside it. If Kafka is unavailable the messages stay unsent: the
+// failure is recorded and they are retried after a backoff that grows with
+// their attempts, holding back later messages of the same keys until then.
+func (r *OutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	tr := otel.Tracer(r.tracerName)
+	ctx, span := tr.Start(ctx, "OutboxRelay.RelayBatch")
+	defer span.End()
+
+	var messages []models.OutboxMessage
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		messages, err = r.outboxRepo.ClaimUnsent(ctx, r.config.OutboxBatchSize, r.config.OutboxClaimTTL)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(messages))
+	batch := make([]kafka.OutboundMessage, 0, len(messages))
+	attempts := 0
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+		batch = append(batch, kafka.OutboundMessage{
+			Topic: m.Topic,
+			Key:   []byte(m.MessageKey),
+			Value: m.Payload,
+		})
+		if m.Attempts > attempts {
+			attempts = m.Attempts
+		}
+	}
+
+	if err := r.publisher.Write(ctx, batch...); err != nil {
+		retryAt := time.Now().Add(r.retryBackoff(attempts + 1))
+		log.Printf("Failed to publish %d outbox messages, retrying at %s: %v", len(ids), retryAt.Format(time.RFC3339), err)
+		return 0, r.outboxRepo.RecordFailure(ctx, ids, err.Error(), retryAt)
+	}
+
+	if err := r.outboxRepo.MarkSent(ctx, ids); err != nil {
+		return 0, err
+	}
+
+	return len(messages), nil
+}
+
+// retryBackoff is how long messages wait after their nth failed attempt:
+// the poll interval, doubled for each attempt up to OutboxMaxBackoff
+func (r *OutboxRelay) retryBackoff(attempts int) time.Duration {
+	backoff := r.config.OutboxPollInterval
+	for i := 1; i < attempts && backoff < r.config.OutboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.config.OutboxMaxBackoff {
+		backoff = r.config.OutboxMaxBackoff
+	}
+	return backoff
+}
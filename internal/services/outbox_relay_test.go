@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/kafka"
+)
+
+// mockPublisher implements Publisher for testing.
+type mockPublisher struct {
+	writeFn func(ctx context.Context, messages ...kafka.OutboundMessage) error
+}
+
+func (m *mockPublisher) Write(ctx context.Context, messages ...kafka.OutboundMessage) error {
+	if m.writeFn != nil {
+		return m.writeFn(ctx, messages...)
+	}
+	return nil
+}
+
+func TestOutboxRelay_RelayBatch_PublishesAndMarksSent(t *testing.T) {
+	var published []kafka.OutboundMessage
+	var sentIDs []int64
+	var claimTTL time.Duration
+
+	txManager := &mockTxManager{}
+	repo := &mockOutboxRepo{
+		claimFn: func(ctx context.Context, limit int, ttl time.Duration) ([]models.OutboxMessage, error) {
+			claimTTL = ttl
+			return []models.OutboxMessage{
+				{ID: 1, Topic: kafka.TopicFlightBookings, MessageKey: "1", Payload: []byte(`{"flight_id":1}`)},
+				{ID: 2, Topic: kafka.TopicPaymentEvents, MessageKey: "10", Payload: []byte(`{"booking_id":10}`)},
+			}, nil
+		},
+		markSentFn: func(ctx context.Context, ids []int64) error {
+			sentIDs = ids
+			return nil
+		},
+	}
+	publisher := &mockPublisher{
+		writeFn: func(ctx context.Context, messages ...kafka.OutboundMessage) error {
+			if txManager.open {
+				t.Fatalf("did not expect messages to be published inside the claim transaction")
+			}
+			published = messages
+			return nil
+		},
+	}
+
+	relay := &OutboxRelay{
+		outboxRepo: repo,
+		publisher:  publisher,
+		txManager:  txManager,
+		config:     &config.AppConfig{OutboxBatchSize: 100, OutboxClaimTTL: time.Minute},
+	}
+
+	relayed, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if relayed != 2 || len(published) != 2 || len(sentIDs) != 2 {
+		t.Fatalf("expected 2 messages relayed and marked sent, got relayed=%d published=%d sent=%v", relayed, len(published), sentIDs)
+	}
+
+	if published[1].Topic != kafka.TopicPaymentEvents || string(published[1].Key) != "10" {
+		t.Fatalf("unexpected published message: %+v", published[1])
+	}
+
+	if txManager.calls != 1 || claimTTL != time.Minute {
+		t.Fatalf("expected the batch to be claimed for a minute in one transaction, got %d transactions and %v", txManager.calls, claimTTL)
+	}
+}
+
+func TestOutboxRelay_RelayBatch_LeavesFailedMessagesUnsent(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		backoff  time.Duration
+	}{
+		{name: "first failure", attempts: 0, backoff: time.Second},
+		{name: "third failure", attempts: 2, backoff: 4 * time.Second},
+		{name: "backoff capped", attempts: 20, backoff: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failureRecorded string
+			var retryAt time.Time
+
+			repo := &mockOutboxRepo{
+				claimFn: func(ctx context.Context, limit int, claimTTL time.Duration) ([]models.OutboxMessage, error) {
+					return []models.OutboxMessage{
+						{ID: 1, Topic: kafka.TopicFlightBookings, MessageKey: "1", Attempts: tt.attempts},
+						{ID: 2, Topic: kafka.TopicFlightBookings, MessageKey: "1"},
+					}, nil
+				},
+				markSentFn: func(ctx context.Context, ids []int64) error {
+					t.Fatalf("did not expect unpublished messages to be marked sent")
+					return nil
+				},
+				recordFailureFn: func(ctx context.Context, ids []int64, publishErr string, at time.Time) error {
+					failureRecorded = publishErr
+					retryAt = at
+					return nil
+				},
+			}
+			publisher := &mockPublisher{
+				writeFn: func(ctx context.Context, messages ...kafka.OutboundMessage) error {
+					return errors.New("broker unavailable")
+				},
+			}
+
+			relay := &OutboxRelay{
+				outboxRepo: repo,
+				publisher:  publisher,
+				txManager:  &mockTxManager{},
+				config:     &config.AppConfig{OutboxBatchSize: 100, OutboxPollInterval: time.Second, OutboxMaxBackoff: time.Minute},
+			}
+
+			before := time.Now()
+			relayed, err := relay.RelayBatch(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if relayed != 0 || failureRecorded == "" {
+				t.Fatalf("expected nothing relayed and a recorded failure, got relayed=%d failure=%q", relayed, failureRecorded)
+			}
+
+			if wait := retryAt.Sub(before); wait < tt.backoff || wait > tt.backoff+time.Second {
+				t.Fatalf("expected a retry in %v, got %v", tt.backoff, wait)
+			}
+		})
+	}
+}
+
+func TestOutboxRelay_RelayBatch_EmptyOutbox(t *testing.T) {
+	relay := &OutboxRelay{
+		outboxRepo: &mockOutboxRepo{},
+		publisher: &mockPublisher{
+			writeFn: func(ctx context.Context, messages ...kafka.OutboundMessage) error {
+				t.Fatalf("did not expect a publish for an empty outbox")
+				return nil
+			},
+		},
+		txManager: &mockTxManager{},
+		config:    &config.AppConfig{OutboxBatchSize: 100},
+	}
+
+	relayed, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if relayed != 0 {
+		t.Fatalf("expected nothing relayed, got %d", relayed)
+	}
+}
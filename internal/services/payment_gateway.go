@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// ErrPaymentNotFound is returned by a gateway for a payment reference it holds
+// no payment for, such as one that was never authorized
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// PaymentGateway defines the payment processor operations used by BookingService.
+// Operations after Authorize are addressed by the booking's payment reference ID.
+type PaymentGateway interface {
+	Authorize(ctx context.Context, req *models.PaymentRequest) (*models.PaymentResult, error)
+	Capture(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error)
+	Refund(ctx context.Context, paymentRefID string, amount float64) (*models.PaymentResult, error)
+	Void(ctx context.Context, paymentRefID string) (*models.PaymentResult, error)
+}
+
+// NewPaymentGateway creates the payment gateway selected by PaymentProvider
+func NewPaymentGateway(config *config.AppConfig) (PaymentGateway, error) {
+	switch config.PaymentProvider {
+	case "fake":
+		outcome := FakePaymentOutcome(config.FakePaymentOutcome)
+		if !outcome.IsValid() {
+			return nil, fmt.Errorf("invalid fake payment outcome: %s", config.FakePaymentOutcome)
+		}
+		return NewFakePaymentGateway(outcome, config.FakePaymentLatency), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", config.PaymentProvider)
+	}
+}
+
+// voidOrRefund gives back a payment taken for a booking or order that will not
+// be completed. The authorization is voided, or, if the payment was already
+// captured and can no longer be voided, the amount is refunded.
+func voidOrRefund(ctx context.Context, gateway PaymentGateway, paymentRefID string, amount float64) error {
+	result, voidErr := gateway.Void(ctx, paymentRefID)
+	if voidErr == nil && result.IsApproved() {
+		return nil
+	}
+
+	result, err := gateway.Refund(ctx, paymentRefID, amount)
+	if err != nil {
+		if voidErr != nil {
+			return fmt.Errorf("void failed: %v, refund failed: %w", voidErr, err)
+		}
+		return fmt.Errorf("refund failed: %w", err)
+	}
+	if !result.IsApproved() {
+		return fmt.Errorf("refund declined: %s", result.Message)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+
+	"go.opentelemetry.io/otel"
+)
+
+// PriceQuoter defines the pricing operations used by FlightService and BookingService.
+type PriceQuoter interface {
+	QuotePrice(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64
+	HoldPrice(flight *models.Flight, fareClass *models.FareClass, price float64) (string, error)
+}
+
+// PricingService computes seat prices from the base fare, load factor and
+// time to departure. Prices are not locked here: HoldPrice issues a quote
+// token that CreateBooking honors for one booking.
+type PricingService struct {
+	config     *config.AppConfig
+	now        func() time.Time
+	tracerName string
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService(config *config.AppConfig) *PricingService {
+	return &PricingService{
+		config:     config,
+		now:        time.Now,
+		tracerName: "airline-booking-system/pricing-service",
+	}
+}
+
+// QuotePrice returns the current per-seat price of a flight, or of one of
+// its fare classes
+func (s *PricingService) QuotePrice(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+	tr := otel.Tracer(s.tracerName)
+	_, span := tr.Start(ctx, "PricingService.QuotePrice")
+	defer span.End()
+
+	baseFare, availableSeats, totalSeats := flight.Price, flight.AvailableSeats, flight.TotalSeats
+	if fareClass != nil {
+		baseFare, availableSeats, totalSeats = fareClass.Price, fareClass.AvailableSeats, fareClass.TotalSeats
+	}
+
+	return s.CalculatePrice(baseFare, availableSeats, totalSeats, flight.Timestamp)
+}
+
+// HoldPrice returns a quote token holding a per-seat price of a flight, or of
+// one of its fare classes, for QuoteTTL. Search results carry these tokens so
+// a booking is charged the price the customer was shown.
+func (s *PricingService) HoldPrice(flight *models.Flight, fareClass *models.FareClass, price float64) (string, error) {
+	quote := models.PriceQuote{
+		FlightID:     flight.ID,
+		BaseFare:     flight.Price,
+		PricePerSeat: price,
+	}
+	if fareClass != nil {
+		quote.FareClassCode = fareClass.Code
+		quote.BaseFare = fareClass.Price
+	}
+
+	return issueQuote([]byte(s.config.QuoteSigningSecret), s.config.QuoteTTL, &quote, s.now())
+}
+
+// CalculatePrice applies the load factor and departure pricing rules to a
+// base fare. The load factor rule with the highest threshold reached and the
+// departure rule with the fewest days still covering the time left are
+// combined, clamped to the configured multiplier range and rounded to cents.
+func (s *PricingService) CalculatePrice(baseFare float64, availableSeats, totalSeats int, departure time.Time) float64 {
+	loadMultiplier := 1.0
+	if totalSeats > 0 {
+		loadFactor := 1 - float64(availableSeats)/float64(totalSeats)
+		for _, rule := range s.config.LoadFactorPricing {
+			if loadFactor >= rule.Threshold {
+				loadMultiplier = rule.Multiplier
+			}
+		}
+	}
+
+	departureMultiplier := 1.0
+	daysLeft := departure.Sub(s.now()).Hours() / 24
+	for _, rule := range s.config.DeparturePricing {
+		if daysLeft <= rule.Threshold {
+			departureMultiplier = rule.Multiplier
+			break
+		}
+	}
+
+	multiplier := math.Max(s.config.MinPriceMultiplier, math.Min(s.config.MaxPriceMultiplier, loadMultiplier*departureMultiplier))
+	return math.Round(baseFare*multiplier*100) / 100
+}
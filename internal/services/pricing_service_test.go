@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// mockPriceQuoter implements PriceQuoter for testing. By default it quotes
+// the base fare and holds no prices.
+type mockPriceQuoter struct {
+	quoteFn func(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64
+	holdFn  func(flight *models.Flight, fareClass *models.FareClass, price float64) (string, error)
+}
+
+func (m *mockPriceQuoter) QuotePrice(ctx context.Context, flight *models.Flight, fareClass *models.FareClass) float64 {
+	if m.quoteFn != nil {
+		return m.quoteFn(ctx, flight, fareClass)
+	}
+	if fareClass != nil {
+		return fareClass.Price
+	}
+	return flight.Price
+}
+
+func (m *mockPriceQuoter) HoldPrice(flight *models.Flight, fareClass *models.FareClass, price float64) (string, error) {
+	if m.holdFn != nil {
+		return m.holdFn(flight, fareClass, price)
+	}
+	return "", nil
+}
+
+func newTestPricingService(now time.Time) *PricingService {
+	return &PricingService{
+		config: &config.AppConfig{
+			LoadFactorPricing: []config.PricingRule{
+				{Threshold: 0.5, Multiplier: 1.1},
+				{Threshold: 0.9, Multiplier: 1.5},
+			},
+			DeparturePricing: []config.PricingRule{
+				{Threshold: 3, Multiplier: 1.3},
+				{Threshold: 14, Multiplier: 1.05},
+			},
+			MinPriceMultiplier: 0.8,
+			MaxPriceMultiplier: 1.8,
+		},
+		now: func() time.Time { return now },
+	}
+}
+
+func TestPricingService_CalculatePrice_Rules(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestPricingService(now)
+
+	tests := []struct {
+		name           string
+		availableSeats int
+		daysLeft       int
+		expected       float64
+	}{
+		{name: "empty flight far out", availableSeats: 100, daysLeft: 60, expected: 100},
+		{name: "half full far out", availableSeats: 50, daysLeft: 60, expected: 110},
+		{name: "empty flight within two weeks", availableSeats: 100, daysLeft: 10, expected: 105},
+		{name: "half full within two weeks", availableSeats: 40, daysLeft: 10, expected: 115.5},
+		{name: "nearly full last minute is capped", availableSeats: 5, daysLeft: 1, expected: 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			departure := now.AddDate(0, 0, tt.daysLeft)
+			if got := svc.CalculatePrice(100, tt.availableSeats, 100, departure); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPricingService_QuotePrice_FollowsEveryBooking(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestPricingService(now)
+
+	flight := &models.Flight{ID: 1, Price: 100, AvailableSeats: 100, TotalSeats: 100, Timestamp: now.AddDate(0, 0, 60)}
+	fareClass := &models.FareClass{Code: "J", Price: 400, AvailableSeats: 12, TotalSeats: 12}
+
+	if got := svc.QuotePrice(context.Background(), flight, fareClass); got != 400 {
+		t.Fatalf("expected the fare class to be quoted at its base fare, got %v", got)
+	}
+
+	// No price is held for later buyers once seats sell
+	fareClass.AvailableSeats = 6
+	if got := svc.QuotePrice(context.Background(), flight, fareClass); got != 440 {
+		t.Fatalf("expected the quote to follow the load factor, got %v", got)
+	}
+
+	flight.AvailableSeats = 50
+	if got := svc.QuotePrice(context.Background(), flight, nil); got != 110 {
+		t.Fatalf("expected the flight to be quoted at 110, got %v", got)
+	}
+}
+
+func TestPricingService_HoldPrice_IssuesSingleUseQuote(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestPricingService(now)
+	svc.config.QuoteSigningSecret = "secret"
+	svc.config.QuoteTTL = 10 * time.Minute
+
+	flight := &models.Flight{ID: 1, Price: 100}
+	fareClass := &models.FareClass{Code: "J", Price: 400}
+
+	token, err := svc.HoldPrice(flight, fareClass, 440)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	quote, err := verifyQuote([]byte("secret"), token, now)
+	if err != nil {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+	if quote.QuoteID == "" || quote.UserID != 0 || !quote.ExpiresAt.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("expected an ID, no user and a 10 minute expiry, got %+v", quote)
+	}
+	if !quote.Matches(1, "J", 3) || quote.BaseFare != 400 || quote.PricePerSeat != 440 {
+		t.Fatalf("expected the fare class price held for any seat count, got %+v", quote)
+	}
+
+	other, _ := svc.HoldPrice(flight, fareClass, 440)
+	if again, _ := verifyQuote([]byte("secret"), other, now); again.QuoteID == quote.QuoteID {
+		t.Fatal("expected every held price to get its own quote ID")
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"airline-booking-system/internal/models"
+)
+
+// errInvalidQuoteToken marks a quote token that is malformed, tampered with
+// or expired
+var errInvalidQuoteToken = errors.New("invalid quote token")
+
+// issueQuote gives a quote a new ID and an expiry ttl from now, and signs it.
+// The ID lets CreateBooking accept the token only once.
+func issueQuote(secret []byte, ttl time.Duration, quote *models.PriceQuote, now time.Time) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate quote ID: %w", err)
+	}
+
+	quote.QuoteID = hex.EncodeToString(id)
+	quote.ExpiresAt = now.Add(ttl).UTC()
+	return signQuote(secret, quote)
+}
+
+// signQuote encodes a quote as a token of the form payload.signature, both
+// base64url encoded, signed with HMAC-SHA256
+func signQuote(secret []byte, quote *models.PriceQuote) (string, error) {
+	payload, err := json.Marshal(quote)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal quote: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(quoteSignature(secret, encoded)), nil
+}
+
+// verifyQuote checks the signature and expiry of a quote token and returns
+// the quote it carries
+func verifyQuote(secret []byte, token string, now time.Time) (*models.PriceQuote, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errInvalidQuoteToken
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(quoteSignature(secret, encoded), expected) {
+		return nil, errInvalidQuoteToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidQuoteToken
+	}
+
+	var quote models.PriceQuote
+	if err := json.Unmarshal(payload, &quote); err != nil {
+		return nil, errInvalidQuoteToken
+	}
+
+	if !now.Before(quote.ExpiresAt) {
+		return nil, errInvalidQuoteToken
+	}
+
+	return &quote, nil
+}
+
+// quoteSignature computes the HMAC-SHA256 of an encoded quote payload
+func quoteSignature(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+)
+
+func TestQuoteToken_RoundTrip(t *testing.T) {
+	now := time.Now()
+	quote := &models.PriceQuote{FlightID: 1, FareClassCode: "Y", Seats: 2, PricePerSeat: 120.5, TotalPrice: 241, ExpiresAt: now.Add(time.Minute)}
+
+	token, err := signQuote([]byte("secret"), quote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	verified, err := verifyQuote([]byte("secret"), token, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !verified.Matches(1, "Y", 2) || verified.PricePerSeat != 120.5 {
+		t.Fatalf("unexpected quote: %+v", verified)
+	}
+}
+
+func TestQuoteToken_Rejected(t *testing.T) {
+	now := time.Now()
+	quote := &models.PriceQuote{FlightID: 1, Seats: 2, PricePerSeat: 100, TotalPrice: 200, ExpiresAt: now.Add(time.Minute)}
+
+	token, err := signQuote([]byte("secret"), quote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cheaper, err := signQuote([]byte("other-secret"), &models.PriceQuote{FlightID: 1, Seats: 2, PricePerSeat: 1, TotalPrice: 2, ExpiresAt: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		now   time.Time
+	}{
+		{name: "malformed", token: "not-a-token", now: now},
+		{name: "signed with another secret", token: cheaper, now: now},
+		{name: "tampered signature", token: token + "x", now: now},
+		{name: "expired", token: token, now: now.Add(2 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifyQuote([]byte("secret"), tt.token, tt.now); !errors.Is(err, errInvalidQuoteToken) {
+				t.Fatalf("expected errInvalidQuoteToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+	"airline-booking-system/pkg/kafka"
+
+	"go.opentelemetry.io/otel"
+)
+
+// FlightRepositorySeatSync defines persistence operations used by SeatCacheSync.
+type FlightRepositorySeatSync interface {
+	GetFlightByID(ctx context.Context, id int64) (*models.Flight, error)
+}
+
+// FlightCacheSeatSync defines cache operations used by SeatCacheSync.
+type FlightCacheSeatSync interface {
+	SetAvailableSeats(ctx context.Context, flightID int64, seats int) error
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+}
+
+// SeatCacheSync keeps the Redis seat cache in line with seat update events.
+// The seat count is read back from the database rather than decremented by
+// the event, so redelivered events leave the cache correct.
+type SeatCacheSync struct {
+	flightRepo   FlightRepositorySeatSync
+	cacheService FlightCacheSeatSync
+	tracerName   string
+}
+
+// NewSeatCacheSync creates a new seat cache sync handler
+func NewSeatCacheSync(flightRepo *repositories.FlightRepository, cacheService *cache.FlightCacheService) *SeatCacheSync {
+	return &SeatCacheSync{
+		flightRepo:   flightRepo,
+		cacheService: cacheService,
+		tracerName:   "airline-booking-system/seat-cache-sync",
+	}
+}
+
+// HandleSeatUpdate applies a seat update event from the flight-bookings topic
+func (s *SeatCacheSync) HandleSeatUpdate(ctx context.Context, message kafka.InboundMessage) error {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "SeatCacheSync.HandleSeatUpdate")
+	defer span.End()
+
+	var event models.SeatUpdateEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal seat update event: %w", err)
+	}
+
+	flight, err := s.flightRepo.GetFlightByID(ctx, event.FlightID)
+	if err != nil {
+		return fmt.Errorf("failed to get flight: %w", err)
+	}
+
+	if err := s.cacheService.SetAvailableSeats(ctx, flight.ID, flight.AvailableSeats); err != nil {
+		return fmt.Errorf("failed to update cached seats: %w", err)
+	}
+
+	// Cached searches listing this flight show the old seat count
+	if err := s.cacheService.InvalidateFlightSearches(ctx, flight.ID); err != nil {
+		return fmt.Errorf("failed to invalidate cached searches: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/models"
+	"airline-booking-system/pkg/kafka"
+)
+
+// mockSeatSyncCache implements FlightCacheSeatSync for testing.
+type mockSeatSyncCache struct {
+	setSeatsFn   func(ctx context.Context, flightID int64, seats int) error
+	invalidateFn func(ctx context.Context, flightID int64) error
+}
+
+func (m *mockSeatSyncCache) SetAvailableSeats(ctx context.Context, flightID int64, seats int) error {
+	if m.setSeatsFn != nil {
+		return m.setSeatsFn(ctx, flightID, seats)
+	}
+	return nil
+}
+
+func (m *mockSeatSyncCache) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	if m.invalidateFn != nil {
+		return m.invalidateFn(ctx, flightID)
+	}
+	return nil
+}
+
+func seatUpdateMessage(t *testing.T, event *models.SeatUpdateEvent) kafka.InboundMessage {
+	t.Helper()
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	return kafka.InboundMessage{Topic: kafka.TopicFlightBookings, Value: value}
+}
+
+func TestSeatCacheSync_HandleSeatUpdate_RefreshesSeatsAndSearch(t *testing.T) {
+	cachedSeats := -1
+	var invalidated int64
+
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return &models.Flight{
+				ID:             id,
+				Source:         "DEL",
+				Destination:    "BOM",
+				Timestamp:      time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+				AvailableSeats: 7,
+			}, nil
+		},
+	}
+	cache := &mockSeatSyncCache{
+		setSeatsFn: func(ctx context.Context, flightID int64, seats int) error {
+			cachedSeats = seats
+			return nil
+		},
+		invalidateFn: func(ctx context.Context, flightID int64) error {
+			invalidated = flightID
+			return nil
+		},
+	}
+
+	sync := &SeatCacheSync{flightRepo: flightRepo, cacheService: cache}
+
+	msg := seatUpdateMessage(t, &models.SeatUpdateEvent{FlightID: 1, SeatsBooked: 2, BookingID: 10})
+	if err := sync.HandleSeatUpdate(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cachedSeats != 7 {
+		t.Fatalf("expected cached seats to be set to 7, got %d", cachedSeats)
+	}
+
+	if invalidated != 1 {
+		t.Fatalf("expected cached searches listing flight 1 to be invalidated, got flight %d", invalidated)
+	}
+}
+
+func TestSeatCacheSync_HandleSeatUpdate_FlightLookupFails(t *testing.T) {
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			return nil, errors.New("db unavailable")
+		},
+	}
+	cache := &mockSeatSyncCache{
+		setSeatsFn: func(ctx context.Context, flightID int64, seats int) error {
+			t.Fatalf("did not expect cache update without a flight")
+			return nil
+		},
+	}
+
+	sync := &SeatCacheSync{flightRepo: flightRepo, cacheService: cache}
+
+	msg := seatUpdateMessage(t, &models.SeatUpdateEvent{FlightID: 1})
+	if err := sync.HandleSeatUpdate(context.Background(), msg); err == nil {
+		t.Fatalf("expected error so the message is not committed, got nil")
+	}
+}
+
+func TestSeatCacheSync_HandleSeatUpdate_RejectsMalformedEvent(t *testing.T) {
+	flightRepo := &mockFlightRepoBooking{
+		getByIDFn: func(ctx context.Context, id int64) (*models.Flight, error) {
+			t.Fatalf("did not expect a flight lookup for a malformed event")
+			return nil, nil
+		},
+	}
+
+	sync := &SeatCacheSync{flightRepo: flightRepo, cacheService: &mockSeatSyncCache{}}
+
+	msg := kafka.InboundMessage{Topic: kafka.TopicFlightBookings, Value: []byte("not json")}
+	if err := sync.HandleSeatUpdate(context.Background(), msg); err == nil {
+		t.Fatalf("expected error so the event is dead-lettered, got nil")
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+// seatInventorySyncJob is the leadership key shared by all seat inventory sync instances
+const seatInventorySyncJob = "seat-inventory-sync"
+
+// Seats booked from a seat inventory and deducted from their flight, and
+// seat inventories reset because they drifted from the database.
+var (
+	seatInventoryDeductedSeatsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "seat_inventory_deducted_seats_total",
+		Help: "Total number of seats booked from a seat inventory and deducted from their flight.",
+	})
+	seatInventoryCorrectionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "seat_inventory_corrections_total",
+		Help: "Total number of seat inventories reset to match the database.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(seatInventoryDeductedSeatsTotal, seatInventoryCorrectionsTotal)
+}
+
+// FlightRepositoryInventory defines flight operations used by SeatInventoryService.
+type FlightRepositoryInventory interface {
+	EnableSeatInventory(ctx context.Context, flightID int64) error
+	DisableSeatInventory(ctx context.Context, flightID int64) error
+	GetSeatInventoryFlightIDs(ctx context.Context) ([]int64, error)
+	GetFlightIDsWithPendingSeatDeductions(ctx context.Context) ([]int64, error)
+	ApplyPendingSeatDeductions(ctx context.Context, flightID int64) (int, error)
+	GetSeatInventory(ctx context.Context, flightID int64) (int, int, error)
+}
+
+// FareClassRepositoryInventory defines fare class operations used by SeatInventoryService.
+type FareClassRepositoryInventory interface {
+	GetFareClassesByFlightIDs(ctx context.Context, flightIDs []int64) ([]models.FareClass, error)
+}
+
+// FlightCacheInventory defines cache operations used by SeatInventoryService.
+type FlightCacheInventory interface {
+	AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	ResignLeadership(ctx context.Context, job string, instanceID string) error
+	AcquireFlightLock(ctx context.Context, flightID int64) (*models.FlightLock, error)
+	ReleaseFlightLock(ctx context.Context, lock *models.FlightLock) error
+	GetInventorySeats(ctx context.Context, flightID int64) (int64, bool, error)
+	SeedInventorySeats(ctx context.Context, flightID int64, seats int64) (bool, error)
+	CorrectInventorySeats(ctx context.Context, flightID int64, expected, seats int64) (bool, error)
+	DeleteInventorySeats(ctx context.Context, flightID int64) error
+	DeleteCachedSeats(ctx context.Context, flightID int64) error
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+}
+
+// SeatInventoryService sells high-demand flights from a seat inventory: a
+// seat counter in Redis that bookings take from atomically instead of taking
+// the flight lock. Bookings made this way record a pending seat deduction,
+// which a background sync applies to flights.available_seats every
+// SeatInventorySyncInterval. The same sync resets any seat inventory that
+// has drifted from the database. Only the replica holding leadership in
+// Redis syncs on each tick.
+type SeatInventoryService struct {
+	flightRepo    FlightRepositoryInventory
+	fareClassRepo FareClassRepositoryInventory
+	cacheService  FlightCacheInventory
+	config        *config.AppConfig
+	instanceID    string
+	tracerName    string
+
+	// lastSeen holds each seat inventory as read by the previous sync. Only
+	// the sync loop touches it.
+	lastSeen map[int64]int64
+}
+
+// NewSeatInventoryService creates a new seat inventory service
+func NewSeatInventoryService(
+	flightRepo *repositories.FlightRepository,
+	fareClassRepo *repositories.FareClassRepository,
+	cacheService *cache.FlightCacheService,
+	config *config.AppConfig,
+) *SeatInventoryService {
+	return &SeatInventoryService{
+		flightRepo:    flightRepo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  cacheService,
+		config:        config,
+		instanceID:    generateInstanceID(),
+		tracerName:    "airline-booking-system/seat-inventory-service",
+		lastSeen:      make(map[int64]int64),
+	}
+}
+
+// EnableSeatInventory starts selling a flight from a seat inventory seeded
+// with its available seats. Flights sold by fare class are not supported.
+func (s *SeatInventoryService) EnableSeatInventory(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "SeatInventoryService.EnableSeatInventory")
+	defer span.End()
+
+	fareClasses, err := s.fareClassRepo.GetFareClassesByFlightIDs(ctx, []int64{flightID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fare classes: %w", err)
+	}
+	if len(fareClasses) > 0 {
+		return nil, models.ErrSeatInventoryUnsupported
+	}
+
+	err = s.withFlightLock(ctx, flightID, func() error {
+		if err := s.flightRepo.EnableSeatInventory(ctx, flightID); err != nil {
+			return err
+		}
+		return s.seedLocked(ctx, flightID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSeatInventoryStatus(ctx, flightID)
+}
+
+// DisableSeatInventory stops selling a flight from its seat inventory and
+// deducts its pending seats, so bookings go back to the flight lock
+func (s *SeatInventoryService) DisableSeatInventory(ctx context.Context, flightID int64) error {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "SeatInventoryService.DisableSeatInventory")
+	defer span.End()
+
+	return s.withFlightLock(ctx, flightID, func() error {
+		// Drop the inventory first so no new booking takes from it
+		if err := s.cacheService.DeleteInventorySeats(ctx, flightID); err != nil {
+			return fmt.Errorf("failed to delete seat inventory: %w", err)
+		}
+		if err := s.flightRepo.DisableSeatInventory(ctx, flightID); err != nil {
+			return err
+		}
+		_, err := s.applyPendingSeats(ctx, flightID)
+		return err
+	})
+}
+
+// GetSeatInventoryStatus reports a flight's seat inventory and its seats
+// still pending deduction
+func (s *SeatInventoryService) GetSeatInventoryStatus(ctx context.Context, flightID int64) (*models.SeatInventoryStatus, error) {
+	seats, tracked, err := s.cacheService.GetInventorySeats(ctx, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seat inventory: %w", err)
+	}
+
+	_, pending, err := s.flightRepo.GetSeatInventory(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SeatInventoryStatus{
+		FlightID:       flightID,
+		Enabled:        tracked,
+		AvailableSeats: seats,
+		PendingSeats:   pending,
+	}, nil
+}
+
+// Run syncs seat inventories every SeatInventorySyncInterval until ctx is cancelled
+func (s *SeatInventoryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SeatInventorySyncInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		// Hand leadership over promptly instead of waiting for the lease to lapse
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.cacheService.ResignLeadership(resignCtx, seatInventorySyncJob, s.instanceID); err != nil {
+			log.Printf("Failed to resign seat inventory sync leadership: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick runs one sync pass if this instance is the leader
+func (s *SeatInventoryService) tick(ctx context.Context) {
+	// The lease outlives one interval so a healthy leader keeps it between ticks
+	leader, err := s.cacheService.AcquireLeadership(ctx, seatInventorySyncJob, s.instanceID, 2*s.config.SeatInventorySyncInterval)
+	if err != nil {
+		log.Printf("Failed to acquire seat inventory sync leadership: %v", err)
+		return
+	}
+
+	if !leader {
+		// Another instance syncs now; what we saw may be stale by the time
+		// leadership comes back
+		s.lastSeen = make(map[int64]int64)
+		return
+	}
+
+	if _, _, err := s.SyncSeatInventory(ctx); err != nil {
+		log.Printf("Failed to sync seat inventory: %v", err)
+	}
+}
+
+// SyncSeatInventory deducts pending seat deductions from their flights and
+// resets seat inventories that drifted from the database. It returns the
+// seats deducted and the number of inventories reset.
+//
+// A seat inventory should equal the flight's available seats less its
+// pending deductions, but a booking that has taken seats and not yet
+// committed looks like drift too. An inventory is therefore only reset when
+// it has not changed since the previous sync, long after any such booking
+// finished, and only if it still holds the value that was checked.
+func (s *SeatInventoryService) SyncSeatInventory(ctx context.Context) (int, int, error) {
+	tr := otel.Tracer(s.tracerName)
+	ctx, span := tr.Start(ctx, "SeatInventoryService.SyncSeatInventory")
+	defer span.End()
+
+	// Flights with pending deductions include those whose inventory was
+	// disabled while bookings were still taking from it
+	pendingFlightIDs, err := s.flightRepo.GetFlightIDsWithPendingSeatDeductions(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deducted := 0
+	for _, flightID := range pendingFlightIDs {
+		seats, err := s.applyPendingSeats(ctx, flightID)
+		if err != nil {
+			log.Printf("Failed to deduct pending seats of flight %d: %v", flightID, err)
+			continue
+		}
+		deducted += seats
+	}
+
+	flightIDs, err := s.flightRepo.GetSeatInventoryFlightIDs(ctx)
+	if err != nil {
+		return deducted, 0, err
+	}
+
+	corrected := 0
+	seen := make(map[int64]int64, len(flightIDs))
+	for _, flightID := range flightIDs {
+		reset, err := s.reconcileFlight(ctx, flightID, seen)
+		if err != nil {
+			log.Printf("Failed to reconcile seat inventory of flight %d: %v", flightID, err)
+			continue
+		}
+		if reset {
+			corrected++
+		}
+	}
+	s.lastSeen = seen
+
+	return deducted, corrected, nil
+}
+
+// reconcileFlight resets a flight's seat inventory if it drifted from the
+// database and has not changed since the previous sync, recording what it
+// read in seen. A missing inventory, as after a Redis flush, is seeded again.
+func (s *SeatInventoryService) reconcileFlight(ctx context.Context, flightID int64, seen map[int64]int64) (bool, error) {
+	seats, tracked, err := s.cacheService.GetInventorySeats(ctx, flightID)
+	if err != nil {
+		return false, err
+	}
+
+	if !tracked {
+		return false, s.seedSeatInventory(ctx, flightID)
+	}
+	seen[flightID] = seats
+
+	available, pending, err := s.flightRepo.GetSeatInventory(ctx, flightID)
+	if err != nil {
+		return false, err
+	}
+
+	expected := int64(available - pending)
+	previous, ok := s.lastSeen[flightID]
+	if seats == expected || !ok || previous != seats {
+		return false, nil
+	}
+
+	reset, err := s.cacheService.CorrectInventorySeats(ctx, flightID, seats, expected)
+	if err != nil || !reset {
+		return false, err
+	}
+
+	log.Printf("Reset seat inventory of flight %d from %d to %d seats", flightID, seats, expected)
+	seatInventoryCorrectionsTotal.Inc()
+	seen[flightID] = expected
+	return true, nil
+}
+
+// seedSeatInventory creates a flight's seat inventory from its available
+// seats unless it already has one. It holds the flight lock so no booking
+// under the lock changes the seats while they are counted.
+func (s *SeatInventoryService) seedSeatInventory(ctx context.Context, flightID int64) error {
+	return s.withFlightLock(ctx, flightID, func() error {
+		return s.seedLocked(ctx, flightID)
+	})
+}
+
+// seedLocked seeds a flight's seat inventory. Callers must hold the flight lock.
+func (s *SeatInventoryService) seedLocked(ctx context.Context, flightID int64) error {
+	if _, err := s.applyPendingSeats(ctx, flightID); err != nil {
+		return err
+	}
+
+	available, pending, err := s.flightRepo.GetSeatInventory(ctx, flightID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.cacheService.SeedInventorySeats(ctx, flightID, int64(available-pending)); err != nil {
+		return fmt.Errorf("failed to seed seat inventory: %w", err)
+	}
+	return nil
+}
+
+// applyPendingSeats deducts a flight's pending seat deductions and drops its
+// cached seat count and searches if any were deducted
+func (s *SeatInventoryService) applyPendingSeats(ctx context.Context, flightID int64) (int, error) {
+	seats, err := s.flightRepo.ApplyPendingSeatDeductions(ctx, flightID)
+	if err != nil || seats == 0 {
+		return 0, err
+	}
+
+	seatInventoryDeductedSeatsTotal.Add(float64(seats))
+	s.cacheService.DeleteCachedSeats(ctx, flightID)
+	if err := s.cacheService.InvalidateFlightSearches(ctx, flightID); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flightID, err)
+	}
+	return seats, nil
+}
+
+// withFlightLock runs fn holding a flight's lock. It fails with
+// models.ErrSeatInventoryBusy if the flight is being booked.
+func (s *SeatInventoryService) withFlightLock(ctx context.Context, flightID int64, fn func() error) error {
+	lock, err := s.cacheService.AcquireFlightLock(ctx, flightID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if lock == nil {
+		return models.ErrSeatInventoryBusy
+	}
+
+	defer func() {
+		if err := s.cacheService.ReleaseFlightLock(ctx, lock); err != nil {
+			log.Printf("Failed to release lock on flight %d: %v", flightID, err)
+		}
+	}()
+
+	return fn()
+}
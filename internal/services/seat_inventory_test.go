@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// mockInventoryFlightRepo implements FlightRepositoryInventory for testing.
+type mockInventoryFlightRepo struct {
+	enableFn       func(ctx context.Context, flightID int64) error
+	disableFn      func(ctx context.Context, flightID int64) error
+	inventoryIDsFn func(ctx context.Context) ([]int64, error)
+	pendingIDsFn   func(ctx context.Context) ([]int64, error)
+	applyPendingFn func(ctx context.Context, flightID int64) (int, error)
+	getInventoryFn func(ctx context.Context, flightID int64) (int, int, error)
+}
+
+func (m *mockInventoryFlightRepo) EnableSeatInventory(ctx context.Context, flightID int64) error {
+	if m.enableFn != nil {
+		return m.enableFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockInventoryFlightRepo) DisableSeatInventory(ctx context.Context, flightID int64) error {
+	if m.disableFn != nil {
+		return m.disableFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockInventoryFlightRepo) GetSeatInventoryFlightIDs(ctx context.Context) ([]int64, error) {
+	if m.inventoryIDsFn != nil {
+		return m.inventoryIDsFn(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockInventoryFlightRepo) GetFlightIDsWithPendingSeatDeductions(ctx context.Context) ([]int64, error) {
+	if m.pendingIDsFn != nil {
+		return m.pendingIDsFn(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockInventoryFlightRepo) ApplyPendingSeatDeductions(ctx context.Context, flightID int64) (int, error) {
+	if m.applyPendingFn != nil {
+		return m.applyPendingFn(ctx, flightID)
+	}
+	return 0, nil
+}
+
+func (m *mockInventoryFlightRepo) GetSeatInventory(ctx context.Context, flightID int64) (int, int, error) {
+	if m.getInventoryFn != nil {
+		return m.getInventoryFn(ctx, flightID)
+	}
+	return 0, 0, nil
+}
+
+// mockInventoryCache implements FlightCacheInventory for testing.
+type mockInventoryCache struct {
+	acquireLeaderFn func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	acquireLockFn   func(ctx context.Context, flightID int64) (*models.FlightLock, error)
+	getSeatsFn      func(ctx context.Context, flightID int64) (int64, bool, error)
+	seedFn          func(ctx context.Context, flightID int64, seats int64) (bool, error)
+	correctFn       func(ctx context.Context, flightID int64, expected, seats int64) (bool, error)
+	deleteSeatsFn   func(ctx context.Context, flightID int64) error
+	deleteCachedFn  func(ctx context.Context, flightID int64) error
+}
+
+func (m *mockInventoryCache) AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+	if m.acquireLeaderFn != nil {
+		return m.acquireLeaderFn(ctx, job, instanceID, ttl)
+	}
+	return true, nil
+}
+
+func (m *mockInventoryCache) ResignLeadership(ctx context.Context, job string, instanceID string) error {
+	return nil
+}
+
+func (m *mockInventoryCache) AcquireFlightLock(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+	if m.acquireLockFn != nil {
+		return m.acquireLockFn(ctx, flightID)
+	}
+	return &models.FlightLock{FlightID: flightID, Token: "token", Fence: 1}, nil
+}
+
+func (m *mockInventoryCache) ReleaseFlightLock(ctx context.Context, lock *models.FlightLock) error {
+	return nil
+}
+
+func (m *mockInventoryCache) GetInventorySeats(ctx context.Context, flightID int64) (int64, bool, error) {
+	if m.getSeatsFn != nil {
+		return m.getSeatsFn(ctx, flightID)
+	}
+	return 0, false, nil
+}
+
+func (m *mockInventoryCache) SeedInventorySeats(ctx context.Context, flightID int64, seats int64) (bool, error) {
+	if m.seedFn != nil {
+		return m.seedFn(ctx, flightID, seats)
+	}
+	return true, nil
+}
+
+func (m *mockInventoryCache) CorrectInventorySeats(ctx context.Context, flightID int64, expected, seats int64) (bool, error) {
+	if m.correctFn != nil {
+		return m.correctFn(ctx, flightID, expected, seats)
+	}
+	return true, nil
+}
+
+func (m *mockInventoryCache) DeleteInventorySeats(ctx context.Context, flightID int64) error {
+	if m.deleteSeatsFn != nil {
+		return m.deleteSeatsFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockInventoryCache) DeleteCachedSeats(ctx context.Context, flightID int64) error {
+	if m.deleteCachedFn != nil {
+		return m.deleteCachedFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockInventoryCache) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	return nil
+}
+
+func newTestSeatInventoryService(repo *mockInventoryFlightRepo, fareClassRepo *mockFareClassRepo, cache *mockInventoryCache) *SeatInventoryService {
+	return &SeatInventoryService{
+		flightRepo:    repo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  cache,
+		config:        &config.AppConfig{SeatInventorySyncInterval: time.Second},
+		instanceID:    "test-instance",
+		lastSeen:      make(map[int64]int64),
+	}
+}
+
+func TestSeatInventoryService_EnableSeatInventory_SeedsFromDatabase(t *testing.T) {
+	var events []string
+	repo := &mockInventoryFlightRepo{
+		enableFn: func(ctx context.Context, flightID int64) error {
+			events = append(events, "enable")
+			return nil
+		},
+		applyPendingFn: func(ctx context.Context, flightID int64) (int, error) {
+			events = append(events, "apply")
+			return 0, nil
+		},
+		getInventoryFn: func(ctx context.Context, flightID int64) (int, int, error) {
+			return 40, 0, nil
+		},
+	}
+	var seeded int64
+	cache := &mockInventoryCache{
+		acquireLockFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			events = append(events, "lock")
+			return &models.FlightLock{FlightID: flightID}, nil
+		},
+		seedFn: func(ctx context.Context, flightID int64, seats int64) (bool, error) {
+			events = append(events, "seed")
+			seeded = seats
+			return true, nil
+		},
+		getSeatsFn: func(ctx context.Context, flightID int64) (int64, bool, error) {
+			return seeded, seeded > 0, nil
+		},
+	}
+
+	svc := newTestSeatInventoryService(repo, &mockFareClassRepo{}, cache)
+
+	status, err := svc.EnableSeatInventory(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !status.Enabled || status.AvailableSeats != 40 {
+		t.Fatalf("expected an inventory of 40 seats, got %+v", status)
+	}
+
+	if got := fmt.Sprint(events); got != "[lock enable apply seed]" {
+		t.Fatalf("expected the inventory seeded under the flight lock, got %s", got)
+	}
+}
+
+func TestSeatInventoryService_EnableSeatInventory_Refusals(t *testing.T) {
+	fareClassRepo := &mockFareClassRepo{
+		getByFlightsFn: func(ctx context.Context, flightIDs []int64) ([]models.FareClass, error) {
+			return []models.FareClass{{FlightID: 3, Code: "Y"}}, nil
+		},
+	}
+	svc := newTestSeatInventoryService(&mockInventoryFlightRepo{}, fareClassRepo, &mockInventoryCache{})
+
+	if _, err := svc.EnableSeatInventory(context.Background(), 3); !errors.Is(err, models.ErrSeatInventoryUnsupported) {
+		t.Fatalf("expected flights sold by fare class to be refused, got %v", err)
+	}
+
+	cache := &mockInventoryCache{
+		acquireLockFn: func(ctx context.Context, flightID int64) (*models.FlightLock, error) {
+			return nil, nil
+		},
+	}
+	svc = newTestSeatInventoryService(&mockInventoryFlightRepo{}, &mockFareClassRepo{}, cache)
+
+	if _, err := svc.EnableSeatInventory(context.Background(), 3); !errors.Is(err, models.ErrSeatInventoryBusy) {
+		t.Fatalf("expected a flight being booked to be refused, got %v", err)
+	}
+}
+
+func TestSeatInventoryService_SyncSeatInventory_DeductsPendingSeats(t *testing.T) {
+	applied := make(map[int64]bool)
+	repo := &mockInventoryFlightRepo{
+		pendingIDsFn: func(ctx context.Context) ([]int64, error) {
+			return []int64{1, 2}, nil
+		},
+		applyPendingFn: func(ctx context.Context, flightID int64) (int, error) {
+			applied[flightID] = true
+			if flightID == 2 {
+				return 0, errors.New("db unavailable")
+			}
+			return 3, nil
+		},
+	}
+	var cacheDropped []int64
+	cache := &mockInventoryCache{
+		deleteCachedFn: func(ctx context.Context, flightID int64) error {
+			cacheDropped = append(cacheDropped, flightID)
+			return nil
+		},
+	}
+
+	svc := newTestSeatInventoryService(repo, &mockFareClassRepo{}, cache)
+
+	deducted, corrected, err := svc.SyncSeatInventory(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A flight that fails is logged and left for the next sync
+	if deducted != 3 || corrected != 0 || !applied[1] || !applied[2] {
+		t.Fatalf("expected 3 seats deducted from flight 1, got deducted=%d corrected=%d applied=%v", deducted, corrected, applied)
+	}
+
+	if len(cacheDropped) != 1 || cacheDropped[0] != 1 {
+		t.Fatalf("expected the cached seats of flight 1 dropped, got %v", cacheDropped)
+	}
+}
+
+func TestSeatInventoryService_SyncSeatInventory_CorrectsStableDrift(t *testing.T) {
+	inventory := int64(30)
+	repo := &mockInventoryFlightRepo{
+		inventoryIDsFn: func(ctx context.Context) ([]int64, error) {
+			return []int64{1}, nil
+		},
+		getInventoryFn: func(ctx context.Context, flightID int64) (int, int, error) {
+			// 38 available less 4 pending
+			return 38, 4, nil
+		},
+	}
+	var corrections []string
+	cache := &mockInventoryCache{
+		getSeatsFn: func(ctx context.Context, flightID int64) (int64, bool, error) {
+			return inventory, true, nil
+		},
+		correctFn: func(ctx context.Context, flightID int64, expected, seats int64) (bool, error) {
+			corrections = append(corrections, fmt.Sprintf("%d->%d", expected, seats))
+			inventory = seats
+			return true, nil
+		},
+	}
+
+	svc := newTestSeatInventoryService(repo, &mockFareClassRepo{}, cache)
+
+	// The first sight of a drift may be a booking in flight
+	if _, corrected, _ := svc.SyncSeatInventory(context.Background()); corrected != 0 {
+		t.Fatalf("did not expect a drift seen once to be corrected")
+	}
+
+	// A booking took seats in between, so the drift is not stable yet
+	inventory = 29
+	if _, corrected, _ := svc.SyncSeatInventory(context.Background()); corrected != 0 {
+		t.Fatalf("did not expect a changing inventory to be corrected")
+	}
+
+	_, corrected, err := svc.SyncSeatInventory(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if corrected != 1 || len(corrections) != 1 || corrections[0] != "29->34" {
+		t.Fatalf("expected the inventory reset from 29 to 34 once, got corrected=%d %v", corrected, corrections)
+	}
+
+	// Once corrected the inventory is left alone
+	if _, corrected, _ := svc.SyncSeatInventory(context.Background()); corrected != 0 {
+		t.Fatalf("did not expect a matching inventory to be corrected again")
+	}
+}
+
+func TestSeatInventoryService_SyncSeatInventory_ReseedsMissingInventory(t *testing.T) {
+	repo := &mockInventoryFlightRepo{
+		inventoryIDsFn: func(ctx context.Context) ([]int64, error) {
+			return []int64{1}, nil
+		},
+		getInventoryFn: func(ctx context.Context, flightID int64) (int, int, error) {
+			return 20, 2, nil
+		},
+	}
+	var seeded int64
+	cache := &mockInventoryCache{
+		seedFn: func(ctx context.Context, flightID int64, seats int64) (bool, error) {
+			seeded = seats
+			return true, nil
+		},
+	}
+
+	svc := newTestSeatInventoryService(repo, &mockFareClassRepo{}, cache)
+
+	if _, _, err := svc.SyncSeatInventory(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if seeded != 18 {
+		t.Fatalf("expected the flushed inventory seeded with 18 seats, got %d", seeded)
+	}
+}
+
+func TestSeatInventoryService_Tick_SkipsWhenNotLeader(t *testing.T) {
+	repo := &mockInventoryFlightRepo{
+		pendingIDsFn: func(ctx context.Context) ([]int64, error) {
+			t.Fatalf("did not expect a follower to sync")
+			return nil, nil
+		},
+	}
+	cache := &mockInventoryCache{
+		acquireLeaderFn: func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+			if job != seatInventorySyncJob || ttl != 2*time.Second {
+				t.Fatalf("unexpected leadership request job=%s ttl=%s", job, ttl)
+			}
+			return false, nil
+		},
+	}
+
+	svc := newTestSeatInventoryService(repo, &mockFareClassRepo{}, cache)
+	svc.lastSeen[1] = 30
+	svc.tick(context.Background())
+
+	if len(svc.lastSeen) != 0 {
+		t.Fatalf("expected a follower to forget the inventories it saw, got %v", svc.lastSeen)
+	}
+}
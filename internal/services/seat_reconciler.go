@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"airline-booking-system/internal/cache"
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+	"airline-booking-system/internal/repositories"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+// seatReconcilerJob is the leadership key shared by all seat reconciler instances
+const seatReconcilerJob = "seat-reconciler"
+
+// Seat counts that disagree with bookings as of the last reconciliation, by
+// store, and the seat counts repaired.
+var (
+	seatReconciliationDriftSeats = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "seat_reconciliation_drift_seats",
+		Help: "Seats by which each store's unrepaired seat counts disagree with bookings as of the last reconciliation.",
+	}, []string{"store"})
+	seatReconciliationMismatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "seat_reconciliation_mismatches",
+		Help: "Number of each store's seat counts left disagreeing with bookings by the last reconciliation.",
+	}, []string{"store"})
+	seatReconciliationRepairsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "seat_reconciliation_repairs_total",
+		Help: "Total number of seat counts repaired to match bookings, by store.",
+	}, []string{"store"})
+)
+
+func init() {
+	prometheus.MustRegister(seatReconciliationDriftSeats, seatReconciliationMismatches, seatReconciliationRepairsTotal)
+}
+
+// FlightRepositoryReconciler defines flight operations used by SeatReconciler.
+type FlightRepositoryReconciler interface {
+	GetFlightSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error)
+	RepairAvailableSeats(ctx context.Context, flightID int64, version int, seats int) (bool, error)
+}
+
+// FareClassRepositoryReconciler defines fare class operations used by SeatReconciler.
+type FareClassRepositoryReconciler interface {
+	GetFareClassSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error)
+	RepairFareClassSeats(ctx context.Context, fareClassID int64, version int, seats int) (bool, error)
+}
+
+// FlightCacheReconciler defines cache operations used by SeatReconciler.
+type FlightCacheReconciler interface {
+	AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	ResignLeadership(ctx context.Context, job string, instanceID string) error
+	GetCachedSeats(ctx context.Context, flightID int64) (int, bool, error)
+	DeleteCachedSeats(ctx context.Context, flightID int64) error
+	GetInventorySeats(ctx context.Context, flightID int64) (int64, bool, error)
+	CorrectInventorySeats(ctx context.Context, flightID int64, expected, seats int64) (bool, error)
+	InvalidateFlightSearches(ctx context.Context, flightID int64) error
+}
+
+// SeatReconciler checks every seat count of the flights yet to depart
+// against the bookings it should be derived from: the total seats less the
+// seats of pending and completed bookings. It covers flights and fare
+// classes in Postgres and the cached seats and seat inventories in Redis,
+// reports the counts that drifted as Prometheus gauges and, if asked to,
+// repairs them. It runs every SeatReconcileInterval on the replica holding
+// leadership in Redis, and on demand from the reconcile command.
+type SeatReconciler struct {
+	flightRepo    FlightRepositoryReconciler
+	fareClassRepo FareClassRepositoryReconciler
+	cacheService  FlightCacheReconciler
+	config        *config.AppConfig
+	instanceID    string
+	tracerName    string
+	now           func() time.Time
+
+	// lastInventory holds each seat inventory as read by the previous pass.
+	// Only one pass runs at a time.
+	lastInventory map[int64]int64
+}
+
+// NewSeatReconciler creates a new seat reconciler
+func NewSeatReconciler(
+	flightRepo *repositories.FlightRepository,
+	fareClassRepo *repositories.FareClassRepository,
+	cacheService *cache.FlightCacheService,
+	config *config.AppConfig,
+) *SeatReconciler {
+	return &SeatReconciler{
+		flightRepo:    flightRepo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  cacheService,
+		config:        config,
+		instanceID:    generateInstanceID(),
+		tracerName:    "airline-booking-system/seat-reconciler",
+		now:           time.Now,
+		lastInventory: make(map[int64]int64),
+	}
+}
+
+// Run reconciles seat counts every SeatReconcileInterval until ctx is cancelled
+func (r *SeatReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.SeatReconcileInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		// Hand leadership over promptly instead of waiting for the lease to lapse
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.cacheService.ResignLeadership(resignCtx, seatReconcilerJob, r.instanceID); err != nil {
+			log.Printf("Failed to resign seat reconciler leadership: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+// tick runs one reconciliation pass if this instance is the leader
+func (r *SeatReconciler) tick(ctx context.Context) {
+	// The lease outlives one interval so a healthy leader keeps it between ticks
+	leader, err := r.cacheService.AcquireLeadership(ctx, seatReconcilerJob, r.instanceID, 2*r.config.SeatReconcileInterval)
+	if err != nil {
+		log.Printf("Failed to acquire seat reconciler leadership: %v", err)
+		return
+	}
+
+	if !leader {
+		r.lastInventory = make(map[int64]int64)
+		return
+	}
+
+	report, err := r.ReconcileSeats(ctx, r.config.SeatReconcileRepair)
+	if err != nil {
+		log.Printf("Failed to reconcile seats: %v", err)
+		return
+	}
+
+	for _, drift := range report.Drifts {
+		log.Printf("Seat count drift in %s", drift)
+	}
+}
+
+// ReconcileSeats checks the seat counts of every flight yet to depart and
+// of its fare classes against their bookings, repairing the ones that
+// drifted if repair is set, and records what is left drifting in the
+// Prometheus gauges.
+//
+// Postgres counts are read in one snapshot with the bookings, so any
+// mismatch is real; a repair only applies if the row has not changed since.
+// A seat inventory is also taken from by bookings that have not committed
+// yet, so it is only repaired once it has held the same drifted value on two
+// passes, and only if it still holds that value.
+func (r *SeatReconciler) ReconcileSeats(ctx context.Context, repair bool) (*models.SeatReconciliationReport, error) {
+	tr := otel.Tracer(r.tracerName)
+	ctx, span := tr.Start(ctx, "SeatReconciler.ReconcileSeats")
+	defer span.End()
+
+	now := r.now()
+	flightCounts, err := r.flightRepo.GetFlightSeatCounts(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SeatReconciliationReport{FlightsChecked: len(flightCounts)}
+	seen := make(map[int64]int64)
+	for _, count := range flightCounts {
+		drifts, err := r.reconcileFlight(ctx, count, repair, seen)
+		report.Drifts = append(report.Drifts, drifts...)
+		if err != nil {
+			log.Printf("Failed to reconcile seats of flight %d: %v", count.FlightID, err)
+		}
+	}
+	r.lastInventory = seen
+
+	fareClassCounts, err := r.fareClassRepo.GetFareClassSeatCounts(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report.FareClassesChecked = len(fareClassCounts)
+	for _, count := range fareClassCounts {
+		drift, err := r.reconcileFareClass(ctx, count, repair)
+		if drift != nil {
+			report.Drifts = append(report.Drifts, *drift)
+		}
+		if err != nil {
+			log.Printf("Failed to reconcile seats of flight %d class %s: %v", count.FlightID, count.Code, err)
+		}
+	}
+
+	recordSeatDrift(report)
+	return report, nil
+}
+
+// reconcileFlight checks a flight's available seats in Postgres, its cached
+// seats and its seat inventory, recording the inventory it read in seen
+func (r *SeatReconciler) reconcileFlight(ctx context.Context, count models.FlightSeatCount, repair bool, seen map[int64]int64) ([]models.SeatDrift, error) {
+	var drifts []models.SeatDrift
+
+	expected := count.ExpectedAvailableSeats()
+	settled := true
+	if count.AvailableSeats != expected {
+		drift := models.SeatDrift{
+			FlightID:      count.FlightID,
+			Store:         models.SeatStoreFlights,
+			RecordedSeats: count.AvailableSeats,
+			ExpectedSeats: expected,
+		}
+
+		if repair {
+			repaired, err := r.flightRepo.RepairAvailableSeats(ctx, count.FlightID, count.Version, expected)
+			if err != nil {
+				return append(drifts, drift), err
+			}
+			drift.Repaired = repaired
+		}
+
+		if drift.Repaired {
+			r.invalidateSearches(ctx, count.FlightID)
+		}
+		settled = drift.Repaired
+		drifts = append(drifts, drift)
+	}
+
+	cached, ok, err := r.cacheService.GetCachedSeats(ctx, count.FlightID)
+	if err != nil {
+		return drifts, err
+	}
+	if ok && cached != expected {
+		drift := models.SeatDrift{
+			FlightID:      count.FlightID,
+			Store:         models.SeatStoreSeatCache,
+			RecordedSeats: cached,
+			ExpectedSeats: expected,
+		}
+
+		// The cache is filled from Postgres, so the repair drops the stale
+		// entry rather than racing seat update events to overwrite it
+		if repair {
+			if err := r.cacheService.DeleteCachedSeats(ctx, count.FlightID); err != nil {
+				return append(drifts, drift), err
+			}
+			drift.Repaired = true
+		}
+		drifts = append(drifts, drift)
+	}
+
+	if !count.InventoryTracked {
+		return drifts, nil
+	}
+
+	// A missing inventory is seeded again by the seat inventory sync
+	seats, tracked, err := r.cacheService.GetInventorySeats(ctx, count.FlightID)
+	if err != nil || !tracked {
+		return drifts, err
+	}
+	seen[count.FlightID] = seats
+
+	expectedInventory := int64(count.ExpectedInventorySeats())
+	if seats == expectedInventory {
+		return drifts, nil
+	}
+
+	drift := models.SeatDrift{
+		FlightID:      count.FlightID,
+		Store:         models.SeatStoreSeatInventory,
+		RecordedSeats: int(seats),
+		ExpectedSeats: int(expectedInventory),
+	}
+
+	// The seat inventory sync resets inventories to match Postgres, so they
+	// are only repaired once Postgres matches the bookings
+	previous, stable := r.lastInventory[count.FlightID]
+	if repair && settled && stable && previous == seats {
+		reset, err := r.cacheService.CorrectInventorySeats(ctx, count.FlightID, seats, expectedInventory)
+		if err != nil {
+			return append(drifts, drift), err
+		}
+		drift.Repaired = reset
+		if reset {
+			seen[count.FlightID] = expectedInventory
+		}
+	}
+
+	return append(drifts, drift), nil
+}
+
+// reconcileFareClass checks a fare class's available seats in Postgres
+func (r *SeatReconciler) reconcileFareClass(ctx context.Context, count models.FareClassSeatCount, repair bool) (*models.SeatDrift, error) {
+	expected := count.ExpectedAvailableSeats()
+	if count.AvailableSeats == expected {
+		return nil, nil
+	}
+
+	drift := &models.SeatDrift{
+		FlightID:      count.FlightID,
+		FareClassCode: count.Code,
+		Store:         models.SeatStoreFareClasses,
+		RecordedSeats: count.AvailableSeats,
+		ExpectedSeats: expected,
+	}
+
+	if repair {
+		repaired, err := r.fareClassRepo.RepairFareClassSeats(ctx, count.FareClassID, count.Version, expected)
+		if err != nil {
+			return drift, err
+		}
+		drift.Repaired = repaired
+	}
+
+	if drift.Repaired {
+		r.invalidateSearches(ctx, count.FlightID)
+	}
+	return drift, nil
+}
+
+// invalidateSearches drops the cached searches listing a flight whose seats
+// were repaired
+func (r *SeatReconciler) invalidateSearches(ctx context.Context, flightID int64) {
+	if err := r.cacheService.InvalidateFlightSearches(ctx, flightID); err != nil {
+		log.Printf("Failed to invalidate cached searches for flight %d: %v", flightID, err)
+	}
+}
+
+// recordSeatDrift counts the repairs of a reconciliation pass and sets the
+// drift gauges of every store to what the pass left in place
+func recordSeatDrift(report *models.SeatReconciliationReport) {
+	seats := make(map[models.SeatStore]int)
+	mismatches := make(map[models.SeatStore]int)
+	for _, drift := range report.Drifts {
+		if drift.Repaired {
+			seatReconciliationRepairsTotal.WithLabelValues(string(drift.Store)).Inc()
+			continue
+		}
+
+		off := drift.Seats()
+		if off < 0 {
+			off = -off
+		}
+		seats[drift.Store] += off
+		mismatches[drift.Store]++
+	}
+
+	for _, store := range models.SeatStores {
+		seatReconciliationDriftSeats.WithLabelValues(string(store)).Set(float64(seats[store]))
+		seatReconciliationMismatches.WithLabelValues(string(store)).Set(float64(mismatches[store]))
+	}
+}
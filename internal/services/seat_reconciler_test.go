@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+)
+
+// mockReconcilerFlightRepo implements FlightRepositoryReconciler for testing.
+type mockReconcilerFlightRepo struct {
+	countsFn func(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error)
+	repairFn func(ctx context.Context, flightID int64, version int, seats int) (bool, error)
+}
+
+func (m *mockReconcilerFlightRepo) GetFlightSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+	if m.countsFn != nil {
+		return m.countsFn(ctx, departingAfter)
+	}
+	return nil, nil
+}
+
+func (m *mockReconcilerFlightRepo) RepairAvailableSeats(ctx context.Context, flightID int64, version int, seats int) (bool, error) {
+	if m.repairFn != nil {
+		return m.repairFn(ctx, flightID, version, seats)
+	}
+	return true, nil
+}
+
+// mockReconcilerFareClassRepo implements FareClassRepositoryReconciler for testing.
+type mockReconcilerFareClassRepo struct {
+	countsFn func(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error)
+	repairFn func(ctx context.Context, fareClassID int64, version int, seats int) (bool, error)
+}
+
+func (m *mockReconcilerFareClassRepo) GetFareClassSeatCounts(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error) {
+	if m.countsFn != nil {
+		return m.countsFn(ctx, departingAfter)
+	}
+	return nil, nil
+}
+
+func (m *mockReconcilerFareClassRepo) RepairFareClassSeats(ctx context.Context, fareClassID int64, version int, seats int) (bool, error) {
+	if m.repairFn != nil {
+		return m.repairFn(ctx, fareClassID, version, seats)
+	}
+	return true, nil
+}
+
+// mockReconcilerCache implements FlightCacheReconciler for testing.
+type mockReconcilerCache struct {
+	acquireLeaderFn func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error)
+	cachedSeatsFn   func(ctx context.Context, flightID int64) (int, bool, error)
+	deleteCachedFn  func(ctx context.Context, flightID int64) error
+	inventoryFn     func(ctx context.Context, flightID int64) (int64, bool, error)
+	correctFn       func(ctx context.Context, flightID int64, expected, seats int64) (bool, error)
+	invalidated     []int64
+}
+
+func (m *mockReconcilerCache) AcquireLeadership(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+	if m.acquireLeaderFn != nil {
+		return m.acquireLeaderFn(ctx, job, instanceID, ttl)
+	}
+	return true, nil
+}
+
+func (m *mockReconcilerCache) ResignLeadership(ctx context.Context, job string, instanceID string) error {
+	return nil
+}
+
+func (m *mockReconcilerCache) GetCachedSeats(ctx context.Context, flightID int64) (int, bool, error) {
+	if m.cachedSeatsFn != nil {
+		return m.cachedSeatsFn(ctx, flightID)
+	}
+	return 0, false, nil
+}
+
+func (m *mockReconcilerCache) DeleteCachedSeats(ctx context.Context, flightID int64) error {
+	if m.deleteCachedFn != nil {
+		return m.deleteCachedFn(ctx, flightID)
+	}
+	return nil
+}
+
+func (m *mockReconcilerCache) GetInventorySeats(ctx context.Context, flightID int64) (int64, bool, error) {
+	if m.inventoryFn != nil {
+		return m.inventoryFn(ctx, flightID)
+	}
+	return 0, false, nil
+}
+
+func (m *mockReconcilerCache) CorrectInventorySeats(ctx context.Context, flightID int64, expected, seats int64) (bool, error) {
+	if m.correctFn != nil {
+		return m.correctFn(ctx, flightID, expected, seats)
+	}
+	return true, nil
+}
+
+func (m *mockReconcilerCache) InvalidateFlightSearches(ctx context.Context, flightID int64) error {
+	m.invalidated = append(m.invalidated, flightID)
+	return nil
+}
+
+func newTestSeatReconciler(flightRepo *mockReconcilerFlightRepo, fareClassRepo *mockReconcilerFareClassRepo, cache *mockReconcilerCache) *SeatReconciler {
+	return &SeatReconciler{
+		flightRepo:    flightRepo,
+		fareClassRepo: fareClassRepo,
+		cacheService:  cache,
+		config:        &config.AppConfig{SeatReconcileInterval: time.Minute},
+		instanceID:    "test-instance",
+		now:           func() time.Time { return time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC) },
+		lastInventory: make(map[int64]int64),
+	}
+}
+
+func driftsByStore(report *models.SeatReconciliationReport) map[models.SeatStore]models.SeatDrift {
+	drifts := make(map[models.SeatStore]models.SeatDrift)
+	for _, drift := range report.Drifts {
+		drifts[drift.Store] = drift
+	}
+	return drifts
+}
+
+func TestSeatReconciler_ReconcileSeats_ReportsDrift(t *testing.T) {
+	flightRepo := &mockReconcilerFlightRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+			return []models.FlightSeatCount{
+				// Bookings hold 30 of 180 seats
+				{FlightID: 1, TotalSeats: 180, AvailableSeats: 148, Version: 4, BookedSeats: 30},
+				// 5 seats booked from the inventory, 2 of them not yet deducted
+				{FlightID: 2, TotalSeats: 100, AvailableSeats: 97, Version: 2, BookedSeats: 5, PendingSeats: 2, InventoryTracked: true},
+			}, nil
+		},
+		repairFn: func(ctx context.Context, flightID int64, version int, seats int) (bool, error) {
+			t.Fatalf("did not expect a repair when only reporting")
+			return false, nil
+		},
+	}
+	fareClassRepo := &mockReconcilerFareClassRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error) {
+			return []models.FareClassSeatCount{
+				{FareClassID: 10, FlightID: 1, Code: "Y", TotalSeats: 150, AvailableSeats: 125, Version: 1, BookedSeats: 25},
+				{FareClassID: 11, FlightID: 1, Code: "J", TotalSeats: 30, AvailableSeats: 26, Version: 1, BookedSeats: 5},
+			}, nil
+		},
+	}
+	cache := &mockReconcilerCache{
+		cachedSeatsFn: func(ctx context.Context, flightID int64) (int, bool, error) {
+			return 150, flightID == 1, nil
+		},
+		inventoryFn: func(ctx context.Context, flightID int64) (int64, bool, error) {
+			return 94, true, nil
+		},
+	}
+
+	svc := newTestSeatReconciler(flightRepo, fareClassRepo, cache)
+
+	report, err := svc.ReconcileSeats(context.Background(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.FlightsChecked != 2 || report.FareClassesChecked != 2 || len(report.Drifts) != 3 {
+		t.Fatalf("expected 3 drifts among 2 flights and 2 fare classes, got %+v", report)
+	}
+
+	drifts := driftsByStore(report)
+	if drift := drifts[models.SeatStoreFlights]; drift.FlightID != 1 || drift.Seats() != -2 {
+		t.Fatalf("expected flight 1 to show 2 seats too few, got %+v", drift)
+	}
+	if drift := drifts[models.SeatStoreFareClasses]; drift.FareClassCode != "J" || drift.Seats() != 1 {
+		t.Fatalf("expected class J to show 1 seat too many, got %+v", drift)
+	}
+	if drift := drifts[models.SeatStoreSeatInventory]; drift.FlightID != 2 || drift.ExpectedSeats != 95 {
+		t.Fatalf("expected the inventory of flight 2 to drift from 95 seats, got %+v", drift)
+	}
+
+	if len(report.Unrepaired()) != 3 {
+		t.Fatalf("did not expect any drift repaired")
+	}
+}
+
+func TestSeatReconciler_ReconcileSeats_RepairsPostgresAndCache(t *testing.T) {
+	var repairedSeats int
+	flightRepo := &mockReconcilerFlightRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+			return []models.FlightSeatCount{
+				{FlightID: 1, TotalSeats: 180, AvailableSeats: 148, Version: 4, BookedSeats: 30},
+			}, nil
+		},
+		repairFn: func(ctx context.Context, flightID int64, version int, seats int) (bool, error) {
+			if flightID != 1 || version != 4 {
+				t.Fatalf("unexpected repair of flight %d at version %d", flightID, version)
+			}
+			repairedSeats = seats
+			return true, nil
+		},
+	}
+	fareClassRepo := &mockReconcilerFareClassRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FareClassSeatCount, error) {
+			return []models.FareClassSeatCount{
+				{FareClassID: 11, FlightID: 1, Code: "J", TotalSeats: 30, AvailableSeats: 26, Version: 1, BookedSeats: 5},
+			}, nil
+		},
+		repairFn: func(ctx context.Context, fareClassID int64, version int, seats int) (bool, error) {
+			// Booked since it was read
+			return false, nil
+		},
+	}
+	var cacheDropped []int64
+	cache := &mockReconcilerCache{
+		cachedSeatsFn: func(ctx context.Context, flightID int64) (int, bool, error) {
+			return 148, true, nil
+		},
+		deleteCachedFn: func(ctx context.Context, flightID int64) error {
+			cacheDropped = append(cacheDropped, flightID)
+			return nil
+		},
+	}
+
+	svc := newTestSeatReconciler(flightRepo, fareClassRepo, cache)
+
+	report, err := svc.ReconcileSeats(context.Background(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if repairedSeats != 150 {
+		t.Fatalf("expected flight 1 repaired to 150 seats, got %d", repairedSeats)
+	}
+
+	drifts := driftsByStore(report)
+	if !drifts[models.SeatStoreFlights].Repaired || !drifts[models.SeatStoreSeatCache].Repaired {
+		t.Fatalf("expected the flight and its cached seats repaired, got %+v", report.Drifts)
+	}
+	if drifts[models.SeatStoreFareClasses].Repaired {
+		t.Fatalf("did not expect a fare class changed since it was read to be repaired")
+	}
+
+	if len(cacheDropped) != 1 || cacheDropped[0] != 1 {
+		t.Fatalf("expected the stale cached seats of flight 1 dropped, got %v", cacheDropped)
+	}
+
+	if len(cache.invalidated) != 1 || cache.invalidated[0] != 1 {
+		t.Fatalf("expected the searches listing flight 1 invalidated once, got %v", cache.invalidated)
+	}
+}
+
+func TestSeatReconciler_ReconcileSeats_RepairsStableInventoryDrift(t *testing.T) {
+	flightRepo := &mockReconcilerFlightRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+			return []models.FlightSeatCount{
+				{FlightID: 2, TotalSeats: 100, AvailableSeats: 97, Version: 2, BookedSeats: 5, PendingSeats: 2, InventoryTracked: true},
+			}, nil
+		},
+	}
+	inventory := int64(93)
+	var corrections [][2]int64
+	cache := &mockReconcilerCache{
+		inventoryFn: func(ctx context.Context, flightID int64) (int64, bool, error) {
+			return inventory, true, nil
+		},
+		correctFn: func(ctx context.Context, flightID int64, expected, seats int64) (bool, error) {
+			corrections = append(corrections, [2]int64{expected, seats})
+			inventory = seats
+			return true, nil
+		},
+	}
+
+	svc := newTestSeatReconciler(flightRepo, &mockReconcilerFareClassRepo{}, cache)
+
+	// The first sight of a drift may be a booking that has not committed
+	report, err := svc.ReconcileSeats(context.Background(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(corrections) != 0 || len(report.Unrepaired()) != 1 {
+		t.Fatalf("did not expect a drift seen once to be repaired, got %v", corrections)
+	}
+
+	report, err = svc.ReconcileSeats(context.Background(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(corrections) != 1 || corrections[0] != [2]int64{93, 95} || len(report.Unrepaired()) != 0 {
+		t.Fatalf("expected the inventory reset from 93 to 95 seats, got %v", corrections)
+	}
+
+	if report, _ = svc.ReconcileSeats(context.Background(), true); len(report.Drifts) != 0 {
+		t.Fatalf("did not expect a repaired inventory to drift, got %+v", report.Drifts)
+	}
+}
+
+func TestSeatReconciler_Tick_SkipsWhenNotLeader(t *testing.T) {
+	flightRepo := &mockReconcilerFlightRepo{
+		countsFn: func(ctx context.Context, departingAfter time.Time) ([]models.FlightSeatCount, error) {
+			t.Fatalf("did not expect a follower to reconcile")
+			return nil, nil
+		},
+	}
+	cache := &mockReconcilerCache{
+		acquireLeaderFn: func(ctx context.Context, job string, instanceID string, ttl time.Duration) (bool, error) {
+			if job != seatReconcilerJob || ttl != 2*time.Minute {
+				t.Fatalf("unexpected leadership request job=%s ttl=%s", job, ttl)
+			}
+			return false, nil
+		},
+	}
+
+	svc := newTestSeatReconciler(flightRepo, &mockReconcilerFareClassRepo{}, cache)
+	svc.lastInventory[2] = 93
+	svc.tick(context.Background())
+
+	if len(svc.lastInventory) != 0 {
+		t.Fatalf("expected a follower to forget the inventories it saw, got %v", svc.lastInventory)
+	}
+}
@@ -0,0 +1,2 @@
+-- Index bookings by payment reference for payment gateway webhook lookups
+CREATE INDEX IF NOT EXISTS idx_bookings_payment_reference_id ON bookings(payment_reference_id);
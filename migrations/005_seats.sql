@@ -0,0 +1,20 @@
+-- Create seats table for seat-level inventory
+CREATE TABLE IF NOT EXISTS seats (
+    id BIGSERIAL PRIMARY KEY,
+    flight_id BIGINT NOT NULL REFERENCES flights(id),
+    seat_row INTEGER NOT NULL CHECK (seat_row > 0),
+    seat_letter CHAR(1) NOT NULL,
+    seat_number VARCHAR(5) GENERATED ALWAYS AS (seat_row::text || seat_letter) STORED,
+    cabin VARCHAR(20) NOT NULL DEFAULT 'economy',
+    is_window BOOLEAN NOT NULL DEFAULT FALSE,
+    is_aisle BOOLEAN NOT NULL DEFAULT FALSE,
+    is_exit_row BOOLEAN NOT NULL DEFAULT FALSE,
+    booking_id BIGINT REFERENCES bookings(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_seats_flight_seat UNIQUE (flight_id, seat_number)
+);
+
+-- Index seats held by a booking so they can be released
+CREATE INDEX IF NOT EXISTS idx_seats_booking_id ON seats(booking_id) WHERE booking_id IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Create fare_classes table for per-class inventory and pricing
+CREATE TABLE IF NOT EXISTS fare_classes (
+    id BIGSERIAL PRIMARY KEY,
+    flight_id BIGINT NOT NULL REFERENCES flights(id),
+    code VARCHAR(2) NOT NULL,
+    cabin VARCHAR(20) NOT NULL,
+    price DECIMAL(10,2) NOT NULL CHECK (price > 0),
+    total_seats INTEGER NOT NULL CHECK (total_seats > 0),
+    available_seats INTEGER NOT NULL CHECK (available_seats >= 0),
+    refundable BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_fare_classes_flight_code UNIQUE (flight_id, code),
+    CONSTRAINT chk_fare_classes_available_seats CHECK (available_seats <= total_seats)
+);
+
+-- Record the fare class a booking was sold in; NULL for flight-level bookings
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS fare_class_code VARCHAR(2);
@@ -0,0 +1,9 @@
+-- Record when each flight lands so connections and trip durations can be computed
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS arrival_timestamp TIMESTAMP;
+
+-- Existing flights get a nominal two hour block time
+UPDATE flights SET arrival_timestamp = timestamp + INTERVAL '2 hours' WHERE arrival_timestamp IS NULL;
+
+ALTER TABLE flights ALTER COLUMN arrival_timestamp SET NOT NULL;
+ALTER TABLE flights ADD CONSTRAINT chk_arrival_after_departure CHECK (arrival_timestamp > timestamp);
+
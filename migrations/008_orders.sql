@@ -0,0 +1,22 @@
+-- Create orders table grouping the bookings of one trip under a single payment
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    payment_reference_id VARCHAR(255) NOT NULL,
+    total_price DECIMAL(10,2) NOT NULL CHECK (total_price > 0),
+    seats_booked INTEGER NOT NULL CHECK (seats_booked > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index pending orders for the reaper
+CREATE INDEX IF NOT EXISTS idx_orders_status_created_at ON orders(status, created_at);
+
+CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders 
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Each segment of an order is a booking; NULL for standalone bookings
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS order_id BIGINT REFERENCES orders(id);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_order_id ON bookings(order_id) WHERE order_id IS NOT NULL;
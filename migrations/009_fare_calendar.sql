@@ -0,0 +1,2 @@
+-- Index flights by route and departure time for fare calendar range scans
+CREATE INDEX IF NOT EXISTS idx_flights_route_timestamp ON flights(source, destination, timestamp);
@@ -0,0 +1,11 @@
+-- Snapshot of route search counts kept in Redis, so cache warm-up still knows
+-- the popular routes after Redis is flushed
+CREATE TABLE IF NOT EXISTS route_search_popularity (
+    source VARCHAR(100) NOT NULL,
+    destination VARCHAR(100) NOT NULL,
+    searches BIGINT NOT NULL CHECK (searches >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, destination)
+);
+
+CREATE INDEX IF NOT EXISTS idx_route_search_popularity_searches ON route_search_popularity(searches DESC);
@@ -0,0 +1,3 @@
+-- Fencing token of the last flight lock holder to reserve seats, so a holder
+-- whose lock expired cannot write over a newer holder
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS lock_fence BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Flights sold from a seat inventory counter in Redis instead of under the
+-- flight lock, for high-demand sales
+CREATE TABLE IF NOT EXISTS seat_inventory_flights (
+    flight_id BIGINT PRIMARY KEY REFERENCES flights(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Seats reserved from a seat inventory that have not been deducted from
+-- flights.available_seats yet
+CREATE TABLE IF NOT EXISTS pending_seat_deductions (
+    booking_id BIGINT PRIMARY KEY REFERENCES bookings(id) ON DELETE CASCADE,
+    flight_id BIGINT NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+    seats INTEGER NOT NULL CHECK (seats > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_seat_deductions_flight_id ON pending_seat_deductions(flight_id);
@@ -0,0 +1,7 @@
+-- Outbox rows are claimed with a lease instead of row locks held while
+-- publishing, and failed rows wait before they are retried
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Find the oldest unsent row of each message key
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent_key ON outbox(topic, message_key, id) WHERE sent_at IS NULL;
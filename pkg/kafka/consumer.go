@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// InboundMessage is a message read from Kafka
+type InboundMessage struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// MessageHandler processes one message. A returned error hands the message to
+// the handler again until the retry attempts run out.
+type MessageHandler func(ctx context.Context, message InboundMessage) error
+
+// Consumer reads a topic as part of a consumer group. A message's offset is
+// committed only after its handler succeeds or the message has been moved to
+// the dead-letter queue.
+type Consumer struct {
+	reader      *kafka.Reader
+	retry       retryPolicy
+	deadLetters *DeadLetterQueue
+}
+
+// NewConsumer creates a new Kafka consumer for the configured group
+func NewConsumer(cfg *config.KafkaConfig, topic string, deadLetters *DeadLetterQueue) *Consumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		GroupID: cfg.GroupID,
+		Topic:   topic,
+	})
+
+	return &Consumer{
+		reader:      reader,
+		retry:       newRetryPolicy(cfg),
+		deadLetters: deadLetters,
+	}
+}
+
+// Run consumes messages until ctx is cancelled. Messages are handled one at a
+// time, so a failing message holds back the ones behind it until it succeeds
+// or is dead-lettered.
+func (c *Consumer) Run(ctx context.Context, handler MessageHandler) error {
+	for {
+		message, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		inbound := InboundMessage{
+			Topic:     message.Topic,
+			Partition: message.Partition,
+			Offset:    message.Offset,
+			Key:       message.Key,
+			Value:     message.Value,
+		}
+
+		attempts, err := c.retry.do(ctx, func() error {
+			err := handler(ctx, inbound)
+			if err != nil {
+				log.Printf("Failed to handle message %s/%d@%d: %v", inbound.Topic, inbound.Partition, inbound.Offset, err)
+			}
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				// The offset stays uncommitted and the message is redelivered
+				return nil
+			}
+
+			if err := c.deadLetter(ctx, inbound, attempts, err); err != nil {
+				return nil
+			}
+		}
+
+		if err := c.reader.CommitMessages(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to commit message: %w", err)
+		}
+	}
+}
+
+// deadLetter moves a message to the dead-letter queue, retrying until it is
+// stored or ctx is cancelled so the message is never committed without a copy
+func (c *Consumer) deadLetter(ctx context.Context, message InboundMessage, attempts int, cause error) error {
+	for {
+		_, err := c.retry.do(ctx, func() error {
+			return c.deadLetters.Send(ctx, models.DeadLetterSourceConsumer, message.Topic, message.Offset, message.Key, message.Value, attempts, cause)
+		})
+		if err == nil {
+			log.Printf("Moved message %s/%d@%d to dead-letter topic after %d attempts: %v",
+				message.Topic, message.Partition, message.Offset, attempts, cause)
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("Failed to dead-letter message %s/%d@%d: %v", message.Topic, message.Partition, message.Offset, err)
+	}
+}
+
+// Close closes the consumer
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
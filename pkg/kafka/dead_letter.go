@@ -0,0 +1,214 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"airline-booking-system/internal/config"
+	"airline-booking-system/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrDeadLettered reports that messages could not be delivered and were moved
+// to the dead-letter topic instead
+var ErrDeadLettered = errors.New("messages moved to dead-letter topic")
+
+// Headers carried by dead-lettered messages
+const (
+	headerSource         = "x-dead-letter-source"
+	headerOriginalTopic  = "x-original-topic"
+	headerOriginalOffset = "x-original-offset"
+	headerError          = "x-error"
+	headerAttempts       = "x-attempts"
+	headerFailedAt       = "x-failed-at"
+)
+
+// DeadLetterQueue stores messages that repeatedly failed to be published or
+// consumed, and replays them to their original topic on request. The topic is
+// written to a single partition so offsets identify dead letters.
+type DeadLetterQueue struct {
+	brokers      []string
+	topic        string
+	writer       *kafka.Writer
+	replayWriter *kafka.Writer
+}
+
+// NewDeadLetterQueue creates a new dead-letter queue
+func NewDeadLetterQueue(cfg *config.KafkaConfig) *DeadLetterQueue {
+	writer := &kafka.Writer{
+		Addr:  kafka.TCP(cfg.Brokers...),
+		Topic: cfg.DeadLetterTopic,
+		Balancer: kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+			return partitions[0]
+		}),
+	}
+
+	replayWriter := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Balancer: &kafka.LeastBytes{},
+	}
+
+	return &DeadLetterQueue{
+		brokers:      cfg.Brokers,
+		topic:        cfg.DeadLetterTopic,
+		writer:       writer,
+		replayWriter: replayWriter,
+	}
+}
+
+// Send moves a message to the dead-letter topic
+func (q *DeadLetterQueue) Send(ctx context.Context, source models.DeadLetterSource, topic string, offset int64, key, value []byte, attempts int, cause error) error {
+	message := kafka.Message{
+		Key:   key,
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: headerSource, Value: []byte(source)},
+			{Key: headerOriginalTopic, Value: []byte(topic)},
+			{Key: headerOriginalOffset, Value: []byte(strconv.FormatInt(offset, 10))},
+			{Key: headerError, Value: []byte(cause.Error())},
+			{Key: headerAttempts, Value: []byte(strconv.Itoa(attempts))},
+			{Key: headerFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+		},
+	}
+
+	if err := q.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to send dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// List returns up to limit dead letters starting at offset
+func (q *DeadLetterQueue) List(ctx context.Context, offset int64, limit int) ([]models.DeadLetter, error) {
+	first, last, err := q.offsets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < first {
+		offset = first
+	}
+
+	deadLetters := []models.DeadLetter{}
+	if offset >= last || limit <= 0 {
+		return deadLetters, nil
+	}
+
+	reader := q.newReader(offset)
+	defer reader.Close()
+
+	for offset < last && len(deadLetters) < limit {
+		message, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, toDeadLetter(message))
+		offset = message.Offset + 1
+	}
+
+	return deadLetters, nil
+}
+
+// Replay republishes the dead letter at offset to its original topic
+func (q *DeadLetterQueue) Replay(ctx context.Context, offset int64) (*models.DeadLetter, error) {
+	first, last, err := q.offsets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < first || offset >= last {
+		return nil, fmt.Errorf("%w: offset %d", models.ErrDeadLetterNotFound, offset)
+	}
+
+	reader := q.newReader(offset)
+	defer reader.Close()
+
+	message, err := reader.ReadMessage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+
+	deadLetter := toDeadLetter(message)
+	if deadLetter.OriginalTopic == "" {
+		return nil, fmt.Errorf("dead letter %d has no original topic", offset)
+	}
+
+	err = q.replayWriter.WriteMessages(ctx, kafka.Message{
+		Topic: deadLetter.OriginalTopic,
+		Key:   message.Key,
+		Value: message.Value,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+
+	return &deadLetter, nil
+}
+
+// Close closes the dead-letter queue
+func (q *DeadLetterQueue) Close() error {
+	if err := q.replayWriter.Close(); err != nil {
+		return err
+	}
+	return q.writer.Close()
+}
+
+// offsets returns the first and next offsets of the dead-letter partition
+func (q *DeadLetterQueue) offsets(ctx context.Context) (int64, int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", q.brokers[0], q.topic, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to connect to dead-letter topic: %w", err)
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read dead-letter offsets: %w", err)
+	}
+
+	return first, last, nil
+}
+
+// newReader creates a reader positioned at offset in the dead-letter partition
+func (q *DeadLetterQueue) newReader(offset int64) *kafka.Reader {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   q.brokers,
+		Topic:     q.topic,
+		Partition: 0,
+	})
+	reader.SetOffset(offset)
+	return reader
+}
+
+// toDeadLetter decodes a message read from the dead-letter topic
+func toDeadLetter(message kafka.Message) models.DeadLetter {
+	deadLetter := models.DeadLetter{
+		Offset:  message.Offset,
+		Key:     string(message.Key),
+		Payload: string(message.Value),
+	}
+
+	for _, header := range message.Headers {
+		value := string(header.Value)
+		switch header.Key {
+		case headerSource:
+			deadLetter.Source = models.DeadLetterSource(value)
+		case headerOriginalTopic:
+			deadLetter.OriginalTopic = value
+		case headerOriginalOffset:
+			deadLetter.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case headerError:
+			deadLetter.Error = value
+		case headerAttempts:
+			deadLetter.Attempts, _ = strconv.Atoi(value)
+		case headerFailedAt:
+			deadLetter.FailedAt, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+
+	return deadLetter
+}
@@ -11,19 +11,41 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
-// Producer handles Kafka message production
+// Topics written by the booking service
+const (
+	TopicFlightBookings       = "flight-bookings"
+	TopicPaymentEvents        = "payment-events"
+	TopicBookingCancellations = "booking-cancellations"
+	TopicBookingExpirations   = "booking-expirations"
+)
+
+// OutboundMessage is an already-encoded message ready to be written to Kafka
+type OutboundMessage struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer handles Kafka message production. Failed writes are retried and
+// messages that still cannot be written are moved to the dead-letter queue.
 type Producer struct {
-	writer *kafka.Writer
+	writer      *kafka.Writer
+	retry       retryPolicy
+	deadLetters *DeadLetterQueue
 }
 
 // NewProducer creates a new Kafka producer
-func NewProducer(cfg *config.KafkaConfig) *Producer {
+func NewProducer(cfg *config.KafkaConfig, deadLetters *DeadLetterQueue) *Producer {
 	writer := &kafka.Writer{
 		Addr:     kafka.TCP(cfg.Brokers...),
 		Balancer: &kafka.LeastBytes{},
 	}
 
-	return &Producer{writer: writer}
+	return &Producer{
+		writer:      writer,
+		retry:       newRetryPolicy(cfg),
+		deadLetters: deadLetters,
+	}
 }
 
 // SendPaymentEvent sends a payment event to Kafka
@@ -33,13 +55,13 @@ func (p *Producer) SendPaymentEvent(ctx context.Context, event *models.PaymentEv
 		return fmt.Errorf("failed to marshal payment event: %w", err)
 	}
 
-	message := kafka.Message{
-		Topic: "payment-events",
+	message := OutboundMessage{
+		Topic: TopicPaymentEvents,
 		Key:   []byte(fmt.Sprintf("%d", event.BookingID)),
 		Value: eventData,
 	}
 
-	err = p.writer.WriteMessages(ctx, message)
+	err = p.Publish(ctx, message)
 	if err != nil {
 		return fmt.Errorf("failed to send payment event: %w", err)
 	}
@@ -54,13 +76,13 @@ func (p *Producer) SendSeatUpdateEvent(ctx context.Context, event *models.SeatUp
 		return fmt.Errorf("failed to marshal seat update event: %w", err)
 	}
 
-	message := kafka.Message{
-		Topic: "flight-bookings",
+	message := OutboundMessage{
+		Topic: TopicFlightBookings,
 		Key:   []byte(fmt.Sprintf("%d", event.FlightID)),
 		Value: eventData,
 	}
 
-	err = p.writer.WriteMessages(ctx, message)
+	err = p.Publish(ctx, message)
 	if err != nil {
 		return fmt.Errorf("failed to send seat update event: %w", err)
 	}
@@ -68,6 +90,117 @@ func (p *Producer) SendSeatUpdateEvent(ctx context.Context, event *models.SeatUp
 	return nil
 }
 
+// SendBookingCancellationEvent sends a booking cancellation event to Kafka
+func (p *Producer) SendBookingCancellationEvent(ctx context.Context, event *models.BookingCancellationEvent) error {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal booking cancellation event: %w", err)
+	}
+
+	message := OutboundMessage{
+		Topic: TopicBookingCancellations,
+		Key:   []byte(fmt.Sprintf("%d", event.FlightID)),
+		Value: eventData,
+	}
+
+	err = p.Publish(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send booking cancellation event: %w", err)
+	}
+
+	return nil
+}
+
+// SendBookingExpiredEvent sends a booking expiry event to Kafka
+func (p *Producer) SendBookingExpiredEvent(ctx context.Context, event *models.BookingExpiredEvent) error {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal booking expired event: %w", err)
+	}
+
+	message := OutboundMessage{
+		Topic: TopicBookingExpirations,
+		Key:   []byte(fmt.Sprintf("%d", event.FlightID)),
+		Value: eventData,
+	}
+
+	err = p.Publish(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send booking expired event: %w", err)
+	}
+
+	return nil
+}
+
+// Publish writes already-encoded messages to Kafka in a single batch,
+// retrying with exponential backoff. If the batch still fails, each message is
+// tried on its own and the ones that fail again are dead-lettered; the
+// returned error then wraps ErrDeadLettered.
+func (p *Producer) Publish(ctx context.Context, messages ...OutboundMessage) error {
+	batch := toKafkaMessages(messages)
+
+	attempts, err := p.retry.do(ctx, func() error {
+		return p.writer.WriteMessages(ctx, batch...)
+	})
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to publish messages: %w", err)
+	}
+
+	deadLettered := 0
+	for _, message := range batch {
+		writeErr := p.writer.WriteMessages(ctx, message)
+		if writeErr == nil {
+			continue
+		}
+
+		dlqErr := p.deadLetters.Send(ctx, models.DeadLetterSourceProducer, message.Topic, -1, message.Key, message.Value, attempts+1, writeErr)
+		if dlqErr != nil {
+			return fmt.Errorf("failed to publish messages: %w", writeErr)
+		}
+		deadLettered++
+	}
+
+	if deadLettered > 0 {
+		return fmt.Errorf("%w: %d of %d after %d attempts: %v", ErrDeadLettered, deadLettered, len(batch), attempts+1, err)
+	}
+
+	return nil
+}
+
+// Write writes already-encoded messages to Kafka in a single batch, retrying
+// with exponential backoff. Unlike Publish it never dead-letters: it is meant
+// for callers such as the outbox relay that keep undelivered messages and
+// retry them later.
+func (p *Producer) Write(ctx context.Context, messages ...OutboundMessage) error {
+	batch := toKafkaMessages(messages)
+
+	_, err := p.retry.do(ctx, func() error {
+		return p.writer.WriteMessages(ctx, batch...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write messages: %w", err)
+	}
+
+	return nil
+}
+
+// toKafkaMessages converts outbound messages to kafka-go messages
+func toKafkaMessages(messages []OutboundMessage) []kafka.Message {
+	batch := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		batch = append(batch, kafka.Message{
+			Topic: m.Topic,
+			Key:   m.Key,
+			Value: m.Value,
+		})
+	}
+	return batch
+}
+
 // Close closes the producer
 func (p *Producer) Close() error {
 	return p.writer.Close()
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"airline-booking-system/internal/config"
+)
+
+// retryPolicy retries an operation with exponential backoff
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// newRetryPolicy builds the retry policy from configuration
+func newRetryPolicy(cfg *config.KafkaConfig) retryPolicy {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return retryPolicy{
+		maxAttempts: maxAttempts,
+		backoff:     cfg.RetryBackoff,
+		maxBackoff:  cfg.MaxRetryBackoff,
+	}
+}
+
+// do runs fn until it succeeds, the attempts run out or ctx is cancelled. It
+// returns the number of attempts made and the last error.
+func (p retryPolicy) do(ctx context.Context, fn func() error) (int, error) {
+	backoff := p.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt == p.maxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if p.maxBackoff > 0 && backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
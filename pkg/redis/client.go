@@ -2,6 +2,9 @@ package redis
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +13,214 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// acquireLeaseScript takes a lease if it is free, or extends it if the caller already owns it
+var acquireLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseLeaseScript deletes a lease only if the caller still owns it
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// acquireLockScript takes the lock keys in the first half of KEYS if all of
+// them are free and returns the next fencing token, or 0 if any is held. The
+// second half of KEYS are the fencing counters of the lock keys, which are
+// all moved to the new token. Counters never expire and never go below the
+// current time in microseconds, so tokens keep increasing even if Redis
+// loses a counter.
+var acquireLockScript = redis.NewScript(`
+local n = #KEYS / 2
+for i = 1, n do
+	if redis.call("EXISTS", KEYS[i]) == 1 then
+		return 0
+	end
+end
+local now = redis.call("TIME")
+local fence = tonumber(now[1]) * 1000000 + tonumber(now[2])
+for i = 1, n do
+	redis.call("SET", KEYS[i], ARGV[1], "PX", ARGV[2])
+	fence = math.max(fence, redis.call("INCR", KEYS[n + i]))
+end
+for i = n + 1, #KEYS do
+	redis.call("SET", KEYS[i], string.format("%d", fence))
+end
+return fence
+`)
+
+// extendLockScript resets the TTL of every lock key only if the caller still
+// owns all of them
+var extendLockScript = redis.NewScript(`
+for i = 1, #KEYS do
+	if redis.call("GET", KEYS[i]) ~= ARGV[1] then
+		return 0
+	end
+end
+for i = 1, #KEYS do
+	redis.call("PEXPIRE", KEYS[i], ARGV[2])
+end
+return 1
+`)
+
+// releaseLockScript deletes the lock keys the caller still owns and returns
+// how many it deleted
+var releaseLockScript = redis.NewScript(`
+local released = 0
+for i = 1, #KEYS do
+	if redis.call("GET", KEYS[i]) == ARGV[1] then
+		released = released + redis.call("DEL", KEYS[i])
+	end
+end
+return released
+`)
+
+// queuePositionLua refreshes the ticket key of queue member ARGV[1] for
+// ARGV[2] milliseconds, drops members whose ticket key expired from the head
+// of queue KEYS[1], and returns the member's position, or -1 if its own
+// ticket expired. A member's ticket key is the queue key, a colon and the
+// member.
+const queuePositionLua = `
+if redis.call("PEXPIRE", KEYS[1] .. ":" .. ARGV[1], ARGV[2]) == 0 then
+	redis.call("ZREM", KEYS[1], ARGV[1])
+	return -1
+end
+while true do
+	local head = redis.call("ZRANGE", KEYS[1], 0, 0)[1]
+	if head == nil or head == ARGV[1] or redis.call("EXISTS", KEYS[1] .. ":" .. head) == 1 then
+		break
+	end
+	redis.call("ZREM", KEYS[1], head)
+end
+return redis.call("ZRANK", KEYS[1], ARGV[1]) or -1
+`
+
+// joinQueueScript appends member ARGV[1] to queue KEYS[1], ordered by the
+// Redis server time, and returns its position. Expired members are dropped
+// from the head first; if the queue still holds ARGV[3] members it returns
+// -1 without adding the member.
+var joinQueueScript = redis.NewScript(`
+while true do
+	local head = redis.call("ZRANGE", KEYS[1], 0, 0)[1]
+	if head == nil or redis.call("EXISTS", KEYS[1] .. ":" .. head) == 1 then
+		break
+	end
+	redis.call("ZREM", KEYS[1], head)
+end
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return -1
+end
+local now = redis.call("TIME")
+redis.call("ZADD", KEYS[1], tonumber(now[1]) * 1000000 + tonumber(now[2]), ARGV[1])
+redis.call("SET", KEYS[1] .. ":" .. ARGV[1], 1, "PX", ARGV[2])
+` + queuePositionLua)
+
+// queuePositionScript returns the position of member ARGV[1] in queue KEYS[1]
+var queuePositionScript = redis.NewScript(queuePositionLua)
+
+// takeCounterScript subtracts ARGV[1] from counter KEYS[1] only if it holds
+// at least that much. It returns the remaining count, -1 if the counter is
+// too low or -2 if it does not exist.
+var takeCounterScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return -2
+end
+if tonumber(current) < tonumber(ARGV[1]) then
+	return -1
+end
+return redis.call("DECRBY", KEYS[1], ARGV[1])
+`)
+
+// returnCounterScript adds ARGV[1] to counter KEYS[1] if it exists
+var returnCounterScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("INCRBY", KEYS[1], ARGV[1])
+return 1
+`)
+
+// compareAndSetScript sets KEYS[1] to ARGV[2] only if it still holds ARGV[1]
+var compareAndSetScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2])
+return 1
+`)
+
+// getOrSetScript returns the current value of a key, setting it first if it is missing
+var getOrSetScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current then
+	return current
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return ARGV[1]
+`)
+
+// deleteIndexedScript deletes every key listed in an index set, then the set,
+// and returns how many listed keys existed
+var deleteIndexedScript = redis.NewScript(`
+local keys = redis.call("SMEMBERS", KEYS[1])
+local deleted = 0
+for i = 1, #keys, 500 do
+	deleted = deleted + redis.call("DEL", unpack(keys, i, math.min(i + 499, #keys)))
+end
+redis.call("DEL", KEYS[1])
+return deleted
+`)
+
+// incrementRankScript increments a member's score in a sorted set and
+// returns its rank by descending score along with the size of the set
+var incrementRankScript = redis.NewScript(`
+redis.call("ZINCRBY", KEYS[1], ARGV[2], ARGV[1])
+return {redis.call("ZREVRANK", KEYS[1], ARGV[1]), redis.call("ZCARD", KEYS[1])}
+`)
+
+// evictLowestScript removes the members of sorted set KEYS[1] other than
+// ARGV[2] whose key no longer exists. Unless ARGV[1] is 0 it then trims the
+// set to ARGV[1] members by removing the lowest scored ones other than
+// ARGV[2], deleting the key each removed member names, and returns them.
+var evictLowestScript = redis.NewScript(`
+redis.call("ZINCRBY", KEYS[1], 0, ARGV[2])
+for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+	if member ~= ARGV[2] and redis.call("EXISTS", member) == 0 then
+		redis.call("ZREM", KEYS[1], member)
+	end
+end
+local max = tonumber(ARGV[1])
+local excess = redis.call("ZCARD", KEYS[1]) - max
+if max <= 0 or excess <= 0 then
+	return {}
+end
+local evicted = {}
+for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, excess)) do
+	if member ~= ARGV[2] and #evicted < excess then
+		table.insert(evicted, member)
+	end
+end
+redis.call("ZREM", KEYS[1], unpack(evicted))
+redis.call("DEL", unpack(evicted))
+return evicted
+`)
+
+// ErrLockNotHeld is returned when releasing a lock that expired or was taken
+// over by another owner
+var ErrLockNotHeld = errors.New("lock not held")
+
+// ErrCounterNotFound is returned when taking from a counter that does not exist
+var ErrCounterNotFound = errors.New("counter not found")
+
 // Client represents Redis client wrapper
 type Client struct {
 	*redis.Client
@@ -31,6 +242,57 @@ func (c *Client) SetJSON(ctx context.Context, key string, value interface{}, ttl
 	return c.Client.Set(ctx, key, value, ttl).Err()
 }
 
+// SetIndexed sets a value with TTL and adds its key to each index set in one
+// transaction. Index sets expire indexTTL after the last key was added, which
+// must be at least the longest TTL of the values they list.
+func (c *Client) SetIndexed(ctx context.Context, key string, value interface{}, ttl time.Duration, indexTTL time.Duration, indexKeys ...string) error {
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		for _, indexKey := range indexKeys {
+			pipe.SAdd(ctx, indexKey, key)
+			pipe.Expire(ctx, indexKey, indexTTL)
+		}
+		return nil
+	})
+	return err
+}
+
+// DeleteIndexed atomically deletes every key listed in an index set along
+// with the set, returning how many listed keys were deleted
+func (c *Client) DeleteIndexed(ctx context.Context, indexKey string) (int64, error) {
+	return deleteIndexedScript.Run(ctx, c.Client, []string{indexKey}).Int64()
+}
+
+// IncrementRank atomically adds increment to a member's score in a sorted
+// set, creating it if needed, and returns the member's rank by descending
+// score (0 for the highest) and the number of members in the set
+func (c *Client) IncrementRank(ctx context.Context, key string, member string, increment float64) (int64, int64, error) {
+	result, err := incrementRankScript.Run(ctx, c.Client, []string{key}, member, increment).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], result[1], nil
+}
+
+// EvictLowest drops the members of a sorted set of keys whose key expired or
+// was deleted, then caps the set at max members unless max is 0. The lowest
+// scored members beyond the cap, other than keep, are removed and the keys
+// they name deleted; keep is added with a zero score if it is missing. It
+// returns the evicted keys.
+func (c *Client) EvictLowest(ctx context.Context, key string, max int, keep string) ([]string, error) {
+	return evictLowestScript.Run(ctx, c.Client, []string{key}, max, keep).StringSlice()
+}
+
+// RaiseScores sets the scores of sorted set members, adding missing members
+// and leaving members that already have a higher score unchanged
+func (c *Client) RaiseScores(ctx context.Context, key string, scores map[string]float64) error {
+	members := make([]redis.Z, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, redis.Z{Score: score, Member: member})
+	}
+	return c.Client.ZAddArgs(ctx, key, redis.ZAddArgs{GT: true, Members: members}).Err()
+}
+
 // Get gets a value from Redis
 func (c *Client) Get(ctx context.Context, key string) (string, error) {
 	return c.Client.Get(ctx, key).Result()
@@ -47,14 +309,163 @@ func (c *Client) Delete(ctx context.Context, key string) error {
 	return c.Client.Del(ctx, key).Err()
 }
 
-// AcquireLock acquires a distributed lock
-func (c *Client) AcquireLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
-	return c.Client.SetNX(ctx, key, "locked", ttl).Result()
+// AcquireLock atomically acquires a distributed lock made of one or more keys
+// under a new owner token: either every key is taken or none is. It returns
+// the token, which extending and releasing the lock require, and a fencing
+// token greater than that of every earlier holder of any of the keys. The
+// token is empty if any key is held by someone else.
+func (c *Client) AcquireLock(ctx context.Context, keys []string, ttl time.Duration) (string, int64, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	scriptKeys := append([]string{}, keys...)
+	for _, key := range keys {
+		scriptKeys = append(scriptKeys, key+":fence")
+	}
+
+	fence, err := acquireLockScript.Run(ctx, c.Client, scriptKeys, token, ttl.Milliseconds()).Int64()
+	if err != nil || fence == 0 {
+		return "", 0, err
+	}
+	return token, fence, nil
 }
 
-// ReleaseLock releases a distributed lock
-func (c *Client) ReleaseLock(ctx context.Context, key string) error {
-	return c.Client.Del(ctx, key).Err()
+// ExtendLock resets the TTL of a lock if all of its keys are still held
+// under token
+func (c *Client) ExtendLock(ctx context.Context, keys []string, token string, ttl time.Duration) (bool, error) {
+	result, err := extendLockScript.Run(ctx, c.Client, keys, token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+// ReleaseLock releases the keys of a lock still held under token, and
+// returns ErrLockNotHeld if any of them had expired or passed to another owner
+func (c *Client) ReleaseLock(ctx context.Context, keys []string, token string) error {
+	released, err := releaseLockScript.Run(ctx, c.Client, keys, token).Int()
+	if err != nil {
+		return err
+	}
+	if released < len(keys) {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// JoinQueue appends a new member to a FIFO queue and returns the member and
+// the number of members ahead of it, or an empty member and -1 if the queue
+// already holds maxLength members. The member has to check its position
+// within ttl to keep its place; members that stop doing so are dropped once
+// they reach the head of the queue.
+func (c *Client) JoinQueue(ctx context.Context, key string, ttl time.Duration, maxLength int) (string, int64, error) {
+	member, err := newToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	position, err := joinQueueScript.Run(ctx, c.Client, []string{key}, member, ttl.Milliseconds(), maxLength).Int64()
+	if err != nil {
+		return "", 0, err
+	}
+	if position < 0 {
+		return "", -1, nil
+	}
+	return member, position, nil
+}
+
+// QueuePosition keeps a queue member's place for another ttl and returns the
+// number of members ahead of it, or -1 if it has lost its place
+func (c *Client) QueuePosition(ctx context.Context, key string, member string, ttl time.Duration) (int64, error) {
+	return queuePositionScript.Run(ctx, c.Client, []string{key}, member, ttl.Milliseconds()).Int64()
+}
+
+// LeaveQueue removes a member from a queue
+func (c *Client) LeaveQueue(ctx context.Context, key string, member string) error {
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, key, member)
+		pipe.Del(ctx, key+":"+member)
+		return nil
+	})
+	return err
+}
+
+// QueueLength returns the number of members in a queue, including members
+// that stopped checking their position but have not reached the head yet
+func (c *Client) QueueLength(ctx context.Context, key string) (int64, error) {
+	return c.Client.ZCard(ctx, key).Result()
+}
+
+// newToken returns a random token identifying a lock owner or queue member
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// AcquireLease acquires or renews a lease held by owner
+func (c *Client) AcquireLease(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	result, err := acquireLeaseScript.Run(ctx, c.Client, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+// ReleaseLease releases a lease if it is still held by owner
+func (c *Client) ReleaseLease(ctx context.Context, key string, owner string) error {
+	return releaseLeaseScript.Run(ctx, c.Client, []string{key}, owner).Err()
+}
+
+// GetOrSet atomically sets a key with TTL unless it already exists and
+// returns the value the key holds afterwards
+func (c *Client) GetOrSet(ctx context.Context, key string, value interface{}, ttl time.Duration) (string, error) {
+	return getOrSetScript.Run(ctx, c.Client, []string{key}, value, ttl.Milliseconds()).Text()
+}
+
+// TakeFromCounter atomically subtracts amount from a counter if it holds at
+// least that much. It returns false if the counter is too low and
+// ErrCounterNotFound if it does not exist.
+func (c *Client) TakeFromCounter(ctx context.Context, key string, amount int64) (bool, error) {
+	remaining, err := takeCounterScript.Run(ctx, c.Client, []string{key}, amount).Int64()
+	if err != nil {
+		return false, err
+	}
+	if remaining == -2 {
+		return false, ErrCounterNotFound
+	}
+	return remaining >= 0, nil
+}
+
+// ReturnToCounter adds amount back to a counter unless it no longer exists
+func (c *Client) ReturnToCounter(ctx context.Context, key string, amount int64) error {
+	return returnCounterScript.Run(ctx, c.Client, []string{key}, amount).Err()
+}
+
+// GetCounter returns the value of a counter and whether it exists
+func (c *Client) GetCounter(ctx context.Context, key string) (int64, bool, error) {
+	value, err := c.Client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+// CompareAndSetCounter sets a counter to value only if it still holds
+// expected, and reports whether it did
+func (c *Client) CompareAndSetCounter(ctx context.Context, key string, expected, value int64) (bool, error) {
+	result, err := compareAndSetScript.Run(ctx, c.Client, []string{key}, expected, value).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
 }
 
 // IncrBy increments a key by the specified amount